@@ -15,16 +15,12 @@ func CreateTable(db *asynql.DB) error {
 }
 
 func Insert(db *asynql.DB) error {
-	r1 := db.Exec(`INSERT INTO test_table (id, name) VALUES (?, ?)`, 1, "alice")
-	r2 := db.Exec(`INSERT INTO test_table (id, name) VALUES (?, ?)`, 2, "bob")
-	r3 := db.Exec(`INSERT INTO test_table (id, name) VALUES (?, ?)`, 3, "jack")
-	for _, ch := range []<-chan *asynql.Result{r1, r2, r3} {
-		result := <-ch
-		if err := result.Err(); err != nil {
-			return err
-		}
-	}
-	return nil
+	_, err := asynql.WaitAll(
+		db.Exec(`INSERT INTO test_table (id, name) VALUES (?, ?)`, 1, "alice"),
+		db.Exec(`INSERT INTO test_table (id, name) VALUES (?, ?)`, 2, "bob"),
+		db.Exec(`INSERT INTO test_table (id, name) VALUES (?, ?)`, 3, "jack"),
+	)
+	return err
 }
 
 func Query(db *asynql.DB) error {
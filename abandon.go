@@ -0,0 +1,97 @@
+package asynql
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// AbandonedResult describes a result of an operation that has not been received within the timeout of WithAbandonDetector.
+// The goroutine of an operation blocks until its result is received, so a channel that nobody receives from leaks a goroutine,
+// and also a connection if the result is a *Rows.
+type AbandonedResult struct {
+	// Op is the kind of the operation.
+	Op HookOp
+
+	// Query is the query of the operation.
+	Query string
+
+	// Args is the arguments of the query.
+	Args []interface{}
+
+	// Delivered is the time when the result became ready to be received.
+	Delivered time.Time
+
+	// Stack is the stack trace of the goroutine that submitted the operation, or nil unless WithLeakStacks is given.
+	Stack []byte
+}
+
+// String returns a description of r that is suitable for a log.
+func (r *AbandonedResult) String() string {
+	s := fmt.Sprintf("asynql: result of %s %q delivered at %v is not received", r.Op, r.Query, r.Delivered.Format(time.RFC3339Nano))
+	if r.Stack != nil {
+		s += "\n" + string(r.Stack)
+	}
+	return s
+}
+
+// WithAbandonDetector returns an Option that reports the results of the operations of the DB, and of its transactions, connections and statements,
+// that have not been received within timeout after they became ready, to report, or to the standard logger if report is nil,
+// for finding the calls such as db.Exec whose channels are never read.
+// report is called in its own goroutine once for each abandoned result. The result is still sent if it is received later.
+func WithAbandonDetector(timeout time.Duration, report func(r *AbandonedResult)) Option {
+	return func(db *DB) {
+		db.leaks.abandon = timeout
+		db.leaks.reportAbandon = report
+	}
+}
+
+// watch reports the result of the operation of query if it is not received within the timeout of d after now.
+func (d *leakDetector) watch(v interface{}, op HookOp, query string, args []interface{}, stack []byte) {
+	w, ok := v.(abandonWatcher)
+	if !ok {
+		return
+	}
+	r := &AbandonedResult{
+		Op:        op,
+		Query:     query,
+		Args:      args,
+		Delivered: time.Now(),
+		Stack:     stack,
+	}
+	w.watchAbandon(time.AfterFunc(d.abandon, func() {
+		if d.reportAbandon == nil {
+			log.Print(r)
+			return
+		}
+		d.reportAbandon(r)
+	}))
+}
+
+// abandonWatcher is implemented by the results that can be watched by WithAbandonDetector.
+type abandonWatcher interface {
+	watchAbandon(t *time.Timer)
+	received()
+}
+
+// abandonTimer is embedded in the results to stop the timer of WithAbandonDetector when they are received.
+type abandonTimer struct {
+	timer *time.Timer
+}
+
+func (a *abandonTimer) watchAbandon(t *time.Timer) {
+	a.timer = t
+}
+
+func (a *abandonTimer) received() {
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+}
+
+// received tells v that it has been received, if it is watched by WithAbandonDetector.
+func received(v interface{}) {
+	if w, ok := v.(abandonWatcher); ok {
+		w.received()
+	}
+}
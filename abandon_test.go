@@ -0,0 +1,47 @@
+package asynql_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithAbandonDetector(t *testing.T) {
+	abandoned := make(chan *asynql.AbandonedResult, 10)
+	db := newTestDB(t, asynql.WithLeakStacks(), asynql.WithAbandonDetector(20*time.Millisecond, func(r *asynql.AbandonedResult) {
+		abandoned <- r
+	}))
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `UPDATE test_table SET name = ? WHERE id = ?`, "carol", 1)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	query := `UPDATE test_table SET name = ? WHERE id = ?`
+	ch := db.ExecContext(ctx, query, "dave", 2)
+	select {
+	case r := <-abandoned:
+		var actual interface{} = []interface{}{r.Op, r.Query, r.Args}
+		var expected interface{} = []interface{}{asynql.HookExec, query, []interface{}{"dave", 2}}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`abandoned result => %#v; want %#v`, actual, expected)
+		}
+		if !bytes.Contains(r.Stack, []byte("TestWithAbandonDetector")) {
+			t.Errorf(`abandoned result.Stack => %s; want the stack of TestWithAbandonDetector`, r.Stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal(`abandoned result is not reported`)
+	}
+	// The result can still be received after it has been reported.
+	if err := (<-ch).Err(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-abandoned:
+		t.Errorf(`abandoned result of %#v is reported; want no more reports`, r.Query)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,90 @@
+package asynql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+)
+
+// ErrAdvisoryLockUnsupported is returned by AdvisoryLock and TryAdvisoryLock when a DB has a dialect other than DialectPostgres.
+var ErrAdvisoryLockUnsupported = errors.New("asynql: advisory locks are supported only by PostgreSQL")
+
+// Unlocker releases a lock.
+type Unlocker interface {
+	// Unlock releases the lock. Calling Unlock more than once has no effect.
+	Unlock() error
+}
+
+// AdvisoryLock obtains the session-level advisory lock of key of PostgreSQL by pg_advisory_lock, waiting until it is available or ctx is done.
+// The lock is held on a connection reserved from the pool, and is released by the returned Unlocker or when ctx is done,
+// after which the connection is returned to the pool.
+func (db *DB) AdvisoryLock(ctx context.Context, key int64) (Unlocker, error) {
+	// pg_advisory_lock returns void, so it is called in the FROM clause to return true.
+	l, _, err := db.advisoryLock(ctx, "SELECT true FROM pg_advisory_lock($1)", key)
+	return l, err
+}
+
+// TryAdvisoryLock is the same as AdvisoryLock, but obtains the lock by pg_try_advisory_lock without waiting,
+// and reports whether the lock is obtained.
+// If the lock is held by another session, TryAdvisoryLock returns a nil Unlocker and false.
+func (db *DB) TryAdvisoryLock(ctx context.Context, key int64) (Unlocker, bool, error) {
+	return db.advisoryLock(ctx, "SELECT pg_try_advisory_lock($1)", key)
+}
+
+// advisoryLock runs query, which returns whether the lock of key is obtained, on a reserved connection.
+func (db *DB) advisoryLock(ctx context.Context, query string, key int64) (Unlocker, bool, error) {
+	if db.Dialect() != DialectPostgres {
+		return nil, false, ErrAdvisoryLockUnsupported
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var ok bool
+	if err := (<-conn.QueryRowContext(ctx, query, key)).Scan(&ok); err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+	l := &advisoryLock{
+		ctx:  context.WithoutCancel(ctx),
+		conn: conn,
+		key:  key,
+	}
+	l.stop = context.AfterFunc(ctx, func() {
+		l.Unlock()
+	})
+	return l, true, nil
+}
+
+// advisoryLock is an Unlocker of a session-level advisory lock held on conn.
+type advisoryLock struct {
+	ctx  context.Context
+	conn *Conn
+	key  int64
+	stop func() bool
+
+	once sync.Once
+	err  error
+}
+
+// Unlock releases the lock by pg_advisory_unlock, and returns the connection to the pool.
+// If the lock cannot be released, the connection is discarded instead, which ends its session and the lock with it.
+func (l *advisoryLock) Unlock() error {
+	l.once.Do(func() {
+		l.stop()
+		var released bool
+		if l.err = (<-l.conn.QueryRowContext(l.ctx, "SELECT pg_advisory_unlock($1)", l.key)).Scan(&released); l.err == nil && !released {
+			l.err = errors.New("asynql: advisory lock was not held")
+		}
+		if l.err != nil {
+			l.conn.Raw(func(interface{}) error {
+				return driver.ErrBadConn
+			})
+		}
+		if err := l.conn.Close(); l.err == nil {
+			l.err = err
+		}
+	})
+	return l.err
+}
@@ -0,0 +1,153 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// lockServer implements the advisory locks of PostgreSQL in memory, which are held by the sessions of lockConn.
+type lockServer struct {
+	mu       sync.Mutex
+	owners   map[int64]*lockConn
+	released chan struct{}
+}
+
+func (s *lockServer) Connect(context.Context) (driver.Conn, error) {
+	return &lockConn{s: s}, nil
+}
+
+func (s *lockServer) Driver() driver.Driver { return nil }
+
+type lockConn struct {
+	s *lockServer
+}
+
+func (c *lockConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *lockConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *lockConn) Close() error {
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	// The locks of a session are released when it ends.
+	for key, owner := range c.s.owners {
+		if owner == c {
+			delete(c.s.owners, key)
+		}
+	}
+	return nil
+}
+
+func (c *lockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	key := args[0].Value.(int64)
+	for {
+		c.s.mu.Lock()
+		owner, held := c.s.owners[key]
+		switch query {
+		case "SELECT true FROM pg_advisory_lock($1)", "SELECT pg_try_advisory_lock($1)":
+			if !held || owner == c {
+				c.s.owners[key] = c
+				c.s.mu.Unlock()
+				return &loRows{v: true}, nil
+			}
+			if query == "SELECT pg_try_advisory_lock($1)" {
+				c.s.mu.Unlock()
+				return &loRows{v: false}, nil
+			}
+		case "SELECT pg_advisory_unlock($1)":
+			if held && owner == c {
+				delete(c.s.owners, key)
+			}
+			c.s.mu.Unlock()
+			c.s.released <- struct{}{}
+			return &loRows{v: held && owner == c}, nil
+		default:
+			c.s.mu.Unlock()
+			return nil, fmt.Errorf("unexpected query %q", query)
+		}
+		c.s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestDB_AdvisoryLock(t *testing.T) {
+	server := &lockServer{owners: map[int64]*lockConn{}, released: make(chan struct{}, 10)}
+	db := asynql.OpenDB(server, asynql.WithDialect(asynql.DialectPostgres))
+	defer db.Close()
+	ctx := context.Background()
+	l, err := db.AdvisoryLock(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := db.TryAdvisoryLock(ctx, 42); err != nil || ok {
+		t.Errorf(`db.TryAdvisoryLock(ctx, 42) while locked => %v, %#v; want false, nil`, ok, err)
+	}
+	locked := make(chan asynql.Unlocker)
+	go func() {
+		l, err := db.AdvisoryLock(ctx, 42)
+		if err != nil {
+			t.Error(err)
+		}
+		locked <- l
+	}()
+	select {
+	case <-locked:
+		t.Fatalf(`db.AdvisoryLock(ctx, 42) returned while locked; want blocked`)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Errorf(`Unlocker.Unlock() twice => %#v; want nil`, err)
+	}
+	if err := (<-locked).Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	l, ok, err := db.TryAdvisoryLock(ctx, 42)
+	if err != nil || !ok {
+		t.Fatalf(`db.TryAdvisoryLock(ctx, 42) after unlock => %v, %#v; want true, nil`, ok, err)
+	}
+	l.Unlock()
+}
+
+func TestDB_AdvisoryLock_Context(t *testing.T) {
+	server := &lockServer{owners: map[int64]*lockConn{}, released: make(chan struct{}, 10)}
+	db := asynql.OpenDB(server, asynql.WithDialect(asynql.DialectPostgres))
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := db.AdvisoryLock(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case <-server.released:
+	case <-time.After(time.Second):
+		t.Fatal(`lock is not released when ctx is done`)
+	}
+	_, ok, err := db.TryAdvisoryLock(context.Background(), 1)
+	var actual interface{} = []interface{}{ok, err}
+	var expected interface{} = []interface{}{true, nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.TryAdvisoryLock(ctx, 1) after ctx is done => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDB_AdvisoryLock_Unsupported(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if _, err := db.AdvisoryLock(context.Background(), 1); !errors.Is(err, asynql.ErrAdvisoryLockUnsupported) {
+		t.Errorf(`db.AdvisoryLock(ctx, 1) on SQLite => %#v; want %#v`, err, asynql.ErrAdvisoryLockUnsupported)
+	}
+}
@@ -0,0 +1,241 @@
+package asynql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ArrayValue is a sql.Scanner and driver.Valuer of a slice, which is returned by Array and JSONArray.
+type ArrayValue struct {
+	v    reflect.Value
+	json bool
+}
+
+// Array returns an ArrayValue of a, which must be a slice of numbers, strings or booleans, or a pointer to it.
+// It binds a as a PostgreSQL array, and scans a PostgreSQL array or a JSON array into a, which must be a pointer to scan.
+// A NULL is scanned as a nil slice, but a NULL element cannot be scanned.
+//
+// ScanStruct scans the columns into the fields of those slice types in the same way without Array.
+func Array(a interface{}) *ArrayValue {
+	return &ArrayValue{
+		v: reflect.ValueOf(a),
+	}
+}
+
+// JSONArray is the same as Array, but binds a as a JSON array for the databases that have no array type, such as SQLite and MySQL.
+func JSONArray(a interface{}) *ArrayValue {
+	return &ArrayValue{
+		v:    reflect.ValueOf(a),
+		json: true,
+	}
+}
+
+// Scan implements sql.Scanner.
+func (a *ArrayValue) Scan(src interface{}) error {
+	if a.v.Kind() != reflect.Pointer || a.v.IsNil() || !isArrayType(a.v.Type().Elem()) {
+		return fmt.Errorf("asynql: cannot scan an array into %v", a.v.Type())
+	}
+	v := a.v.Elem()
+	var s string
+	switch src := src.(type) {
+	case nil:
+		v.SetZero()
+		return nil
+	case []byte:
+		s = string(src)
+	case string:
+		s = src
+	default:
+		return fmt.Errorf("asynql: cannot scan %T into %v as an array", src, v.Type())
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") && !strings.Contains(s, "]={") {
+		p := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(s), p.Interface()); err != nil {
+			return fmt.Errorf("asynql: cannot scan %q into %v: %w", s, v.Type(), err)
+		}
+		v.Set(p.Elem())
+		return nil
+	}
+	elems, err := parsePGArray(s)
+	if err != nil {
+		return fmt.Errorf("asynql: cannot scan %q into %v: %w", s, v.Type(), err)
+	}
+	slice := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+	for i, elem := range elems {
+		if elem == nil {
+			return fmt.Errorf("asynql: cannot scan a NULL element of %q into %v", s, v.Type())
+		}
+		if err := setArrayElem(slice.Index(i), *elem); err != nil {
+			return fmt.Errorf("asynql: cannot scan %q into %v: %w", s, v.Type(), err)
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (a *ArrayValue) Value() (driver.Value, error) {
+	v := a.v
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if !isArrayType(v.Type()) {
+		return nil, fmt.Errorf("asynql: cannot bind %v as an array", a.v.Type())
+	}
+	if v.IsNil() {
+		return nil, nil
+	}
+	if a.json {
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		e := v.Index(i)
+		switch e.Kind() {
+		case reflect.String:
+			b.WriteByte('"')
+			for _, c := range []byte(e.String()) {
+				if c == '"' || c == '\\' {
+					b.WriteByte('\\')
+				}
+				b.WriteByte(c)
+			}
+			b.WriteByte('"')
+		case reflect.Bool:
+			b.WriteString(strconv.FormatBool(e.Bool()))
+		case reflect.Float32, reflect.Float64:
+			b.WriteString(strconv.FormatFloat(e.Float(), 'g', -1, e.Type().Bits()))
+		default:
+			fmt.Fprint(&b, e.Interface())
+		}
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// isArrayType reports whether t is a slice type that Array supports.
+func isArrayType(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || reflect.PointerTo(t).Implements(scannerType) {
+		return false
+	}
+	switch t.Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+func setArrayElem(e reflect.Value, s string) error {
+	switch e.Kind() {
+	case reflect.String:
+		e.SetString(s)
+	case reflect.Bool:
+		switch s {
+		case "t", "true":
+			e.SetBool(true)
+		case "f", "false":
+			e.SetBool(false)
+		default:
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, e.Type().Bits())
+		if err != nil {
+			return err
+		}
+		e.SetFloat(f)
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, e.Type().Bits())
+		if err != nil {
+			return err
+		}
+		e.SetUint(n)
+	default:
+		n, err := strconv.ParseInt(s, 10, e.Type().Bits())
+		if err != nil {
+			return err
+		}
+		e.SetInt(n)
+	}
+	return nil
+}
+
+var errMalformedArray = errors.New("malformed array")
+
+// parsePGArray parses a one-dimensional PostgreSQL array literal such as {1,"a b",NULL}.
+// A NULL element is returned as nil.
+func parsePGArray(s string) ([]*string, error) {
+	if i := strings.IndexByte(s, '='); i >= 0 && strings.HasPrefix(s, "[") {
+		// The bounds of an array that doesn't start at 1, such as [0:1]={1,2}.
+		s = s[i+1:]
+	}
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, errMalformedArray
+	}
+	s = s[1 : len(s)-1]
+	var elems []*string
+	for i := 0; i < len(s); {
+		var elem *string
+		switch s[i] {
+		case '{':
+			return nil, errors.New("multi-dimensional arrays are not supported")
+		case '"':
+			var b strings.Builder
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+					if i == len(s) {
+						break
+					}
+				}
+				b.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errMalformedArray
+			}
+			i++
+			e := b.String()
+			elem = &e
+		default:
+			j := strings.IndexByte(s[i:], ',')
+			if j < 0 {
+				j = len(s) - i
+			}
+			e := strings.TrimSpace(s[i : i+j])
+			i += j
+			if !strings.EqualFold(e, "NULL") {
+				elem = &e
+			}
+		}
+		elems = append(elems, elem)
+		if i < len(s) {
+			if s[i] != ',' {
+				return nil, errMalformedArray
+			}
+			i++
+			if i == len(s) {
+				return nil, errMalformedArray
+			}
+		}
+	}
+	return elems, nil
+}
@@ -0,0 +1,111 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestArray_Scan(t *testing.T) {
+	for _, v := range []struct {
+		src      interface{}
+		dest     interface{}
+		expected interface{}
+	}{
+		{[]byte(`{1,2,3}`), new([]int64), []int64{1, 2, 3}},
+		{`{}`, new([]int64), []int64{}},
+		{`[0:1]={4,5}`, new([]int32), []int32{4, 5}},
+		{`{a,"b c","d\"e\\f",NULL_NOT}`, new([]string), []string{"a", "b c", `d"e\f`, "NULL_NOT"}},
+		{`{1.5,-2e3}`, new([]float64), []float64{1.5, -2000}},
+		{`{t,f}`, new([]bool), []bool{true, false}},
+		{`[1, 2]`, new([]uint), []uint{1, 2}},
+		{`["x", "y"]`, new([]string), []string{"x", "y"}},
+		{nil, &[]string{"x"}, []string(nil)},
+	} {
+		if err := asynql.Array(v.dest).Scan(v.src); err != nil {
+			t.Errorf(`asynql.Array(%T).Scan(%#v) => %#v; want nil`, v.dest, v.src, err)
+			continue
+		}
+		var actual interface{} = reflect.ValueOf(v.dest).Elem().Interface()
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`asynql.Array(%T).Scan(%#v) => %#v; want %#v`, v.dest, v.src, actual, v.expected)
+		}
+	}
+
+	for _, v := range []struct {
+		src  interface{}
+		dest interface{}
+	}{
+		{`{1,NULL}`, new([]int64)},
+		{`{{1,2},{3,4}}`, new([]int64)},
+		{`{1,x}`, new([]int64)},
+		{`{"a`, new([]string)},
+		{`1,2`, new([]int64)},
+		{int64(1), new([]int64)},
+		{`{1}`, []int64{}},
+		{`{1}`, new([]struct{})},
+	} {
+		if err := asynql.Array(v.dest).Scan(v.src); err == nil {
+			t.Errorf(`asynql.Array(%T).Scan(%#v) => nil; want error`, v.dest, v.src)
+		}
+	}
+}
+
+func TestArray_Value(t *testing.T) {
+	for _, v := range []struct {
+		value    *asynql.ArrayValue
+		expected driver.Value
+	}{
+		{asynql.Array([]int64{1, 2}), `{1,2}`},
+		{asynql.Array(&[]string{"a", `b"c\`}), `{"a","b\"c\\"}`},
+		{asynql.Array([]float64{1.5}), `{1.5}`},
+		{asynql.Array([]bool{true, false}), `{true,false}`},
+		{asynql.Array([]int64{}), `{}`},
+		{asynql.Array([]int64(nil)), nil},
+		{asynql.JSONArray([]string{"a", "b"}), `["a","b"]`},
+	} {
+		actual, err := v.value.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`Value() => %#v; want %#v`, actual, v.expected)
+		}
+	}
+}
+
+func TestArray_ScanStruct(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE tags (id INTEGER, tags TEXT, scores TEXT)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-db.ExecContext(ctx, `INSERT INTO tags VALUES (1, ?, ?)`,
+		asynql.JSONArray([]string{"a", "b"}), asynql.Array([]float64{0.5, 1}))).Err(); err != nil {
+		t.Fatal(err)
+	}
+	type tagged struct {
+		ID     int
+		Tags   []string
+		Scores []float64
+	}
+	rows := <-db.QueryContext(ctx, `SELECT id, tags, scores FROM tags`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var actual tagged
+	for rows.Next() {
+		if err := rows.ScanStruct(&actual); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := tagged{1, []string{"a", "b"}, []float64{0.5, 1}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`rows.ScanStruct(&tagged{}) => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,33 @@
+package asynql
+
+import (
+	"context"
+)
+
+// WithErrorHandler returns an Option that sets handler to the handler of the errors of ExecAsync and ExecAsyncContext.
+// handler is called with the error, which is a *QueryError, in the goroutine of the operation.
+// The errors are discarded if no handler is set.
+func WithErrorHandler(handler func(ctx context.Context, err error)) Option {
+	return func(db *DB) {
+		db.errorHandler = handler
+	}
+}
+
+// ExecAsync is the same as ExecAsyncContext with context.Background().
+func (db *DB) ExecAsync(query string, args ...interface{}) {
+	db.ExecAsyncContext(context.Background(), query, args...)
+}
+
+// ExecAsyncContext executes query with args in the background like ExecContext, but doesn't return a channel,
+// for the writes whose results nobody waits for, such as best-effort audit rows.
+// The error of the execution is passed to the handler set by WithErrorHandler.
+//
+// Note that the execution fails when ctx is canceled, so a ctx that ends with a request should be detached by context.WithoutCancel.
+func (db *DB) ExecAsyncContext(ctx context.Context, query string, args ...interface{}) {
+	ch := db.ExecContext(ctx, query, args...)
+	go func() {
+		if err := (<-ch).Err(); err != nil && db.errorHandler != nil {
+			db.errorHandler(ctx, err)
+		}
+	}()
+}
@@ -0,0 +1,40 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_ExecAsync(t *testing.T) {
+	errs := make(chan error, 1)
+	// WithFIFO runs the query after the insertion that nobody waits for.
+	db := newTestDB(t, asynql.WithFIFO(), asynql.WithErrorHandler(func(ctx context.Context, err error) {
+		errs <- err
+	}))
+	defer db.Close()
+	db.ExecAsync(`INSERT INTO unknown_table (id) VALUES (1)`)
+	var qe *asynql.QueryError
+	if err := <-errs; !errors.As(err, &qe) {
+		t.Fatalf(`db.ExecAsync(...); handled error => %#v; want *asynql.QueryError`, err)
+	}
+
+	db.ExecAsync(`INSERT INTO test_table (id, name) VALUES (3, 'carol')`)
+	var n int
+	if err := (<-db.QueryRow(`SELECT COUNT(*) FROM test_table`)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = n
+	var expected interface{} = 3
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`COUNT(*) after db.ExecAsync(...) => %#v; want %#v`, actual, expected)
+	}
+	select {
+	case err := <-errs:
+		t.Errorf(`db.ExecAsync(...); handled error => %#v; want none`, err)
+	default:
+	}
+}
@@ -0,0 +1,178 @@
+// Package asynqlsqlc adapts asynql to the code generated by sqlc.
+//
+// DBTX implements the DBTX interface that the generated code takes, so the generated queries run through
+// the channels of asynql, with its hooks, metrics, retries and limits:
+//
+//	queries := gen.New(asynqlsqlc.New(db))
+//	user, err := queries.GetUser(ctx, id)
+//
+// The generated methods are synchronous. asynql.Go runs them concurrently:
+//
+//	user := asynql.Go(func() (gen.User, error) { return queries.GetUser(ctx, id) })
+//	orders := asynql.Go(func() ([]gen.Order, error) { return queries.ListOrders(ctx, id) })
+//	if _, err := asynql.WaitAll(user, orders); err != nil {
+//		...
+//	}
+//
+// The generated code takes *sql.Rows and *sql.Row, whose Close and Scan cannot finish a query of asynql,
+// so DBTX reads the rows into memory by asynql.Materialize and closes them before returning copies of them.
+// The rows of QueryContext are limited by WithMaxResultSize and WithResultLimit as the other helpers that read rows into memory are.
+package asynqlsqlc
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/naoina/asynql"
+)
+
+// querier is the asynchronous operations of *asynql.DB, *asynql.Tx and *asynql.Conn.
+type querier interface {
+	asynql.Execer
+	asynql.Queryer
+	asynql.RowQueryer
+}
+
+// DBTX is a synchronous facade of an *asynql.DB, *asynql.Tx or *asynql.Conn, which implements the DBTX interface of sqlc.
+type DBTX struct {
+	q       querier
+	prepare func(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// New returns a new DBTX of db.
+func New(db *asynql.DB) *DBTX {
+	return &DBTX{
+		q:       db,
+		prepare: db.DB.PrepareContext,
+	}
+}
+
+// NewTx returns a new DBTX of tx, which is passed to the WithTx method of the generated Queries.
+func NewTx(tx *asynql.Tx) *DBTX {
+	return &DBTX{
+		q:       tx,
+		prepare: tx.Tx.PrepareContext,
+	}
+}
+
+// NewConn returns a new DBTX of c.
+func NewConn(c *asynql.Conn) *DBTX {
+	return &DBTX{
+		q:       c,
+		prepare: c.Conn.PrepareContext,
+	}
+}
+
+// ExecContext executes a query with args, and waits for the result.
+func (d *DBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r := <-d.q.ExecContext(ctx, query, args...)
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	return r.Result, nil
+}
+
+// PrepareContext prepares a statement, which sqlc uses if emit_prepared_queries is enabled.
+// The statement is the one of database/sql, so the queries through it are not run by asynql.
+func (d *DBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.prepare(ctx, query)
+}
+
+// QueryContext executes a query with args, and waits for the rows, which are read into memory.
+func (d *DBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	snap, err := asynql.Materialize(<-d.q.QueryContext(ctx, query, args...))
+	if err != nil {
+		return nil, err
+	}
+	rs := snap.Rows()
+	return rs.Rows, rs.Err()
+}
+
+// QueryRowContext executes a query that is expected to return at most one row, and waits for the row, which is read into memory.
+// The query is run as a Query of asynql rather than a QueryRow, since the columns of a row are not known until it is scanned.
+func (d *DBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	snap, err := firstRow(<-d.q.QueryContext(ctx, query, args...))
+	if err != nil {
+		return errRow(err)
+	}
+	return snap.Row().Row
+}
+
+// firstRow reads the first row of rs into a Snapshot, and closes rs.
+func firstRow(rs *asynql.Rows) (*asynql.Snapshot, error) {
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	columns, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	snap := &asynql.Snapshot{
+		Columns: columns,
+	}
+	if rs.Next() {
+		values := make([]interface{}, len(columns))
+		dests := make([]interface{}, len(columns))
+		for i := range values {
+			dests[i] = &values[i]
+		}
+		if err := rs.Scan(dests...); err != nil {
+			return nil, err
+		}
+		snap.Values = [][]interface{}{values}
+	}
+	return snap, rs.Err()
+}
+
+var (
+	errDBOnce sync.Once
+	errDB     *sql.DB
+)
+
+// errRow returns a *sql.Row that has err, which database/sql doesn't provide a way to construct.
+// It is made by a query to errDB, whose driver fails with the error carried by the context.
+func errRow(err error) *sql.Row {
+	errDBOnce.Do(func() {
+		errDB = sql.OpenDB(errConnector{})
+	})
+	return errDB.QueryRowContext(context.WithValue(context.Background(), errKey{}, err), "")
+}
+
+type errKey struct{}
+
+type errConnector struct{}
+
+func (errConnector) Connect(context.Context) (driver.Conn, error) {
+	return errConn{}, nil
+}
+
+func (errConnector) Driver() driver.Driver {
+	return errDriver{}
+}
+
+type errDriver struct{}
+
+func (errDriver) Open(string) (driver.Conn, error) {
+	return errConn{}, nil
+}
+
+type errConn struct{}
+
+func (errConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return nil, ctx.Value(errKey{}).(error)
+}
+
+func (errConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (errConn) Close() error {
+	return nil
+}
+
+func (errConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
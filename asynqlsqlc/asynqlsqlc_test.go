@@ -0,0 +1,183 @@
+package asynqlsqlc_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/naoina/asynql"
+	"github.com/naoina/asynql/asynqlsqlc"
+)
+
+// DBTX and Queries are in the shape of the code generated by sqlc.
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+type Queries struct {
+	db DBTX
+}
+
+func (q *Queries) CreateUser(ctx context.Context, id int64, name string) error {
+	_, err := q.db.ExecContext(ctx, `INSERT INTO users (id, name) VALUES (?, ?)`, id, name)
+	return err
+}
+
+func (q *Queries) GetUser(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, `SELECT name FROM users WHERE id = ?`, id)
+	var name string
+	err := row.Scan(&name)
+	return name, err
+}
+
+func (q *Queries) ListUsers(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT name FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func newTestDB(t *testing.T, opts ...asynql.Option) *asynql.DB {
+	db, err := asynql.Open("sqlite3", ":memory:", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	if err := (<-db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDBTX(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	q := &Queries{db: asynqlsqlc.New(db)}
+	if err := q.CreateUser(ctx, 1, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Queries{db: asynqlsqlc.NewTx(tx)}).CreateUser(ctx, 2, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	names, err := q.ListUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = names
+	var expected interface{} = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`q.ListUsers(ctx) => %#v; want %#v`, actual, expected)
+	}
+
+	name := asynql.Go(func() (string, error) {
+		return q.GetUser(ctx, 2)
+	})
+	missing := asynql.Go(func() (string, error) {
+		return q.GetUser(ctx, 3)
+	})
+	o := <-name
+	actual = []interface{}{o.V, o.Err()}
+	expected = []interface{}{"bob", nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`q.GetUser(ctx, 2) => %#v; want %#v`, actual, expected)
+	}
+	actual = (<-missing).Err()
+	expected = sql.ErrNoRows
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`q.GetUser(ctx, 3) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDBTX_QueryRowContext_Expired(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// The operation is dropped before reaching the database, so asynql has no *sql.Row to return.
+	q := &Queries{db: asynqlsqlc.New(db)}
+	if _, err := q.GetUser(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf(`q.GetUser(ctx, 1) => %#v; want %#v`, err, context.Canceled)
+	}
+}
+
+func TestDBTX_Tx_Commit(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &Queries{db: asynqlsqlc.NewTx(tx)}
+	if err := q.CreateUser(ctx, 1, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.ListUsers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.GetUser(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	// Commit waits for the queries of tx, so it returns only if the rows and the row have been released.
+	done := make(chan error, 1)
+	go func() {
+		done <- tx.Commit()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal(`tx.Commit() doesn't return`)
+	}
+}
+
+func TestDBTX_QueryContext_Limit(t *testing.T) {
+	db := newTestDB(t, asynql.WithMaxResultSize(1, 0))
+	defer db.Close()
+	ctx := context.Background()
+	q := &Queries{db: asynqlsqlc.New(db)}
+	for _, v := range []struct {
+		id   int64
+		name string
+	}{{1, "alice"}, {2, "bob"}} {
+		if err := q.CreateUser(ctx, v.id, v.name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := q.ListUsers(ctx); !errors.Is(err, asynql.ErrResultTooLarge) {
+		t.Errorf(`q.ListUsers(ctx) => %#v; want %#v`, err, asynql.ErrResultTooLarge)
+	}
+	name, err := q.GetUser(ctx, 2)
+	var actual interface{} = []interface{}{name, err}
+	var expected interface{} = []interface{}{"bob", nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`q.GetUser(ctx, 2) => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,435 @@
+// Package asynqltest provides a scriptable fake database for the tests of the code that uses asynql.
+//
+// New returns a real *asynql.DB whose driver answers the queries from the expectations registered on the Mock,
+// so the code under test runs through the same channels, transactions and statements as in production,
+// without a database server, SQLite or a mocking library:
+//
+//	db, mock := asynqltest.New()
+//	defer db.Close()
+//	mock.ExpectQuery("SELECT name FROM users WHERE id = ?").WithArgs(1).
+//		WillReturnRows([]string{"name"}, []driver.Value{"alice"})
+//	...
+//	if err := mock.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+//
+// The expectations are matched regardless of their order, because asynchronous operations run in no particular order.
+package asynqltest
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// ErrUnexpected is returned by an operation that matches no expectation.
+var ErrUnexpected = errors.New("asynqltest: unexpected operation")
+
+type op int
+
+const (
+	opExec op = iota
+	opQuery
+	opBegin
+	opCommit
+	opRollback
+)
+
+func (o op) String() string {
+	switch o {
+	case opExec:
+		return "exec"
+	case opQuery:
+		return "query"
+	case opBegin:
+		return "begin"
+	case opCommit:
+		return "commit"
+	case opRollback:
+		return "rollback"
+	}
+	return "unknown"
+}
+
+// Mock holds the expectations of a fake database.
+// It is safe for concurrent use.
+type Mock struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	unexpected   []string
+}
+
+// New returns a new *asynql.DB that is backed by the returned Mock.
+// opts are passed to asynql.OpenDB.
+func New(opts ...asynql.Option) (*asynql.DB, *Mock) {
+	m := &Mock{}
+	return asynql.OpenDB(&connector{m: m}, opts...), m
+}
+
+// ExpectExec expects an Exec of query, which is compared ignoring the differences in whitespace.
+// By default, the Exec returns a result with no rows affected.
+func (m *Mock) ExpectExec(query string) *Expectation {
+	return m.expect(opExec, query)
+}
+
+// ExpectQuery expects a Query or QueryRow of query, which is compared ignoring the differences in whitespace.
+// By default, the Query returns no columns and no rows.
+func (m *Mock) ExpectQuery(query string) *Expectation {
+	return m.expect(opQuery, query)
+}
+
+// ExpectBegin expects a beginning of a transaction.
+func (m *Mock) ExpectBegin() *Expectation {
+	return m.expect(opBegin, "")
+}
+
+// ExpectCommit expects a commit of a transaction.
+func (m *Mock) ExpectCommit() *Expectation {
+	return m.expect(opCommit, "")
+}
+
+// ExpectRollback expects a rollback of a transaction.
+func (m *Mock) ExpectRollback() *Expectation {
+	return m.expect(opRollback, "")
+}
+
+func (m *Mock) expect(o op, query string) *Expectation {
+	e := &Expectation{
+		op:    o,
+		query: query,
+		times: 1,
+	}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// ExpectationsWereMet returns an error if an expectation has not been met,
+// or an operation has matched no expectation.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for _, e := range m.expectations {
+		if e.times > 0 && e.calls < e.times {
+			errs = append(errs, fmt.Errorf("asynqltest: %v has been called %d times; want %d", e, e.calls, e.times))
+		}
+	}
+	for _, s := range m.unexpected {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrUnexpected, s))
+	}
+	return errors.Join(errs...)
+}
+
+// match finds the expectation of an operation and counts the call.
+func (m *Mock) match(o op, query string, args []driver.NamedValue) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if e.matches(o, query, args) && (e.times == 0 || e.calls < e.times) {
+			e.calls++
+			return e, nil
+		}
+	}
+	s := o.String()
+	if query != "" {
+		s += fmt.Sprintf(" %q with %v", query, namedValues(args))
+	}
+	m.unexpected = append(m.unexpected, s)
+	return nil, fmt.Errorf("%w: %s", ErrUnexpected, s)
+}
+
+// Expectation is an expected operation and its canned outcome.
+type Expectation struct {
+	op    op
+	query string
+	args  []driver.Value
+
+	columns      []string
+	rows         [][]driver.Value
+	lastInsertID int64
+	rowsAffected int64
+	err          error
+	delay        time.Duration
+
+	// times is the expected number of the calls, or 0 for any number.
+	times int
+	calls int
+}
+
+// WithArgs restricts the expectation to the operations with args.
+// The arguments are compared after the default conversion of database/sql, e.g. an int is compared as an int64.
+// Without WithArgs, the expectation matches any arguments.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = make([]driver.Value, len(args))
+	for i, arg := range args {
+		v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			panic(fmt.Sprintf("asynqltest: cannot convert argument %d: %v", i, err))
+		}
+		e.args[i] = v
+	}
+	return e
+}
+
+// WillReturnResult sets the result of an Exec.
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.lastInsertID, e.rowsAffected = lastInsertID, rowsAffected
+	return e
+}
+
+// WillReturnRows sets the columns and the rows of a Query.
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]driver.Value) *Expectation {
+	e.columns, e.rows = columns, rows
+	return e
+}
+
+// WillReturnError makes the operation fail with err.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// WillDelay delays the outcome of the operation by d, which is useful to exercise the asynchronous code paths.
+// The delay is cut short by the cancellation of the context of the operation.
+func (e *Expectation) WillDelay(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+// Times sets the number of the times the operation is expected, which is 1 by default.
+// If n is 0, the expectation matches any number of the operations, including none.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// String returns the description of e.
+func (e *Expectation) String() string {
+	if e.query == "" {
+		return e.op.String()
+	}
+	if e.args == nil {
+		return fmt.Sprintf("%v %q", e.op, e.query)
+	}
+	return fmt.Sprintf("%v %q with %v", e.op, e.query, e.args)
+}
+
+func (e *Expectation) matches(o op, query string, args []driver.NamedValue) bool {
+	if e.op != o || collapseSpace(e.query) != collapseSpace(query) {
+		return false
+	}
+	return e.args == nil || valuesEqual(e.args, namedValues(args))
+}
+
+// valuesEqual reports whether a and b are the same values.
+// The times are compared by time.Time.Equal, because they may have lost the location and the monotonic clock in a recording.
+func valuesEqual(a, b []driver.Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if t, ok := a[i].(time.Time); ok {
+			if u, ok := b[i].(time.Time); !ok || !t.Equal(u) {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// wait waits for the delay of e.
+func (e *Expectation) wait(ctx context.Context) error {
+	if e.delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(e.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func toNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+// run matches an operation and waits for its delay.
+func (m *Mock) run(ctx context.Context, o op, query string, args []driver.NamedValue) (*Expectation, error) {
+	e, err := m.match(o, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.wait(ctx); err != nil {
+		return nil, err
+	}
+	return e, e.err
+}
+
+type connector struct {
+	m *Mock
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &conn{m: c.m}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("asynqltest: use New to open a fake database")
+}
+
+type conn struct {
+	m *Mock
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.m.run(ctx, opBegin, "", nil); err != nil {
+		return nil, err
+	}
+	return &tx{m: c.m}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.m.run(ctx, opExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &result{lastInsertID: e.lastInsertID, rowsAffected: e.rowsAffected}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.m.run(ctx, opQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: e.columns, values: e.rows}, nil
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+type tx struct {
+	m *Mock
+}
+
+func (t *tx) Commit() error {
+	_, err := t.m.run(context.Background(), opCommit, "", nil)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	_, err := t.m.run(context.Background(), opRollback, "", nil)
+	return err
+}
+
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r *result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r *result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	i       int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.i >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.i])
+	r.i++
+	return nil
+}
@@ -0,0 +1,105 @@
+package asynqltest_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+	"github.com/naoina/asynql/asynqltest"
+)
+
+func TestMock(t *testing.T) {
+	db, mock := asynqltest.New()
+	defer db.Close()
+	mock.ExpectQuery("SELECT name FROM users WHERE id = ?").WithArgs(1).
+		WillReturnRows([]string{"name"}, []driver.Value{"alice"})
+	mock.ExpectQuery("SELECT name FROM users WHERE id = ?").WithArgs(2).
+		WillReturnRows([]string{"name"}, []driver.Value{"bob"}).WillDelay(10 * time.Millisecond)
+	mock.ExpectExec("UPDATE users SET name = ?").WillReturnResult(0, 2)
+
+	slow := db.QueryRow("SELECT name FROM users WHERE id = ?", 2)
+	fast := db.QueryRow("SELECT name\n  FROM users WHERE id = ?", 1)
+	var names []string
+	for _, ch := range []<-chan *asynql.Row{fast, slow} {
+		var name string
+		if err := (<-ch).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	var actual interface{} = names
+	var expected interface{} = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryRow(...).Scan => %#v; want %#v`, actual, expected)
+	}
+
+	r := <-db.Exec("UPDATE users SET name = ?", "carol")
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = n
+	expected = int64(2)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Exec(...).RowsAffected() => %#v; want %#v`, actual, expected)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMock_Tx(t *testing.T) {
+	db, mock := asynqltest.New()
+	defer db.Close()
+	errBoom := errors.New("boom")
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users (name) VALUES (?)").WithArgs("alice").WillReturnResult(1, 1)
+	mock.ExpectExec("INSERT INTO users (name) VALUES (?)").WithArgs("bob").WillReturnError(errBoom)
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := tx.Prepare("INSERT INTO users (name) VALUES (?)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-stmt.Exec("alice")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-stmt.Exec("bob")).Err(); !errors.Is(err, errBoom) {
+		t.Errorf(`stmt.Exec("bob").Err() => %#v; want %#v`, err, errBoom)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMock_Unexpected(t *testing.T) {
+	db, mock := asynqltest.New()
+	defer db.Close()
+	mock.ExpectExec("DELETE FROM users").Times(0)
+	mock.ExpectQuery("SELECT 1")
+
+	if err := (<-db.Exec("DELETE FROM groups")).Err(); !errors.Is(err, asynqltest.ErrUnexpected) {
+		t.Errorf(`db.Exec("DELETE FROM groups").Err() => %#v; want %#v`, err, asynqltest.ErrUnexpected)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	(<-db.ExecContext(ctx, "DELETE FROM users")).Err()
+	err := mock.ExpectationsWereMet()
+	if !errors.Is(err, asynqltest.ErrUnexpected) {
+		t.Errorf(`mock.ExpectationsWereMet() => %#v; want %#v`, err, asynqltest.ErrUnexpected)
+	}
+}
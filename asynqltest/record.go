@@ -0,0 +1,408 @@
+package asynqltest
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// Recorder is a driver.Connector that records the queries, the arguments and the results
+// of the connections of another connector, for the golden tests that replay them by Replay.
+//
+//	rec := asynqltest.NewRecorder(connector)
+//	db := asynql.OpenDB(rec)
+//	... // run against a live database
+//	err := rec.WriteFile("testdata/golden.json")
+//
+// The rows of a query are read into memory as soon as it is executed.
+// The transactions are not recorded, since they cannot affect the results in a replay.
+type Recorder struct {
+	c driver.Connector
+
+	mu      sync.Mutex
+	entries []recordEntry
+}
+
+// NewRecorder returns a new Recorder that records the connections of c.
+// For a driver that is opened by name, the connector can be obtained by the OpenConnector method of its driver.DriverContext.
+func NewRecorder(c driver.Connector) *Recorder {
+	return &Recorder{
+		c: c,
+	}
+}
+
+// Connect implements driver.Connector.
+func (r *Recorder) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := r.c.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &recordConn{Conn: conn, r: r}, nil
+}
+
+// Driver implements driver.Connector.
+// It returns the driver of the underlying connector, so that the dialect is detected as usual.
+func (r *Recorder) Driver() driver.Driver {
+	return r.c.Driver()
+}
+
+// Save writes the recordings to w in JSON.
+func (r *Recorder) Save(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.entries)
+}
+
+// WriteFile writes the recordings to the file named path in JSON.
+func (r *Recorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (r *Recorder) record(e recordEntry, args []driver.NamedValue, err error) {
+	e.Args = make([]recordValue, len(args))
+	for i, arg := range args {
+		e.Args[i] = recordValue{arg.Value}
+	}
+	if err != nil {
+		e.Err = err.Error()
+	}
+	r.mu.Lock()
+	r.entries = append(r.entries, e)
+	r.mu.Unlock()
+}
+
+func (r *Recorder) recordExec(query string, args []driver.NamedValue, res driver.Result, err error) {
+	e := recordEntry{
+		Op:    opExec.String(),
+		Query: query,
+	}
+	if err == nil {
+		e.LastInsertID, _ = res.LastInsertId()
+		e.RowsAffected, _ = res.RowsAffected()
+	}
+	r.record(e, args, err)
+}
+
+// recordQuery reads the rows of a query into memory and records them.
+func (r *Recorder) recordQuery(query string, args []driver.NamedValue, rs driver.Rows, err error) (driver.Rows, error) {
+	e := recordEntry{
+		Op:    opQuery.String(),
+		Query: query,
+	}
+	var mem *rows
+	if err == nil {
+		mem, err = readRows(rs)
+	}
+	if err == nil {
+		e.Columns = mem.columns
+		for _, row := range mem.values {
+			values := make([]recordValue, len(row))
+			for i, v := range row {
+				values[i] = recordValue{v}
+			}
+			e.Rows = append(e.Rows, values)
+		}
+	}
+	r.record(e, args, err)
+	if err != nil {
+		return nil, err
+	}
+	return mem, nil
+}
+
+func readRows(rs driver.Rows) (*rows, error) {
+	defer rs.Close()
+	mem := &rows{
+		columns: rs.Columns(),
+	}
+	for {
+		dest := make([]driver.Value, len(mem.columns))
+		if err := rs.Next(dest); err != nil {
+			if err == io.EOF {
+				return mem, nil
+			}
+			return nil, err
+		}
+		for i, v := range dest {
+			// The driver may reuse the buffer in the next call of Next.
+			if b, ok := v.([]byte); ok {
+				dest[i] = append([]byte(nil), b...)
+			}
+		}
+		mem.values = append(mem.values, dest)
+	}
+}
+
+// Replay returns a new *asynql.DB that serves the recordings written by Recorder.Save from r, and the Mock that holds them.
+// Each recording is served once, and the recordings of the same query and arguments are served in the order they were recorded,
+// so the results are deterministic even if the queries run concurrently.
+// The transactions always succeed.
+// opts are passed to asynql.OpenDB; WithDialect is needed if the code under test depends on the dialect.
+func Replay(r io.Reader, opts ...asynql.Option) (*asynql.DB, *Mock, error) {
+	var entries []recordEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, nil, fmt.Errorf("asynqltest: cannot read the recordings: %w", err)
+	}
+	db, m := New(opts...)
+	for _, entry := range entries {
+		args := make([]interface{}, len(entry.Args))
+		for i, arg := range entry.Args {
+			args[i] = arg.v
+		}
+		var e *Expectation
+		switch entry.Op {
+		case opExec.String():
+			e = m.ExpectExec(entry.Query).WillReturnResult(entry.LastInsertID, entry.RowsAffected)
+		case opQuery.String():
+			rows := make([][]driver.Value, len(entry.Rows))
+			for i, row := range entry.Rows {
+				rows[i] = make([]driver.Value, len(row))
+				for j, v := range row {
+					rows[i][j] = v.v
+				}
+			}
+			e = m.ExpectQuery(entry.Query).WillReturnRows(entry.Columns, rows...)
+		default:
+			db.Close()
+			return nil, nil, fmt.Errorf("asynqltest: unknown operation %q in the recordings", entry.Op)
+		}
+		e.WithArgs(args...)
+		if entry.Err != "" {
+			e.WillReturnError(errors.New(entry.Err))
+		}
+	}
+	m.ExpectBegin().Times(0)
+	m.ExpectCommit().Times(0)
+	m.ExpectRollback().Times(0)
+	return db, m, nil
+}
+
+// ReplayFile is the same as Replay, but reads the recordings from the file named path.
+func ReplayFile(path string, opts ...asynql.Option) (*asynql.DB, *Mock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return Replay(f, opts...)
+}
+
+// recordEntry is a recorded operation.
+type recordEntry struct {
+	Op           string          `json:"op"`
+	Query        string          `json:"query"`
+	Args         []recordValue   `json:"args"`
+	Columns      []string        `json:"columns,omitempty"`
+	Rows         [][]recordValue `json:"rows,omitempty"`
+	LastInsertID int64           `json:"last_insert_id,omitempty"`
+	RowsAffected int64           `json:"rows_affected,omitempty"`
+	Err          string          `json:"error,omitempty"`
+}
+
+// recordValue is a driver.Value that keeps its type in JSON.
+// A value of a type other than those of driver.Value is recorded as a string.
+type recordValue struct {
+	v driver.Value
+}
+
+type typedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (v recordValue) MarshalJSON() ([]byte, error) {
+	var typ string
+	var value interface{} = v.v
+	switch x := v.v.(type) {
+	case nil:
+		return []byte("null"), nil
+	case int64:
+		typ = "int64"
+	case float64:
+		typ = "float64"
+	case bool:
+		typ = "bool"
+	case []byte:
+		typ = "bytes"
+	case string:
+		typ = "string"
+	case time.Time:
+		typ, value = "time", x.Format(time.RFC3339Nano)
+	default:
+		typ, value = "string", fmt.Sprint(x)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(typedValue{Type: typ, Value: b})
+}
+
+func (v *recordValue) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		v.v = nil
+		return nil
+	}
+	var tv typedValue
+	if err := json.Unmarshal(data, &tv); err != nil {
+		return err
+	}
+	var err error
+	switch tv.Type {
+	case "int64":
+		var x int64
+		err = json.Unmarshal(tv.Value, &x)
+		v.v = x
+	case "float64":
+		var x float64
+		err = json.Unmarshal(tv.Value, &x)
+		v.v = x
+	case "bool":
+		var x bool
+		err = json.Unmarshal(tv.Value, &x)
+		v.v = x
+	case "bytes":
+		var x []byte
+		err = json.Unmarshal(tv.Value, &x)
+		v.v = x
+	case "string":
+		var x string
+		err = json.Unmarshal(tv.Value, &x)
+		v.v = x
+	case "time":
+		var s string
+		if err = json.Unmarshal(tv.Value, &s); err == nil {
+			v.v, err = time.Parse(time.RFC3339Nano, s)
+		}
+	default:
+		err = fmt.Errorf("asynqltest: unknown type %q in the recordings", tv.Type)
+	}
+	return err
+}
+
+// recordConn records the operations of a driver.Conn.
+type recordConn struct {
+	driver.Conn
+	r *Recorder
+}
+
+func (c *recordConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *recordConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(ctx, query)
+	} else {
+		s, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &recordStmt{Stmt: s, r: c.r, query: query}, nil
+}
+
+func (c *recordConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// ExecContext records the Exec if the underlying conn executes it directly.
+// Otherwise, database/sql prepares the query, and the statement records it.
+func (c *recordConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	res, err := e.ExecContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	c.r.recordExec(query, args, res, err)
+	return res, err
+}
+
+// QueryContext records the Query if the underlying conn executes it directly.
+// Otherwise, database/sql prepares the query, and the statement records it.
+func (c *recordConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	rs, err := q.QueryContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	return c.r.recordQuery(query, args, rs, err)
+}
+
+func (c *recordConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// recordStmt records the operations of a driver.Stmt.
+type recordStmt struct {
+	driver.Stmt
+	r     *Recorder
+	query string
+}
+
+func (s *recordStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamedValues(args))
+}
+
+func (s *recordStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamedValues(args))
+}
+
+func (s *recordStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(namedValues(args))
+	}
+	s.r.recordExec(s.query, args, res, err)
+	return res, err
+}
+
+func (s *recordStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var rs driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rs, err = q.QueryContext(ctx, args)
+	} else {
+		rs, err = s.Stmt.Query(namedValues(args))
+	}
+	return s.r.recordQuery(s.query, args, rs, err)
+}
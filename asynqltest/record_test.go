@@ -0,0 +1,103 @@
+package asynqltest_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/naoina/asynql"
+	"github.com/naoina/asynql/asynqltest"
+)
+
+type dsnConnector struct {
+	d   driver.Driver
+	dsn string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.d }
+
+func sqliteConnector(t *testing.T) driver.Connector {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	return dsnConnector{d: db.Driver(), dsn: ":memory:"}
+}
+
+// run is the code under test, which is run against both a live database and a replay.
+func run(t *testing.T, db *asynql.DB) ([]string, error) {
+	stmt, err := db.Prepare(`INSERT INTO users (id, name) VALUES (?, ?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	for i, name := range []string{"alice", "bob"} {
+		if err := (<-stmt.Exec(i+1, name)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows := <-db.Query(`SELECT name FROM users WHERE id >= ? ORDER BY id`, 1)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names, (<-db.Exec(`DELETE FROM missing_table`)).Err()
+}
+
+func TestRecorder(t *testing.T) {
+	rec := asynqltest.NewRecorder(sqliteConnector(t))
+	db := asynql.OpenDB(rec)
+	db.SetMaxOpenConns(1)
+	if err := (<-db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	live, liveErr := run(t, db)
+	db.Close()
+	if liveErr == nil {
+		t.Fatal(`run(t, db) => _, nil; want error`)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	db, mock, err := asynqltest.Replay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := (<-db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	replayed, replayedErr := run(t, db)
+	var actual interface{} = replayed
+	var expected interface{} = live
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`run(t, replay) => %#v; want %#v`, actual, expected)
+	}
+	if replayedErr == nil || errors.Unwrap(replayedErr).Error() != errors.Unwrap(liveErr).Error() {
+		t.Errorf(`run(t, replay) => _, %#v; want %#v`, replayedErr, liveErr)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if err := (<-db.Exec(`DELETE FROM users`)).Err(); !errors.Is(err, asynqltest.ErrUnexpected) {
+		t.Errorf(`db.Exec("DELETE FROM users").Err() => %#v; want %#v`, err, asynqltest.ErrUnexpected)
+	}
+}
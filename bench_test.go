@@ -0,0 +1,61 @@
+package asynql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// BenchmarkDB_ExecContext measures the overhead of asynql on top of database/sql with a driver that does nothing.
+func BenchmarkDB_ExecContext(b *testing.B) {
+	db := asynql.OpenDB(&notifyConnector{conn: sleepConn{}})
+	defer db.Close()
+	ctx := context.Background()
+	b.Run("asynql", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := (<-db.ExecContext(ctx, "exec")).Err(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("database/sql", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := db.DB.ExecContext(ctx, "exec"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDB_ExecContext_Parallel(b *testing.B) {
+	db := asynql.OpenDB(&notifyConnector{conn: sleepConn{}})
+	defer db.Close()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := (<-db.ExecContext(ctx, "exec")).Err(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkDB_ExecContext_Workers(b *testing.B) {
+	db := asynql.OpenDB(&notifyConnector{conn: sleepConn{}}, asynql.WithWorkers(4, 64))
+	defer db.Close()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := (<-db.ExecContext(ctx, "exec")).Err(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
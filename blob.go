@@ -0,0 +1,173 @@
+package asynql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// DefaultBlobChunkSize is the number of the bytes that BlobReader reads per query if no chunk size is given.
+const DefaultBlobChunkSize = 1 << 20
+
+// ErrNoWhere is reported when no columns are given to match the row by, which would match all the rows of the table.
+var ErrNoWhere = errors.New("asynql: no columns to match rows by")
+
+// errBlobReaderClosed is returned by the Read of a closed BlobReader.
+var errBlobReaderClosed = errors.New("asynql: read from closed BlobReader")
+
+// ScanReader returns a reader of the column col of the current row, counting from 0, instead of scanning it into a []byte.
+// The reader reads the buffer of the driver without copying it, so it is valid only until Next, NextResultSet or Close is called.
+// A NULL is read as empty.
+//
+// database/sql reads a value into memory before it is returned, so ScanReader saves a copy of a large value,
+// but the value is not streamed from the database. Use BlobReader to stream a value in chunks.
+func (rs *Rows) ScanReader(col int) (io.ReadCloser, error) {
+	columns, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if col < 0 || col >= len(columns) {
+		return nil, fmt.Errorf("asynql: ScanReader: column index %d out of range [0, %d)", col, len(columns))
+	}
+	raws := make([]sql.RawBytes, len(columns))
+	dests := make([]interface{}, len(columns))
+	for i := range raws {
+		dests[i] = &raws[i]
+	}
+	if err := rs.Scan(dests...); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(raws[col])), nil
+}
+
+// Blob is a driver.Valuer that binds the content of a reader as a BLOB, which is returned by BlobFrom.
+type Blob struct {
+	r io.Reader
+
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// BlobFrom returns a Blob that binds the content of r.
+// r is read into memory when the argument is bound for the first time, because database/sql takes a BLOB as a []byte,
+// and the content is reused when the operation is retried. It is not streamed to the database in chunks;
+// a value that doesn't fit in memory must be stored by the LargeObjects of PostgreSQL or split into several rows.
+// The content of a *bytes.Buffer is bound without being copied, and the buffer of the content of a *bytes.Reader
+// or *strings.Reader is allocated at once.
+// r is not closed.
+func BlobFrom(r io.Reader) *Blob {
+	return &Blob{
+		r: r,
+	}
+}
+
+// Value implements driver.Valuer.
+func (b *Blob) Value() (driver.Value, error) {
+	b.once.Do(func() {
+		switch r := b.r.(type) {
+		case *bytes.Buffer:
+			b.data = r.Bytes()
+		case interface{ Len() int }:
+			b.data = make([]byte, r.Len())
+			_, b.err = io.ReadFull(b.r, b.data)
+		default:
+			b.data, b.err = io.ReadAll(r)
+		}
+	})
+	return b.data, b.err
+}
+
+// BlobReader returns a reader that streams the BLOB in column of the row of table that matches where, reading chunkSize bytes per query
+// by substr(column, offset, chunkSize), so that only a chunk of a large value is in memory at once.
+// chunkSize defaults to DefaultBlobChunkSize if it is not positive.
+// The columns of where are compared for equality, joined by AND, and must identify a single row whose value doesn't change while it is read.
+// A NULL is read as empty, sql.ErrNoRows is returned by Read if no row matches, and ErrNoWhere if where is empty.
+// Only the values are bound as arguments: table, column and the keys of where are written into the SELECT as SQL.
+//
+// substr counts bytes of a BLOB in SQLite, bytea in PostgreSQL and a binary string in MySQL.
+// The large objects of PostgreSQL are streamed by LargeObjects instead.
+func (db *DB) BlobReader(ctx context.Context, table, column string, where map[string]interface{}, chunkSize int) io.ReadCloser {
+	if chunkSize <= 0 {
+		chunkSize = DefaultBlobChunkSize
+	}
+	r := &blobReader{
+		ctx:       ctx,
+		db:        db,
+		chunkSize: chunkSize,
+		offset:    1,
+	}
+	if len(where) == 0 {
+		r.err = ErrNoWhere
+		return r
+	}
+	var b strings.Builder
+	b.WriteString("SELECT substr(" + column + ", ?, ?) FROM " + table + " WHERE ")
+	for i, col := range sortedKeys(where) {
+		if i > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(col + " = ?")
+		r.args = append(r.args, where[col])
+	}
+	r.query = db.Dialect().Rebind(b.String())
+	return r
+}
+
+type blobReader struct {
+	ctx       context.Context
+	db        *DB
+	query     string
+	args      []interface{}
+	chunkSize int
+
+	// offset is the position of the next chunk, counting from 1, and buf is the unread part of the current chunk.
+	offset int64
+	buf    []byte
+	eof    bool
+	err    error
+}
+
+// Read implements io.Reader.
+func (r *blobReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		switch {
+		case r.err != nil:
+			return 0, r.err
+		case r.eof:
+			return 0, io.EOF
+		}
+		r.fetch()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// fetch reads the next chunk. A chunk shorter than chunkSize is the last one.
+func (r *blobReader) fetch() {
+	var chunk []byte
+	args := append([]interface{}{r.offset, r.chunkSize}, r.args...)
+	if err := (<-r.db.QueryRowContext(r.ctx, r.query, args...)).Scan(&chunk); err != nil {
+		r.err = err
+		return
+	}
+	r.offset += int64(len(chunk))
+	r.eof = len(chunk) < r.chunkSize
+	r.buf = chunk
+}
+
+// Close implements io.Closer.
+func (r *blobReader) Close() error {
+	r.buf = nil
+	if r.err == nil {
+		r.err = errBlobReaderClosed
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package asynql_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/naoina/asynql"
+)
+
+func TestBlob(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE files (id INTEGER, name TEXT, content BLOB)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	large := bytes.Repeat([]byte("0123456789"), 100000)
+	for i, r := range []io.Reader{
+		bytes.NewReader(large),
+		bytes.NewBuffer(large),
+		strings.NewReader(string(large)),
+		iotest.HalfReader(bytes.NewReader(large)),
+	} {
+		if err := (<-db.ExecContext(ctx, `INSERT INTO files VALUES (?, 'f', ?)`, i, asynql.BlobFrom(r))).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (<-db.ExecContext(ctx, `INSERT INTO files VALUES (9, 'null', NULL)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := <-db.QueryContext(ctx, `SELECT id, name, content FROM files ORDER BY id`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var lengths []int
+	for rows.Next() {
+		r, err := rows.ScanReader(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+		if len(b) > 0 && !bytes.Equal(b, large) {
+			t.Errorf(`rows.ScanReader(2) read %d different bytes; want the inserted content`, len(b))
+		}
+		lengths = append(lengths, len(b))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = lengths
+	var expected interface{} = []int{len(large), len(large), len(large), len(large), 0}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`lengths of rows.ScanReader(2) => %#v; want %#v`, actual, expected)
+	}
+
+	rows = <-db.QueryContext(ctx, `SELECT id FROM files`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	rows.Next()
+	if _, err := rows.ScanReader(1); err == nil {
+		t.Errorf(`rows.ScanReader(1) of a single column => nil; want error`)
+	}
+}
+
+func TestDB_BlobReader(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE files (id INTEGER, name TEXT, content BLOB)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	var content []byte
+	for i := 0; i < 1000; i++ {
+		content = append(content, byte(i))
+	}
+	for _, v := range []struct {
+		id      int
+		content interface{}
+	}{
+		{1, content},
+		{2, content[:994]},
+		{3, []byte{}},
+		{4, nil},
+	} {
+		if err := (<-db.ExecContext(ctx, `INSERT INTO files VALUES (?, 'f', ?)`, v.id, v.content)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range []struct {
+		id        int
+		chunkSize int
+		expect    []byte
+	}{
+		{1, 7, content},
+		{1, 10, content},
+		{1, 0, content},
+		{2, 7, content[:994]},
+		{3, 7, nil},
+		{4, 7, nil},
+	} {
+		r := db.BlobReader(ctx, "files", "content", map[string]interface{}{"id": v.id, "name": "f"}, v.chunkSize)
+		actual, err := io.ReadAll(iotest.OneByteReader(r))
+		if err != nil {
+			t.Fatalf(`BlobReader(id %d, %d) read error %v; want nil`, v.id, v.chunkSize, err)
+		}
+		r.Close()
+		if !bytes.Equal(actual, v.expect) {
+			t.Errorf(`BlobReader(id %d, %d) read %d bytes; want %d bytes of the inserted content`, v.id, v.chunkSize, len(actual), len(v.expect))
+		}
+	}
+
+	for _, v := range []struct {
+		where  map[string]interface{}
+		expect error
+	}{
+		{map[string]interface{}{"id": 5}, sql.ErrNoRows},
+		{nil, asynql.ErrNoWhere},
+	} {
+		r := db.BlobReader(ctx, "files", "content", v.where, 7)
+		if _, err := io.ReadAll(r); err != v.expect {
+			t.Errorf(`BlobReader(%v) read error %v; want %v`, v.where, err, v.expect)
+		}
+		r.Close()
+	}
+}
@@ -0,0 +1,397 @@
+package asynql
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of entries of the cache that CachedQuery uses if no Cache is given by WithCache.
+const DefaultCacheSize = 1024
+
+// Cache stores the results of CachedQuery.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the entry for key.
+	Get(key string) (*CacheEntry, bool)
+
+	// Set stores entry for key.
+	Set(key string, entry *CacheEntry)
+
+	// Delete removes the entry for key.
+	Delete(key string)
+}
+
+// EvictNotifier is an optional interface of a Cache that removes entries by itself, as LRUCache does when it is full.
+// CachedQuery registers f by OnEvict to forget the tables that the results of the removed entries read from.
+type EvictNotifier interface {
+	// OnEvict registers f to be called with the key and the entry that the cache has removed by itself.
+	// f must not be called while the cache is locked.
+	OnEvict(f func(key string, entry *CacheEntry))
+}
+
+// CacheEntry is a result stored in a Cache.
+type CacheEntry struct {
+	// Snapshot is the result of the query.
+	Snapshot *Snapshot
+
+	// Expires is the time when the entry becomes stale.
+	Expires time.Time
+}
+
+// WithCache returns an Option that makes CachedQuery use c.
+func WithCache(c Cache) Option {
+	return func(db *DB) {
+		db.cache = c
+	}
+}
+
+// CachedQuery is similar to Query, but serves the result from the cache of db if available.
+// A result is cached for ttl. When a stale result is found, it is still delivered immediately,
+// and the cache is refreshed in the background. The stale result is removed if the refresh fails.
+//
+// The cached results that read from a table are invalidated when a statement that modifies the table,
+// such as INSERT, UPDATE, DELETE or DROP TABLE, is executed through db,
+// or when a transaction of db that executed such a statement is committed.
+// The tables are found by a lightweight inspection of the queries, so it can miss tables in complex statements,
+// and it cannot notice modifications made by others.
+func (db *DB) CachedQuery(query string, args []interface{}, ttl time.Duration) <-chan *Rows {
+	return db.CachedQueryContext(context.Background(), query, args, ttl)
+}
+
+// CachedQueryContext is similar to QueryContext, but serves the result from the cache of db as CachedQuery does.
+func (db *DB) CachedQueryContext(ctx context.Context, query string, args []interface{}, ttl time.Duration) <-chan *Rows {
+	ch := make(chan *Rows)
+	go func() {
+		deliver(db, ch, db.cachedQuery(ctx, query, args, ttl))
+	}()
+	return ch
+}
+
+func (db *DB) cachedQuery(ctx context.Context, query string, args []interface{}, ttl time.Duration) *Rows {
+	cache := db.resultCache()
+	key := cacheKey(query, args)
+	if entry, ok := cache.Get(key); ok {
+		if time.Now().After(entry.Expires) {
+			go func() {
+				if _, err := db.refreshCache(context.WithoutCancel(ctx), key, query, args, ttl); err != nil {
+					// The stale result is not served any longer once it cannot be refreshed.
+					db.dropCache(key, entry)
+				}
+			}()
+		}
+		return entry.Snapshot.Rows()
+	}
+	snap, err := db.refreshCache(ctx, key, query, args, ttl)
+	if err != nil {
+		return &Rows{err: err}
+	}
+	return snap.Rows()
+}
+
+// refreshCache executes a query and stores the result in the cache.
+// Concurrent refreshes of the same key share the execution.
+func (db *DB) refreshCache(ctx context.Context, key, query string, args []interface{}, ttl time.Duration) (*Snapshot, error) {
+	return db.cacheFlight.do(ctx, key, func(ctx context.Context) (*Snapshot, error) {
+		// The key is indexed before the query runs, so that a write during the query can tell that the result is stale.
+		gen, n := db.indexCache(key, readTables(query))
+		defer db.releaseGeneration(key, gen)
+		// The query runs as a Query of db, so that it is checked, limited and reported as the other operations are.
+		snap, err := Materialize(<-db.QueryContext(ctx, query, args...))
+		if err != nil {
+			return nil, err
+		}
+		db.storeCache(key, gen, n, &CacheEntry{
+			Snapshot: snap,
+			Expires:  time.Now().Add(ttl),
+		})
+		return snap, nil
+	})
+}
+
+// cacheGeneration counts the invalidations of a key whose result is being refreshed.
+// refs is the number of the refreshes of the key in progress, which may be more than one for a DB and its ReadOnly facade.
+type cacheGeneration struct {
+	n    uint64
+	refs int
+}
+
+// storeCache stores entry for key unless key has been invalidated since its generation was n.
+func (db *DB) storeCache(key string, gen *cacheGeneration, n uint64, entry *CacheEntry) {
+	if db.primary != nil {
+		db.primary.storeCache(key, gen, n, entry)
+		return
+	}
+	db.cacheMu.Lock()
+	defer db.cacheMu.Unlock()
+	if gen.n != n {
+		return
+	}
+	db.resultCache().Set(key, entry)
+	if k := db.cacheKeys[key]; k != nil {
+		k.entry = entry
+	}
+	db.forgetEvicted()
+}
+
+// dropCache removes entry for key from the cache unless it has been replaced.
+func (db *DB) dropCache(key string, entry *CacheEntry) {
+	if db.primary != nil {
+		db.primary.dropCache(key, entry)
+		return
+	}
+	db.cacheMu.Lock()
+	defer db.cacheMu.Unlock()
+	if k := db.cacheKeys[key]; k == nil || k.entry != entry || db.cacheGen[key] != nil {
+		return
+	}
+	db.cache.Delete(key)
+	db.forgetCache(key)
+}
+
+// releaseGeneration ends the refresh of key that indexCache has started.
+func (db *DB) releaseGeneration(key string, gen *cacheGeneration) {
+	if db.primary != nil {
+		db.primary.releaseGeneration(key, gen)
+		return
+	}
+	db.cacheMu.Lock()
+	defer db.cacheMu.Unlock()
+	if gen.refs--; gen.refs == 0 {
+		delete(db.cacheGen, key)
+		// Nothing has been stored for key if all of its refreshes have failed.
+		if k := db.cacheKeys[key]; k != nil && k.entry == nil {
+			db.forgetCache(key)
+		}
+	}
+}
+
+func (db *DB) resultCache() Cache {
+	if db.primary != nil {
+		return db.primary.resultCache()
+	}
+	db.cacheOnce.Do(func() {
+		if db.cache == nil {
+			db.cache = NewLRUCache(DefaultCacheSize)
+		}
+		if n, ok := db.cache.(EvictNotifier); ok {
+			n.OnEvict(db.evicted)
+		}
+	})
+	return db.cache
+}
+
+// indexCache records that the cached result for key reads from tables, and starts a refresh of key.
+// It returns the generation of key and its current count, which must be released by releaseGeneration.
+func (db *DB) indexCache(key string, tables []string) (*cacheGeneration, uint64) {
+	if db.primary != nil {
+		return db.primary.indexCache(key, tables)
+	}
+	db.cacheMu.Lock()
+	defer db.cacheMu.Unlock()
+	if db.cacheTables == nil {
+		db.cacheTables = make(map[string]map[string]struct{})
+	}
+	if db.cacheGen == nil {
+		db.cacheGen = make(map[string]*cacheGeneration)
+	}
+	if db.cacheKeys == nil {
+		db.cacheKeys = make(map[string]*cachedKey)
+	}
+	db.forgetEvicted()
+	gen := db.cacheGen[key]
+	if gen == nil {
+		gen = &cacheGeneration{}
+		db.cacheGen[key] = gen
+	}
+	gen.refs++
+	for _, table := range tables {
+		keys := db.cacheTables[table]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			db.cacheTables[table] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	if db.cacheKeys[key] == nil {
+		db.cacheKeys[key] = &cachedKey{tables: tables}
+	}
+	return gen, gen.n
+}
+
+// cachedKey is a key indexed by indexCache.
+type cachedKey struct {
+	// tables are the tables that the result for the key reads from.
+	tables []string
+
+	// entry is the entry stored for the key, or nil if none has been stored yet.
+	entry *CacheEntry
+}
+
+// forgetCache removes key from the index of the tables. cacheMu must be held.
+func (db *DB) forgetCache(key string) {
+	k := db.cacheKeys[key]
+	if k == nil {
+		return
+	}
+	for _, table := range k.tables {
+		if keys := db.cacheTables[table]; keys != nil {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(db.cacheTables, table)
+			}
+		}
+	}
+	delete(db.cacheKeys, key)
+}
+
+// evictedEntry is an entry that the cache has removed by itself.
+type evictedEntry struct {
+	key   string
+	entry *CacheEntry
+}
+
+// evicted records that the cache has removed entry for key.
+// The cache may remove entries while cacheMu is held by storeCache, so the keys are forgotten later by forgetEvicted.
+func (db *DB) evicted(key string, entry *CacheEntry) {
+	db.evictMu.Lock()
+	defer db.evictMu.Unlock()
+	db.evictedEntries = append(db.evictedEntries, evictedEntry{key: key, entry: entry})
+}
+
+// forgetEvicted forgets the keys whose entries the cache has removed, unless they have been stored again
+// or are being refreshed. cacheMu must be held.
+func (db *DB) forgetEvicted() {
+	db.evictMu.Lock()
+	evicted := db.evictedEntries
+	db.evictedEntries = nil
+	db.evictMu.Unlock()
+	for _, e := range evicted {
+		if k := db.cacheKeys[e.key]; k != nil && k.entry == e.entry && db.cacheGen[e.key] == nil {
+			db.forgetCache(e.key)
+		}
+	}
+}
+
+// invalidateCache removes the cached results that read from the tables that query modifies,
+// and bumps the generation of those being refreshed so that they are not stored.
+func (db *DB) invalidateCache(query string) {
+	db.cacheMu.Lock()
+	empty := len(db.cacheTables) == 0
+	db.cacheMu.Unlock()
+	if empty {
+		return
+	}
+	tables := writtenTables(query)
+	db.cacheMu.Lock()
+	defer db.cacheMu.Unlock()
+	for _, table := range tables {
+		for key := range db.cacheTables[table] {
+			db.cache.Delete(key)
+			if gen := db.cacheGen[key]; gen != nil {
+				gen.n++
+			}
+			db.forgetCache(key)
+		}
+	}
+	db.forgetEvicted()
+}
+
+// cacheKey returns the key of a query with args in a Cache.
+// Queries that differ only in whitespace and comments share the same key.
+func cacheKey(query string, args []interface{}) string {
+	return fmt.Sprintf("%s\x00%#v", canonicalQuery(query), args)
+}
+
+// LRUCache is an in-memory Cache that evicts the least recently used entry when it is full.
+type LRUCache struct {
+	size int
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+	onEvict []func(key string, entry *CacheEntry)
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewLRUCache returns a new LRUCache that holds up to size entries.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get implements the Cache interface.
+func (c *LRUCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*lruItem).entry, true
+}
+
+// Set implements the Cache interface.
+func (c *LRUCache) Set(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruItem).entry = entry
+		c.ll.MoveToFront(e)
+		c.mu.Unlock()
+		return
+	}
+	c.entries[key] = c.ll.PushFront(&lruItem{
+		key:   key,
+		entry: entry,
+	})
+	var evicted []*lruItem
+	for c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		item := e.Value.(*lruItem)
+		delete(c.entries, item.key)
+		evicted = append(evicted, item)
+	}
+	onEvict := c.onEvict
+	c.mu.Unlock()
+	for _, item := range evicted {
+		for _, f := range onEvict {
+			f(item.key, item.entry)
+		}
+	}
+}
+
+// OnEvict implements the EvictNotifier interface.
+// f is called when the least recently used entry is evicted by Set.
+func (c *LRUCache) OnEvict(f func(key string, entry *CacheEntry)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvict = append(c.onEvict[:len(c.onEvict):len(c.onEvict)], f)
+}
+
+// Delete implements the Cache interface.
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.ll.Remove(e)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
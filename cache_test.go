@@ -0,0 +1,243 @@
+package asynql_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/naoina/asynql"
+)
+
+// pauseHook is called by the SQL function pause() of the "sqlite3_pause" driver.
+var pauseHook struct {
+	sync.Mutex
+	fn func()
+}
+
+func init() {
+	sql.Register("sqlite3_pause", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("pause", func() int {
+				pauseHook.Lock()
+				fn := pauseHook.fn
+				pauseHook.fn = nil
+				pauseHook.Unlock()
+				if fn != nil {
+					fn()
+				}
+				return 0
+			}, false)
+		},
+	})
+}
+
+func TestDB_CachedQuery(t *testing.T) {
+	cache := asynql.NewLRUCache(10)
+	db := newTestDB(t, asynql.WithCache(cache))
+	defer db.Close()
+	query := `SELECT name FROM test_table ORDER BY id`
+	actual := scanNames(t, <-db.CachedQuery(query, nil, time.Hour))
+	expected := []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.CachedQuery(%#v, nil, time.Hour) => %#v; want %#v`, query, actual, expected)
+	}
+	// Written through the underlying *sql.DB, which the cache cannot notice.
+	if _, err := db.DB.Exec(`INSERT INTO test_table (id, name) VALUES (3, "carol")`); err != nil {
+		t.Fatal(err)
+	}
+	actual = scanNames(t, <-db.CachedQuery("SELECT name\n  FROM test_table ORDER BY id", nil, time.Hour))
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.CachedQuery(%#v, nil, time.Hour) after insert => %#v; want %#v`, query, actual, expected)
+	}
+	if n := cache.Len(); n != 1 {
+		t.Errorf(`cache.Len() => %#v; want 1`, n)
+	}
+}
+
+func TestDB_CachedQuery_Stale(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT name FROM test_table ORDER BY id`
+	scanNames(t, <-db.CachedQuery(query, nil, 0))
+	// Written through the underlying *sql.DB, which the cache cannot notice.
+	if _, err := db.DB.Exec(`INSERT INTO test_table (id, name) VALUES (3, "carol")`); err != nil {
+		t.Fatal(err)
+	}
+	// The stale result is served while the cache is refreshed in the background.
+	actual := scanNames(t, <-db.CachedQuery(query, nil, time.Hour))
+	expected := []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.CachedQuery(%#v, nil, time.Hour) => %#v; want %#v`, query, actual, expected)
+	}
+	expected = []string{"alice", "bob", "carol"}
+	for i := 0; i < 100; i++ {
+		if actual = scanNames(t, <-db.CachedQuery(query, nil, time.Hour)); reflect.DeepEqual(actual, expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf(`db.CachedQuery(%#v, nil, time.Hour) after refresh => %#v; want %#v`, query, actual, expected)
+}
+
+func TestDB_CachedQuery_Evicted(t *testing.T) {
+	cache := asynql.NewLRUCache(2)
+	db := newTestDB(t, asynql.WithCache(cache))
+	defer db.Close()
+	for i := 0; i < 5; i++ {
+		scanNames(t, <-db.CachedQuery(`SELECT name FROM test_table WHERE id > ?`, []interface{}{i}, time.Hour))
+	}
+	if actual, expected := asynql.CachedTables(db), 2; actual != expected {
+		t.Errorf(`asynql.CachedTables(db) after eviction => %#v; want %#v`, actual, expected)
+	}
+
+	// The stale result that fails to be refreshed is removed.
+	query := `SELECT name FROM other_table`
+	if _, err := db.DB.Exec(`CREATE TABLE other_table (name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	scanNames(t, <-db.CachedQuery(query, nil, 0))
+	if _, err := db.DB.Exec(`DROP TABLE other_table`); err != nil {
+		t.Fatal(err)
+	}
+	scanNames(t, <-db.CachedQuery(query, nil, time.Hour))
+	for i := 0; i < 100; i++ {
+		if asynql.CachedTables(db) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if actual, expected := asynql.CachedTables(db), 1; actual != expected {
+		t.Errorf(`asynql.CachedTables(db) after expiry => %#v; want %#v`, actual, expected)
+	}
+	if err := (<-db.CachedQuery(query, nil, time.Hour)).Err(); err == nil {
+		t.Errorf(`db.CachedQuery(%#v, nil, time.Hour).Err() after expiry => nil; want error`, query)
+	}
+}
+
+func TestDB_CachedQuery_Instrumented(t *testing.T) {
+	var rec hookRecorder
+	db := newTestDB(t, asynql.WithHook(rec.hook))
+	defer db.Close()
+	query := `DELETE FROM test_table RETURNING name`
+	if err := (<-db.ReadOnly().CachedQuery(query, nil, time.Minute)).Err(); !errors.Is(err, asynql.ErrReadOnly) {
+		t.Errorf(`db.ReadOnly().CachedQuery(%#v, nil, time.Minute) => %#v; want %#v`, query, err, asynql.ErrReadOnly)
+	}
+	var n int
+	if err := (<-db.QueryRow(`SELECT COUNT(*) FROM test_table`)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = n
+	var expected interface{} = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`rows after db.ReadOnly().CachedQuery(%#v, nil, time.Minute) => %#v; want %#v`, query, actual, expected)
+	}
+
+	query = `SELECT name FROM test_table ORDER BY id`
+	scanNames(t, <-db.CachedQuery(query, nil, time.Minute))
+	events := rec.summary()
+	actual = events[len(events)-1]
+	expected = []interface{}{asynql.HookQuery, query, false, false}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`hook event of db.CachedQuery(%#v, nil, time.Minute) => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := asynql.NewLRUCache(2)
+	entry := &asynql.CacheEntry{}
+	cache.Set("a", entry)
+	cache.Set("b", entry)
+	cache.Get("a")
+	cache.Set("c", entry)
+	for _, v := range []struct {
+		key      string
+		expected bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	} {
+		_, actual := cache.Get(v.key)
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`cache.Get(%#v) => _, %#v; want %#v`, v.key, actual, v.expected)
+		}
+	}
+}
+
+func TestDB_CachedQuery_Invalidation(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT t.name FROM test_table AS t ORDER BY t.id`
+	scanNames(t, <-db.CachedQuery(query, nil, time.Hour))
+
+	if err := (<-db.Exec(`INSERT INTO "test_table" (id, name) VALUES (3, "carol")`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	actual := scanNames(t, <-db.CachedQuery(query, nil, time.Hour))
+	expected := []string{"alice", "bob", "carol"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.CachedQuery(%#v, nil, time.Hour) after db.Exec => %#v; want %#v`, query, actual, expected)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-tx.Exec(`DELETE FROM test_table WHERE id = 1`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	actual = scanNames(t, <-db.CachedQuery(query, nil, time.Hour))
+	expected = []string{"bob", "carol"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.CachedQuery(%#v, nil, time.Hour) after tx.Commit => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestDB_CachedQuery_InvalidatedDuringRefresh(t *testing.T) {
+	// WAL lets the write commit while the refresh is reading the snapshot before it.
+	db, err := asynql.Open("sqlite3_pause", filepath.Join(t.TempDir(), "test.db")+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`CREATE TABLE test_table (id INTEGER, name TEXT)`,
+		`INSERT INTO test_table (id, name) VALUES (1, "alice")`,
+	} {
+		if _, err := db.DB.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entered, resume := make(chan struct{}), make(chan struct{})
+	pauseHook.Lock()
+	pauseHook.fn = func() {
+		close(entered)
+		<-resume
+	}
+	pauseHook.Unlock()
+	query := `SELECT name FROM test_table WHERE pause() = 0 ORDER BY id`
+	ch := db.CachedQuery(query, nil, time.Hour)
+	<-entered
+	if err := (<-db.Exec(`INSERT INTO test_table (id, name) VALUES (2, "bob")`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	close(resume)
+	actual := scanNames(t, <-ch)
+	expected := []string{"alice"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.CachedQuery(%#v, nil, time.Hour) across db.Exec => %#v; want %#v`, query, actual, expected)
+	}
+	actual = scanNames(t, <-db.CachedQuery(query, nil, time.Hour))
+	expected = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.CachedQuery(%#v, nil, time.Hour) after db.Exec => %#v; want %#v`, query, actual, expected)
+	}
+}
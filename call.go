@@ -0,0 +1,104 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrCallUnsupported is reported by Call when a DB has DialectSQLite, which has no stored procedures.
+var ErrCallUnsupported = errors.New("asynql: stored procedures are not supported by the dialect")
+
+// OutParam is an output parameter of a stored procedure, which is returned by Out.
+type OutParam struct {
+	// Dest is a pointer to the variable that receives the value of the parameter.
+	Dest interface{}
+}
+
+// Out returns an output parameter of Call, whose value is stored in dest.
+// dest must be a pointer that can be passed to Scan.
+func Out(dest interface{}) OutParam {
+	return OutParam{
+		Dest: dest,
+	}
+}
+
+// CallResult represents a result of Call.
+type CallResult struct {
+	err error
+}
+
+// Err returns an error.
+func (r *CallResult) Err() error {
+	return r.err
+}
+
+// Call calls the stored procedure name with args in the syntax of the dialect of db, and then sends the result on the returned channel.
+// The arguments returned by Out are output parameters, whose values are stored before the result is sent.
+// name may be qualified by a schema; it is written into the CALL statement unquoted, so it must not come from user input.
+//
+// The output parameters are bound by the dialect as follows:
+//
+//   - DialectPostgres passes NULL for them, and scans the row that CALL returns.
+//   - DialectMySQL binds them to session variables, and selects the variables on the same connection.
+//   - The other dialects pass them as sql.Out, which is supported by some drivers such as those of SQL Server and Oracle.
+//
+// DialectSQLite reports ErrCallUnsupported.
+func (db *DB) Call(ctx context.Context, name string, args ...interface{}) <-chan *CallResult {
+	ch := make(chan *CallResult)
+	go func() {
+		deliver(db, ch, &CallResult{
+			err: db.call(ctx, name, args),
+		})
+	}()
+	return ch
+}
+
+func (db *DB) call(ctx context.Context, name string, args []interface{}) error {
+	d := db.Dialect()
+	if d == DialectSQLite {
+		return ErrCallUnsupported
+	}
+	var params []string
+	var in, outs []interface{}
+	for _, arg := range args {
+		out, ok := arg.(OutParam)
+		switch {
+		case !ok:
+			in = append(in, arg)
+			params = append(params, d.Placeholder(len(in)))
+		case d == DialectPostgres:
+			params = append(params, "NULL")
+			outs = append(outs, out.Dest)
+		case d == DialectMySQL:
+			params = append(params, "@asynql_out"+strconv.Itoa(len(outs)))
+			outs = append(outs, out.Dest)
+		default:
+			in = append(in, sql.Out{Dest: out.Dest})
+			params = append(params, d.Placeholder(len(in)))
+		}
+	}
+	query := "CALL " + name + "(" + strings.Join(params, ", ") + ")"
+	switch {
+	case len(outs) == 0:
+		return (<-db.ExecContext(ctx, query, in...)).Err()
+	case d == DialectPostgres:
+		return (<-db.QueryRowContext(ctx, query, in...)).Scan(outs...)
+	}
+	// The session variables of MySQL are visible only on the connection that set them.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := (<-conn.ExecContext(ctx, query, in...)).Err(); err != nil {
+		return err
+	}
+	vars := make([]string, len(outs))
+	for i := range outs {
+		vars[i] = "@asynql_out" + strconv.Itoa(i)
+	}
+	return (<-conn.QueryRowContext(ctx, "SELECT "+strings.Join(vars, ", "))).Scan(outs...)
+}
@@ -0,0 +1,99 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// callConn records the queries, sets 42 to the sql.Out arguments, and returns a row of 7 for any query.
+type callConn struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (c *callConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *callConn) Close() error                        { return nil }
+func (c *callConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *callConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(sql.Out); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c *callConn) record(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+}
+
+func (c *callConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query)
+	for _, arg := range args {
+		if out, ok := arg.Value.(sql.Out); ok {
+			*out.Dest.(*int64) = 42
+		}
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *callConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query)
+	return &callRows{}, nil
+}
+
+type callRows struct {
+	done bool
+}
+
+func (r *callRows) Columns() []string { return []string{"v"} }
+func (r *callRows) Close() error      { return nil }
+
+func (r *callRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0], r.done = int64(7), true
+	return nil
+}
+
+func TestDB_Call(t *testing.T) {
+	for _, v := range []struct {
+		dialect  asynql.Dialect
+		queries  []string
+		expected int64
+	}{
+		{asynql.DialectPostgres, []string{"CALL p($1, NULL)"}, 7},
+		{asynql.DialectMySQL, []string{"CALL p(?, @asynql_out0)", "SELECT @asynql_out0"}, 7},
+		{asynql.DialectUnknown, []string{"CALL p(?, ?)"}, 42},
+	} {
+		conn := &callConn{}
+		db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithDialect(v.dialect))
+		var out int64
+		if err := (<-db.Call(context.Background(), "p", 1, asynql.Out(&out))).Err(); err != nil {
+			t.Errorf(`%v: db.Call(ctx, "p", 1, asynql.Out(&out)).Err() => %#v; want nil`, v.dialect, err)
+		}
+		db.Close()
+		var actual interface{} = []interface{}{conn.queries, out}
+		var expected interface{} = []interface{}{v.queries, v.expected}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%v: db.Call(ctx, "p", 1, asynql.Out(&out)) => %#v; want %#v`, v.dialect, actual, expected)
+		}
+	}
+
+	db := newTestDB(t)
+	defer db.Close()
+	err := (<-db.Call(context.Background(), "p")).Err()
+	if !errors.Is(err, asynql.ErrCallUnsupported) {
+		t.Errorf(`db.Call(ctx, "p").Err() => %#v; want %#v`, err, asynql.ErrCallUnsupported)
+	}
+}
@@ -0,0 +1,128 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoSource is returned by a Chain that has no sources.
+var ErrNoSource = errors.New("asynql: chain has no sources")
+
+// ChainSource is a data source of a Chain.
+type ChainSource struct {
+	// Name identifies the source in Provenance.
+	Name string
+
+	// Source is the source to read from.
+	Source Queryer
+
+	// Timeout limits the time to read the whole result from the source.
+	// Zero means no limit other than the deadline of the context.
+	Timeout time.Duration
+}
+
+// Chain reads from an ordered list of sources, such as a result cache, replicas and a primary,
+// falling back to the next source when a read fails or times out.
+type Chain struct {
+	sources []ChainSource
+}
+
+// NewChain returns a new Chain that reads from sources in the given order.
+func NewChain(sources ...ChainSource) *Chain {
+	return &Chain{
+		sources: sources,
+	}
+}
+
+// ChainRows represents a result of Chain.QueryContext.
+// The rows are read into memory from the source within its timeout.
+type ChainRows struct {
+	*Rows
+
+	// Provenance describes where the rows came from.
+	Provenance Provenance
+
+	err error
+}
+
+// Err returns an error.
+func (cr *ChainRows) Err() error {
+	if cr.err != nil {
+		return cr.err
+	}
+	return cr.Rows.Err()
+}
+
+// Provenance describes how a result of a Chain was obtained.
+type Provenance struct {
+	// Source is the name of the source that the result came from.
+	// It is empty if all the sources failed.
+	Source string
+
+	// Attempts is the reads in the order they were attempted, including the successful one.
+	Attempts []Attempt
+}
+
+// Attempt represents a read from a source of a Chain.
+type Attempt struct {
+	Source   string
+	Duration time.Duration
+	Err      error
+}
+
+// QueryContext executes a query with args against the sources in order until one succeeds,
+// and then sends the result on the returned channel.
+// If all the sources fail, the error of the last one is reported.
+func (c *Chain) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *ChainRows {
+	ch := make(chan *ChainRows)
+	go func() {
+		deliver(nil, ch, c.query(ctx, query, args))
+	}()
+	return ch
+}
+
+func (c *Chain) query(ctx context.Context, query string, args []interface{}) *ChainRows {
+	if len(c.sources) == 0 {
+		return &ChainRows{err: ErrNoSource}
+	}
+	var prov Provenance
+	var err error
+	for _, src := range c.sources {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		var snap *Snapshot
+		snap, err = c.read(ctx, src, query, args)
+		prov.Attempts = append(prov.Attempts, Attempt{
+			Source:   src.Name,
+			Duration: time.Since(start),
+			Err:      err,
+		})
+		if err == nil {
+			prov.Source = src.Name
+			return &ChainRows{
+				Rows:       snap.Rows(),
+				Provenance: prov,
+			}
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return &ChainRows{
+		Provenance: prov,
+		err:        fmt.Errorf("asynql: all sources failed: %w", err),
+	}
+}
+
+func (c *Chain) read(ctx context.Context, src ChainSource, query string, args []interface{}) (*Snapshot, error) {
+	if src.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, src.Timeout)
+		defer cancel()
+	}
+	return Materialize(<-src.Source.QueryContext(ctx, query, args...))
+}
@@ -0,0 +1,84 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// blockingQueryer doesn't start a query until ctx is done.
+type blockingQueryer struct {
+	q asynql.Queryer
+}
+
+func (b blockingQueryer) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *asynql.Rows {
+	ch := make(chan *asynql.Rows)
+	go func() {
+		<-ctx.Done()
+		ch <- (<-b.q.QueryContext(ctx, query, args...))
+	}()
+	return ch
+}
+
+func TestChain_QueryContext(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	empty, err := asynql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Close()
+	chain := asynql.NewChain(
+		asynql.ChainSource{Name: "slow", Source: blockingQueryer{db}, Timeout: 10 * time.Millisecond},
+		asynql.ChainSource{Name: "empty", Source: empty},
+		asynql.ChainSource{Name: "primary", Source: db},
+	)
+	query := `SELECT name FROM test_table WHERE id = ?`
+	rows := <-chain.QueryContext(context.Background(), query, 2)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	var actual interface{} = names
+	var expected interface{} = []string{"bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`chain.QueryContext(ctx, %#v, 2) => %#v; want %#v`, query, actual, expected)
+	}
+	actual = rows.Provenance.Source
+	expected = "primary"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`chain.QueryContext(ctx, %#v, 2); Provenance.Source => %#v; want %#v`, query, actual, expected)
+	}
+	var failed []string
+	for _, a := range rows.Provenance.Attempts {
+		if a.Err != nil {
+			failed = append(failed, a.Source)
+		}
+	}
+	actual = failed
+	expected = []string{"slow", "empty"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`chain.QueryContext(ctx, %#v, 2); failed attempts => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestChain_QueryContext_AllFailed(t *testing.T) {
+	chain := asynql.NewChain()
+	rows := <-chain.QueryContext(context.Background(), `SELECT 1`)
+	var actual interface{} = rows.Err()
+	var expected interface{} = asynql.ErrNoSource
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`chain.QueryContext(ctx, "SELECT 1"); Err() => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,70 @@
+package asynql
+
+import (
+	"errors"
+)
+
+// ErrChannelClosed is reported by the helpers that receive a result from a channel, such as WaitAll and Then,
+// when the channel is closed without a result, e.g. because its result has been received already.
+var ErrChannelClosed = errors.New("asynql: channel closed without a result")
+
+// WithOpenChannels returns an Option that keeps the channel of the result of an operation of the DB, and of its transactions and connections,
+// open after the result is sent, as the earlier versions of asynql did.
+// By default, such a channel is closed after its single result is sent, so that it can be ranged over,
+// and a second receive returns nil immediately instead of blocking forever.
+// WithOpenChannels is for the code that depends on the second receive blocking, e.g. a select that receives from the channel in a loop.
+//
+// The channels that send a stream of results, such as those of QueryPages, are always closed after the last result.
+func WithOpenChannels() Option {
+	return func(db *DB) {
+		db.openChannels = true
+	}
+}
+
+// deliver sends v, which is the single result of an operation of q, on ch, and then closes ch unless q keeps the channels open.
+func deliver[T any](q interface{}, ch chan<- T, v T) {
+	ch <- v
+	closeChannel(dbOf(q), ch)
+}
+
+// Deliver sends v, which is the single result of an operation of db, on ch, and then closes ch unless db keeps the channels open by WithOpenChannels.
+// It is for the packages built on a DB, so that their operations return the channels that behave as those of the DB.
+func Deliver[T any](db *DB, ch chan<- T, v T) {
+	deliver(db, ch, v)
+}
+
+// sendResult returns a channel on which v, which is the single result of an operation of q, is sent.
+func sendResult[T any](q interface{}, v T) <-chan T {
+	ch := make(chan T)
+	go deliver(q, ch, v)
+	return ch
+}
+
+// closeChannel closes ch, which has sent the single result of an operation of db, unless db keeps the channels open.
+func closeChannel[T any](db *DB, ch chan<- T) {
+	if db == nil || !db.openChannels {
+		close(ch)
+	}
+}
+
+// receive receives the result of ch and returns it with its error, which is ErrChannelClosed if ch is closed without a result.
+func receive[T Errer](ch <-chan T) (T, error) {
+	v, ok := <-ch
+	if !ok {
+		return v, ErrChannelClosed
+	}
+	return v, v.Err()
+}
+
+// dbOf returns the DB that q belongs to, or nil if it is not known.
+func dbOf(q interface{}) *DB {
+	switch q := q.(type) {
+	case *DB:
+		return q
+	case *Tx:
+		return q.db
+	case *Conn:
+		return q.db
+	}
+	return nil
+}
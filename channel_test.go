@@ -0,0 +1,94 @@
+package asynql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestResultChannelClosed(t *testing.T) {
+	for _, v := range []struct {
+		name string
+		opts []asynql.Option
+	}{
+		{"default", nil},
+		{"fifo", []asynql.Option{asynql.WithFIFO()}},
+		{"workers", []asynql.Option{asynql.WithWorkers(2, 10)}},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			db := newTestDB(t, v.opts...)
+			defer db.Close()
+			ctx := context.Background()
+			var n int
+			for r := range db.ExecContext(ctx, `UPDATE test_table SET name = ? WHERE id = ?`, "carol", 1) {
+				if err := r.Err(); err != nil {
+					t.Fatal(err)
+				}
+				n++
+			}
+			if n != 1 {
+				t.Errorf(`number of results => %v; want 1`, n)
+			}
+			ch := db.QueryRowContext(ctx, `SELECT name FROM test_table WHERE id = ?`, 1)
+			var name string
+			if err := (<-ch).Scan(&name); err != nil {
+				t.Fatal(err)
+			}
+			if r, ok := <-ch; r != nil || ok {
+				t.Errorf(`second receive => %#v, %v; want nil, false`, r, ok)
+			}
+			exists := db.Exists(ctx, `SELECT 1 FROM test_table`)
+			<-exists
+			if r, ok := <-exists; r != nil || ok {
+				t.Errorf(`second receive of db.Exists => %#v, %v; want nil, false`, r, ok)
+			}
+		})
+	}
+}
+
+func TestWithOpenChannels(t *testing.T) {
+	db := newTestDB(t, asynql.WithOpenChannels())
+	defer db.Close()
+	ctx := context.Background()
+	for _, ch := range []<-chan *asynql.Result{
+		db.ExecContext(ctx, `UPDATE test_table SET name = ? WHERE id = ?`, "carol", 1),
+		db.UpdateVersioned(ctx, "test_table", map[string]interface{}{"name": "dave"}, map[string]interface{}{"id": 3}, "id", 3),
+	} {
+		<-ch
+		select {
+		case r, ok := <-ch:
+			t.Errorf(`second receive => %#v, %v; want to block`, r, ok)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	for _, v := range []struct {
+		opts   []asynql.Option
+		closed bool
+	}{
+		{nil, true},
+		{[]asynql.Option{asynql.WithOpenChannels()}, false},
+	} {
+		db := newTestDB(t, v.opts...)
+		ch := make(chan int)
+		go asynql.Deliver(db, ch, 1)
+		if actual := <-ch; actual != 1 {
+			t.Errorf(`first receive => %v; want 1`, actual)
+		}
+		select {
+		case _, ok := <-ch:
+			if ok || !v.closed {
+				t.Errorf(`second receive with %d options => %v; want closed %v`, len(v.opts), ok, v.closed)
+			}
+		case <-time.After(10 * time.Millisecond):
+			if v.closed {
+				t.Errorf(`second receive with %d options blocks; want closed`, len(v.opts))
+			}
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,89 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidChunkSize is reported by QueryChunks when the chunk size is not positive.
+var ErrInvalidChunkSize = errors.New("asynql: chunk size must be positive")
+
+// Chunk represents a batch of the rows of the result of QueryChunks.
+// The rows of a chunk are read into memory, so they can be iterated by Rows of the Snapshot any number of times.
+type Chunk struct {
+	*Snapshot
+
+	// Number is the index of the chunk, counting from 0.
+	Number int
+
+	err error
+}
+
+// Err returns an error.
+func (c *Chunk) Err() error {
+	return c.err
+}
+
+// QueryChunks executes a query and then sends its rows on the returned channel in chunks of chunkSize rows,
+// so that a consumer of a large result, such as an ETL job, neither receives every row on a channel nor holds the whole result in memory.
+// Unlike QueryPages, the query is executed once and holds a connection until the last chunk has been received.
+// The last chunk has fewer rows than chunkSize, and no empty chunk is sent.
+// If the query or reading the rows fails, a Chunk with the error is sent.
+// The channel is closed after the last chunk or the error, or when ctx is done.
+func (db *DB) QueryChunks(ctx context.Context, query string, args []interface{}, chunkSize int) <-chan *Chunk {
+	ch := make(chan *Chunk)
+	go func() {
+		defer close(ch)
+		send := func(c *Chunk) bool {
+			select {
+			case ch <- c:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if chunkSize <= 0 {
+			send(&Chunk{err: ErrInvalidChunkSize})
+			return
+		}
+		rs := <-db.QueryContext(ctx, query, args...)
+		if err := rs.Err(); err != nil {
+			send(&Chunk{err: err})
+			return
+		}
+		defer rs.Close()
+		columns, err := rs.Columns()
+		if err != nil {
+			send(&Chunk{err: err})
+			return
+		}
+		for n := 0; ; n++ {
+			s := &Snapshot{
+				Columns: columns,
+				Values:  make([][]interface{}, 0, chunkSize),
+			}
+			for len(s.Values) < chunkSize && rs.Next() {
+				values, err := scanRow(rs, len(columns))
+				if err != nil {
+					send(&Chunk{Number: n, err: err})
+					return
+				}
+				s.Values = append(s.Values, values)
+			}
+			if len(s.Values) < chunkSize {
+				if err := rs.Err(); err != nil {
+					send(&Chunk{Number: n, err: err})
+					return
+				}
+				if len(s.Values) > 0 {
+					send(&Chunk{Snapshot: s, Number: n})
+				}
+				return
+			}
+			if !send(&Chunk{Snapshot: s, Number: n}) {
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,98 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_QueryChunks(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := (<-db.Exec(`INSERT INTO test_table (id, name) VALUES (3, "carol"), (4, "dave")`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		chunkSize int
+		expected  [][]string
+	}{
+		{3, [][]string{{"alice", "bob", "carol"}, {"dave"}}},
+		{2, [][]string{{"alice", "bob"}, {"carol", "dave"}}},
+		{10, [][]string{{"alice", "bob", "carol", "dave"}}},
+	} {
+		var actual [][]string
+		for c := range db.QueryChunks(context.Background(), `SELECT name FROM test_table ORDER BY id`, nil, v.chunkSize) {
+			if err := c.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if c.Number != len(actual) {
+				t.Errorf(`c.Number => %#v; want %#v`, c.Number, len(actual))
+			}
+			actual = append(actual, scanNames(t, c.Rows()))
+		}
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`db.QueryChunks(ctx, query, nil, %v) => %#v; want %#v`, v.chunkSize, actual, v.expected)
+		}
+	}
+}
+
+func TestDB_QueryChunks_Empty(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	var n int
+	for c := range db.QueryChunks(context.Background(), `SELECT name FROM test_table WHERE id = 0`, nil, 10) {
+		if err := c.Err(); err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 0 {
+		t.Errorf(`number of chunks => %v; want 0`, n)
+	}
+}
+
+func TestDB_QueryChunks_Error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	for _, v := range []struct {
+		query     string
+		chunkSize int
+	}{
+		{`SELECT * FROM missing_table`, 10},
+		{`SELECT name FROM test_table`, 0},
+	} {
+		var errs []error
+		for c := range db.QueryChunks(context.Background(), v.query, nil, v.chunkSize) {
+			errs = append(errs, c.Err())
+		}
+		if len(errs) != 1 || errs[0] == nil {
+			t.Errorf(`db.QueryChunks(ctx, %#v, nil, %v) errors => %#v; want an error`, v.query, v.chunkSize, errs)
+		}
+	}
+	for c := range db.QueryChunks(context.Background(), `SELECT name FROM test_table`, nil, 0) {
+		if err := c.Err(); !errors.Is(err, asynql.ErrInvalidChunkSize) {
+			t.Errorf(`db.QueryChunks(ctx, query, nil, 0).Err() => %#v; want %#v`, err, asynql.ErrInvalidChunkSize)
+		}
+	}
+}
+
+func TestDB_QueryChunks_Cancel(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := db.QueryChunks(ctx, `SELECT name FROM test_table ORDER BY id`, nil, 1)
+	c := <-ch
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for range ch {
+	}
+	// The connection has been released, so that another query can run on the pool of one connection.
+	if err := (<-db.Exec(`UPDATE test_table SET name = name`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+}
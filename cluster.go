@@ -0,0 +1,107 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// ErrNoShardKey is returned by the ShardFunc created by ShardByHash if the query doesn't have the argument to route by.
+var ErrNoShardKey = errors.New("asynql: no argument to route by")
+
+// ShardFunc returns the index of the shard, in [0, n), that a query with args is routed to.
+type ShardFunc func(args []interface{}, n int) (int, error)
+
+// ShardByHash returns a ShardFunc that routes a query by the hash of its i-th argument.
+func ShardByHash(i int) ShardFunc {
+	return func(args []interface{}, n int) (int, error) {
+		if i < 0 || i >= len(args) {
+			return 0, ErrNoShardKey
+		}
+		h := fnv.New32a()
+		fmt.Fprint(h, args[i])
+		return int(h.Sum32() % uint32(n)), nil
+	}
+}
+
+// Cluster is a set of databases over which data is horizontally partitioned.
+// Exec and Query of Cluster are routed to a shard by the ShardFunc of the Cluster.
+type Cluster struct {
+	shards []*DB
+	route  ShardFunc
+}
+
+// NewCluster returns a new Cluster that routes queries to shards by route.
+func NewCluster(route ShardFunc, shards ...*DB) *Cluster {
+	return &Cluster{
+		shards: shards,
+		route:  route,
+	}
+}
+
+// Shards returns the shards of the cluster.
+func (c *Cluster) Shards() []*DB {
+	return c.shards
+}
+
+// Shard returns the shard that a query with args is routed to.
+func (c *Cluster) Shard(args ...interface{}) (*DB, error) {
+	if len(c.shards) == 0 {
+		return nil, errors.New("asynql: cluster has no shards")
+	}
+	i, err := c.route(args, len(c.shards))
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= len(c.shards) {
+		return nil, fmt.Errorf("asynql: shard index %d out of range [0, %d)", i, len(c.shards))
+	}
+	return c.shards[i], nil
+}
+
+// Close closes all the shards.
+func (c *Cluster) Close() error {
+	var err error
+	for _, db := range c.shards {
+		if e := db.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Exec is similar to DB.Exec, but executes query on the shard that args are routed to.
+func (c *Cluster) Exec(query string, args ...interface{}) <-chan *Result {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext is similar to DB.ExecContext, but executes query on the shard that args are routed to.
+// If the routing fails, the error is sent on the returned channel.
+func (c *Cluster) ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	db, err := c.Shard(args...)
+	if err != nil {
+		return sendResult(nil, &Result{err: err})
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// Query is similar to DB.Query, but executes query on the shard that args are routed to.
+func (c *Cluster) Query(query string, args ...interface{}) <-chan *Rows {
+	return c.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is similar to DB.QueryContext, but executes query on the shard that args are routed to.
+// If the routing fails, the error is sent on the returned channel.
+func (c *Cluster) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	db, err := c.Shard(args...)
+	if err != nil {
+		return sendResult(nil, &Rows{err: err})
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// QueryAllShards executes query on all the shards concurrently as ScatterQuery does.
+func (c *Cluster) QueryAllShards(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	return ScatterQuery(ctx, c.shards, query, args...)
+}
@@ -0,0 +1,59 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func shardByID(args []interface{}, n int) (int, error) {
+	return args[0].(int) % n, nil
+}
+
+func TestCluster(t *testing.T) {
+	dbs := newShardDBs(t, nil, nil)
+	cluster := asynql.NewCluster(shardByID, dbs...)
+	defer cluster.Close()
+	query := `INSERT INTO test_table (id, name) VALUES (?, ?)`
+	if _, err := asynql.WaitAll(
+		cluster.Exec(query, 1, "alice"),
+		cluster.Exec(query, 2, "bob"),
+		cluster.Exec(query, 3, "carol"),
+	); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range [][]string{{"bob"}, {"alice", "carol"}} {
+		actual := scanNames(t, <-dbs[i].Query(`SELECT name FROM test_table ORDER BY id`))
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`names in shard %d => %#v; want %#v`, i, actual, expected)
+		}
+	}
+
+	query = `SELECT name FROM test_table WHERE id = ?`
+	actual := scanNames(t, <-cluster.Query(query, 3))
+	expected := []string{"carol"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`cluster.Query(%#v, 3) => %#v; want %#v`, query, actual, expected)
+	}
+
+	query = `SELECT name FROM test_table ORDER BY name`
+	actual = scanNames(t, <-cluster.QueryAllShards(context.Background(), query))
+	expected = []string{"bob", "alice", "carol"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`cluster.QueryAllShards(ctx, %#v) => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestCluster_RoutingError(t *testing.T) {
+	dbs := newShardDBs(t, nil)
+	cluster := asynql.NewCluster(asynql.ShardByHash(0), dbs...)
+	defer cluster.Close()
+	result := <-cluster.Exec(`DELETE FROM test_table`)
+	var actual interface{} = result.Err()
+	var expected interface{} = asynql.ErrNoShardKey
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`cluster.Exec("DELETE FROM test_table"); Result.Err() => %#v; want %#v`, actual, expected)
+	}
+}
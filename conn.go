@@ -0,0 +1,132 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Conn is same the sql.Conn, but some methods have been provided as asynchronous implementation.
+// All the operations of a Conn are executed on the same connection,
+// so session state such as temporary tables and variables is shared between them.
+type Conn struct {
+	*sql.Conn
+
+	db *DB
+	wg sync.WaitGroup
+}
+
+// Conn is the same as sql.DB.Conn, but returns an *asynql.Conn instead.
+func (db *DB) Conn(ctx context.Context) (*Conn, error) {
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		Conn: conn,
+		db:   db,
+	}, nil
+}
+
+// BeginTx is the same as sql.Conn.BeginTx, but returns an *asynql.Tx instead.
+func (c *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := c.Conn.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newTx(ctx, c.db, tx), nil
+}
+
+// Pinned reserves a connection of the pool, calls fn with it, and then returns the connection to the pool,
+// so that session state such as temporary tables, SET LOCAL and user variables survives across the asynchronous operations in fn.
+// The results of the operations in fn must be received before fn returns, because the connection is returned after they have been received.
+// The session state is kept on the connection after it has been returned, so fn should clean up what the other users of the pool must not see.
+// Pinned returns the error of fn, or the error of reserving the connection.
+func (db *DB) Pinned(ctx context.Context, fn func(conn *Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return fn(conn)
+}
+
+// Close is same the sql.Conn.Close, but waits the end of the all queries.
+func (c *Conn) Close() error {
+	c.wg.Wait()
+	return c.Conn.Close()
+}
+
+// Exec is similar to sql.Conn.ExecContext, but returns a channel of *asynql.Result.
+// Exec executes query with args and then sends the result on the returned channel.
+func (c *Conn) Exec(query string, args ...interface{}) <-chan *Result {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext is similar to sql.Conn.ExecContext, but returns a channel of *asynql.Result.
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	ctx = killable(ctx, c.db)
+	return dispatch(ctx, c.db, c, &c.wg, instrument(ctx, c.db, HookExec, query, args, func() *Result {
+		if dryRunOf(c.db) {
+			return dryExec(ctx, c.Conn, query, args)
+		}
+		result, err := c.Conn.ExecContext(ctx, query, args...)
+		if err == nil {
+			c.db.invalidateCache(query)
+		}
+		return &Result{
+			Result: result,
+			err:    err,
+		}
+	}))
+}
+
+// PrepareContext is the same as sql.Conn.PrepareContext, but returns a *asynql.Stmt instead.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	stmt, err := c.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{
+		Stmt:  stmt,
+		db:    c.db,
+		conn:  c,
+		query: query,
+		stats: newStmtStats(query),
+		wg:    &c.wg,
+	}, nil
+}
+
+// Query is similar to sql.Conn.QueryContext, but returns a channel of *asynql.Rows.
+// Query executes a query with args and then sends the result on the returned channel.
+func (c *Conn) Query(query string, args ...interface{}) <-chan *Rows {
+	return c.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is similar to sql.Conn.QueryContext, but returns a channel of *asynql.Rows.
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	ctx = killable(ctx, c.db)
+	return dispatch(ctx, c.db, c, &c.wg, instrument(ctx, c.db, HookQuery, query, args, func() *Rows {
+		rows, err := c.Conn.QueryContext(ctx, query, args...)
+		return &Rows{
+			Rows: rows,
+			err:  err,
+		}
+	}))
+}
+
+// QueryRow is similar to sql.Conn.QueryRowContext, but returns a channel of *asynql.Row.
+// QueryRow executes a query with args and then sends the result on the returned channel.
+func (c *Conn) QueryRow(query string, args ...interface{}) <-chan *Row {
+	return c.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext is similar to sql.Conn.QueryRowContext, but returns a channel of *asynql.Row.
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) <-chan *Row {
+	ctx = killable(ctx, c.db)
+	return dispatch(ctx, c.db, c, &c.wg, instrument(ctx, c.db, HookQueryRow, query, args, func() *Row {
+		return &Row{
+			Row: c.Conn.QueryRowContext(ctx, query, args...),
+		}
+	}))
+}
@@ -0,0 +1,84 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestConn(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := `CREATE TEMP TABLE temp_table (id INTEGER)`
+	if err := (<-conn.ExecContext(ctx, query)).Err(); err != nil {
+		t.Fatalf(`conn.ExecContext(ctx, %#v) => %#v; want nil`, query, err)
+	}
+	if _, err := asynql.WaitAll(
+		conn.ExecContext(ctx, `INSERT INTO temp_table (id) VALUES (1)`),
+		conn.ExecContext(ctx, `INSERT INTO temp_table (id) VALUES (2)`),
+	); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	query = `SELECT COUNT(*) FROM temp_table`
+	if err := (<-conn.QueryRowContext(ctx, query)).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = count
+	var expected interface{} = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`conn.QueryRowContext(ctx, %#v) => %#v; want %#v`, query, actual, expected)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_Pinned(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	var count int
+	err := db.Pinned(ctx, func(conn *asynql.Conn) error {
+		if err := (<-conn.ExecContext(ctx, `CREATE TEMP TABLE temp_table (id INTEGER)`)).Err(); err != nil {
+			return err
+		}
+		if _, err := asynql.WaitAll(
+			conn.ExecContext(ctx, `INSERT INTO temp_table (id) VALUES (1)`),
+			conn.ExecContext(ctx, `INSERT INTO temp_table (id) VALUES (2)`),
+		); err != nil {
+			return err
+		}
+		return (<-conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM temp_table`)).Scan(&count)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = count
+	var expected interface{} = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`COUNT(*) in db.Pinned(ctx, fn) => %#v; want %#v`, actual, expected)
+	}
+
+	// The connection has been returned to the pool.
+	if err := (<-db.ExecContext(ctx, `DROP TABLE temp_table`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	errFn := errors.New("fn failed")
+	actual = db.Pinned(ctx, func(conn *asynql.Conn) error {
+		return errFn
+	})
+	expected = errFn
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Pinned(ctx, fn) => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,110 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// wrapsConnector reports whether the connector of a new pool needs to be wrapped by wrapConnector.
+func (db *DB) wrapsConnector() bool {
+	return db.connInit != nil || db.location != nil
+}
+
+// wrapConnector returns a driver.Connector that applies the options of db to the connections of c.
+func (db *DB) wrapConnector(c driver.Connector) driver.Connector {
+	if db.connInit != nil {
+		c = &initConnector{
+			Connector: c,
+			init:      db.connInit,
+		}
+	}
+	if db.location != nil {
+		c = &locConnector{
+			Connector: c,
+			loc:       db.location,
+		}
+	}
+	return c
+}
+
+// connectorOf returns a driver.Connector that opens connections to dataSourceName with d.
+func connectorOf(d driver.Driver, dataSourceName string) (driver.Connector, error) {
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dataSourceName)
+	}
+	return &dsnConnector{
+		driver: d,
+		dsn:    dataSourceName,
+	}, nil
+}
+
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// initConnector runs init on every connection it opens.
+type initConnector struct {
+	driver.Connector
+
+	init func(ctx context.Context, conn *sql.Conn) error
+}
+
+func (c *initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.initConn(ctx, dc); err != nil {
+		dc.Close()
+		return nil, err
+	}
+	return dc, nil
+}
+
+// initConn passes dc to c.init as an *sql.Conn by way of a temporary pool that consists only of dc.
+func (c *initConnector) initConn(ctx context.Context, dc driver.Conn) error {
+	db := sql.OpenDB(&singleConnector{
+		conn:   nopCloseConn{wrapConn(dc)},
+		driver: c.Driver(),
+	})
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return c.init(ctx, conn)
+}
+
+// singleConnector always returns the same connection.
+type singleConnector struct {
+	conn   driver.Conn
+	driver driver.Driver
+}
+
+func (c *singleConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.conn, nil
+}
+
+func (c *singleConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// nopCloseConn is a driver.Conn whose Close does nothing.
+type nopCloseConn struct {
+	*connWrapper
+}
+
+func (nopCloseConn) Close() error {
+	return nil
+}
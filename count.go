@@ -0,0 +1,74 @@
+package asynql
+
+import (
+	"context"
+)
+
+// CountedRows represents a result of QueryWithCount.
+type CountedRows struct {
+	// Rows is the rows of the query, which are read into memory.
+	*Rows
+
+	// Count is the number of the rows that the query returns without its ORDER BY, LIMIT and OFFSET clauses.
+	Count int64
+
+	err error
+}
+
+// Err returns an error.
+func (cr *CountedRows) Err() error {
+	if cr.err != nil {
+		return cr.err
+	}
+	return cr.Rows.Err()
+}
+
+// QueryWithCount executes a query with args, and concurrently counts the rows of the query without its paging clauses,
+// and then sends both on the returned channel.
+// It is useful for paginated APIs, which return a page of the rows together with the total number of them:
+//
+//	cr := <-db.QueryWithCount(ctx, `SELECT * FROM users WHERE active = ? ORDER BY id LIMIT ? OFFSET ?`, true, 20, 40)
+//
+// The count is taken by SELECT COUNT(*) over the query from which the top-level ORDER BY, LIMIT, OFFSET and FETCH clauses are removed,
+// along with the trailing arguments that only those clauses refer to.
+// The rows are read into memory, so that the count doesn't wait for a connection that the rows hold.
+func (db *DB) QueryWithCount(ctx context.Context, query string, args ...interface{}) <-chan *CountedRows {
+	ch := make(chan *CountedRows)
+	go func() {
+		head, tail := splitPaging(query)
+		countArgs := args
+		if tail != "" {
+			if n := countParams(head); n < len(args) && countParams(tail) > 0 {
+				countArgs = args[:n]
+			}
+		}
+		rows := db.QueryContext(ctx, query, args...)
+		row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+head+") AS asynql_count", countArgs...)
+		// Each result holds a connection until it is read, so read whichever comes first.
+		var snap *Snapshot
+		var count int64
+		var err, cerr error
+		for rows != nil || row != nil {
+			select {
+			case rs := <-rows:
+				snap, err = Materialize(rs)
+				rows = nil
+			case r := <-row:
+				cerr = r.Scan(&count)
+				row = nil
+			}
+		}
+		if err == nil {
+			err = cerr
+		}
+		cr := &CountedRows{
+			Count: count,
+			err:   err,
+		}
+		if err == nil {
+			cr.Rows = snap.Rows()
+		}
+		deliver(db, ch, cr)
+	}()
+	return ch
+}
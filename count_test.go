@@ -0,0 +1,37 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDB_QueryWithCount(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := (<-db.Exec(`INSERT INTO test_table (id, name) VALUES (3, "carol"), (4, "dave")`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		query    string
+		args     []interface{}
+		names    []string
+		expected int64
+	}{
+		{`SELECT name FROM test_table ORDER BY id LIMIT 2 OFFSET 1`, nil, []string{"bob", "carol"}, 4},
+		{`SELECT name FROM test_table WHERE id > ? ORDER BY id LIMIT ? OFFSET ?`, []interface{}{1, 1, 1}, []string{"carol"}, 3},
+		{`SELECT name FROM test_table WHERE id IN (SELECT id FROM test_table ORDER BY id LIMIT 3) ORDER BY id DESC`, nil, []string{"carol", "bob", "alice"}, 3},
+		{`SELECT name FROM test_table WHERE id = 1 UNION SELECT name FROM test_table WHERE id = 2 ORDER BY name LIMIT 1`, nil, []string{"alice"}, 2},
+		{`SELECT name FROM test_table WHERE id = 4`, nil, []string{"dave"}, 1},
+	} {
+		cr := <-db.QueryWithCount(context.Background(), v.query, v.args...)
+		if err := cr.Err(); err != nil {
+			t.Fatalf(`db.QueryWithCount(ctx, %#v, ...) => %v`, v.query, err)
+		}
+		var actual interface{} = []interface{}{scanNames(t, cr.Rows), cr.Count}
+		var expected interface{} = []interface{}{v.names, v.expected}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`db.QueryWithCount(ctx, %#v, %#v) => %#v; want %#v`, v.query, v.args, actual, expected)
+		}
+	}
+}
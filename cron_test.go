@@ -0,0 +1,58 @@
+package asynql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestNextSchedule(t *testing.T) {
+	// 2024-01-31 is a Wednesday.
+	base := time.Date(2024, 1, 31, 10, 30, 15, 0, time.UTC)
+	for _, v := range []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 31, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * sat,sun", time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 * *", time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"@every 1h30m", time.Date(2024, 1, 31, 12, 0, 15, 0, time.UTC)},
+		{"0 0 30 feb *", time.Time{}},
+	} {
+		actual, err := asynql.NextSchedule(v.spec, base)
+		if err != nil {
+			t.Errorf(`NextSchedule(%#v, %v) => %v`, v.spec, base, err)
+			continue
+		}
+		if !actual.Equal(v.expected) {
+			t.Errorf(`NextSchedule(%#v, %v) => %v; want %v`, v.spec, base, actual, v.expected)
+		}
+	}
+}
+
+func TestNextSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@fortnightly",
+		"@every -1s",
+	} {
+		if _, err := asynql.NextSchedule(spec, time.Now()); err == nil {
+			t.Errorf(`NextSchedule(%#v, now) => nil; want error`, spec)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package asynql
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// DefaultCSVProgressInterval is the default number of the rows between the progress reports of ExportCSV.
+const DefaultCSVProgressInterval = 1000
+
+// CSVOptions configures WriteCSV and ExportCSV.
+type CSVOptions struct {
+	// Comma is the field delimiter. It defaults to ','.
+	Comma rune
+
+	// UseCRLF terminates each line with \r\n instead of \n.
+	UseCRLF bool
+
+	// NoHeader omits the header row of the column names.
+	NoHeader bool
+
+	// Null is written for NULL values. It defaults to the empty string.
+	Null string
+
+	// TimeFormat is the layout of time.Time values. It defaults to time.RFC3339Nano.
+	TimeFormat string
+
+	// ProgressInterval is the number of the rows between the progress reports of ExportCSV.
+	// It defaults to DefaultCSVProgressInterval.
+	ProgressInterval int
+}
+
+// WriteCSV writes the remaining rows of rs to w as CSV, preceded by a header row of the column names.
+// The fields are quoted as needed by encoding/csv.
+// opts may be nil for the defaults.
+// rs is closed when WriteCSV returns, and the number of the rows written, excluding the header, is returned.
+func (rs *Rows) WriteCSV(w io.Writer, opts *CSVOptions) (int64, error) {
+	return rs.writeCSV(w, opts, nil)
+}
+
+func (rs *Rows) writeCSV(w io.Writer, opts *CSVOptions, progress func(n int64)) (int64, error) {
+	if err := rs.Err(); err != nil {
+		return 0, err
+	}
+	defer rs.Close()
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	timeFormat := opts.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+	interval := int64(opts.ProgressInterval)
+	if interval <= 0 {
+		interval = DefaultCSVProgressInterval
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		return 0, err
+	}
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	cw.UseCRLF = opts.UseCRLF
+	if !opts.NoHeader {
+		if err := cw.Write(columns); err != nil {
+			return 0, err
+		}
+	}
+	values := make([]interface{}, len(columns))
+	dests := make([]interface{}, len(columns))
+	for i := range values {
+		dests[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	var n int64
+	for rs.Next() {
+		if err := rs.Scan(dests...); err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			record[i] = csvField(v, opts.Null, timeFormat)
+		}
+		if err := cw.Write(record); err != nil {
+			return n, err
+		}
+		n++
+		if progress != nil && n%interval == 0 {
+			progress(n)
+		}
+	}
+	if err := rs.Err(); err != nil {
+		return n, err
+	}
+	cw.Flush()
+	return n, cw.Error()
+}
+
+func csvField(v interface{}, null, timeFormat string) string {
+	switch v := v.(type) {
+	case nil:
+		return null
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(timeFormat)
+	}
+	return fmt.Sprint(v)
+}
+
+// ExportProgress reports the progress of ExportCSV.
+type ExportProgress struct {
+	// Rows is the number of the rows written so far.
+	Rows int64
+
+	// Done reports whether the export has finished, successfully or not.
+	Done bool
+
+	err error
+}
+
+// Err returns an error.
+func (p *ExportProgress) Err() error {
+	return p.err
+}
+
+// ExportCSV executes a query with args and writes the rows to w by WriteCSV,
+// reporting the progress on the returned channel every opts.ProgressInterval rows.
+// A progress report is dropped if the previous one has not been received yet, so a slow receiver doesn't slow down the export.
+// The last report has Done set, and then the channel is closed.
+// The last report is dropped too if ctx is done before it is received.
+func (db *DB) ExportCSV(ctx context.Context, w io.Writer, opts *CSVOptions, query string, args ...interface{}) <-chan *ExportProgress {
+	ch := make(chan *ExportProgress, 1)
+	go func() {
+		defer close(ch)
+		n, err := (<-db.QueryContext(ctx, query, args...)).writeCSV(w, opts, func(n int64) {
+			select {
+			case ch <- &ExportProgress{Rows: n}:
+			default:
+			}
+		})
+		select {
+		case ch <- &ExportProgress{
+			Rows: n,
+			Done: true,
+			err:  err,
+		}:
+		case <-ctx.Done():
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,60 @@
+package asynql_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestRows_WriteCSV(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := (<-db.Exec(`INSERT INTO test_table (id, name) VALUES (3, NULL), (4, 'say "hi", bob')`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	query := `SELECT id, name FROM test_table ORDER BY id`
+	for _, v := range []struct {
+		opts     *asynql.CSVOptions
+		expected string
+	}{
+		{nil, "id,name\n1,alice\n2,bob\n3,\n4,\"say \"\"hi\"\", bob\"\n"},
+		{&asynql.CSVOptions{Comma: ';', NoHeader: true, Null: `\N`, UseCRLF: true}, "1;alice\r\n2;bob\r\n3;\\N\r\n4;\"say \"\"hi\"\", bob\"\r\n"},
+	} {
+		var buf bytes.Buffer
+		n, err := (<-db.Query(query)).WriteCSV(&buf, v.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = []interface{}{n, buf.String()}
+		var expected interface{} = []interface{}{int64(4), v.expected}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`rows.WriteCSV(w, %#v) => %#v; want %#v`, v.opts, actual, expected)
+		}
+	}
+}
+
+func TestDB_ExportCSV(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	var buf bytes.Buffer
+	var last *asynql.ExportProgress
+	var reports int
+	for p := range db.ExportCSV(context.Background(), &buf, &asynql.CSVOptions{ProgressInterval: 1}, `SELECT name FROM test_table ORDER BY id`) {
+		last = p
+		reports++
+	}
+	if err := last.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = []interface{}{last.Rows, last.Done, buf.String()}
+	var expected interface{} = []interface{}{int64(2), true, "name\nalice\nbob\n"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`last progress of db.ExportCSV => %#v; want %#v`, actual, expected)
+	}
+	if reports < 1 || reports > 3 {
+		t.Errorf(`number of progress reports => %v; want 1 to 3`, reports)
+	}
+}
@@ -0,0 +1,75 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// ErrCursorUnsupported is reported by QueryCursor when a DB has a dialect other than DialectPostgres.
+var ErrCursorUnsupported = errors.New("asynql: server-side cursors are supported only by PostgreSQL")
+
+// cursorName is the name of the cursor declared by QueryCursor, which is unique in its own transaction.
+const cursorName = "asynql_cursor"
+
+// QueryCursor executes a query through a server-side cursor of PostgreSQL, and then sends its rows on the returned channel
+// in chunks of up to fetchSize rows, so that a result far bigger than memory can be processed chunk by chunk.
+// The cursor is declared by DECLARE in a transaction that QueryCursor begins, and each chunk is fetched by FETCH
+// only after the previous one has been received, so neither the client nor the driver holds more than a chunk.
+// The transaction is rolled back after the last chunk, so query should not modify the database.
+// If a query fails, a Chunk with the error is sent, and no empty chunk is sent.
+// The channel is closed after the last chunk or the error, or when ctx is done.
+func (db *DB) QueryCursor(ctx context.Context, query string, args []interface{}, fetchSize int) <-chan *Chunk {
+	ch := make(chan *Chunk)
+	go func() {
+		defer close(ch)
+		send := func(c *Chunk) bool {
+			select {
+			case ch <- c:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		switch {
+		case db.Dialect() != DialectPostgres:
+			send(&Chunk{err: ErrCursorUnsupported})
+			return
+		case fetchSize <= 0:
+			send(&Chunk{err: ErrInvalidChunkSize})
+			return
+		}
+		tx, err := db.DB.BeginTx(ctx, nil)
+		if err != nil {
+			send(&Chunk{err: err})
+			return
+		}
+		t := newTx(ctx, db, tx)
+		defer t.Rollback()
+		// DECLARE is run as a query, since it doesn't write and so is allowed in maintenance mode.
+		rs := <-t.QueryContext(ctx, "DECLARE "+cursorName+" NO SCROLL CURSOR FOR "+query, args...)
+		if err := rs.Err(); err != nil {
+			send(&Chunk{err: err})
+			return
+		}
+		if err := rs.Close(); err != nil {
+			send(&Chunk{err: err})
+			return
+		}
+		fetch := "FETCH FORWARD " + strconv.Itoa(fetchSize) + " FROM " + cursorName
+		for n := 0; ; n++ {
+			s, err := Materialize(<-t.QueryContext(ctx, fetch))
+			if err != nil {
+				send(&Chunk{Number: n, err: err})
+				return
+			}
+			if len(s.Values) == 0 {
+				return
+			}
+			if !send(&Chunk{Snapshot: s, Number: n}) || len(s.Values) < fetchSize {
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,129 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// cursorConn is a driver.Conn that implements a server-side cursor of PostgreSQL over names in memory.
+type cursorConn struct {
+	mu      sync.Mutex
+	names   []string
+	queries []string
+	pos     int
+}
+
+func (c *cursorConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *cursorConn) Close() error                        { return nil }
+func (c *cursorConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *cursorConn) Commit() error                       { return nil }
+
+func (c *cursorConn) Rollback() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, "ROLLBACK")
+	return nil
+}
+
+func (c *cursorConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+	if strings.HasPrefix(query, "DECLARE asynql_cursor NO SCROLL CURSOR FOR ") {
+		c.pos = 0
+		return &nameRows{}, nil
+	}
+	var n int
+	if _, err := fmt.Sscanf(query, "FETCH FORWARD %d FROM asynql_cursor", &n); err != nil {
+		return nil, fmt.Errorf("unexpected query %q", query)
+	}
+	end := min(c.pos+n, len(c.names))
+	rows := &nameRows{names: c.names[c.pos:end]}
+	c.pos = end
+	return rows, nil
+}
+
+type nameRows struct {
+	names []string
+}
+
+func (r *nameRows) Columns() []string { return []string{"name"} }
+func (r *nameRows) Close() error      { return nil }
+
+func (r *nameRows) Next(dest []driver.Value) error {
+	if len(r.names) == 0 {
+		return io.EOF
+	}
+	dest[0], r.names = r.names[0], r.names[1:]
+	return nil
+}
+
+func TestDB_QueryCursor(t *testing.T) {
+	for _, v := range []struct {
+		fetchSize int
+		expected  [][]string
+		fetches   int
+	}{
+		{2, [][]string{{"alice", "bob"}, {"carol"}}, 2},
+		{3, [][]string{{"alice", "bob", "carol"}}, 2},
+		{10, [][]string{{"alice", "bob", "carol"}}, 1},
+	} {
+		conn := &cursorConn{names: []string{"alice", "bob", "carol"}}
+		db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithDialect(asynql.DialectPostgres))
+		var actual [][]string
+		for c := range db.QueryCursor(context.Background(), `SELECT name FROM users WHERE id > $1`, []interface{}{0}, v.fetchSize) {
+			if err := c.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if c.Number != len(actual) {
+				t.Errorf(`c.Number => %#v; want %#v`, c.Number, len(actual))
+			}
+			actual = append(actual, scanNames(t, c.Rows()))
+		}
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`db.QueryCursor(ctx, query, args, %v) => %#v; want %#v`, v.fetchSize, actual, v.expected)
+		}
+		db.Close()
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM asynql_cursor", v.fetchSize)
+		expected := []string{"DECLARE asynql_cursor NO SCROLL CURSOR FOR SELECT name FROM users WHERE id > $1"}
+		for i := 0; i < v.fetches; i++ {
+			expected = append(expected, fetch)
+		}
+		expected = append(expected, "ROLLBACK")
+		if !reflect.DeepEqual(conn.queries, expected) {
+			t.Errorf(`queries of db.QueryCursor(ctx, query, args, %v) => %#v; want %#v`, v.fetchSize, conn.queries, expected)
+		}
+	}
+}
+
+func TestDB_QueryCursor_Error(t *testing.T) {
+	sqlite := newTestDB(t)
+	defer sqlite.Close()
+	postgres := asynql.OpenDB(&notifyConnector{conn: &cursorConn{}}, asynql.WithDialect(asynql.DialectPostgres))
+	defer postgres.Close()
+	for _, v := range []struct {
+		db        *asynql.DB
+		fetchSize int
+		expected  error
+	}{
+		{sqlite, 10, asynql.ErrCursorUnsupported},
+		{postgres, 0, asynql.ErrInvalidChunkSize},
+	} {
+		var errs []error
+		for c := range v.db.QueryCursor(context.Background(), `SELECT name FROM test_table`, nil, v.fetchSize) {
+			errs = append(errs, c.Err())
+		}
+		if len(errs) != 1 || !errors.Is(errs[0], v.expected) {
+			t.Errorf(`db.QueryCursor(ctx, query, nil, %v) errors => %#v; want [%#v]`, v.fetchSize, errs, v.expected)
+		}
+	}
+}
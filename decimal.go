@@ -0,0 +1,183 @@
+package asynql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an arbitrary-precision decimal number, which scans a NUMERIC or DECIMAL column without the loss of float64.
+// It keeps the scale of the number, so 1.50 is scanned and bound as 1.50.
+// The zero value is 0.
+//
+// A NULL cannot be scanned into a Decimal, but can be into a *Decimal.
+// ScanStruct scans the columns into the string and big.Rat fields tagged with `db:"name,decimal"` in the same way.
+type Decimal struct {
+	// coef is the coefficient, or nil for 0.
+	coef *big.Int
+
+	// exp is the exponent, so that the number is coef * 10^exp.
+	exp int
+}
+
+// NewDecimal returns the Decimal of coef * 10^exp.
+func NewDecimal(coef int64, exp int) Decimal {
+	return Decimal{
+		coef: big.NewInt(coef),
+		exp:  exp,
+	}
+}
+
+// ParseDecimal parses s, which is a decimal number with an optional sign, fraction and exponent such as -12.50 or 1.5e-3.
+func ParseDecimal(s string) (Decimal, error) {
+	t := strings.TrimSpace(s)
+	exp := 0
+	if i := strings.IndexAny(t, "eE"); i >= 0 {
+		e, err := strconv.Atoi(t[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("asynql: invalid decimal %q", s)
+		}
+		exp = e
+		t = t[:i]
+	}
+	if i := strings.IndexByte(t, '.'); i >= 0 {
+		exp -= len(t) - i - 1
+		t = t[:i] + t[i+1:]
+	}
+	digits := strings.TrimLeft(t, "+-")
+	if len(t)-len(digits) > 1 || digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("asynql: invalid decimal %q", s)
+	}
+	coef, ok := new(big.Int).SetString(t, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("asynql: invalid decimal %q", s)
+	}
+	return Decimal{
+		coef: coef,
+		exp:  exp,
+	}, nil
+}
+
+// String returns d in the decimal notation without an exponent.
+func (d Decimal) String() string {
+	if d.coef == nil {
+		return "0"
+	}
+	s := d.coef.String()
+	if d.exp >= 0 {
+		return s + strings.Repeat("0", d.exp)
+	}
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	if n := -d.exp; len(s) <= n {
+		s = strings.Repeat("0", n-len(s)+1) + s
+	}
+	i := len(s) + d.exp
+	return sign + s[:i] + "." + s[i:]
+}
+
+// Rat returns d as a big.Rat.
+func (d Decimal) Rat() *big.Rat {
+	r := new(big.Rat)
+	if d.coef == nil {
+		return r
+	}
+	r.SetInt(d.coef)
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(d.exp))), nil)
+	if d.exp >= 0 {
+		return r.Mul(r, new(big.Rat).SetInt(pow))
+	}
+	return r.Quo(r, new(big.Rat).SetInt(pow))
+}
+
+// Float64 returns the nearest float64 to d.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Cmp compares d and e, and returns -1, 0 or +1 as d < e, d == e or d > e.
+// The numbers of different scales such as 1.5 and 1.50 are equal.
+func (d Decimal) Cmp(e Decimal) int {
+	return d.Rat().Cmp(e.Rat())
+}
+
+// Scan implements sql.Scanner.
+func (d *Decimal) Scan(src interface{}) error {
+	v, err := decimalOf(src)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Value implements driver.Valuer.
+// d is bound as a string, which the databases convert to NUMERIC or DECIMAL without loss.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// decimalOf converts a value of a column into a Decimal.
+func decimalOf(src interface{}) (Decimal, error) {
+	switch src := src.(type) {
+	case []byte:
+		return ParseDecimal(string(src))
+	case string:
+		return ParseDecimal(src)
+	case int64:
+		return NewDecimal(src, 0), nil
+	case float64:
+		// The shortest representation that reads back as src, e.g. 0.1 rather than 0.1000000000000000055511151231257827.
+		return ParseDecimal(strconv.FormatFloat(src, 'f', -1, 64))
+	case nil:
+		return Decimal{}, fmt.Errorf("asynql: cannot scan NULL into a Decimal")
+	}
+	return Decimal{}, fmt.Errorf("asynql: cannot scan %T into a Decimal", src)
+}
+
+// decimalField is a sql.Scanner that scans a decimal number into the field of a struct tagged with `db:"name,decimal"`.
+type decimalField struct {
+	dest reflect.Value
+}
+
+func (f decimalField) Scan(src interface{}) error {
+	if src == nil {
+		f.dest.SetZero()
+		return nil
+	}
+	d, err := decimalOf(src)
+	if err != nil {
+		return err
+	}
+	switch dest := f.dest.Addr().Interface().(type) {
+	case *string:
+		*dest = d.String()
+	case *big.Rat:
+		dest.Set(d.Rat())
+	case **big.Rat:
+		*dest = d.Rat()
+	default:
+		return fmt.Errorf("asynql: cannot scan a decimal into %v", f.dest.Type())
+	}
+	return nil
+}
+
+var ratType = reflect.TypeOf(big.Rat{})
+
+// isDecimalFieldType reports whether t is a type of the fields that can be tagged with the decimal option.
+func isDecimalFieldType(t reflect.Type) bool {
+	return t.Kind() == reflect.String || t == ratType || t == reflect.PointerTo(ratType)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
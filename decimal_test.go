@@ -0,0 +1,114 @@
+package asynql_test
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestParseDecimal(t *testing.T) {
+	for _, v := range []struct {
+		s        string
+		expected string
+	}{
+		{"0", "0"},
+		{"12.50", "12.50"},
+		{"-0.005", "-0.005"},
+		{"+7", "7"},
+		{".5", "0.5"},
+		{"1.5e3", "1500"},
+		{"15E-4", "0.0015"},
+		{"123456789012345678901234567890.123456789", "123456789012345678901234567890.123456789"},
+	} {
+		d, err := asynql.ParseDecimal(v.s)
+		if err != nil {
+			t.Errorf(`asynql.ParseDecimal(%q) => %#v; want nil`, v.s, err)
+			continue
+		}
+		if actual := d.String(); actual != v.expected {
+			t.Errorf(`asynql.ParseDecimal(%q).String() => %#v; want %#v`, v.s, actual, v.expected)
+		}
+	}
+	for _, s := range []string{"", "-", "1.2.3", "1e", "abc", "--1", "1,5"} {
+		if _, err := asynql.ParseDecimal(s); err == nil {
+			t.Errorf(`asynql.ParseDecimal(%q) => nil; want error`, s)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	d := asynql.NewDecimal(150, -2)
+	var actual interface{} = []interface{}{d.String(), d.Rat().String(), d.Float64(), d.Cmp(asynql.NewDecimal(15, -1)), d.Cmp(asynql.NewDecimal(2, 0))}
+	var expected interface{} = []interface{}{"1.50", "3/2", 1.5, 0, -1}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.NewDecimal(150, -2) => %#v; want %#v`, actual, expected)
+	}
+	if actual, expected := (asynql.Decimal{}).String(), "0"; actual != expected {
+		t.Errorf(`asynql.Decimal{}.String() => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDecimal_Scan(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE prices (id INTEGER, amount TEXT, rate REAL, total TEXT)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	amount, err := asynql.ParseDecimal("19999999999999999.99")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-db.ExecContext(ctx, `INSERT INTO prices VALUES (1, ?, 0.1, '1.25'), (2, NULL, NULL, NULL)`, amount)).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var d asynql.Decimal
+	if err := (<-db.QueryRowContext(ctx, `SELECT amount FROM prices WHERE id = 1`)).Scan(&d); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := d.String(), "19999999999999999.99"; actual != expected {
+		t.Errorf(`Scan(&Decimal{}) => %#v; want %#v`, actual, expected)
+	}
+	if err := (<-db.QueryRowContext(ctx, `SELECT amount FROM prices WHERE id = 2`)).Scan(&d); err == nil {
+		t.Errorf(`Scan(&Decimal{}) of NULL => nil; want error`)
+	}
+
+	type price struct {
+		ID     int
+		Amount *asynql.Decimal
+		Rate   string   `db:"rate,decimal"`
+		Total  *big.Rat `db:"total,decimal"`
+	}
+	rows := <-db.QueryContext(ctx, `SELECT id, amount, rate, total FROM prices ORDER BY id`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var results []interface{}
+	for rows.Next() {
+		var p price
+		if err := rows.ScanStruct(&p); err != nil {
+			t.Fatal(err)
+		}
+		var amount, total interface{}
+		if p.Amount != nil {
+			amount = p.Amount.String()
+		}
+		if p.Total != nil {
+			total = p.Total.String()
+		}
+		results = append(results, []interface{}{p.ID, amount, p.Rate, total})
+	}
+	actual := results
+	expected := []interface{}{
+		[]interface{}{1, "19999999999999999.99", "0.1", "5/4"},
+		[]interface{}{2, nil, "", nil},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`rows.ScanStruct(&price{}) => %#v; want %#v`, actual, expected)
+	}
+}
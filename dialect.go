@@ -0,0 +1,163 @@
+package asynql
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Dialect is an SQL dialect.
+// It is used by the helpers that generate SQL, such as the queue package.
+type Dialect int
+
+const (
+	// DialectUnknown is a dialect that is not known to asynql.
+	// The generated SQL uses ? placeholders and only the syntax that is common to the major databases.
+	DialectUnknown Dialect = iota
+
+	// DialectSQLite is the dialect of SQLite.
+	DialectSQLite
+
+	// DialectPostgres is the dialect of PostgreSQL.
+	DialectPostgres
+
+	// DialectMySQL is the dialect of MySQL and MariaDB.
+	DialectMySQL
+)
+
+var dialectNames = [...]string{
+	DialectUnknown:  "unknown",
+	DialectSQLite:   "sqlite",
+	DialectPostgres: "postgres",
+	DialectMySQL:    "mysql",
+}
+
+// String returns the name of d.
+func (d Dialect) String() string {
+	if d < 0 || int(d) >= len(dialectNames) {
+		return "Dialect(" + strconv.Itoa(int(d)) + ")"
+	}
+	return dialectNames[d]
+}
+
+// Placeholder returns the placeholder of the n-th argument, counting from 1.
+func (d Dialect) Placeholder(n int) string {
+	if d == DialectPostgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Rebind replaces the ? placeholders in query with the placeholders of d.
+// The question marks in string literals, quoted identifiers and comments are left as they are.
+func (d Dialect) Rebind(query string) string {
+	if d != DialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	last, n := 0, 0
+	for _, t := range lexSQL(query) {
+		if t.kind != tokParam || t.text != "?" {
+			continue
+		}
+		n++
+		b.WriteString(query[last:t.pos])
+		b.WriteString(d.Placeholder(n))
+		last = t.end
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// SupportsSkipLocked reports whether d supports SELECT ... FOR UPDATE SKIP LOCKED.
+// MySQL supports it since 8.0 and MariaDB since 10.6.
+func (d Dialect) SupportsSkipLocked() bool {
+	return d == DialectPostgres || d == DialectMySQL
+}
+
+// IdentityColumn returns the type and the constraints of a BIGINT primary key column whose values are generated by d,
+// for the tables that the helpers create.
+func (d Dialect) IdentityColumn() string {
+	switch d {
+	case DialectSQLite:
+		return "INTEGER PRIMARY KEY AUTOINCREMENT"
+	case DialectPostgres:
+		return "BIGSERIAL PRIMARY KEY"
+	case DialectMySQL:
+		return "BIGINT AUTO_INCREMENT PRIMARY KEY"
+	}
+	return "BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"
+}
+
+// BlobType returns the type of a column of arbitrary binary data in d.
+func (d Dialect) BlobType() string {
+	switch d {
+	case DialectPostgres:
+		return "BYTEA"
+	case DialectMySQL:
+		return "LONGBLOB"
+	}
+	return "BLOB"
+}
+
+// QuoteIdent returns name quoted as an identifier of d, so that it can be spliced into the generated SQL.
+// The parts of name separated by dots, such as the schema of a table, are quoted separately.
+// A quoted identifier is case-sensitive in most databases, so name must be spelled as the object was created.
+func (d Dialect) QuoteIdent(name string) string {
+	q := `"`
+	if d == DialectMySQL {
+		q = "`"
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = q + strings.ReplaceAll(part, q, q+q) + q
+	}
+	return strings.Join(parts, ".")
+}
+
+// WithDialect returns an Option that sets the dialect of the DB instead of detecting it from the driver.
+func WithDialect(d Dialect) Option {
+	return func(db *DB) {
+		db.dialect = d
+		db.dialectOnce.Do(func() {})
+	}
+}
+
+// Dialect returns the dialect of db.
+// Unless it is set by WithDialect, it is detected from the package of the driver:
+// the drivers whose package paths contain "sqlite", "mysql", "lib/pq", "pgx" or "postgres" are recognized.
+func (db *DB) Dialect() Dialect {
+	db.dialectOnce.Do(func() {
+		db.dialect = detectDialect(db.Driver())
+	})
+	return db.dialect
+}
+
+func detectDialect(d driver.Driver) Dialect {
+	t := reflect.TypeOf(d)
+	if t == nil {
+		return DialectUnknown
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := strings.ToLower(t.PkgPath() + "." + t.Name())
+	switch {
+	case strings.Contains(name, "sqlite"):
+		return DialectSQLite
+	case strings.Contains(name, "mysql"):
+		return DialectMySQL
+	case strings.Contains(name, "lib/pq"), strings.Contains(name, "pgx"), strings.Contains(name, "postgres"):
+		return DialectPostgres
+	}
+	return DialectUnknown
+}
+
+// dialectOf returns the dialect of the DB that q belongs to, or DialectUnknown if it is not known.
+func dialectOf(q interface{}) Dialect {
+	if db := dbOf(q); db != nil {
+		return db.Dialect()
+	}
+	return DialectUnknown
+}
@@ -0,0 +1,80 @@
+package asynql_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_Dialect(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	var actual interface{} = db.Dialect()
+	var expected interface{} = asynql.DialectSQLite
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Dialect() => %#v; want %#v`, actual, expected)
+	}
+
+	db = newTestDB(t, asynql.WithDialect(asynql.DialectPostgres))
+	defer db.Close()
+	actual = db.Dialect()
+	expected = asynql.DialectPostgres
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Dialect() with WithDialect(DialectPostgres) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDialect_Rebind(t *testing.T) {
+	for _, v := range []struct {
+		dialect  asynql.Dialect
+		query    string
+		expected string
+	}{
+		{asynql.DialectPostgres, `SELECT * FROM t WHERE a = ? AND b = '?' AND c = ?`, `SELECT * FROM t WHERE a = $1 AND b = '?' AND c = $2`},
+		{asynql.DialectPostgres, `SELECT 1`, `SELECT 1`},
+		{asynql.DialectSQLite, `SELECT * FROM t WHERE a = ?`, `SELECT * FROM t WHERE a = ?`},
+		{asynql.DialectMySQL, `SELECT * FROM t WHERE a = ?`, `SELECT * FROM t WHERE a = ?`},
+	} {
+		actual := v.dialect.Rebind(v.query)
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`%v.Rebind(%#v) => %#v; want %#v`, v.dialect, v.query, actual, v.expected)
+		}
+	}
+}
+
+func TestDialect_IdentityColumn(t *testing.T) {
+	for _, v := range []struct {
+		dialect  asynql.Dialect
+		expected []string
+	}{
+		{asynql.DialectSQLite, []string{"INTEGER PRIMARY KEY AUTOINCREMENT", "BLOB"}},
+		{asynql.DialectPostgres, []string{"BIGSERIAL PRIMARY KEY", "BYTEA"}},
+		{asynql.DialectMySQL, []string{"BIGINT AUTO_INCREMENT PRIMARY KEY", "LONGBLOB"}},
+		{asynql.DialectUnknown, []string{"BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY", "BLOB"}},
+	} {
+		actual := []string{v.dialect.IdentityColumn(), v.dialect.BlobType()}
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`%v.IdentityColumn(), %v.BlobType() => %#v; want %#v`, v.dialect, v.dialect, actual, v.expected)
+		}
+	}
+}
+
+func TestDialect_QuoteIdent(t *testing.T) {
+	for _, v := range []struct {
+		dialect  asynql.Dialect
+		name     string
+		expected string
+	}{
+		{asynql.DialectSQLite, `locks`, `"locks"`},
+		{asynql.DialectPostgres, `app.locks`, `"app"."locks"`},
+		{asynql.DialectPostgres, `x"; DROP TABLE t; --`, `"x""; DROP TABLE t; --"`},
+		{asynql.DialectMySQL, "app.locks", "`app`.`locks`"},
+		{asynql.DialectMySQL, "x`y", "`x``y`"},
+	} {
+		actual := v.dialect.QuoteIdent(v.name)
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`%v.QuoteIdent(%#v) => %#v; want %#v`, v.dialect, v.name, actual, v.expected)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package asynql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// fifoKey identifies the queue of the operations of an owner in FIFO mode.
+// It cannot collide with the keys of DB.Lane, which share the same laneSet.
+type fifoKey struct {
+	owner interface{}
+}
+
+// dispatch runs fn, which performs an asynchronous operation of owner, and sends its result on the returned channel.
+// owner is db itself, or a *Tx or *Conn of db.
+// If wg is not nil, it is incremented until the result has been received.
+//
+// In FIFO mode, the operations of the same owner are run one at a time in the order of submission,
+// and the results are sent from other goroutines so that a result that is not received doesn't stall the queue.
+// If db has a concurrency limit, the operations of db itself wait for a slot in the order of their priorities.
+// If db has workers, the operations are run by them, and the results are sent from other goroutines only if they are not being received.
+func dispatch[T any](ctx context.Context, db *DB, owner interface{}, wg *sync.WaitGroup, fn func() T) <-chan T {
+	ch, _ := submit(ctx, db, owner, wg, fn, false)
+	return ch
+}
+
+// submit is the same as dispatch, but returns ErrQueueFull instead of waiting for room in the queue of the workers of db if try is true,
+// and returns ErrQuotaExceeded instead of sending a result with it if the quota of the tenant of ctx is exceeded.
+func submit[T any](ctx context.Context, db *DB, owner interface{}, wg *sync.WaitGroup, fn func() T, try bool) (<-chan T, error) {
+	if wg != nil {
+		wg.Add(1)
+	}
+	ch := make(chan T)
+	if db == nil {
+		go func() {
+			send(nil, ch, wg, fn())
+		}()
+		return ch, nil
+	}
+	queued := db.ops.enqueue()
+	var err error
+	if tenant := db.quota.tenantOf(ctx); tenant != "" {
+		err = submitQuota(ctx, db, owner, wg, ch, queued, tenant, fn, try)
+	} else {
+		err = startOp(ctx, db, owner, wg, ch, queued, fn, try)
+	}
+	if err == nil {
+		return ch, nil
+	}
+	db.ops.queued.Add(-1)
+	v, ok := errResult[T](err)
+	if db.kill != nil {
+		finishOp(ctx, v)
+	}
+	if ok && !try {
+		go send(db, ch, wg, v)
+		return ch, nil
+	}
+	if wg != nil {
+		wg.Done()
+	}
+	return nil, err
+}
+
+// startOp runs fn in the way that db is configured with, and sends its result on ch.
+// If try is true, it returns ErrQueueFull instead of waiting for room in the queue of the workers of db.
+func startOp[T any](ctx context.Context, db *DB, owner interface{}, wg *sync.WaitGroup, ch chan T, queued time.Time, fn func() T, try bool) error {
+	switch {
+	case db.fifo:
+		db.lanes.submit(fifoKey{owner}, func() {
+			go send(db, ch, wg, runOp(ctx, db, owner, queued, fn))
+		})
+	case db.workers != nil:
+		op := func() {
+			offer(db, ch, wg, runOp(ctx, db, owner, queued, fn))
+		}
+		if !try {
+			db.workers.submit(ctx, op)
+		} else if !db.workers.trySubmit(op) {
+			return ErrQueueFull
+		}
+	default:
+		go func() {
+			send(db, ch, wg, runOp(ctx, db, owner, queued, fn))
+		}()
+	}
+	return nil
+}
+
+// submitQuota schedules fn when tenant has a free slot of the quota of db, and frees the slot when fn returns.
+func submitQuota[T any](ctx context.Context, db *DB, owner interface{}, wg *sync.WaitGroup, ch chan T, queued time.Time, tenant string, fn func() T, try bool) error {
+	run := func() T {
+		defer db.quota.release(tenant)
+		return fn()
+	}
+	now, err := db.quota.admit(tenant, func() {
+		startOp(ctx, db, owner, wg, ch, queued, run, false)
+	})
+	if err != nil || !now {
+		return err
+	}
+	if err := startOp(ctx, db, owner, wg, ch, queued, run, try); err != nil {
+		db.quota.release(tenant)
+		return err
+	}
+	return nil
+}
+
+// runOp runs fn, which has been counted as queued since queued, waiting for Resume if db is paused
+// and for a slot of the concurrency limit of db if owner is db itself.
+func runOp[T any](ctx context.Context, db *DB, owner interface{}, queued time.Time, fn func() T) T {
+	if owner == db {
+		if resumed := db.paused.Load(); resumed != nil {
+			select {
+			case <-*resumed:
+			case <-ctx.Done():
+			}
+		}
+	}
+	if db.limiter != nil && owner == db && db.limiter.acquire(ctx, priorityOf(ctx)) {
+		defer db.limiter.release()
+	}
+	return track(&db.ops, queued, fn)
+}
+
+// send sends v on ch, closes ch unless db keeps the channels open, and then decrements wg if it is not nil.
+func send[T any](db *DB, ch chan<- T, wg *sync.WaitGroup, v T) {
+	ch <- v
+	received(v)
+	closeChannel(db, ch)
+	if wg != nil {
+		wg.Done()
+	}
+}
+
+// offer sends v on ch if the receiver is ready, or otherwise from another goroutine so that a worker doesn't wait for the receiver.
+func offer[T any](db *DB, ch chan T, wg *sync.WaitGroup, v T) {
+	select {
+	case ch <- v:
+		received(v)
+		closeChannel(db, ch)
+		if wg != nil {
+			wg.Done()
+		}
+	default:
+		go send(db, ch, wg, v)
+	}
+}
+
+// errResult returns a result of an operation that has failed with err, if T is a type of the results.
+func errResult[T any](err error) (T, bool) {
+	var v T
+	switch interface{}(v).(type) {
+	case *Result:
+		return interface{}(&Result{err: err}).(T), true
+	case *Rows:
+		return interface{}(&Rows{err: err}).(T), true
+	case *Row:
+		return interface{}(&Row{err: err}).(T), true
+	}
+	return v, false
+}
@@ -0,0 +1,333 @@
+// Package dlock implements lease-based distributed locks on top of asynql.
+//
+// A lock is a row of a lock table, which is inserted by the owner that acquires the lock, and is stolen by another owner
+// once its lease expires. So the locks work on any database that asynql supports, and services can coordinate
+// singleton jobs without Redis or etcd.
+// The owner of a lock must refresh it before its lease expires, and must stop the work protected by the lock
+// when a refresh reports ErrLockLost.
+// The expiries are computed from the clocks of the clients, so the TTL of the locks must be much longer than the skew of the clocks.
+package dlock
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+const (
+	// DefaultTable is the default name of the lock table.
+	DefaultTable = "asynql_locks"
+
+	// DefaultTTL is the default duration of the lease of a lock.
+	DefaultTTL = 30 * time.Second
+)
+
+var (
+	// ErrNotAcquired is reported by TryAcquire when the lock is held by another owner.
+	ErrNotAcquired = errors.New("dlock: lock is held by another owner")
+
+	// ErrLockLost is reported when a lock is refreshed or released after its lease has expired and it has been stolen.
+	ErrLockLost = errors.New("dlock: lock has been lost")
+
+	// ErrInvalidInterval is reported by Acquire when the interval between the tries is not positive.
+	ErrInvalidInterval = errors.New("dlock: interval must be positive")
+
+	// ErrInvalidTTL is reported when a lock is acquired or a campaign is started by a Locker whose TTL is not positive.
+	ErrInvalidTTL = errors.New("dlock: TTL must be positive")
+)
+
+// Locker acquires the locks stored in a lock table.
+type Locker struct {
+	db    *asynql.DB
+	table string
+	ttl   time.Duration
+	owner string
+}
+
+// Option configures a Locker created by New.
+type Option func(*Locker)
+
+// WithTable returns an Option that stores the locks in table instead of DefaultTable.
+func WithTable(table string) Option {
+	return func(l *Locker) {
+		l.table = table
+	}
+}
+
+// WithTTL returns an Option that sets the duration of the lease of a lock.
+func WithTTL(d time.Duration) Option {
+	return func(l *Locker) {
+		l.ttl = d
+	}
+}
+
+// WithOwner returns an Option that records owner, e.g. the host name and the process ID, as the owner of the locks acquired by the Locker.
+// It is informational, and is returned by Owner.
+func WithOwner(owner string) Option {
+	return func(l *Locker) {
+		l.owner = owner
+	}
+}
+
+// New returns a new Locker of the locks in db.
+// The SQL is generated for the dialect of db.
+func New(db *asynql.DB, opts ...Option) *Locker {
+	l := &Locker{
+		db:    db,
+		table: DefaultTable,
+		ttl:   DefaultTTL,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Result represents a result of an operation on a lock.
+type Result struct {
+	err error
+}
+
+// Err returns an error.
+func (r *Result) Err() error {
+	return r.err
+}
+
+// LockResult represents a result of Locker.TryAcquire and Locker.Acquire.
+type LockResult struct {
+	*Lock
+
+	err error
+}
+
+// Err returns an error.
+func (r *LockResult) Err() error {
+	return r.err
+}
+
+// Lock is an acquired lock.
+type Lock struct {
+	// Name is the name of the lock.
+	Name string
+
+	// Fence is incremented each time the lock is acquired, so that the writes protected by the lock can reject
+	// the writes of a previous owner whose lease has expired by comparing their fences.
+	Fence int64
+
+	// Until is the time when the lease expires as of the acquisition.
+	// It is not updated by Refresh, which reports the new one in RefreshResult.
+	Until time.Time
+
+	l     *Locker
+	token string
+}
+
+// RefreshResult represents a result of Lock.Refresh.
+type RefreshResult struct {
+	// Until is the time when the refreshed lease expires.
+	Until time.Time
+
+	err error
+}
+
+// Err returns an error.
+func (r *RefreshResult) Err() error {
+	return r.err
+}
+
+// OwnerResult represents a result of Locker.Owner.
+type OwnerResult struct {
+	// Owner is the owner of the lock given by WithOwner. It is empty if the lock is not held.
+	Owner string
+
+	err error
+}
+
+// Err returns an error.
+func (r *OwnerResult) Err() error {
+	return r.err
+}
+
+// CreateTable creates the lock table if it doesn't exist.
+func (l *Locker) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	token VARCHAR(64) NOT NULL,
+	owner VARCHAR(255) NOT NULL,
+	fence BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, l.db.Dialect().QuoteIdent(l.table))
+	return (<-l.db.ExecContext(ctx, query)).Err()
+}
+
+// TryAcquire acquires the lock of name if it is not held or its lease has expired, and then sends the lock on the returned channel.
+// If the lock is held by another owner, a LockResult with ErrNotAcquired is sent.
+func (l *Locker) TryAcquire(ctx context.Context, name string) <-chan *LockResult {
+	ch := make(chan *LockResult)
+	go func() {
+		lock, err := l.tryAcquire(ctx, name)
+		asynql.Deliver(l.db, ch, &LockResult{
+			Lock: lock,
+			err:  err,
+		})
+	}()
+	return ch
+}
+
+// Acquire is similar to TryAcquire, but tries to acquire the lock every interval until it is acquired or ctx is done.
+// If interval is not positive, a LockResult with ErrInvalidInterval is sent.
+// The channel is buffered, so the result doesn't keep the goroutine alive if it is never received after ctx is done.
+func (l *Locker) Acquire(ctx context.Context, name string, interval time.Duration) <-chan *LockResult {
+	ch := make(chan *LockResult, 1)
+	go func() {
+		if interval <= 0 {
+			asynql.Deliver(l.db, ch, &LockResult{err: ErrInvalidInterval})
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			lock, err := l.tryAcquire(ctx, name)
+			if err != ErrNotAcquired {
+				asynql.Deliver(l.db, ch, &LockResult{
+					Lock: lock,
+					err:  err,
+				})
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				asynql.Deliver(l.db, ch, &LockResult{err: ctx.Err()})
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (l *Locker) tryAcquire(ctx context.Context, name string) (*Lock, error) {
+	if l.ttl <= 0 {
+		return nil, ErrInvalidTTL
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	until := now.Add(l.ttl)
+	// Steal the lock if its lease has expired.
+	query := l.sql(`UPDATE %s SET token = ?, owner = ?, fence = fence + 1, expires_at = ? WHERE name = ? AND expires_at <= ?`)
+	r := <-l.db.ExecContext(ctx, query, token, l.owner, millis(until), name, millis(now))
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		query = l.sql(`INSERT INTO %s (name, token, owner, fence, expires_at) VALUES (?, ?, ?, 1, ?)`)
+		if err := (<-l.db.ExecContext(ctx, query, name, token, l.owner, millis(until))).Err(); err != nil {
+			if asynql.IsUniqueViolation(err) {
+				return nil, ErrNotAcquired
+			}
+			return nil, err
+		}
+	}
+	lock := &Lock{
+		Name:  name,
+		Until: until,
+		l:     l,
+		token: token,
+	}
+	query = l.sql(`SELECT fence FROM %s WHERE name = ? AND token = ?`)
+	if err := (<-l.db.QueryRowContext(ctx, query, name, token)).Scan(&lock.Fence); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// Owner sends the owner of the lock of name on the returned channel.
+func (l *Locker) Owner(ctx context.Context, name string) <-chan *OwnerResult {
+	ch := make(chan *OwnerResult)
+	go func() {
+		r := &OwnerResult{}
+		query := l.sql(`SELECT owner FROM %s WHERE name = ? AND expires_at > ?`)
+		if err := (<-l.db.QueryRowContext(ctx, query, name, millis(time.Now()))).Scan(&r.Owner); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			r.err = err
+		}
+		asynql.Deliver(l.db, ch, r)
+	}()
+	return ch
+}
+
+// Refresh extends the lease of the lock by the TTL from now, and then sends the new expiry on the returned channel.
+// It reports ErrLockLost if the lease has expired and the lock has been stolen.
+func (k *Lock) Refresh(ctx context.Context) <-chan *RefreshResult {
+	ch := make(chan *RefreshResult)
+	go func() {
+		r := &RefreshResult{}
+		until := time.Now().Add(k.l.ttl)
+		query := k.l.sql(`UPDATE %s SET expires_at = ? WHERE name = ? AND token = ?`)
+		if r.err = exec(ctx, k.l.db, query, millis(until), k.Name, k.token); r.err == nil {
+			r.Until = until
+		}
+		asynql.Deliver(k.l.db, ch, r)
+	}()
+	return ch
+}
+
+// Release releases the lock.
+// It reports ErrLockLost if the lease has expired and the lock has been stolen.
+func (k *Lock) Release(ctx context.Context) <-chan *Result {
+	ch := make(chan *Result)
+	go func() {
+		// The row is expired rather than deleted so that the fence keeps increasing.
+		query := k.l.sql(`UPDATE %s SET expires_at = 0 WHERE name = ? AND token = ?`)
+		asynql.Deliver(k.l.db, ch, &Result{
+			err: exec(ctx, k.l.db, query, k.Name, k.token),
+		})
+	}()
+	return ch
+}
+
+// exec executes query, and returns ErrLockLost if it affects no rows.
+func exec(ctx context.Context, db *asynql.DB, query string, args ...interface{}) error {
+	r := <-db.ExecContext(ctx, query, args...)
+	if err := r.Err(); err != nil {
+		return err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// sql returns query with the quoted table name embedded and the placeholders rebound to the dialect.
+func (l *Locker) sql(query string) string {
+	d := l.db.Dialect()
+	return d.Rebind(fmt.Sprintf(query, d.QuoteIdent(l.table)))
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,164 @@
+package dlock_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/naoina/asynql"
+	"github.com/naoina/asynql/dlock"
+)
+
+func newTestLocker(t *testing.T, opts ...dlock.Option) (*asynql.DB, *dlock.Locker) {
+	db, err := asynql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	l := dlock.New(db, opts...)
+	if err := l.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db, l
+}
+
+func TestLocker(t *testing.T) {
+	db, l1 := newTestLocker(t, dlock.WithOwner("worker-1"))
+	defer db.Close()
+	l2 := dlock.New(db, dlock.WithOwner("worker-2"))
+	ctx := context.Background()
+	r := <-l1.TryAcquire(ctx, "job")
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	lock := r.Lock
+	var actual interface{} = lock.Fence
+	var expected interface{} = int64(1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`lock.Fence => %#v; want %#v`, actual, expected)
+	}
+	actual = (<-l2.TryAcquire(ctx, "job")).Err()
+	expected = dlock.ErrNotAcquired
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`l2.TryAcquire(ctx, "job") => %#v; want %#v`, actual, expected)
+	}
+	o := <-l2.Owner(ctx, "job")
+	if err := o.Err(); err != nil {
+		t.Fatal(err)
+	}
+	actual = o.Owner
+	expected = "worker-1"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`l2.Owner(ctx, "job") => %#v; want %#v`, actual, expected)
+	}
+	refreshed := <-lock.Refresh(ctx)
+	if err := refreshed.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !refreshed.Until.After(lock.Until) {
+		t.Errorf(`lock.Refresh(ctx).Until => %v; want after %v`, refreshed.Until, lock.Until)
+	}
+	if err := (<-lock.Release(ctx)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	o = <-l2.Owner(ctx, "job")
+	actual = []interface{}{o.Owner, o.Err()}
+	expected = []interface{}{"", nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`l2.Owner(ctx, "job") => %#v; want %#v`, actual, expected)
+	}
+	r = <-l2.TryAcquire(ctx, "job")
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	actual = r.Fence
+	expected = int64(2)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`lock.Fence => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestLocker_Steal(t *testing.T) {
+	db, l1 := newTestLocker(t, dlock.WithTTL(50*time.Millisecond))
+	defer db.Close()
+	l2 := dlock.New(db)
+	ctx := context.Background()
+	r := <-l1.TryAcquire(ctx, "job")
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	stale := r.Lock
+	r = <-l2.Acquire(ctx, "job", 10*time.Millisecond)
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = r.Fence
+	var expected interface{} = int64(2)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`lock.Fence => %#v; want %#v`, actual, expected)
+	}
+	actual = (<-stale.Refresh(ctx)).Err()
+	expected = dlock.ErrLockLost
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`stale.Refresh(ctx) => %#v; want %#v`, actual, expected)
+	}
+	actual = (<-stale.Release(ctx)).Err()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`stale.Release(ctx) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestLocker_Acquire_Canceled(t *testing.T) {
+	db, l := newTestLocker(t)
+	defer db.Close()
+	if err := (<-l.TryAcquire(context.Background(), "job")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := (<-l.Acquire(ctx, "job", 10*time.Millisecond)).Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(`l.Acquire(ctx, "job", 10ms) => %#v; want %#v`, err, context.DeadlineExceeded)
+	}
+}
+
+func TestLocker_Acquire_InvalidInterval(t *testing.T) {
+	db, l := newTestLocker(t)
+	defer db.Close()
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := (<-l.Acquire(context.Background(), "job", interval)).Err(); err != dlock.ErrInvalidInterval {
+			t.Errorf(`l.Acquire(ctx, "job", %v) => %#v; want %#v`, interval, err, dlock.ErrInvalidInterval)
+		}
+	}
+}
+
+func TestLocker_ChannelClosed(t *testing.T) {
+	db, l := newTestLocker(t)
+	defer db.Close()
+	ctx := context.Background()
+	acquired := l.Acquire(ctx, "job", 10*time.Millisecond)
+	r := <-acquired
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := <-acquired; r != nil || ok {
+		t.Errorf(`second receive of l.Acquire => %#v, %v; want nil, false`, r, ok)
+	}
+	owners := l.Owner(ctx, "job")
+	<-owners
+	if o, ok := <-owners; o != nil || ok {
+		t.Errorf(`second receive of l.Owner => %#v, %v; want nil, false`, o, ok)
+	}
+	refreshes := r.Refresh(ctx)
+	<-refreshes
+	if rr, ok := <-refreshes; rr != nil || ok {
+		t.Errorf(`second receive of lock.Refresh => %#v, %v; want nil, false`, rr, ok)
+	}
+	releases := r.Release(ctx)
+	<-releases
+	if rr, ok := <-releases; rr != nil || ok {
+		t.Errorf(`second receive of lock.Release => %#v, %v; want nil, false`, rr, ok)
+	}
+}
@@ -0,0 +1,99 @@
+package dlock
+
+import (
+	"context"
+	"time"
+)
+
+// LeadershipEvent represents a gain or a loss of the leadership of an election.
+type LeadershipEvent struct {
+	// Leader reports whether the leadership has been gained.
+	Leader bool
+
+	// Fence is the fence of the lock of the leadership if Leader is true.
+	Fence int64
+
+	err error
+}
+
+// Err returns the error that has caused the loss of the leadership, such as ErrLockLost.
+func (e *LeadershipEvent) Err() error {
+	return e.err
+}
+
+// Election elects a leader among the replicas that campaign with the lock of the same name,
+// e.g. to choose the replica that runs scheduled jobs.
+type Election struct {
+	l    *Locker
+	name string
+}
+
+// NewElection returns a new Election of the lock of name in l.
+func NewElection(l *Locker, name string) *Election {
+	return &Election{
+		l:    l,
+		name: name,
+	}
+}
+
+// minInterval is the minimum interval between the tries and the refreshes of Campaign.
+const minInterval = time.Millisecond
+
+// Campaign tries to acquire the lock of the election and maintains its lease until ctx is done,
+// sending an event on the returned channel each time the leadership is gained or lost.
+// The lock is tried and refreshed every third of the TTL of the Locker, and the leadership is lost when the lock is stolen,
+// or when it cannot be refreshed before its lease expires. After a loss, Campaign tries to regain the leadership.
+// The receiver must keep receiving the events, since the lease is not refreshed while an event is waiting to be received.
+// When ctx is done, the lock is released and the channel is closed, which also means the loss of the leadership.
+// If the TTL of the Locker is not positive, an event with ErrInvalidTTL is sent and the channel is closed.
+func (e *Election) Campaign(ctx context.Context) <-chan *LeadershipEvent {
+	ch := make(chan *LeadershipEvent)
+	go func() {
+		defer close(ch)
+		send := func(ev *LeadershipEvent) bool {
+			select {
+			case ch <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if e.l.ttl <= 0 {
+			send(&LeadershipEvent{err: ErrInvalidTTL})
+			return
+		}
+		// The interval has a floor so that a TTL of a few nanoseconds doesn't make a busy loop or a zero interval.
+		ticker := time.NewTicker(max(e.l.ttl/3, minInterval))
+		defer ticker.Stop()
+		var lock *Lock
+		var until time.Time
+		defer func() {
+			if lock != nil {
+				<-lock.Release(context.WithoutCancel(ctx))
+			}
+		}()
+		for {
+			if lock == nil {
+				if l, err := e.l.tryAcquire(ctx, e.name); err == nil {
+					lock, until = l, l.Until
+					if !send(&LeadershipEvent{Leader: true, Fence: lock.Fence}) {
+						return
+					}
+				}
+			} else if r := <-lock.Refresh(ctx); r.err == nil {
+				until = r.Until
+			} else if r.err == ErrLockLost || !time.Now().Before(until) {
+				lock = nil
+				if !send(&LeadershipEvent{err: r.err}) {
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
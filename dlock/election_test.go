@@ -0,0 +1,95 @@
+package dlock_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql/dlock"
+)
+
+func TestElection_Campaign(t *testing.T) {
+	db, l := newTestLocker(t, dlock.WithTTL(60*time.Millisecond))
+	defer db.Close()
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	ch1 := dlock.NewElection(l, "leader").Campaign(ctx1)
+	ev := <-ch1
+	var actual interface{} = []interface{}{ev.Leader, ev.Fence, ev.Err()}
+	var expected interface{} = []interface{}{true, int64(1), nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf(`<-e1.Campaign(ctx) => %#v; want %#v`, actual, expected)
+	}
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	ch2 := dlock.NewElection(l, "leader").Campaign(ctx2)
+	select {
+	case ev := <-ch2:
+		t.Fatalf(`<-e2.Campaign(ctx) => %#v; want no events while e1 is the leader`, ev)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	cancel1()
+	if _, ok := <-ch1; ok {
+		t.Fatalf(`e1.Campaign(ctx) has not been closed after ctx is done`)
+	}
+	ev = <-ch2
+	actual = []interface{}{ev.Leader, ev.Fence, ev.Err()}
+	expected = []interface{}{true, int64(2), nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`<-e2.Campaign(ctx) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestElection_Campaign_Lost(t *testing.T) {
+	db, l := newTestLocker(t, dlock.WithTTL(60*time.Millisecond))
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := dlock.NewElection(l, "leader").Campaign(ctx)
+	if ev := <-ch; !ev.Leader {
+		t.Fatalf(`<-e.Campaign(ctx) => %#v; want the leadership`, ev)
+	}
+	// Another owner takes the lock over.
+	if err := (<-db.ExecContext(ctx, `UPDATE asynql_locks SET token = 'other', expires_at = ?`, time.Now().Add(time.Hour).UnixMilli())).Err(); err != nil {
+		t.Fatal(err)
+	}
+	ev := <-ch
+	var actual interface{} = []interface{}{ev.Leader, ev.Err()}
+	var expected interface{} = []interface{}{false, dlock.ErrLockLost}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`<-e.Campaign(ctx) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestElection_Campaign_InvalidTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		db, l := newTestLocker(t, dlock.WithTTL(ttl))
+		ch := dlock.NewElection(l, "leader").Campaign(context.Background())
+		select {
+		case ev := <-ch:
+			if err := ev.Err(); err != dlock.ErrInvalidTTL {
+				t.Errorf(`<-e.Campaign(ctx) with WithTTL(%v) => %#v; want %#v`, ttl, err, dlock.ErrInvalidTTL)
+			}
+			if _, ok := <-ch; ok {
+				t.Errorf(`e.Campaign(ctx) with WithTTL(%v) has not been closed after ErrInvalidTTL`, ttl)
+			}
+		case <-time.After(time.Second):
+			t.Errorf(`<-e.Campaign(ctx) with WithTTL(%v) has not been sent`, ttl)
+		}
+		if err := (<-l.TryAcquire(context.Background(), "job")).Err(); err != dlock.ErrInvalidTTL {
+			t.Errorf(`l.TryAcquire(ctx, "job") with WithTTL(%v) => %#v; want %#v`, ttl, err, dlock.ErrInvalidTTL)
+		}
+		db.Close()
+	}
+
+	// A TTL of a few nanoseconds doesn't panic.
+	db, l := newTestLocker(t, dlock.WithTTL(2))
+	defer db.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	for range dlock.NewElection(l, "leader").Campaign(ctx) {
+	}
+}
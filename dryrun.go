@@ -0,0 +1,67 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// WithDryRun returns an Option that runs the DB in dry run mode, which is useful to preview migrations and batch jobs.
+// In dry run mode, the query of each Exec is prepared and executed with its arguments in a transaction that is always rolled back,
+// so the query is validated by the database and the result such as RowsAffected is what it would be, but nothing is changed.
+// The Exec of a Tx is executed in the transaction as usual, but Commit rolls back the transaction instead.
+// Query and QueryRow are not affected.
+// The statements are reported to the hooks added by WithHook with HookEvent.DryRun set.
+//
+// Note that the statements that cannot be rolled back, such as DDL on MySQL or sequences on PostgreSQL, still have effects.
+func WithDryRun() Option {
+	return func(db *DB) {
+		db.dryRun = true
+	}
+}
+
+func dryRunOf(db *DB) bool {
+	return db != nil && db.dryRun
+}
+
+// beginner is the interface of *sql.DB and *sql.Conn to begin a transaction.
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// dryExec executes query with args in a new transaction of b, which is rolled back.
+func dryExec(ctx context.Context, b beginner, query string, args []interface{}) *Result {
+	tx, err := b.BeginTx(ctx, nil)
+	if err != nil {
+		return &Result{err: err}
+	}
+	defer tx.Rollback()
+	return dryExecTx(ctx, tx, query, args)
+}
+
+// dryExecTx prepares query and executes it with args in tx.
+func dryExecTx(ctx context.Context, tx *sql.Tx, query string, args []interface{}) *Result {
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return &Result{err: err}
+	}
+	defer stmt.Close()
+	result, err := stmt.ExecContext(ctx, args...)
+	return &Result{
+		Result: result,
+		err:    err,
+	}
+}
+
+// dryExecStmt executes stmt with args in a new transaction of b, which is rolled back.
+func dryExecStmt(ctx context.Context, b beginner, stmt *sql.Stmt, args []interface{}) *Result {
+	tx, err := b.BeginTx(ctx, nil)
+	if err != nil {
+		return &Result{err: err}
+	}
+	defer tx.Rollback()
+	result, err := tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)
+	return &Result{
+		Result: result,
+		err:    err,
+	}
+}
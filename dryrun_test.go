@@ -0,0 +1,75 @@
+package asynql_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithDryRun(t *testing.T) {
+	var rec hookRecorder
+	db := newTestDB(t, asynql.WithDryRun(), asynql.WithHook(rec.hook))
+	defer db.Close()
+
+	r := <-db.Exec(`DELETE FROM test_table WHERE id > ?`, 0)
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = n
+	var expected interface{} = int64(2)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Exec(DELETE).RowsAffected() => %#v; want %#v`, actual, expected)
+	}
+	if err := (<-db.Exec(`DELETE FROM missing_table`)).Err(); err == nil {
+		t.Errorf(`db.Exec("DELETE FROM missing_table").Err() => nil; want error`)
+	}
+
+	stmt, err := db.Prepare(`INSERT INTO test_table (id, name) VALUES (?, ?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-stmt.Exec(3, "carol")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-tx.Exec(`UPDATE test_table SET name = 'dave'`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	actual = scanNames(t, <-tx.Query(`SELECT name FROM test_table ORDER BY id`))
+	expected = []string{"dave", "dave"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`tx.Query after tx.Exec => %#v; want %#v`, actual, expected)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	actual = scanNames(t, <-db.Query(`SELECT name FROM test_table ORDER BY id`))
+	expected = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Query after dry run => %#v; want %#v`, actual, expected)
+	}
+	actual = rec.summary()
+	expected = [][]interface{}{
+		{asynql.HookExec, `DELETE FROM test_table WHERE id > ?`, true, false},
+		{asynql.HookExec, `DELETE FROM missing_table`, true, true},
+		{asynql.HookExec, `INSERT INTO test_table (id, name) VALUES (?, ?)`, true, false},
+		{asynql.HookExec, `UPDATE test_table SET name = 'dave'`, true, false},
+		{asynql.HookQuery, `SELECT name FROM test_table ORDER BY id`, true, false},
+		{asynql.HookCommit, ``, true, false},
+		{asynql.HookQuery, `SELECT name FROM test_table ORDER BY id`, true, false},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`hook events => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,222 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// maxQueryErrorLen is the maximum length of the normalized query in the message of a QueryError.
+const maxQueryErrorLen = 80
+
+// QueryError is the error of an asynchronous operation, which tells which of the concurrent queries failed.
+// The errors delivered on the channels of the operations are wrapped in it, and errors.Is and errors.As see through it.
+type QueryError struct {
+	// Query is the normalized query, in which the literals are replaced by Normalize.
+	Query string
+
+	// Fingerprint is the fingerprint of the query.
+	Fingerprint string
+
+	// Args is the types of the arguments, since the values may be sensitive.
+	Args []string
+
+	// Duration is the time until the operation failed.
+	Duration time.Duration
+
+	// Err is the underlying error.
+	Err error
+}
+
+func newQueryError(query string, args []interface{}, d time.Duration, err error) error {
+	var qe *QueryError
+	if errors.As(err, &qe) {
+		return err
+	}
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = fmt.Sprintf("%T", arg)
+	}
+	return &QueryError{
+		Query:       Normalize(query),
+		Fingerprint: Fingerprint(query),
+		Args:        types,
+		Duration:    d,
+		Err:         err,
+	}
+}
+
+// Error returns the message of the underlying error with the query, its arguments and the duration.
+func (e *QueryError) Error() string {
+	query := e.Query
+	if len(query) > maxQueryErrorLen {
+		query = query[:maxQueryErrorLen] + "..."
+	}
+	return fmt.Sprintf("asynql: query %q (%s) with args (%s) failed after %v: %v",
+		query, e.Fingerprint, strings.Join(e.Args, ", "), e.Duration, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// IsUniqueViolation reports whether err is caused by a violation of a unique or primary key constraint.
+//
+// The error classification functions understand the errors of github.com/lib/pq, github.com/jackc/pgx,
+// github.com/go-sql-driver/mysql, github.com/mattn/go-sqlite3 and modernc.org/sqlite
+// without depending on them, and look into the errors wrapped by err.
+func IsUniqueViolation(err error) bool {
+	return classifyError(err, func(c errorCode) bool {
+		switch {
+		case c.sqlState != "":
+			return c.sqlState == "23505"
+		case c.mysql != 0:
+			return c.mysql == 1062 || c.mysql == 1586
+		case c.sqlite != 0:
+			// SQLITE_CONSTRAINT_UNIQUE and SQLITE_CONSTRAINT_PRIMARYKEY.
+			return c.sqlite == 2067 || c.sqlite == 1555
+		}
+		return false
+	})
+}
+
+// IsForeignKeyViolation reports whether err is caused by a violation of a foreign key constraint.
+func IsForeignKeyViolation(err error) bool {
+	return classifyError(err, func(c errorCode) bool {
+		switch {
+		case c.sqlState != "":
+			return c.sqlState == "23503"
+		case c.mysql != 0:
+			return c.mysql == 1216 || c.mysql == 1217 || c.mysql == 1451 || c.mysql == 1452
+		case c.sqlite != 0:
+			// SQLITE_CONSTRAINT_FOREIGNKEY.
+			return c.sqlite == 787
+		}
+		return false
+	})
+}
+
+// IsSerializationFailure reports whether err is caused by a conflict with a concurrent transaction,
+// such as a serialization failure or a deadlock, after which the transaction should be retried.
+func IsSerializationFailure(err error) bool {
+	return classifyError(err, func(c errorCode) bool {
+		switch {
+		case c.sqlState != "":
+			return c.sqlState == "40001" || c.sqlState == "40P01"
+		case c.mysql != 0:
+			return c.mysql == 1213
+		case c.sqlite != 0:
+			// SQLITE_BUSY_SNAPSHOT.
+			return c.sqlite == 517
+		}
+		return false
+	})
+}
+
+// IsTimeout reports whether err is caused by a timeout, such as a statement timeout, a lock timeout,
+// an expired deadline of the context or a network timeout.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return classifyError(err, func(c errorCode) bool {
+		switch {
+		case c.sqlState != "":
+			// query_canceled, which is also caused by statement_timeout, and lock_not_available.
+			return c.sqlState == "57014" || c.sqlState == "55P03"
+		case c.mysql != 0:
+			// ER_LOCK_WAIT_TIMEOUT and ER_QUERY_TIMEOUT.
+			return c.mysql == 1205 || c.mysql == 3024
+		case c.sqlite != 0:
+			// SQLITE_BUSY after busy_timeout, except SQLITE_BUSY_SNAPSHOT.
+			return c.sqlite&0xff == 5 && c.sqlite != 517
+		}
+		return false
+	})
+}
+
+// errorCode is the code of a driver error.
+// Only one of the fields is set.
+type errorCode struct {
+	// sqlState is the SQLSTATE of PostgreSQL.
+	sqlState string
+
+	// mysql is the error number of MySQL.
+	mysql int
+
+	// sqlite is the extended result code of SQLite.
+	sqlite int
+}
+
+// classifyError calls fn with the code of each driver error in the tree of err, and reports whether any of them is true.
+func classifyError(err error, fn func(errorCode) bool) bool {
+	if err == nil {
+		return false
+	}
+	if c, ok := errorCodeOf(err); ok && fn(c) {
+		return true
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return classifyError(e.Unwrap(), fn)
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			if classifyError(err, fn) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// errorCodeOf returns the code of err if it is an error of a known driver.
+// The drivers are recognized by the shapes of their error types, since they cannot be imported.
+func errorCodeOf(err error) (errorCode, bool) {
+	// *pgconn.PgError of pgx, and *pq.Error of lib/pq.
+	if e, ok := err.(interface{ SQLState() string }); ok {
+		if s := e.SQLState(); s != "" {
+			return errorCode{sqlState: s}, true
+		}
+	}
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return errorCode{}, false
+		}
+		v = v.Elem()
+	}
+	pkg := v.Type().PkgPath()
+	switch {
+	case strings.Contains(pkg, "sqlite"):
+		// sqlite3.Error of mattn/go-sqlite3 has ExtendedCode, and *sqlite.Error of modernc.org/sqlite has Code().
+		if v.Kind() == reflect.Struct {
+			if f := v.FieldByName("ExtendedCode"); f.IsValid() && f.CanInt() {
+				return errorCode{sqlite: int(f.Int())}, true
+			}
+		}
+		if e, ok := err.(interface{ Code() int }); ok {
+			return errorCode{sqlite: e.Code()}, true
+		}
+	case v.Kind() != reflect.Struct:
+	case strings.Contains(pkg, "mysql"):
+		// *mysql.MySQLError has Number.
+		if f := v.FieldByName("Number"); f.IsValid() && f.CanUint() {
+			return errorCode{mysql: int(f.Uint())}, true
+		}
+	case strings.HasSuffix(pkg, "/pq"):
+		// Older versions of *pq.Error have only Code.
+		if f := v.FieldByName("Code"); f.IsValid() && f.Kind() == reflect.String && len(f.String()) == 5 {
+			return errorCode{sqlState: f.String()}, true
+		}
+	}
+	return errorCode{}, false
+}
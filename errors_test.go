@@ -0,0 +1,131 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// pgError has the same method as *pgconn.PgError of pgx.
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string    { return "ERROR: " + e.code }
+func (e *pgError) SQLState() string { return e.code }
+
+func TestIsUniqueViolation(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := (<-db.Exec(`CREATE UNIQUE INDEX test_table_name ON test_table (name)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		query    string
+		expected bool
+	}{
+		{`INSERT INTO test_table (id, name) VALUES (3, 'alice')`, true},
+		{`INSERT INTO missing_table (id) VALUES (1)`, false},
+	} {
+		err := (<-db.Exec(v.query)).Err()
+		var actual interface{} = asynql.IsUniqueViolation(err)
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`IsUniqueViolation(%#v) => %#v; want %#v`, err, actual, expected)
+		}
+	}
+}
+
+func TestIsForeignKeyViolation(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	for _, query := range []string{
+		`PRAGMA foreign_keys = ON`,
+		`CREATE TABLE parent (id INTEGER PRIMARY KEY)`,
+		`CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parent (id))`,
+	} {
+		if err := (<-db.Exec(query)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := (<-db.Exec(`INSERT INTO child (id, parent_id) VALUES (1, 3)`)).Err()
+	var actual interface{} = asynql.IsForeignKeyViolation(err)
+	var expected interface{} = true
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`IsForeignKeyViolation(%#v) => %#v; want %#v`, err, actual, expected)
+	}
+	actual = asynql.IsUniqueViolation(err)
+	expected = false
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`IsUniqueViolation(%#v) => %#v; want %#v`, err, actual, expected)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	for _, v := range []struct {
+		err      error
+		fn       func(error) bool
+		name     string
+		expected bool
+	}{
+		{&pgError{"23505"}, asynql.IsUniqueViolation, "IsUniqueViolation", true},
+		{fmt.Errorf("insert: %w", &pgError{"23505"}), asynql.IsUniqueViolation, "IsUniqueViolation", true},
+		{errors.Join(errors.New("a"), &pgError{"23503"}), asynql.IsForeignKeyViolation, "IsForeignKeyViolation", true},
+		{&pgError{"40001"}, asynql.IsSerializationFailure, "IsSerializationFailure", true},
+		{&pgError{"40P01"}, asynql.IsSerializationFailure, "IsSerializationFailure", true},
+		{&pgError{"23505"}, asynql.IsSerializationFailure, "IsSerializationFailure", false},
+		{&pgError{"57014"}, asynql.IsTimeout, "IsTimeout", true},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), asynql.IsTimeout, "IsTimeout", true},
+		{context.Canceled, asynql.IsTimeout, "IsTimeout", false},
+		{nil, asynql.IsUniqueViolation, "IsUniqueViolation", false},
+		{errors.New("UNIQUE constraint failed"), asynql.IsUniqueViolation, "IsUniqueViolation", false},
+	} {
+		var actual interface{} = v.fn(v.err)
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%s(%#v) => %#v; want %#v`, v.name, v.err, actual, expected)
+		}
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT name FROM missing_table WHERE id = ? AND name = 'alice'`
+	err := (<-db.Query(query, 1)).Err()
+	var qe *asynql.QueryError
+	if !errors.As(err, &qe) {
+		t.Fatalf(`db.Query(%#v, 1).Err() => %#v; want *asynql.QueryError`, query, err)
+	}
+	var actual interface{} = []interface{}{qe.Query, qe.Fingerprint, qe.Args}
+	var expected interface{} = []interface{}{
+		"select name from missing_table where id = ? and name = ?",
+		asynql.Fingerprint(query),
+		[]string{"int"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Query(%#v, 1).Err() => %#v; want %#v`, query, actual, expected)
+	}
+	if qe.Err == nil || qe.Duration <= 0 {
+		t.Errorf(`db.Query(%#v, 1).Err() => %#v; want the underlying error and the duration`, query, qe)
+	}
+	if strings.Contains(err.Error(), "alice") {
+		t.Errorf(`db.Query(%#v, 1).Err().Error() => %#v; want the literals removed`, query, err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range []error{
+		(<-db.ExecContext(ctx, `DELETE FROM test_table`)).Err(),
+		(<-db.QueryRowContext(ctx, `SELECT name FROM test_table`)).Scan(new(string)),
+	} {
+		if !errors.Is(err, context.Canceled) || !errors.As(err, &qe) {
+			t.Errorf(`error of canceled operation => %#v; want *asynql.QueryError of context.Canceled`, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+)
+
+// ScriptResult represents a result of ExecMulti.
+type ScriptResult struct {
+	// Statements is the statements of the script.
+	Statements []string
+
+	// Results is the results of the statements that have been executed, in order.
+	// If a statement fails, it has the results of the preceding statements, unless the script was batched.
+	// A result is nil if the driver doesn't report the results of the statements of a batch separately.
+	Results []sql.Result
+
+	// Batched reports whether the script was executed in one round trip.
+	Batched bool
+
+	err error
+}
+
+// Err returns an error.
+func (r *ScriptResult) Err() error {
+	return r.err
+}
+
+// ExecMulti executes script, which consists of statements separated by semicolons, and then sends the results on the returned channel.
+//
+// If a DB has DialectMySQL and the multiStatements parameter of the driver is enabled, the script is executed in one round trip.
+// Otherwise, the statements are executed one by one on the same connection until one of them fails.
+// A batch that fails may have executed the statements before the failed one.
+// The statements of a DB that has hooks or policies, is read-only or is in maintenance mode are never batched,
+// so that each of them is checked and reported as the other operations are.
+//
+// The script is split at the semicolons outside of literals, identifiers and comments,
+// so the bodies of triggers and procedures that contain semicolons cannot be in a script.
+func (db *DB) ExecMulti(ctx context.Context, script string) <-chan *ScriptResult {
+	ch := make(chan *ScriptResult)
+	go func() {
+		r := &ScriptResult{
+			Statements: splitStatements(script),
+		}
+		r.err = db.execMulti(ctx, script, r)
+		deliver(db, ch, r)
+	}()
+	return ch
+}
+
+func (db *DB) execMulti(ctx context.Context, script string, r *ScriptResult) error {
+	if len(r.Statements) == 0 {
+		return nil
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if len(r.Statements) > 1 && !dryRunOf(db) && !db.guarded() && db.Dialect() == DialectMySQL && db.supportsMultiStatements(ctx, conn) {
+		r.Batched = true
+		return db.execBatch(ctx, conn, script, r)
+	}
+	for _, stmt := range r.Statements {
+		result := <-conn.ExecContext(ctx, stmt)
+		if err := result.Err(); err != nil {
+			return err
+		}
+		r.Results = append(r.Results, result.Result)
+	}
+	return nil
+}
+
+// guarded reports whether the statements of db must go through instrument one by one,
+// since the hooks, the policies, ReadOnly and the maintenance mode would not see the statements of a batch.
+func (db *DB) guarded() bool {
+	return len(db.hooks) > 0 || len(db.policies) > 0 || db.primary != nil || db.maintenance.Load()
+}
+
+// multiStatementProbe is executed to find whether the multiStatements parameter of MySQL is enabled.
+const multiStatementProbe = "DO 0; DO 0"
+
+// supportsMultiStatements reports whether the driver executes multiple statements in one Exec.
+// The answer is remembered unless the probe fails for another reason, such as a broken connection.
+func (db *DB) supportsMultiStatements(ctx context.Context, conn *Conn) bool {
+	db.multiStmtMu.Lock()
+	defer db.multiStmtMu.Unlock()
+	if db.multiStmt != nil {
+		return *db.multiStmt
+	}
+	_, err := conn.Conn.ExecContext(ctx, multiStatementProbe)
+	switch {
+	case err == nil:
+	case classifyError(err, func(c errorCode) bool { return c.mysql == 1064 }):
+		// ER_PARSE_ERROR at the second statement.
+	default:
+		return false
+	}
+	ok := err == nil
+	db.multiStmt = &ok
+	return ok
+}
+
+// execBatch executes script in one round trip on the raw connection of conn,
+// whose result is not wrapped by database/sql so that the results of the statements can be read.
+func (db *DB) execBatch(ctx context.Context, conn *Conn, script string, r *ScriptResult) error {
+	start := time.Now()
+	var result driver.Result
+	err := conn.Raw(func(dc interface{}) error {
+		ex, ok := dc.(driver.ExecerContext)
+		if !ok {
+			return driver.ErrSkip
+		}
+		var err error
+		result, err = ex.ExecContext(ctx, script, nil)
+		return err
+	})
+	if err != nil {
+		return newQueryError(script, nil, time.Since(start), err)
+	}
+	db.invalidateCache(script)
+	r.Results = make([]sql.Result, len(r.Statements))
+	if m, ok := result.(interface {
+		AllLastInsertIds() []int64
+		AllRowsAffected() []int64
+	}); ok {
+		ids, affected := m.AllLastInsertIds(), m.AllRowsAffected()
+		if len(ids) == len(r.Statements) && len(affected) == len(r.Statements) {
+			for i := range r.Results {
+				r.Results[i] = batchResult{
+					lastInsertID: ids[i],
+					rowsAffected: affected[i],
+				}
+			}
+			return nil
+		}
+	}
+	r.Results[len(r.Results)-1] = result
+	return nil
+}
+
+// batchResult is the result of a statement of a batch.
+type batchResult struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r batchResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r batchResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// splitStatements splits script at the semicolons outside of literals, identifiers and comments,
+// and returns the non-empty statements.
+func splitStatements(script string) []string {
+	var stmts []string
+	start := 0
+	add := func(end int) {
+		if s := strings.TrimSpace(script[start:end]); s != "" {
+			stmts = append(stmts, s)
+		}
+	}
+	for _, t := range lexSQL(script) {
+		if t.isPunct(";") {
+			add(t.pos)
+			start = t.end
+		}
+	}
+	add(len(script))
+	return stmts
+}
@@ -0,0 +1,151 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// batchConn is a driver.Conn that mimics MySQL with or without the multiStatements parameter.
+// The statements of a batch are separated by ";\n" for simplicity, and the statement "fail" fails.
+type batchConn struct {
+	multi bool
+
+	mu      sync.Mutex
+	queries []string
+}
+
+func (c *batchConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *batchConn) Close() error                        { return nil }
+func (c *batchConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *batchConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+	stmts := strings.Split(query, ";\n")
+	if (len(stmts) > 1 || query == "DO 0; DO 0") && !c.multi {
+		return nil, errors.New("syntax error")
+	}
+	result := &multiStmtResult{}
+	for i, stmt := range stmts {
+		if strings.TrimSpace(stmt) == "fail" {
+			return nil, errors.New("failed")
+		}
+		result.ids = append(result.ids, int64(i+1))
+		result.affected = append(result.affected, int64(len(stmt)))
+	}
+	return result, nil
+}
+
+type multiStmtResult struct {
+	ids      []int64
+	affected []int64
+}
+
+func (r *multiStmtResult) LastInsertId() (int64, error) { return r.ids[len(r.ids)-1], nil }
+func (r *multiStmtResult) RowsAffected() (int64, error) { return r.affected[len(r.affected)-1], nil }
+func (r *multiStmtResult) AllLastInsertIds() []int64    { return r.ids }
+func (r *multiStmtResult) AllRowsAffected() []int64     { return r.affected }
+
+func TestDB_ExecMulti(t *testing.T) {
+	script := "INSERT a;\n-- a comment; with a semicolon\nINSERT 'b;c';\n\nINSERT d;"
+	for _, multi := range []bool{false, true} {
+		conn := &batchConn{multi: multi}
+		db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithDialect(asynql.DialectMySQL))
+		r := <-db.ExecMulti(context.Background(), script)
+		if err := r.Err(); err != nil {
+			t.Fatalf(`multi %v: db.ExecMulti(ctx, %q) => %#v; want nil`, multi, script, err)
+		}
+		var ids []int64
+		for _, result := range r.Results {
+			id, err := result.LastInsertId()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		var actual interface{} = []interface{}{r.Statements, r.Batched, ids}
+		var expected interface{} = []interface{}{
+			[]string{"INSERT a", "-- a comment; with a semicolon\nINSERT 'b;c'", "INSERT d"}, multi, []int64{1, 2, 3},
+		}
+		if !multi {
+			expected.([]interface{})[2] = []int64{1, 1, 1}
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`multi %v: db.ExecMulti(ctx, %q) => %#v; want %#v`, multi, script, actual, expected)
+		}
+
+		conn.queries = nil
+		r = <-db.ExecMulti(context.Background(), "INSERT a;\nfail;\nINSERT b")
+		actual = []interface{}{r.Err() != nil, len(r.Results), len(conn.queries)}
+		// The failed probe is repeated, because the error of the fake is not known as ER_PARSE_ERROR.
+		expected = []interface{}{true, 1, 3}
+		if multi {
+			expected = []interface{}{true, 0, 1}
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`multi %v: db.ExecMulti(ctx, "INSERT a;\nfail;\nINSERT b") error, results, queries => %#v; want %#v`, multi, actual, expected)
+		}
+		db.Close()
+	}
+}
+
+func TestDB_ExecMulti_SQLite(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	r := <-db.ExecMulti(context.Background(), `
+		CREATE TEMP TABLE tmp (id INTEGER);
+		INSERT INTO tmp (id) SELECT id FROM test_table;
+		UPDATE test_table SET name = 'carol' WHERE id IN (SELECT id FROM tmp);
+	`)
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := r.Results[2].RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = []interface{}{len(r.Results), n, r.Batched}
+	var expected interface{} = []interface{}{3, int64(2), false}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.ExecMulti(ctx, script) => %#v; want %#v`, actual, expected)
+	}
+}
+
+type scriptExecer interface {
+	ExecMulti(ctx context.Context, script string) <-chan *asynql.ScriptResult
+}
+
+func TestDB_ExecMulti_Guarded(t *testing.T) {
+	script := "INSERT a;\nINSERT b"
+	for _, v := range []struct {
+		name     string
+		db       func(db *asynql.DB) scriptExecer
+		expected error
+	}{
+		{"ReadOnly", func(db *asynql.DB) scriptExecer {
+			return db.ReadOnly()
+		}, asynql.ErrReadOnly},
+		{"SetMaintenance", func(db *asynql.DB) scriptExecer {
+			db.SetMaintenance(true)
+			return db
+		}, asynql.ErrMaintenance},
+	} {
+		conn := &batchConn{multi: true}
+		db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithDialect(asynql.DialectMySQL))
+		r := <-v.db(db).ExecMulti(context.Background(), script)
+		var actual interface{} = []interface{}{errors.Is(r.Err(), v.expected), r.Batched, conn.queries}
+		var expected interface{} = []interface{}{true, false, []string(nil)}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%s: db.ExecMulti(ctx, %q) => (rejected, batched, executed) %#v; want %#v`, v.name, script, actual, expected)
+		}
+		db.Close()
+	}
+}
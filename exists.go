@@ -0,0 +1,37 @@
+package asynql
+
+import (
+	"context"
+)
+
+// BoolResult represents a result of Exists.
+type BoolResult struct {
+	Value bool
+
+	err error
+}
+
+// Err returns an error.
+func (r *BoolResult) Err() error {
+	return r.err
+}
+
+// Exists reports whether query with args returns any rows, and then sends the result on the returned channel.
+// The query is wrapped as SELECT EXISTS (query), or as a CASE expression for the dialects other than
+// DialectSQLite, DialectPostgres and DialectMySQL, so that the database stops at the first row.
+func (db *DB) Exists(ctx context.Context, query string, args ...interface{}) <-chan *BoolResult {
+	ch := make(chan *BoolResult)
+	go func() {
+		var wrapped string
+		switch db.Dialect() {
+		case DialectSQLite, DialectPostgres, DialectMySQL:
+			wrapped = "SELECT EXISTS (" + query + ")"
+		default:
+			wrapped = "SELECT CASE WHEN EXISTS (" + query + ") THEN 1 ELSE 0 END"
+		}
+		r := &BoolResult{}
+		r.err = (<-db.QueryRowContext(ctx, wrapped, args...)).Scan(&r.Value)
+		deliver(db, ch, r)
+	}()
+	return ch
+}
@@ -0,0 +1,34 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_Exists(t *testing.T) {
+	for _, dialect := range []asynql.Dialect{asynql.DialectSQLite, asynql.DialectUnknown} {
+		db := newTestDB(t, asynql.WithDialect(dialect))
+		for _, v := range []struct {
+			query    string
+			args     []interface{}
+			expected bool
+		}{
+			{`SELECT * FROM test_table WHERE name = ?`, []interface{}{"alice"}, true},
+			{`SELECT * FROM test_table WHERE name = ?`, []interface{}{"carol"}, false},
+		} {
+			r := <-db.Exists(context.Background(), v.query, v.args...)
+			if err := r.Err(); err != nil {
+				t.Fatal(err)
+			}
+			var actual interface{} = r.Value
+			var expected interface{} = v.expected
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf(`%v: db.Exists(ctx, %#v, %#v) => %#v; want %#v`, dialect, v.query, v.args, actual, expected)
+			}
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,214 @@
+package asynql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PlanNode is a node of a query plan.
+type PlanNode struct {
+	// Type is the kind of the node, e.g. "Seq Scan" for PostgreSQL, "ALL on users" for MySQL,
+	// or the detail such as "SCAN users" for SQLite.
+	Type string
+
+	// Cost is the estimated total cost of the node, or zero if the database doesn't estimate it.
+	Cost float64
+
+	// Rows is the estimated number of the rows of the node, or zero if the database doesn't estimate it.
+	Rows float64
+
+	// Children is the child nodes.
+	Children []*PlanNode
+}
+
+// PlanResult represents a result of Explain.
+type PlanResult struct {
+	// Raw is the plan as returned by the database.
+	// It is JSON for PostgreSQL and MySQL, and the lines of EXPLAIN output for the others.
+	Raw string
+
+	// Root is the parsed plan, or nil if the dialect is not supported.
+	Root *PlanNode
+
+	err error
+}
+
+// Err returns an error.
+func (r *PlanResult) Err() error {
+	return r.err
+}
+
+// Explain explains the plan of query with args in the syntax of the dialect of db, and then sends the plan on the returned channel.
+// The plan is parsed for DialectPostgres, DialectMySQL and DialectSQLite.
+// Note that EXPLAIN without ANALYZE doesn't execute the query, but some databases evaluate the subqueries in it.
+func (db *DB) Explain(ctx context.Context, query string, args ...interface{}) <-chan *PlanResult {
+	ch := make(chan *PlanResult)
+	go func() {
+		deliver(db, ch, db.explain(ctx, query, args))
+	}()
+	return ch
+}
+
+func (db *DB) explain(ctx context.Context, query string, args []interface{}) *PlanResult {
+	d := db.Dialect()
+	var prefix string
+	switch d {
+	case DialectPostgres:
+		prefix = "EXPLAIN (FORMAT JSON) "
+	case DialectMySQL:
+		prefix = "EXPLAIN FORMAT=JSON "
+	case DialectSQLite:
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		prefix = "EXPLAIN "
+	}
+	snap, err := Materialize(<-db.QueryContext(ctx, prefix+query, args...))
+	if err != nil {
+		return &PlanResult{err: err}
+	}
+	if d == DialectSQLite {
+		return sqlitePlan(snap)
+	}
+	var lines []string
+	for _, row := range snap.Values {
+		if len(row) > 0 {
+			lines = append(lines, planText(row[len(row)-1]))
+		}
+	}
+	r := &PlanResult{
+		Raw: strings.Join(lines, "\n"),
+	}
+	if d == DialectPostgres || d == DialectMySQL {
+		r.Root, r.err = parsePlan(d, r.Raw)
+	}
+	return r
+}
+
+func planText(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// sqlitePlan builds the plan from the rows of EXPLAIN QUERY PLAN, which are (id, parent, notused, detail).
+func sqlitePlan(snap *Snapshot) *PlanResult {
+	root := &PlanNode{Type: "QUERY PLAN"}
+	nodes := map[int64]*PlanNode{0: root}
+	var lines []string
+	for _, row := range snap.Values {
+		if len(row) < 4 {
+			continue
+		}
+		id, _ := row[0].(int64)
+		parent, _ := row[1].(int64)
+		n := &PlanNode{Type: planText(row[3])}
+		lines = append(lines, n.Type)
+		nodes[id] = n
+		p := nodes[parent]
+		if p == nil {
+			p = root
+		}
+		p.Children = append(p.Children, n)
+	}
+	return &PlanResult{
+		Raw:  strings.Join(lines, "\n"),
+		Root: root,
+	}
+}
+
+// parsePlan parses the JSON plan of PostgreSQL or MySQL.
+func parsePlan(d Dialect, raw string) (*PlanNode, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return nil, fmt.Errorf("asynql: cannot parse the plan: %w", err)
+	}
+	if d == DialectPostgres {
+		// [{"Plan": {...}}]
+		if a, ok := v.([]interface{}); ok && len(a) > 0 {
+			if m, ok := a[0].(map[string]interface{}); ok {
+				if p, ok := m["Plan"].(map[string]interface{}); ok {
+					return postgresPlanNode(p), nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("asynql: unexpected plan format")
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("asynql: unexpected plan format")
+	}
+	qb, ok := m["query_block"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("asynql: unexpected plan format")
+	}
+	root := &PlanNode{Type: "query_block"}
+	if cost, ok := qb["cost_info"].(map[string]interface{}); ok {
+		root.Cost = planNumber(cost["query_cost"])
+	}
+	mysqlPlanChildren(root, qb)
+	return root, nil
+}
+
+func postgresPlanNode(p map[string]interface{}) *PlanNode {
+	n := &PlanNode{
+		Cost: planNumber(p["Total Cost"]),
+		Rows: planNumber(p["Plan Rows"]),
+	}
+	n.Type, _ = p["Node Type"].(string)
+	if children, ok := p["Plans"].([]interface{}); ok {
+		for _, c := range children {
+			if m, ok := c.(map[string]interface{}); ok {
+				n.Children = append(n.Children, postgresPlanNode(m))
+			}
+		}
+	}
+	return n
+}
+
+// mysqlPlanChildren appends the tables found in v to n.
+// The operations such as ordering_operation and nested_loop are flattened, since their shapes vary between versions.
+func mysqlPlanChildren(n *PlanNode, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if t, ok := v["table"].(map[string]interface{}); ok {
+			child := &PlanNode{
+				Rows: planNumber(t["rows_examined_per_scan"]),
+			}
+			name, _ := t["table_name"].(string)
+			access, _ := t["access_type"].(string)
+			child.Type = strings.TrimSpace(access + " on " + name)
+			if cost, ok := t["cost_info"].(map[string]interface{}); ok {
+				child.Cost = planNumber(cost["prefix_cost"])
+			}
+			n.Children = append(n.Children, child)
+			mysqlPlanChildren(child, t)
+		}
+		for k, c := range v {
+			if k != "table" && k != "cost_info" {
+				mysqlPlanChildren(n, c)
+			}
+		}
+	case []interface{}:
+		for _, c := range v {
+			mysqlPlanChildren(n, c)
+		}
+	}
+}
+
+func planNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
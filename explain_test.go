@@ -0,0 +1,65 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_Explain(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT name FROM test_table WHERE id IN (SELECT id FROM test_table WHERE name = ?)`
+	r := <-db.Explain(context.Background(), query, "alice")
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Raw == "" {
+		t.Errorf(`db.Explain(ctx, %#v, "alice").Raw => %#v; want non-empty`, query, r.Raw)
+	}
+	var actual interface{} = r.Root.Type
+	var expected interface{} = "QUERY PLAN"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Explain(ctx, %#v, "alice").Root.Type => %#v; want %#v`, query, actual, expected)
+	}
+	if len(r.Root.Children) == 0 {
+		t.Errorf(`db.Explain(ctx, %#v, "alice").Root.Children => %#v; want non-empty`, query, r.Root.Children)
+	}
+
+	r = <-db.Explain(context.Background(), `SELECT * FROM missing_table`)
+	if r.Err() == nil {
+		t.Errorf(`db.Explain(ctx, "SELECT * FROM missing_table").Err() => nil; want error`)
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	for _, v := range []struct {
+		dialect  asynql.Dialect
+		raw      string
+		expected *asynql.PlanNode
+	}{
+		{asynql.DialectPostgres, `[{"Plan": {"Node Type": "Hash Join", "Total Cost": 10.5, "Plan Rows": 3, "Plans": [
+			{"Node Type": "Seq Scan", "Total Cost": 1.2, "Plan Rows": 20},
+			{"Node Type": "Hash", "Total Cost": 2, "Plan Rows": 1}
+		]}}]`, &asynql.PlanNode{Type: "Hash Join", Cost: 10.5, Rows: 3, Children: []*asynql.PlanNode{
+			{Type: "Seq Scan", Cost: 1.2, Rows: 20},
+			{Type: "Hash", Cost: 2, Rows: 1},
+		}}},
+		{asynql.DialectMySQL, `{"query_block": {"select_id": 1, "cost_info": {"query_cost": "4.50"},
+			"table": {"table_name": "users", "access_type": "ALL", "rows_examined_per_scan": 40, "cost_info": {"prefix_cost": "4.50"}}}}`,
+			&asynql.PlanNode{Type: "query_block", Cost: 4.5, Children: []*asynql.PlanNode{
+				{Type: "ALL on users", Cost: 4.5, Rows: 40},
+			}}},
+	} {
+		actual, err := asynql.ParsePlan(v.dialect, v.raw)
+		if err != nil {
+			t.Errorf(`ParsePlan(%v, %#v) => _, %#v; want nil`, v.dialect, v.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`ParsePlan(%v, %#v) => %#v; want %#v`, v.dialect, v.raw, actual, v.expected)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package asynql
+
+import (
+	"time"
+)
+
+// Exported for the tests of the unexported helpers.
+var (
+	WrittenTables = writtenTables
+	ReadTables    = readTables
+	IsReadQuery   = isReadQuery
+	ParsePlan     = parsePlan
+	GTIDSubset    = gtidSubset
+)
+
+// NextSchedule returns the first time after t on the schedule of spec.
+func NextSchedule(spec string, t time.Time) (time.Time, error) {
+	s, err := parseSchedule(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.next(t), nil
+}
+
+// CachedTables returns the number of the keys indexed by the tables that CachedQuery reads from.
+func CachedTables(db *DB) int {
+	db.cacheMu.Lock()
+	defer db.cacheMu.Unlock()
+	n := 0
+	for _, keys := range db.cacheTables {
+		n += len(keys)
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+package asynql
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Normalize returns the normalized form of query, in which the literals and placeholders are replaced with ?,
+// the comments are removed, the whitespace is collapsed, and the keywords and unquoted identifiers are lower-cased.
+// The lists of values such as `IN (1, 2, 3)` and the rows of `VALUES (?, ?), (?, ?)` are collapsed into one,
+// so that the queries that differ only in the number of values have the same normalized form.
+func Normalize(query string) string {
+	return joinTokens(lexSQL(query), true, func(t token) string {
+		switch t.kind {
+		case tokString, tokNumber, tokParam:
+			return "?"
+		case tokIdent:
+			return `"` + strings.ReplaceAll(t.text, `"`, `""`) + `"`
+		}
+		return t.text
+	})
+}
+
+// Fingerprint returns a stable fingerprint of query, which is a hash of Normalize(query) in 16 hexadecimal digits.
+// It is useful as the key to aggregate the metrics and slow queries of the same kind.
+func Fingerprint(query string) string {
+	h := fnv.New64a()
+	h.Write([]byte(Normalize(query)))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// canonicalQuery returns query without the comments and the redundant whitespace.
+// Unlike Normalize, it keeps the literals and the case of the tokens, so the queries
+// that have the same canonical form are the same query.
+func canonicalQuery(query string) string {
+	return joinTokens(lexSQL(query), false, func(t token) string {
+		return query[t.pos:t.end]
+	})
+}
+
+// joinTokens joins the texts of tokens by single spaces, except around parentheses, commas and dots.
+// If collapse is true, the lists of ? are collapsed.
+func joinTokens(tokens []token, collapse bool, text func(token) string) string {
+	var out []string
+	for _, t := range tokens {
+		s := text(t)
+		n := len(out)
+		switch {
+		case !collapse:
+		case s == "?" && n >= 2 && out[n-1] == "," && out[n-2] == "?":
+			// ?, ? => ?
+			out = out[:n-1]
+			continue
+		case s == ")" && n >= 6 && out[n-1] == "?" && out[n-2] == "(" && out[n-3] == "," &&
+			out[n-4] == ")" && out[n-5] == "?" && out[n-6] == "(":
+			// (?), (?) => (?)
+			out = out[:n-3]
+			continue
+		}
+		out = append(out, s)
+	}
+	var b strings.Builder
+	for i, s := range out {
+		if i > 0 && s != "," && s != ")" && s != "." && out[i-1] != "(" && out[i-1] != "." {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
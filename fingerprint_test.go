@@ -0,0 +1,49 @@
+package asynql_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, v := range []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT name FROM users WHERE id = 1", "select name from users where id = ?"},
+		{"select  name\n\tFROM users -- comment\nWHERE id = $1", "select name from users where id = ?"},
+		{"SELECT * FROM users WHERE name = 'a b' /* c */ AND age > 2.5e3", "select * from users where name = ? and age > ?"},
+		{"SELECT u.name FROM users AS u WHERE u.id IN (1, 2, 3)", "select u.name from users as u where u.id in (?)"},
+		{"INSERT INTO t (a, b) VALUES (?, ?), (?, ?), (?, ?)", "insert into t (a, b) values (?)"},
+		{`SELECT "Name" FROM [Users]`, `select "Name" from "Users"`},
+		{"SELECT count(*) FROM t WHERE s = $$it's$$", "select count (*) from t where s = ?"},
+	} {
+		var actual interface{} = asynql.Normalize(v.query)
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`Normalize(%#v) => %#v; want %#v`, v.query, actual, expected)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	for _, v := range []struct {
+		a, b     string
+		expected bool
+	}{
+		{"SELECT name FROM users WHERE id = 1", "select name\nfrom users where id = 2", true},
+		{"SELECT name FROM users WHERE id IN (?, ?)", "SELECT name FROM users WHERE id IN (?)", true},
+		{"SELECT name FROM users WHERE id = 1", "SELECT name FROM groups WHERE id = 1", false},
+	} {
+		var actual interface{} = asynql.Fingerprint(v.a) == asynql.Fingerprint(v.b)
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`Fingerprint(%#v) == Fingerprint(%#v) => %#v; want %#v`, v.a, v.b, actual, expected)
+		}
+	}
+	if actual := asynql.Fingerprint("SELECT 1"); len(actual) != 16 {
+		t.Errorf(`Fingerprint("SELECT 1") => %#v; want 16 hexadecimal digits`, actual)
+	}
+}
@@ -0,0 +1,184 @@
+package asynql
+
+import (
+	"context"
+	"time"
+)
+
+// HookOp is the kind of an operation reported to the hooks.
+type HookOp int
+
+const (
+	// HookExec is an Exec of a DB, Tx, Conn or Stmt.
+	HookExec HookOp = iota
+
+	// HookQuery is a Query of a DB, Tx, Conn or Stmt.
+	HookQuery
+
+	// HookQueryRow is a QueryRow of a DB, Tx, Conn or Stmt.
+	HookQueryRow
+
+	// HookCommit is the end of a transaction by Commit, including by Commit in dry run mode.
+	HookCommit
+
+	// HookRollback is the end of a transaction by Rollback.
+	HookRollback
+)
+
+// String returns the name of op.
+func (op HookOp) String() string {
+	switch op {
+	case HookExec:
+		return "exec"
+	case HookQuery:
+		return "query"
+	case HookQueryRow:
+		return "query_row"
+	case HookCommit:
+		return "commit"
+	case HookRollback:
+		return "rollback"
+	}
+	return "unknown"
+}
+
+// HookEvent describes an operation that has been completed.
+type HookEvent struct {
+	// Op is the kind of the operation.
+	Op HookOp
+
+	// Query is the query of the operation, or empty for HookCommit and HookRollback.
+	Query string
+
+	// Name is the name of the query template in the Registry if the query is run by the NamedQuery methods,
+	// or the name given by WithQueryName.
+	Name string
+
+	// Tags is the tags given by WithTag, or nil if there are none. It must not be modified.
+	Tags map[string]string
+
+	// Labels is the labels given by WithLabel, or nil if there are none. It must not be modified.
+	Labels map[string]string
+
+	// Args is the arguments of the query.
+	Args []interface{}
+
+	// DryRun reports whether the operation was run in dry run mode, i.e. its effects have been rolled back.
+	DryRun bool
+
+	// Duration is the time taken by the operation.
+	// For a Query, it is the time until the rows are ready, not until they are read.
+	// For HookCommit and HookRollback, it is the time from the beginning to the end of the transaction.
+	Duration time.Duration
+
+	// Err is the error of the operation.
+	// For a QueryRow, it is the error known before Scan.
+	Err error
+
+	// Tx is the statistics of the transaction for HookCommit and HookRollback, or nil for the other operations.
+	Tx *TxStats
+}
+
+// Fingerprint returns the fingerprint of e.Query, which is useful to aggregate the events of the same kind of queries.
+func (e *HookEvent) Fingerprint() string {
+	return Fingerprint(e.Query)
+}
+
+// Hook is a function that is called with the event of each completed operation.
+// A hook is called in the goroutine of the operation before its result is sent, so it should return quickly.
+type Hook func(ctx context.Context, e *HookEvent)
+
+// WithHook returns an Option that adds hook to the hook chain of the DB.
+// The hooks are called in the order they were added.
+func WithHook(hook Hook) Option {
+	return func(db *DB) {
+		db.hooks = append(db.hooks, hook)
+	}
+}
+
+// instrument returns a function that runs fn unless ctx is done or db rejects it by its modes or policies, reports it to the hooks of db and to the statistics of the labels of ctx,
+// and wraps its error in a *QueryError.
+func instrument[T errWrapper](ctx context.Context, db *DB, op HookOp, query string, args []interface{}, fn func() T) func() T {
+	var stack []byte
+	if db != nil {
+		stack = db.leaks.stack(op)
+	}
+	return func() T {
+		var ls *labelStats
+		if db != nil {
+			if ls = db.labelStatsOf(ctx); ls != nil {
+				ls.inFlight.Add(1)
+			}
+		}
+		start := time.Now()
+		v, ok := expired[T](ctx)
+		switch {
+		case ok:
+			if db != nil {
+				db.ops.expired.Add(1)
+			}
+		case db.rejectsWrite(op, query):
+			v, _ = errResult[T](ErrMaintenance)
+		case db.rejectsStatement(query):
+			v, _ = errResult[T](ErrReadOnly)
+		default:
+			if err := db.checkPolicies(ctx, op, query, args); err != nil {
+				v, _ = errResult[T](err)
+			} else {
+				v = fn()
+			}
+		}
+		if db != nil && db.kill != nil {
+			finishOp(ctx, v)
+		}
+		if rs, ok := interface{}(v).(*Rows); ok && rs.err == nil {
+			rs.limit = db.resultLimitOf(ctx)
+			if db != nil && db.leaks.grace > 0 {
+				db.leaks.track(rs, query, args, stack)
+			}
+		}
+		d := time.Since(start)
+		err := v.wrapErr(query, args, d)
+		if ls != nil {
+			ls.stats.record(d, err)
+			ls.inFlight.Add(-1)
+		}
+		if db != nil && db.leaks.abandon > 0 {
+			defer db.leaks.watch(v, op, query, args, stack)
+		}
+		if db == nil || len(db.hooks) == 0 {
+			return v
+		}
+		e := &HookEvent{
+			Op:       op,
+			Query:    query,
+			Name:     queryNameOf(ctx),
+			Tags:     tagsOf(ctx),
+			Labels:   labelsOf(ctx),
+			Args:     args,
+			DryRun:   db.dryRun,
+			Duration: d,
+			Err:      err,
+		}
+		for _, hook := range db.hooks {
+			hook(ctx, e)
+		}
+		return v
+	}
+}
+
+// expired returns a result that has the error of ctx if ctx is done, so that an operation that has waited to run
+// until its caller stopped waiting doesn't spend a connection.
+func expired[T errWrapper](ctx context.Context) (T, bool) {
+	if err := ctx.Err(); err != nil {
+		return errResult[T](err)
+	}
+	var v T
+	return v, false
+}
+
+// errWrapper is implemented by the results of the operations.
+type errWrapper interface {
+	// wrapErr wraps the error of the result in a *QueryError if it has an error, and returns the original error.
+	wrapErr(query string, args []interface{}, d time.Duration) error
+}
@@ -0,0 +1,66 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+type hookRecorder struct {
+	mu     sync.Mutex
+	events []asynql.HookEvent
+}
+
+func (r *hookRecorder) hook(ctx context.Context, e *asynql.HookEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, *e)
+}
+
+// summary returns the op, query and dry run flag of each event, and whether it has an error.
+func (r *hookRecorder) summary() [][]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var s [][]interface{}
+	for _, e := range r.events {
+		s = append(s, []interface{}{e.Op, e.Query, e.DryRun, e.Err != nil})
+	}
+	return s
+}
+
+func TestWithHook(t *testing.T) {
+	var rec hookRecorder
+	var order []int
+	db := newTestDB(t, asynql.WithHook(rec.hook), asynql.WithHook(func(ctx context.Context, e *asynql.HookEvent) {
+		order = append(order, len(rec.events))
+	}))
+	defer db.Close()
+	if err := (<-db.Exec(`UPDATE test_table SET name = ? WHERE id = 1`, "carol")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	scanNames(t, <-db.Query(`SELECT name FROM test_table`))
+	(<-db.Exec(`DELETE FROM missing_table`)).Err()
+
+	var actual interface{} = rec.summary()
+	var expected interface{} = [][]interface{}{
+		{asynql.HookExec, `UPDATE test_table SET name = ? WHERE id = 1`, false, false},
+		{asynql.HookQuery, `SELECT name FROM test_table`, false, false},
+		{asynql.HookExec, `DELETE FROM missing_table`, false, true},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`hook events => %#v; want %#v`, actual, expected)
+	}
+	actual = rec.events[0].Args
+	expected = []interface{}{"carol"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`hook events[0].Args => %#v; want %#v`, actual, expected)
+	}
+	actual = order
+	expected = []int{1, 2, 3}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`hooks called after %#v events; want %#v`, actual, expected)
+	}
+}
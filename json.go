@@ -0,0 +1,122 @@
+package asynql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"unicode/utf8"
+)
+
+// JSONOptions configures WriteJSON.
+type JSONOptions struct {
+	// OmitNull omits the keys of NULL values instead of writing null.
+	OmitNull bool
+
+	// BytesAsBase64 writes []byte values in base64 as encoding/json does.
+	// By default, they are written as strings if they are valid UTF-8, since many drivers return text as []byte.
+	BytesAsBase64 bool
+
+	// Convert converts the value of column before it is encoded, if it is not nil.
+	// It is useful to map the types that are not encoded as intended, e.g. a JSON column to json.RawMessage.
+	Convert func(column string, v interface{}) interface{}
+}
+
+// WriteResult represents a result of an export such as QueryJSON.
+type WriteResult struct {
+	// Rows is the number of the rows written.
+	Rows int64
+
+	err error
+}
+
+// Err returns an error.
+func (r *WriteResult) Err() error {
+	return r.err
+}
+
+// WriteJSON writes the remaining rows of rs to w as a JSON array of objects that have the column names as the keys,
+// in the order of the columns.
+// The rows are streamed, so the whole result is never held in memory.
+// opts may be nil for the defaults.
+// rs is closed when WriteJSON returns, and the number of the rows written is returned.
+func (rs *Rows) WriteJSON(w io.Writer, opts *JSONOptions) (int64, error) {
+	if err := rs.Err(); err != nil {
+		return 0, err
+	}
+	defer rs.Close()
+	if opts == nil {
+		opts = &JSONOptions{}
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		return 0, err
+	}
+	keys := make([][]byte, len(columns))
+	for i, col := range columns {
+		if keys[i], err = json.Marshal(col); err != nil {
+			return 0, err
+		}
+	}
+	bw := bufio.NewWriter(w)
+	values := make([]interface{}, len(columns))
+	dests := make([]interface{}, len(columns))
+	for i := range values {
+		dests[i] = &values[i]
+	}
+	bw.WriteByte('[')
+	var n int64
+	for rs.Next() {
+		if err := rs.Scan(dests...); err != nil {
+			return n, err
+		}
+		if n > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteByte('{')
+		first := true
+		for i, v := range values {
+			if opts.Convert != nil {
+				v = opts.Convert(columns[i], v)
+			}
+			if v == nil && opts.OmitNull {
+				continue
+			}
+			if b, ok := v.([]byte); ok && !opts.BytesAsBase64 && utf8.Valid(b) {
+				v = string(b)
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return n, err
+			}
+			if !first {
+				bw.WriteByte(',')
+			}
+			first = false
+			bw.Write(keys[i])
+			bw.WriteByte(':')
+			bw.Write(b)
+		}
+		bw.WriteByte('}')
+		n++
+	}
+	if err := rs.Err(); err != nil {
+		return n, err
+	}
+	bw.WriteByte(']')
+	return n, bw.Flush()
+}
+
+// QueryJSON executes a query with args and writes the rows to w by WriteJSON,
+// and then sends the result on the returned channel.
+func (db *DB) QueryJSON(ctx context.Context, w io.Writer, opts *JSONOptions, query string, args ...interface{}) <-chan *WriteResult {
+	ch := make(chan *WriteResult)
+	go func() {
+		n, err := (<-db.QueryContext(ctx, query, args...)).WriteJSON(w, opts)
+		deliver(db, ch, &WriteResult{
+			Rows: n,
+			err:  err,
+		})
+	}()
+	return ch
+}
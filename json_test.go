@@ -0,0 +1,59 @@
+package asynql_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestRows_WriteJSON(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := (<-db.Exec(`INSERT INTO test_table (id, name) VALUES (3, NULL)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	query := `SELECT id, name, CAST('x' AS BLOB) AS raw FROM test_table ORDER BY id`
+	for _, v := range []struct {
+		opts     *asynql.JSONOptions
+		expected string
+	}{
+		{nil, `[{"id":1,"name":"alice","raw":"x"},{"id":2,"name":"bob","raw":"x"},{"id":3,"name":null,"raw":"x"}]`},
+		{&asynql.JSONOptions{OmitNull: true, BytesAsBase64: true}, `[{"id":1,"name":"alice","raw":"eA=="},{"id":2,"name":"bob","raw":"eA=="},{"id":3,"raw":"eA=="}]`},
+		{&asynql.JSONOptions{Convert: func(column string, v interface{}) interface{} {
+			if column == "name" && v != nil {
+				return strings.ToUpper(v.(string))
+			}
+			return v
+		}}, `[{"id":1,"name":"ALICE","raw":"x"},{"id":2,"name":"BOB","raw":"x"},{"id":3,"name":null,"raw":"x"}]`},
+	} {
+		var buf bytes.Buffer
+		n, err := (<-db.Query(query)).WriteJSON(&buf, v.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = []interface{}{n, buf.String()}
+		var expected interface{} = []interface{}{int64(3), v.expected}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`rows.WriteJSON(w, %#v) => %#v; want %#v`, v.opts, actual, expected)
+		}
+	}
+}
+
+func TestDB_QueryJSON(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	var buf bytes.Buffer
+	r := <-db.QueryJSON(context.Background(), &buf, nil, `SELECT name FROM test_table WHERE id > ?`, 5)
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = []interface{}{r.Rows, buf.String()}
+	var expected interface{} = []interface{}{int64(0), `[]`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryJSON(ctx, w, nil, query, 5) => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,59 @@
+package asynql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON is a sql.Scanner and driver.Valuer that stores V in a JSON column,
+// such as jsonb of PostgreSQL, JSON of MySQL and TEXT of SQLite.
+// A NULL is scanned as the zero value of T.
+//
+// ScanStruct scans a column into a field of any type as JSON if the field is tagged with `db:"name,json"`.
+type JSON[T any] struct {
+	V T
+}
+
+// Scan implements sql.Scanner.
+func (j *JSON[T]) Scan(src interface{}) error {
+	return scanJSON(&j.V, src)
+}
+
+// Value implements driver.Valuer.
+func (j JSON[T]) Value() (driver.Value, error) {
+	b, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, fmt.Errorf("asynql: cannot bind %T as JSON: %w", j.V, err)
+	}
+	return string(b), nil
+}
+
+// jsonField is a sql.Scanner that unmarshals a JSON column into the field of a struct tagged with `db:"name,json"`.
+type jsonField struct {
+	dest interface{}
+}
+
+func (f jsonField) Scan(src interface{}) error {
+	return scanJSON(f.dest, src)
+}
+
+// scanJSON unmarshals src into dest, which is a pointer.
+// A NULL sets the zero value to dest.
+func scanJSON(dest interface{}, src interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case nil:
+		b = []byte("null")
+	case []byte:
+		b = src
+	case string:
+		b = []byte(src)
+	default:
+		return fmt.Errorf("asynql: cannot scan %T into %T as JSON", src, dest)
+	}
+	if err := json.Unmarshal(b, dest); err != nil {
+		return fmt.Errorf("asynql: cannot scan JSON into %T: %w", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+type payload struct {
+	Kind  string   `json:"kind"`
+	Items []string `json:"items"`
+}
+
+func TestJSON(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE events (id INTEGER, payload TEXT, meta TEXT)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	p := payload{Kind: "created", Items: []string{"a", "b"}}
+	if err := (<-db.ExecContext(ctx, `INSERT INTO events VALUES (1, ?, ?), (2, NULL, NULL)`,
+		asynql.JSON[payload]{V: p}, asynql.JSON[map[string]int]{V: map[string]int{"n": 1}})).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw string
+	if err := (<-db.QueryRowContext(ctx, `SELECT payload FROM events WHERE id = 1`)).Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"kind":"created","items":["a","b"]}`; raw != expected {
+		t.Errorf(`bound JSON[payload] => %#v; want %#v`, raw, expected)
+	}
+
+	var j asynql.JSON[payload]
+	if err := (<-db.QueryRowContext(ctx, `SELECT payload FROM events WHERE id = 1`)).Scan(&j); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(j.V, p) {
+		t.Errorf(`Scan(&JSON[payload]{}) => %#v; want %#v`, j.V, p)
+	}
+
+	type event struct {
+		ID      int
+		Payload *payload       `db:"payload,json"`
+		Meta    map[string]int `db:"meta,json"`
+	}
+	rows := <-db.QueryContext(ctx, `SELECT id, payload, meta FROM events ORDER BY id`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var actual []event
+	for rows.Next() {
+		var e event
+		if err := rows.ScanStruct(&e); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, e)
+	}
+	expected := []event{{1, &p, map[string]int{"n": 1}}, {2, nil, nil}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`rows.ScanStruct(&event{}) => %#v; want %#v`, actual, expected)
+	}
+
+	if err := (<-db.QueryRowContext(ctx, `SELECT 'not json'`)).Scan(&j); err == nil {
+		t.Errorf(`Scan(&JSON[payload]{}) of invalid JSON => nil; want error`)
+	}
+}
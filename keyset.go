@@ -0,0 +1,173 @@
+package asynql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keyset pages through the result of a query by the values of its ordering columns,
+// which is faster than OFFSET for deep pages and doesn't skip or repeat rows when the table is modified.
+// Each row is scanned into a T, by ScanStruct if T is a struct.
+//
+//	k := &asynql.Keyset[User]{
+//		Queryer:  db,
+//		Query:    `SELECT id, name FROM users WHERE active = ?`,
+//		Args:     []interface{}{true},
+//		Columns:  []string{"id"},
+//		PageSize: 100,
+//	}
+//	for p := <-k.Page(ctx, nil); p.Err() == nil && len(p.Items) > 0; p = <-k.Page(ctx, p.Cursor) {
+//		...
+//	}
+type Keyset[T any] struct {
+	// Queryer is where the query is executed.
+	Queryer Queryer
+
+	// Query is the query to page through, without ORDER BY and LIMIT.
+	// It is wrapped as a derived table, so the columns must have distinct names.
+	Query string
+
+	// Args is the arguments of Query.
+	Args []interface{}
+
+	// Columns is the ordering columns, which must be in the result of Query and must be unique together.
+	// They are written unquoted into the ORDER BY clause and the seek condition, so they must not come from user input.
+	Columns []string
+
+	// Desc orders the rows in descending order.
+	Desc bool
+
+	// PageSize is the maximum number of the rows of a page.
+	PageSize int
+}
+
+// KeysetPage represents a result of Keyset.Page.
+type KeysetPage[T any] struct {
+	// Items is the rows of the page.
+	Items []T
+
+	// Cursor is the values of the ordering columns of the last row, which is passed to Keyset.Page to get the next page.
+	// It is nil if this is the last page.
+	Cursor []interface{}
+
+	err error
+}
+
+// Err returns an error.
+func (p *KeysetPage[T]) Err() error {
+	return p.err
+}
+
+// Page executes the query for the page that follows the row whose ordering columns have the values of after,
+// and then sends the page on the returned channel.
+// after is nil for the first page, and otherwise the Cursor of the previous page.
+func (k *Keyset[T]) Page(ctx context.Context, after []interface{}) <-chan *KeysetPage[T] {
+	ch := make(chan *KeysetPage[T])
+	go func() {
+		items, cursor, err := k.page(ctx, after)
+		deliver(k.Queryer, ch, &KeysetPage[T]{
+			Items:  items,
+			Cursor: cursor,
+			err:    err,
+		})
+	}()
+	return ch
+}
+
+func (k *Keyset[T]) page(ctx context.Context, after []interface{}) ([]T, []interface{}, error) {
+	if len(k.Columns) == 0 {
+		return nil, nil, fmt.Errorf("asynql: keyset has no columns")
+	}
+	if after != nil && len(after) != len(k.Columns) {
+		return nil, nil, fmt.Errorf("asynql: keyset cursor has %d values for %d columns", len(after), len(k.Columns))
+	}
+	query, args := k.build(after)
+	snap, err := Materialize(<-k.Queryer.QueryContext(ctx, query, args...))
+	if err != nil {
+		return nil, nil, err
+	}
+	items := make([]T, 0, len(snap.Values))
+	rs := snap.Rows()
+	defer rs.Close()
+	for rs.Next() {
+		var v T
+		if err := scanValue(rs, &v); err != nil {
+			return nil, nil, err
+		}
+		items = append(items, v)
+	}
+	if err := rs.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(snap.Values) < k.PageSize || len(snap.Values) == 0 {
+		return items, nil, nil
+	}
+	last := snap.Values[len(snap.Values)-1]
+	cursor := make([]interface{}, len(k.Columns))
+	for i, col := range k.Columns {
+		j := columnIndex(snap.Columns, col)
+		if j < 0 {
+			return nil, nil, fmt.Errorf("asynql: keyset column %q is not in the result", col)
+		}
+		cursor[i] = last[j]
+	}
+	return items, cursor, nil
+}
+
+// build returns the query for the page after the given cursor, and its arguments.
+// The condition of the cursor is expanded as (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ...,
+// which is understood by every dialect and can use an index on the columns.
+func (k *Keyset[T]) build(after []interface{}) (string, []interface{}) {
+	d := dialectOf(k.Queryer)
+	args := append([]interface{}(nil), k.Args...)
+	placeholder := func(v interface{}) string {
+		args = append(args, v)
+		return d.Placeholder(len(args))
+	}
+	op, dir := " > ", ""
+	if k.Desc {
+		op, dir = " < ", " DESC"
+	}
+	var b strings.Builder
+	b.WriteString("SELECT * FROM (")
+	b.WriteString(k.Query)
+	b.WriteString(") AS asynql_keyset")
+	if after != nil {
+		b.WriteString(" WHERE ")
+		for i := range k.Columns {
+			if i > 0 {
+				b.WriteString(" OR ")
+			}
+			b.WriteString("(")
+			for j := 0; j < i; j++ {
+				b.WriteString(k.Columns[j] + " = " + placeholder(after[j]) + " AND ")
+			}
+			b.WriteString(k.Columns[i] + op + placeholder(after[i]))
+			b.WriteString(")")
+		}
+	}
+	b.WriteString(" ORDER BY ")
+	for i, col := range k.Columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(col + dir)
+	}
+	b.WriteString(" LIMIT " + strconv.Itoa(k.PageSize))
+	return b.String(), args
+}
+
+// columnIndex returns the index of the column named name, ignoring the case and a table qualifier of name, or -1.
+func columnIndex(columns []string, name string) int {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	for i, col := range columns {
+		if strings.EqualFold(col, name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,61 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestKeyset(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := (<-db.Exec(`INSERT INTO test_table (id, name) VALUES (3, "bob"), (4, "carol"), (5, "alice")`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	type user struct {
+		ID   int64
+		Name string
+	}
+	for _, v := range []struct {
+		columns  []string
+		desc     bool
+		expected [][]user
+	}{
+		{[]string{"id"}, false, [][]user{
+			{{1, "alice"}, {2, "bob"}},
+			{{3, "bob"}, {4, "carol"}},
+			{{5, "alice"}},
+		}},
+		{[]string{"name", "id"}, true, [][]user{
+			{{4, "carol"}, {3, "bob"}},
+			{{2, "bob"}, {5, "alice"}},
+			{{1, "alice"}},
+		}},
+	} {
+		k := &asynql.Keyset[user]{
+			Queryer:  db,
+			Query:    `SELECT id, name FROM test_table WHERE id > ?`,
+			Args:     []interface{}{0},
+			Columns:  v.columns,
+			Desc:     v.desc,
+			PageSize: 2,
+		}
+		var actual [][]user
+		var cursor []interface{}
+		for i := 0; i < 10; i++ {
+			p := <-k.Page(context.Background(), cursor)
+			if err := p.Err(); err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, p.Items)
+			if cursor = p.Cursor; cursor == nil {
+				break
+			}
+		}
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`Keyset{Columns: %#v, Desc: %v} pages => %#v; want %#v`, v.columns, v.desc, actual, v.expected)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package asynql
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// WithKillSwitch returns an Option that tracks the contexts of the operations of the DB and its transactions, connections and statements,
+// so that CancelAll can cancel them.
+// It costs a cancelable context for each operation, so it is an option.
+func WithKillSwitch() Option {
+	return func(db *DB) {
+		db.kill = &killSwitch{
+			ops: make(map[*killOp]struct{}),
+		}
+	}
+}
+
+// CancelAll cancels the contexts of all the operations that have not finished, for the emergencies such as a failover,
+// a shutdown or an overload, where waiting for them is not acceptable.
+// The operations whose results have not been sent fail with reason, or with context.Canceled if reason is nil,
+// whether they are running or waiting to run. The rows that are being read are closed, and a Row that has not been scanned fails.
+// An operation is finished when its Result is sent, its Rows is closed or its Row is scanned.
+// The operations started after CancelAll are not affected.
+//
+// CancelAll has no effect unless the DB is created with WithKillSwitch.
+func (db *DB) CancelAll(reason error) {
+	if db.kill == nil {
+		return
+	}
+	if reason == nil {
+		reason = context.Canceled
+	}
+	db.kill.mu.Lock()
+	ops := db.kill.ops
+	db.kill.ops = make(map[*killOp]struct{})
+	db.kill.mu.Unlock()
+	for op := range ops {
+		op.killed.Store(true)
+		op.cancel(reason)
+	}
+}
+
+// killSwitch tracks the operations of a DB created with WithKillSwitch.
+type killSwitch struct {
+	mu  sync.Mutex
+	ops map[*killOp]struct{}
+}
+
+type killOpKey struct{}
+
+// killOp is the context of an operation that CancelAll can cancel.
+type killOp struct {
+	context.Context
+
+	cancel context.CancelCauseFunc
+	ks     *killSwitch
+	killed atomic.Bool
+}
+
+func (op *killOp) Value(key interface{}) interface{} {
+	if key == (killOpKey{}) {
+		return op
+	}
+	return op.Context.Value(key)
+}
+
+// killable returns a context of an operation derived from ctx, which CancelAll of db can cancel.
+// It returns ctx itself if db is not created with WithKillSwitch.
+func killable(ctx context.Context, db *DB) context.Context {
+	if db == nil || db.kill == nil {
+		return ctx
+	}
+	cctx, cancel := context.WithCancelCause(ctx)
+	op := &killOp{
+		Context: cctx,
+		cancel:  cancel,
+		ks:      db.kill,
+	}
+	db.kill.mu.Lock()
+	db.kill.ops[op] = struct{}{}
+	db.kill.mu.Unlock()
+	return op
+}
+
+// release stops tracking op, and releases its context.
+func (op *killOp) release() {
+	op.ks.mu.Lock()
+	delete(op.ks.ops, op)
+	op.ks.mu.Unlock()
+	op.cancel(nil)
+}
+
+// finishOp replaces the error of v with the reason of CancelAll if the operation of ctx has been canceled by it,
+// and releases the context of the operation when v is finished.
+func finishOp(ctx context.Context, v interface{}) {
+	op, ok := ctx.Value(killOpKey{}).(*killOp)
+	if !ok {
+		return
+	}
+	var reason error
+	if op.killed.Load() {
+		reason = context.Cause(op)
+	}
+	switch r := v.(type) {
+	case *Result:
+		if r.err != nil && reason != nil {
+			r.err = reason
+		}
+		op.release()
+	case *Rows:
+		if r.err != nil && reason != nil {
+			r.err = reason
+		}
+		if r.Rows == nil {
+			op.release()
+		} else {
+			r.release = op.release
+		}
+	case *Row:
+		if reason != nil && r.Err() != nil {
+			r.err = reason
+		}
+		if r.Row == nil {
+			op.release()
+		} else {
+			r.release = op.release
+		}
+	default:
+		op.release()
+	}
+}
@@ -0,0 +1,101 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// hangConn is a driver.Conn whose Exec of "hang" waits until its context is done.
+type hangConn struct {
+	started chan struct{}
+}
+
+func (c *hangConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *hangConn) Close() error                        { return nil }
+func (c *hangConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *hangConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "hang" {
+		c.started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestDB_CancelAll(t *testing.T) {
+	conn := &hangConn{started: make(chan struct{})}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithKillSwitch(), asynql.WithMaxConcurrency(1))
+	defer db.Close()
+	ctx := context.Background()
+	running := db.ExecContext(ctx, "hang")
+	<-conn.started
+	waiting := db.ExecContext(ctx, "next")
+	errFailover := errors.New("failover")
+	db.CancelAll(errFailover)
+	for name, ch := range map[string]<-chan *asynql.Result{"running": running, "waiting": waiting} {
+		if err := (<-ch).Err(); !errors.Is(err, errFailover) {
+			t.Errorf(`%s operation => %#v; want %#v`, name, err, errFailover)
+		}
+	}
+
+	// The operations started after CancelAll are not affected.
+	r := <-db.ExecContext(ctx, "next")
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = n
+	var expected interface{} = int64(1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.ExecContext(ctx, "next") after db.CancelAll(err) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDB_CancelAll_Rows(t *testing.T) {
+	db := newTestDB(t, asynql.WithKillSwitch())
+	defer db.Close()
+	ctx := context.Background()
+	rows := <-db.QueryContext(ctx, `SELECT name FROM test_table ORDER BY id`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	db.CancelAll(nil)
+	// database/sql closes the rows in the background when their context is canceled.
+	time.Sleep(10 * time.Millisecond)
+	if rows.Next() {
+		t.Errorf(`rows.Next() after db.CancelAll(nil) => true; want false`)
+	}
+	if err := rows.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf(`rows.Err() after db.CancelAll(nil) => %#v; want %#v`, err, context.Canceled)
+	}
+	rows.Close()
+
+	// The rows read after CancelAll are not affected.
+	actual := scanNames(t, <-db.QueryContext(ctx, `SELECT name FROM test_table ORDER BY id`))
+	expected := []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryContext(ctx, ...) after db.CancelAll(nil) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDB_CancelAll_Disabled(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	row := <-db.QueryRowContext(ctx, `SELECT name FROM test_table WHERE id = 1`)
+	db.CancelAll(errors.New("ignored"))
+	var name string
+	if err := row.Scan(&name); err != nil {
+		t.Fatalf(`row.Scan(&name) after db.CancelAll(err) without WithKillSwitch => %#v; want nil`, err)
+	}
+}
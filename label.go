@@ -0,0 +1,149 @@
+package asynql
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type labelsKey struct{}
+
+// labelSet is the labels of a context, with the key that identifies them in the statistics.
+type labelSet struct {
+	labels map[string]string
+	key    string
+}
+
+// WithLabel returns a copy of ctx that labels the queries run with it by key and value, such as "op" and "checkout",
+// so that the load of the database can be broken down by business operation rather than by query.
+// The labels are exposed to the hooks by HookEvent.Labels, and are the dimensions of LabelStats.
+// Unlike the tags of WithTag, each set of labels has its own statistics, so the values should be few, not the IDs of requests.
+// The labels of ctx are inherited, and a label of the same key is replaced.
+func WithLabel(ctx context.Context, key, value string) context.Context {
+	labels := maps.Clone(labelsOf(ctx))
+	if labels == nil {
+		labels = make(map[string]string, 1)
+	}
+	labels[key] = value
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k + "=" + labels[k])
+	}
+	return context.WithValue(ctx, labelsKey{}, &labelSet{
+		labels: labels,
+		key:    b.String(),
+	})
+}
+
+// Labels returns a copy of the labels given by WithLabel, or nil if there are none.
+func Labels(ctx context.Context) map[string]string {
+	return maps.Clone(labelsOf(ctx))
+}
+
+// labelsOf returns the labels of ctx, which must not be modified.
+func labelsOf(ctx context.Context) map[string]string {
+	if ls, ok := ctx.Value(labelsKey{}).(*labelSet); ok {
+		return ls.labels
+	}
+	return nil
+}
+
+// LabelStats is the statistics of the operations of a set of labels given by WithLabel.
+type LabelStats struct {
+	// Labels is the labels of the operations.
+	Labels map[string]string
+
+	// InFlight is the number of the operations that are running.
+	InFlight int64
+
+	// Executions is the number of the completed operations, and Errors is the number of those that failed.
+	Executions int64
+	Errors     int64
+
+	// TotalDuration, MinDuration and MaxDuration summarize the durations of the completed operations.
+	// For a Query, the duration is the time until the rows are ready, not until they are read.
+	TotalDuration time.Duration
+	MinDuration   time.Duration
+	MaxDuration   time.Duration
+}
+
+// MeanDuration returns the mean duration of the operations.
+func (s LabelStats) MeanDuration() time.Duration {
+	if s.Executions == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Executions)
+}
+
+// labelStats accumulates LabelStats.
+type labelStats struct {
+	labels   map[string]string
+	inFlight atomic.Int64
+	stats    stmtStats
+}
+
+// labelStatsOf returns the statistics of the labels of ctx, or nil if there are none.
+func (db *DB) labelStatsOf(ctx context.Context) *labelStats {
+	ls, ok := ctx.Value(labelsKey{}).(*labelSet)
+	if !ok {
+		return nil
+	}
+	db.statsMu.Lock()
+	defer db.statsMu.Unlock()
+	if s, ok := db.labelStats[ls.key]; ok {
+		return s
+	}
+	if db.labelStats == nil {
+		db.labelStats = make(map[string]*labelStats)
+	}
+	s := &labelStats{
+		labels: ls.labels,
+	}
+	db.labelStats[ls.key] = s
+	return s
+}
+
+// LabelStats returns the statistics of the operations of the DB and its transactions, connections and statements
+// for each set of labels given by WithLabel.
+// The statistics are sorted in the descending order of the total duration.
+func (db *DB) LabelStats() []LabelStats {
+	db.statsMu.Lock()
+	keys := make([]string, 0, len(db.labelStats))
+	stats := make(map[string]LabelStats, len(db.labelStats))
+	for key, s := range db.labelStats {
+		st := s.stats.snapshot()
+		keys = append(keys, key)
+		stats[key] = LabelStats{
+			Labels:        maps.Clone(s.labels),
+			InFlight:      s.inFlight.Load(),
+			Executions:    st.Executions,
+			Errors:        st.Errors,
+			TotalDuration: st.TotalDuration,
+			MinDuration:   st.MinDuration,
+			MaxDuration:   st.MaxDuration,
+		}
+	}
+	db.statsMu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if di, dj := stats[keys[i]].TotalDuration, stats[keys[j]].TotalDuration; di != dj {
+			return di > dj
+		}
+		return keys[i] < keys[j]
+	})
+	sorted := make([]LabelStats, len(keys))
+	for i, key := range keys {
+		sorted[i] = stats[key]
+	}
+	return sorted
+}
@@ -0,0 +1,88 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithLabel(t *testing.T) {
+	var rec hookRecorder
+	db := newTestDB(t, asynql.WithHook(rec.hook))
+	defer db.Close()
+	checkout := asynql.WithLabel(context.Background(), "op", "checkout")
+	signup := asynql.WithLabel(asynql.WithLabel(context.Background(), "team", "growth"), "op", "signup")
+	if _, err := asynql.WaitAll(
+		db.ExecContext(checkout, `UPDATE test_table SET name = ? WHERE id = 1`, "carol"),
+		db.ExecContext(checkout, `DELETE FROM missing_table`),
+		db.ExecContext(signup, `INSERT INTO test_table (id, name) VALUES (3, 'dave')`),
+		db.ExecContext(context.Background(), `UPDATE test_table SET name = ? WHERE id = 2`, "erin"),
+	); err == nil {
+		t.Fatal(`asynql.WaitAll(...) => nil; want error of missing_table`)
+	}
+	labels := map[string]map[string]string{}
+	for _, e := range rec.events {
+		labels[e.Query] = e.Labels
+	}
+	var actual interface{} = labels
+	var expected interface{} = map[string]map[string]string{
+		`UPDATE test_table SET name = ? WHERE id = 1`:          {"op": "checkout"},
+		`DELETE FROM missing_table`:                            {"op": "checkout"},
+		`INSERT INTO test_table (id, name) VALUES (3, 'dave')`: {"op": "signup", "team": "growth"},
+		`UPDATE test_table SET name = ? WHERE id = 2`:          nil,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`hook events Labels => %#v; want %#v`, actual, expected)
+	}
+
+	stats := map[string][]int64{}
+	for _, s := range db.LabelStats() {
+		if s.MaxDuration < s.MinDuration || s.TotalDuration < s.MaxDuration {
+			t.Errorf(`LabelStats() durations of %#v => %v, %v, %v; want min <= max <= total`, s.Labels, s.MinDuration, s.MaxDuration, s.TotalDuration)
+		}
+		stats[s.Labels["op"]+"/"+s.Labels["team"]] = []int64{s.InFlight, s.Executions, s.Errors}
+	}
+	actual = stats
+	expected = map[string][]int64{
+		"checkout/":     {0, 2, 1},
+		"signup/growth": {0, 1, 0},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.LabelStats() => %#v; want %#v`, actual, expected)
+	}
+	actual = asynql.Labels(signup)
+	expected = map[string]string{"op": "signup", "team": "growth"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Labels(ctx) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDB_LabelStats_InFlight(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn})
+	defer db.Close()
+	ctx := asynql.WithLabel(context.Background(), "op", "report")
+	ch := db.ExecContext(ctx, "block")
+	<-conn.started
+	stats := db.LabelStats()
+	var actual interface{} = []interface{}{len(stats), stats[0].InFlight, stats[0].Executions}
+	var expected interface{} = []interface{}{1, int64(1), int64(0)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.LabelStats() while running => %#v; want %#v`, actual, expected)
+	}
+	close(conn.release)
+	if err := (<-ch).Err(); err != nil {
+		t.Fatal(err)
+	}
+	stats = db.LabelStats()
+	actual = []interface{}{stats[0].InFlight, stats[0].Executions}
+	expected = []interface{}{int64(0), int64(1)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.LabelStats() after completion => %#v; want %#v`, actual, expected)
+	}
+}
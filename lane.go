@@ -0,0 +1,117 @@
+package asynql
+
+import (
+	"context"
+	"sync"
+)
+
+// Lane is an ordered execution lane of a DB, which is returned by DB.Lane.
+// The operations submitted to a lane are executed one at a time in the order of submission,
+// while the operations of different lanes are executed concurrently.
+// The methods of Lane are similar to those of DB.
+type Lane struct {
+	db  *DB
+	key interface{}
+}
+
+// Lane returns the lane identified by key, which must be comparable.
+// It is useful to keep the order of the writes for the same entity, e.g. a user or an aggregate,
+// without serializing the writes for the others.
+// An operation is complete when its result is ready to be sent, so a Query on a lane doesn't wait for its rows to be read
+// before the next operation starts.
+func (db *DB) Lane(key interface{}) *Lane {
+	return &Lane{
+		db:  db,
+		key: key,
+	}
+}
+
+// Exec is similar to DB.Exec, but runs on the lane.
+func (l *Lane) Exec(query string, args ...interface{}) <-chan *Result {
+	return l.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext is similar to DB.ExecContext, but runs on the lane.
+func (l *Lane) ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	return laneRun(l, func() <-chan *Result {
+		return l.db.ExecContext(ctx, query, args...)
+	})
+}
+
+// Query is similar to DB.Query, but runs on the lane.
+func (l *Lane) Query(query string, args ...interface{}) <-chan *Rows {
+	return l.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is similar to DB.QueryContext, but runs on the lane.
+func (l *Lane) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	return laneRun(l, func() <-chan *Rows {
+		return l.db.QueryContext(ctx, query, args...)
+	})
+}
+
+// QueryRow is similar to DB.QueryRow, but runs on the lane.
+func (l *Lane) QueryRow(query string, args ...interface{}) <-chan *Row {
+	return l.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext is similar to DB.QueryRowContext, but runs on the lane.
+func (l *Lane) QueryRowContext(ctx context.Context, query string, args ...interface{}) <-chan *Row {
+	return laneRun(l, func() <-chan *Row {
+		return l.db.QueryRowContext(ctx, query, args...)
+	})
+}
+
+// laneRun submits start to the lane l, and returns a channel that receives the result of start.
+// The result is sent from another goroutine so that a caller that doesn't receive it doesn't block the lane.
+func laneRun[T any](l *Lane, start func() <-chan T) <-chan T {
+	ch := make(chan T)
+	l.db.lanes.submit(l.key, func() {
+		v := <-start()
+		go deliver(l.db, ch, v)
+	})
+	return ch
+}
+
+// laneSet runs the operations of each lane in order.
+// A lane has a goroutine only while it has operations to run.
+type laneSet struct {
+	mu     sync.Mutex
+	queues map[interface{}]*laneQueue
+}
+
+type laneQueue struct {
+	ops []func()
+}
+
+func (s *laneSet) submit(key interface{}, op func()) {
+	s.mu.Lock()
+	if q, ok := s.queues[key]; ok {
+		q.ops = append(q.ops, op)
+		s.mu.Unlock()
+		return
+	}
+	if s.queues == nil {
+		s.queues = make(map[interface{}]*laneQueue)
+	}
+	q := &laneQueue{}
+	s.queues[key] = q
+	s.mu.Unlock()
+	go s.run(key, q, op)
+}
+
+func (s *laneSet) run(key interface{}, q *laneQueue, op func()) {
+	for {
+		op()
+		s.mu.Lock()
+		if len(q.ops) == 0 {
+			delete(s.queues, key)
+			s.mu.Unlock()
+			return
+		}
+		op = q.ops[0]
+		q.ops[0] = nil
+		q.ops = q.ops[1:]
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,47 @@
+package asynql_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestLane(t *testing.T) {
+	db := newTestFileDB(t)
+	defer db.Close()
+	if _, err := db.DB.Exec(`CREATE TABLE log (seq INTEGER PRIMARY KEY AUTOINCREMENT, lane TEXT, n INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	var results []<-chan *asynql.Result
+	for i := 0; i < 20; i++ {
+		for _, key := range []string{"a", "b"} {
+			results = append(results, db.Lane(key).Exec(`INSERT INTO log (lane, n) VALUES (?, ?)`, key, i))
+		}
+	}
+	if _, err := asynql.WaitAll(results...); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		rows := <-db.Query(`SELECT n FROM log WHERE lane = ? ORDER BY seq`, key)
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		var actual []int
+		for rows.Next() {
+			var n int
+			if err := rows.Scan(&n); err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, n)
+		}
+		rows.Close()
+		var expected []int
+		for i := 0; i < 20; i++ {
+			expected = append(expected, i)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`db.Lane(%#v).Exec order => %#v; want %#v`, key, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrLargeObjectUnsupported is reported by the operations of LargeObjects when a DB has a dialect other than DialectPostgres.
+var ErrLargeObjectUnsupported = errors.New("asynql: large objects are supported only by PostgreSQL")
+
+// The modes of LargeObjects.Open.
+const (
+	LargeObjectModeWrite = 0x20000
+	LargeObjectModeRead  = 0x40000
+)
+
+// LargeObjects provides the large objects of PostgreSQL, which store large binary data in chunks, in a transaction.
+// The large objects are operated by the server-side functions such as lo_open and loread, so they work with any driver.
+// The operations of a large object must be run one at a time, waiting for the result of the previous one.
+type LargeObjects struct {
+	tx *Tx
+}
+
+// LargeObjects returns the LargeObjects of tx.
+// A large object can be opened only in a transaction, and is closed at the end of the transaction.
+func (tx *Tx) LargeObjects() *LargeObjects {
+	return &LargeObjects{
+		tx: tx,
+	}
+}
+
+// OIDResult represents a result of LargeObjects.Create.
+type OIDResult struct {
+	// OID is the OID of the created large object.
+	OID uint32
+
+	err error
+}
+
+// Err returns an error.
+func (r *OIDResult) Err() error {
+	return r.err
+}
+
+// LargeObjectResult represents a result of LargeObjects.Open.
+type LargeObjectResult struct {
+	*LargeObject
+
+	err error
+}
+
+// Err returns an error.
+func (r *LargeObjectResult) Err() error {
+	return r.err
+}
+
+// IOResult represents a result of the operations of a LargeObject.
+type IOResult struct {
+	// N is the number of the bytes read or written, or the new offset for Seek.
+	N int64
+
+	err error
+}
+
+// Err returns an error.
+func (r *IOResult) Err() error {
+	return r.err
+}
+
+// Create creates an empty large object, and then sends its OID on the returned channel.
+func (lo *LargeObjects) Create(ctx context.Context) <-chan *OIDResult {
+	ch := make(chan *OIDResult)
+	go func() {
+		r := &OIDResult{}
+		r.err = lo.queryRow(ctx, &r.OID, "SELECT lo_create(0)")
+		deliver(lo.tx, ch, r)
+	}()
+	return ch
+}
+
+// Open opens the large object of oid with mode, which is LargeObjectModeRead, LargeObjectModeWrite or both,
+// and then sends it on the returned channel.
+func (lo *LargeObjects) Open(ctx context.Context, oid uint32, mode int) <-chan *LargeObjectResult {
+	ch := make(chan *LargeObjectResult)
+	go func() {
+		obj := &LargeObject{
+			lo:  lo,
+			OID: oid,
+		}
+		err := lo.queryRow(ctx, &obj.fd, "SELECT lo_open($1, $2)", oid, mode)
+		if err != nil {
+			obj = nil
+		}
+		deliver(lo.tx, ch, &LargeObjectResult{
+			LargeObject: obj,
+			err:         err,
+		})
+	}()
+	return ch
+}
+
+// Remove removes the large object of oid, and then sends the result on the returned channel.
+func (lo *LargeObjects) Remove(ctx context.Context, oid uint32) <-chan *IOResult {
+	return lo.io(ctx, "SELECT lo_unlink($1)", oid)
+}
+
+// queryRow runs query, which must be a SELECT of a function, and scans its result into dest.
+func (lo *LargeObjects) queryRow(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if lo.tx.db.Dialect() != DialectPostgres {
+		return ErrLargeObjectUnsupported
+	}
+	return (<-lo.tx.QueryRowContext(ctx, query, args...)).Scan(dest)
+}
+
+// io runs query, and sends the number that it returns.
+func (lo *LargeObjects) io(ctx context.Context, query string, args ...interface{}) <-chan *IOResult {
+	ch := make(chan *IOResult)
+	go func() {
+		r := &IOResult{}
+		r.err = lo.queryRow(ctx, &r.N, query, args...)
+		deliver(lo.tx, ch, r)
+	}()
+	return ch
+}
+
+// LargeObject is an open large object.
+type LargeObject struct {
+	// OID is the OID of the large object.
+	OID uint32
+
+	lo *LargeObjects
+	fd int32
+}
+
+// Read reads up to len(p) bytes into p at the current offset, and then sends the number of the bytes read on the returned channel.
+// At the end of the large object, an IOResult with io.EOF is sent.
+func (o *LargeObject) Read(ctx context.Context, p []byte) <-chan *IOResult {
+	ch := make(chan *IOResult)
+	go func() {
+		var data []byte
+		err := o.lo.queryRow(ctx, &data, "SELECT loread($1, $2)", o.fd, len(p))
+		n := copy(p, data)
+		if err == nil && n == 0 && len(p) > 0 {
+			err = io.EOF
+		}
+		deliver(o.lo.tx, ch, &IOResult{
+			N:   int64(n),
+			err: err,
+		})
+	}()
+	return ch
+}
+
+// Write writes p at the current offset, and then sends the number of the bytes written on the returned channel.
+func (o *LargeObject) Write(ctx context.Context, p []byte) <-chan *IOResult {
+	return o.lo.io(ctx, "SELECT lowrite($1, $2)", o.fd, p)
+}
+
+// Seek sets the offset for the next Read or Write to offset, interpreted according to whence as io.Seeker,
+// and then sends the new offset on the returned channel.
+func (o *LargeObject) Seek(ctx context.Context, offset int64, whence int) <-chan *IOResult {
+	return o.lo.io(ctx, "SELECT lo_lseek64($1, $2, $3)", o.fd, offset, whence)
+}
+
+// Truncate truncates the large object to size, and then sends the result on the returned channel.
+func (o *LargeObject) Truncate(ctx context.Context, size int64) <-chan *IOResult {
+	return o.lo.io(ctx, "SELECT lo_truncate64($1, $2)", o.fd, size)
+}
+
+// Close closes the large object, and then sends the result on the returned channel.
+func (o *LargeObject) Close(ctx context.Context) <-chan *IOResult {
+	return o.lo.io(ctx, "SELECT lo_close($1)", o.fd)
+}
+
+// ReadWriteSeeker returns an io.ReadWriteSeeker of the large object, which runs the operations with ctx and waits for their results,
+// so that the large object can be streamed by io.Copy and the like.
+func (o *LargeObject) ReadWriteSeeker(ctx context.Context) io.ReadWriteSeeker {
+	return &largeObjectIO{
+		o:   o,
+		ctx: ctx,
+	}
+}
+
+type largeObjectIO struct {
+	o   *LargeObject
+	ctx context.Context
+}
+
+func (l *largeObjectIO) Read(p []byte) (int, error) {
+	r := <-l.o.Read(l.ctx, p)
+	return int(r.N), r.Err()
+}
+
+func (l *largeObjectIO) Write(p []byte) (int, error) {
+	r := <-l.o.Write(l.ctx, p)
+	if err := r.Err(); err != nil {
+		return int(r.N), err
+	}
+	if int(r.N) < len(p) {
+		return int(r.N), io.ErrShortWrite
+	}
+	return int(r.N), nil
+}
+
+func (l *largeObjectIO) Seek(offset int64, whence int) (int64, error) {
+	r := <-l.o.Seek(l.ctx, offset, whence)
+	return r.N, r.Err()
+}
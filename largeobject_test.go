@@ -0,0 +1,189 @@
+package asynql_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// loConn is a driver.Conn that implements the functions of the large objects of PostgreSQL in memory.
+type loConn struct {
+	mu      sync.Mutex
+	objects map[int64][]byte
+	fds     map[int64]*loFD
+	next    int64
+}
+
+type loFD struct {
+	oid    int64
+	offset int64
+}
+
+func (c *loConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *loConn) Close() error                        { return nil }
+func (c *loConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *loConn) Commit() error                       { return nil }
+func (c *loConn) Rollback() error                     { return nil }
+
+func (c *loConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	arg := func(i int) int64 { return args[i].Value.(int64) }
+	var v driver.Value
+	switch query {
+	case "SELECT lo_create(0)":
+		c.next++
+		c.objects[c.next] = nil
+		v = c.next
+	case "SELECT lo_open($1, $2)":
+		if _, ok := c.objects[arg(0)]; !ok {
+			return nil, fmt.Errorf("large object %d does not exist", arg(0))
+		}
+		c.next++
+		c.fds[c.next] = &loFD{oid: arg(0)}
+		v = c.next
+	case "SELECT loread($1, $2)":
+		fd := c.fds[arg(0)]
+		data := c.objects[fd.oid][min(fd.offset, int64(len(c.objects[fd.oid]))):]
+		data = data[:min(int64(len(data)), arg(1))]
+		fd.offset += int64(len(data))
+		v = append([]byte(nil), data...)
+	case "SELECT lowrite($1, $2)":
+		fd := c.fds[arg(0)]
+		data := args[1].Value.([]byte)
+		obj := c.objects[fd.oid]
+		if end := fd.offset + int64(len(data)); end > int64(len(obj)) {
+			obj = append(obj, make([]byte, end-int64(len(obj)))...)
+		}
+		copy(obj[fd.offset:], data)
+		c.objects[fd.oid] = obj
+		fd.offset += int64(len(data))
+		v = int64(len(data))
+	case "SELECT lo_lseek64($1, $2, $3)":
+		fd := c.fds[arg(0)]
+		switch arg(2) {
+		case io.SeekStart:
+			fd.offset = arg(1)
+		case io.SeekCurrent:
+			fd.offset += arg(1)
+		case io.SeekEnd:
+			fd.offset = int64(len(c.objects[fd.oid])) + arg(1)
+		}
+		v = fd.offset
+	case "SELECT lo_truncate64($1, $2)":
+		fd := c.fds[arg(0)]
+		c.objects[fd.oid] = c.objects[fd.oid][:arg(1)]
+		v = int64(0)
+	case "SELECT lo_close($1)":
+		delete(c.fds, arg(0))
+		v = int64(0)
+	case "SELECT lo_unlink($1)":
+		delete(c.objects, arg(0))
+		v = int64(1)
+	default:
+		return nil, fmt.Errorf("unexpected query %q", query)
+	}
+	return &loRows{v: v}, nil
+}
+
+type loRows struct {
+	v    driver.Value
+	done bool
+}
+
+func (r *loRows) Columns() []string { return []string{"v"} }
+func (r *loRows) Close() error      { return nil }
+
+func (r *loRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.v
+	return nil
+}
+
+func TestLargeObjects(t *testing.T) {
+	conn := &loConn{objects: map[int64][]byte{}, fds: map[int64]*loFD{}}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithDialect(asynql.DialectPostgres))
+	defer db.Close()
+	ctx := context.Background()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	lo := tx.LargeObjects()
+	created := <-lo.Create(ctx)
+	if err := created.Err(); err != nil {
+		t.Fatal(err)
+	}
+	opened := <-lo.Open(ctx, created.OID, asynql.LargeObjectModeRead|asynql.LargeObjectModeWrite)
+	if err := opened.Err(); err != nil {
+		t.Fatal(err)
+	}
+	obj := opened.LargeObject
+	content := strings.Repeat("large object ", 1000)
+	rws := obj.ReadWriteSeeker(ctx)
+	if _, err := io.Copy(rws, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rws.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, rws); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != content {
+		t.Errorf(`read content of %d bytes; want the written content of %d bytes`, buf.Len(), len(content))
+	}
+
+	if err := (<-obj.Truncate(ctx, 5)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-obj.Seek(ctx, 0, io.SeekStart)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 10)
+	r := <-obj.Read(ctx, p)
+	var actual interface{} = []interface{}{r.N, r.Err(), string(p[:r.N])}
+	var expected interface{} = []interface{}{int64(5), nil, "large"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`obj.Read(ctx, p) after obj.Truncate(ctx, 5) => %#v; want %#v`, actual, expected)
+	}
+	if err := (<-obj.Read(ctx, p)).Err(); err != io.EOF {
+		t.Errorf(`obj.Read(ctx, p) at the end => %#v; want %#v`, err, io.EOF)
+	}
+	if err := (<-obj.Close(ctx)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-lo.Remove(ctx, created.OID)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-lo.Open(ctx, created.OID, asynql.LargeObjectModeRead)).Err(); err == nil {
+		t.Errorf(`lo.Open(ctx, removed, mode) => nil; want error`)
+	}
+}
+
+func TestLargeObjects_Unsupported(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := (<-tx.LargeObjects().Create(context.Background())).Err(); err != asynql.ErrLargeObjectUnsupported {
+		t.Errorf(`tx.LargeObjects().Create(ctx) => %#v; want %#v`, err, asynql.ErrLargeObjectUnsupported)
+	}
+}
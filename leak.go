@@ -0,0 +1,100 @@
+package asynql
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// RowsLeak describes a *Rows that has not been closed within the grace period of WithLeakDetector.
+// An open *Rows holds a connection of the pool, so forgetting to close it can exhaust the pool.
+type RowsLeak struct {
+	// Query is the query of the rows.
+	Query string
+
+	// Args is the arguments of the query.
+	Args []interface{}
+
+	// Opened is the time when the rows were sent.
+	Opened time.Time
+
+	// Stack is the stack trace of the goroutine that called the query, or nil unless WithLeakStacks is given.
+	Stack []byte
+}
+
+// String returns a description of l that is suitable for a log.
+func (l *RowsLeak) String() string {
+	s := fmt.Sprintf("asynql: rows of %q opened at %v are not closed", l.Query, l.Opened.Format(time.RFC3339Nano))
+	if l.Stack != nil {
+		s += "\n" + string(l.Stack)
+	}
+	return s
+}
+
+// leakDetector is the configuration of WithLeakDetector, WithAbandonDetector and WithLeakStacks.
+type leakDetector struct {
+	grace  time.Duration
+	report func(l *RowsLeak)
+
+	abandon       time.Duration
+	reportAbandon func(r *AbandonedResult)
+
+	stacks bool
+}
+
+// WithLeakDetector returns an Option that reports the *Rows of the queries of the DB, and of its transactions, connections and statements,
+// that are still open after grace to report, or to the standard logger if report is nil.
+// report is called in its own goroutine once for each leaked *Rows.
+// The rows that have been closed by Close, or by Next reaching the end, are not reported.
+func WithLeakDetector(grace time.Duration, report func(l *RowsLeak)) Option {
+	return func(db *DB) {
+		db.leaks.grace = grace
+		db.leaks.report = report
+	}
+}
+
+// WithLeakStacks returns an Option that captures the stack trace of every operation for WithLeakDetector and WithAbandonDetector,
+// to tell where a leaked *Rows or an abandoned result comes from.
+// It is meant for debugging, since capturing a stack trace is expensive.
+func WithLeakStacks() Option {
+	return func(db *DB) {
+		db.leaks.stacks = true
+	}
+}
+
+// stack returns the stack trace of the calling goroutine if d captures the stack traces of the operations of op.
+func (d *leakDetector) stack(op HookOp) []byte {
+	if !d.stacks || d.abandon <= 0 && (d.grace <= 0 || op != HookQuery) {
+		return nil
+	}
+	return debug.Stack()
+}
+
+// track reports rs of query if it is still open after the grace period of d.
+func (d *leakDetector) track(rs *Rows, query string, args []interface{}, stack []byte) {
+	l := &RowsLeak{
+		Query:  query,
+		Args:   args,
+		Opened: time.Now(),
+		Stack:  stack,
+	}
+	t := time.AfterFunc(d.grace, func() {
+		// Columns fails once the rows are closed, including by database/sql at the end of the rows or of the context.
+		if _, err := rs.Rows.Columns(); err != nil {
+			return
+		}
+		if d.report == nil {
+			log.Print(l)
+			return
+		}
+		d.report(l)
+	})
+	release := rs.release
+	rs.release = func() {
+		t.Stop()
+		if release != nil {
+			release()
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package asynql_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithLeakDetector(t *testing.T) {
+	leaks := make(chan *asynql.RowsLeak, 10)
+	db := newTestDB(t, asynql.WithLeakDetector(20*time.Millisecond, func(l *asynql.RowsLeak) {
+		leaks <- l
+	}))
+	defer db.Close()
+	ctx := context.Background()
+	closed := <-db.QueryContext(ctx, `SELECT name FROM test_table WHERE id = ?`, 1)
+	if err := closed.Err(); err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	drained := <-db.QueryContext(ctx, `SELECT name FROM test_table WHERE id = ?`, 2)
+	if err := drained.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for drained.Next() {
+	}
+	query := `SELECT name FROM test_table ORDER BY id`
+	leaked := <-db.QueryContext(ctx, query)
+	if err := leaked.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer leaked.Close()
+	select {
+	case l := <-leaks:
+		var actual interface{} = []interface{}{l.Query, l.Stack}
+		var expected interface{} = []interface{}{query, []byte(nil)}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`leak => %#v; want %#v`, actual, expected)
+		}
+	case <-time.After(time.Second):
+		t.Fatal(`leak is not reported`)
+	}
+	select {
+	case l := <-leaks:
+		t.Errorf(`leak of %#v is reported; want no more leaks`, l.Query)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWithLeakStacks(t *testing.T) {
+	leaks := make(chan *asynql.RowsLeak, 1)
+	db := newTestDB(t, asynql.WithLeakStacks(), asynql.WithLeakDetector(time.Millisecond, func(l *asynql.RowsLeak) {
+		leaks <- l
+	}))
+	defer db.Close()
+	rs := <-db.Query(`SELECT name FROM test_table`)
+	if err := rs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	select {
+	case l := <-leaks:
+		if !bytes.Contains(l.Stack, []byte("TestWithLeakStacks")) {
+			t.Errorf(`leak.Stack => %s; want the stack of TestWithLeakStacks`, l.Stack)
+		}
+	case <-time.After(time.Second):
+		t.Fatal(`leak is not reported`)
+	}
+}
@@ -0,0 +1,423 @@
+package asynql
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a token of a query.
+type tokenKind int
+
+const (
+	// tokWord is a keyword or an unquoted identifier. Its text is lower-cased.
+	tokWord tokenKind = iota
+
+	// tokIdent is a quoted identifier. Its text is unquoted.
+	tokIdent
+
+	// tokString is a string literal, including the quotes.
+	tokString
+
+	// tokNumber is a numeric literal.
+	tokNumber
+
+	// tokParam is a placeholder such as ?, $1, :name or @name.
+	tokParam
+
+	// tokPunct is any other character such as an operator or a parenthesis.
+	tokPunct
+)
+
+// token is a lexical token of a query.
+type token struct {
+	kind tokenKind
+	text string
+
+	// pos is the byte offset of the token in the query.
+	pos int
+
+	// end is the byte offset just after the token in the query.
+	end int
+}
+
+// is reports whether t is the given keyword, which must be lower-cased.
+func (t token) is(word string) bool {
+	return t.kind == tokWord && t.text == word
+}
+
+// isPunct reports whether t is the given punctuation.
+func (t token) isPunct(p string) bool {
+	return t.kind == tokPunct && t.text == p
+}
+
+// lexSQL splits query into tokens, dropping whitespace and comments.
+// It understands the syntax that is common to the major dialects well enough to find keywords and literals,
+// but it is not a parser.
+func lexSQL(query string) []token {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(query)
+			}
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				i += j + 4
+			} else {
+				i = len(query)
+			}
+			continue
+		case c == '\'':
+			i = skipQuoted(query, i, '\'')
+			tokens = append(tokens, token{kind: tokString, text: query[start:i], pos: start, end: i})
+		case c == '"' || c == '`':
+			i = skipQuoted(query, i, c)
+			tokens = append(tokens, token{kind: tokIdent, text: unquoteIdent(query[start:i]), pos: start, end: i})
+		case c == '[':
+			if j := strings.IndexByte(query[i:], ']'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(query)
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: strings.Trim(query[start:i], "[]"), pos: start, end: i})
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			for i++; i < len(query) && isDigit(query[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: tokParam, text: query[start:i], pos: start, end: i})
+		case c == '$':
+			// A dollar-quoted string of PostgreSQL such as $$text$$ or $tag$text$tag$.
+			j := i + 1
+			for j < len(query) && (isIdentStart(query[j]) || isDigit(query[j])) {
+				j++
+			}
+			if j < len(query) && query[j] == '$' {
+				tag := query[i : j+1]
+				if k := strings.Index(query[j+1:], tag); k >= 0 {
+					i = j + 1 + k + len(tag)
+				} else {
+					i = len(query)
+				}
+				tokens = append(tokens, token{kind: tokString, text: query[start:i], pos: start, end: i})
+				break
+			}
+			i++
+			tokens = append(tokens, token{kind: tokPunct, text: "$", pos: start, end: i})
+		case c == '?':
+			i++
+			tokens = append(tokens, token{kind: tokParam, text: "?", pos: start, end: i})
+		case (c == ':' || c == '@') && i+1 < len(query) && isIdentStart(query[i+1]) && (c != ':' || i == 0 || query[i-1] != ':'):
+			for i++; i < len(query) && isIdentByte(query[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: tokParam, text: query[start:i], pos: start, end: i})
+		case isDigit(c) || c == '.' && i+1 < len(query) && isDigit(query[i+1]):
+			i = skipNumber(query, i)
+			tokens = append(tokens, token{kind: tokNumber, text: query[start:i], pos: start, end: i})
+		case isIdentStart(c):
+			for i++; i < len(query) && isIdentByte(query[i]); i++ {
+			}
+			tokens = append(tokens, token{kind: tokWord, text: strings.ToLower(query[start:i]), pos: start, end: i})
+		default:
+			_, size := utf8.DecodeRuneInString(query[i:])
+			i += size
+			tokens = append(tokens, token{kind: tokPunct, text: query[start:i], pos: start, end: i})
+		}
+	}
+	return tokens
+}
+
+// skipQuoted returns the offset just after the quoted text that starts at query[i].
+// A doubled quote is an escaped quote, as is a quote preceded by a backslash in a string literal.
+func skipQuoted(query string, i int, quote byte) int {
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if quote == '\'' {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func skipNumber(query string, i int) int {
+	if strings.HasPrefix(query[i:], "0x") || strings.HasPrefix(query[i:], "0X") {
+		for i += 2; i < len(query) && isHexDigit(query[i]); i++ {
+		}
+		return i
+	}
+	for ; i < len(query) && (isDigit(query[i]) || query[i] == '.'); i++ {
+	}
+	if i < len(query) && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < len(query) && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if j < len(query) && isDigit(query[j]) {
+			for i = j; i < len(query) && isDigit(query[i]); i++ {
+			}
+		}
+	}
+	return i
+}
+
+func unquoteIdent(s string) string {
+	q := s[:1]
+	s = strings.TrimPrefix(strings.TrimSuffix(s[1:], q), q)
+	return strings.ReplaceAll(s, q+q, q)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= utf8.RuneSelf
+}
+
+func isIdentByte(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
+
+// tableName reads a possibly qualified table name at tokens[i], and returns its last component in lower case,
+// and the index just after it.
+// It returns an empty name if tokens[i] is not a name.
+func tableName(tokens []token, i int) (string, int) {
+	name := ""
+	for i < len(tokens) && (tokens[i].kind == tokWord || tokens[i].kind == tokIdent) {
+		name = strings.ToLower(tokens[i].text)
+		i++
+		if i+1 < len(tokens) && tokens[i].isPunct(".") {
+			i++
+			continue
+		}
+		break
+	}
+	return name, i
+}
+
+// writtenTables returns the names of the tables that query modifies.
+func writtenTables(query string) []string {
+	tokens := lexSQL(query)
+	var tables []string
+	add := func(name string) {
+		if name != "" {
+			tables = appendUnique(tables, name)
+		}
+	}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind != tokWord {
+			continue
+		}
+		switch t.text {
+		case "insert", "replace", "merge":
+			for j := i + 1; j < len(tokens) && j <= i+3; j++ {
+				if tokens[j].is("into") {
+					name, _ := tableName(tokens, j+1)
+					add(name)
+					break
+				}
+			}
+		case "update":
+			if i > 0 && (tokens[i-1].is("do") || tokens[i-1].is("for") || tokens[i-1].is("key")) {
+				// ON CONFLICT DO UPDATE, SELECT ... FOR UPDATE and ON DUPLICATE KEY UPDATE.
+				continue
+			}
+			j := i + 1
+			if j < len(tokens) && tokens[j].is("or") {
+				j += 2
+			}
+			for j < len(tokens) && (tokens[j].is("only") || tokens[j].is("low_priority") || tokens[j].is("ignore")) {
+				j++
+			}
+			name, _ := tableName(tokens, j)
+			add(name)
+		case "delete":
+			if i+1 < len(tokens) && tokens[i+1].is("from") {
+				j := i + 2
+				if j < len(tokens) && tokens[j].is("only") {
+					j++
+				}
+				name, _ := tableName(tokens, j)
+				add(name)
+			}
+		case "truncate":
+			j := i + 1
+			if j < len(tokens) && tokens[j].is("table") {
+				j++
+			}
+			for {
+				var name string
+				name, j = tableName(tokens, j)
+				add(name)
+				if j >= len(tokens) || !tokens[j].isPunct(",") {
+					break
+				}
+				j++
+			}
+		case "drop", "alter":
+			if i+1 < len(tokens) && tokens[i+1].is("table") {
+				j := i + 2
+				if j+1 < len(tokens) && tokens[j].is("if") && tokens[j+1].is("exists") {
+					j += 2
+				}
+				name, _ := tableName(tokens, j)
+				add(name)
+			}
+		}
+	}
+	return tables
+}
+
+// isReadQuery reports whether every statement of query only reads: it starts with a read-only verb,
+// modifies no table, stores nothing by INTO and doesn't lock the rows that it reads.
+// Such a query can be shared by concurrent callers, run again after a failure or sent to a replica.
+func isReadQuery(query string) bool {
+	stmts := splitStatements(query)
+	for _, stmt := range stmts {
+		tokens := lexSQL(stmt)
+		if writesStatement(stmt, tokens) {
+			return false
+		}
+		for i, t := range tokens {
+			var next token
+			if i+1 < len(tokens) {
+				next = tokens[i+1]
+			}
+			switch {
+			case t.is("for") && (next.is("update") || next.is("share") || next.is("no") || next.is("key")):
+				// FOR UPDATE, FOR SHARE, FOR NO KEY UPDATE and FOR KEY SHARE.
+				return false
+			case t.is("lock") && next.is("in"):
+				// LOCK IN SHARE MODE of MySQL.
+				return false
+			}
+		}
+	}
+	return len(stmts) > 0
+}
+
+// writesStatement reports whether stmt, a single statement whose tokens are tokens, may write:
+// it doesn't start with a read-only verb, modifies a table or stores its result by SELECT ... INTO,
+// which creates a table or assigns variables.
+func writesStatement(stmt string, tokens []token) bool {
+	if !readOnlyVerbs[statementVerb(tokens)] || len(writtenTables(stmt)) > 0 {
+		return true
+	}
+	for _, t := range tokens {
+		if t.is("into") {
+			return true
+		}
+	}
+	return false
+}
+
+// aliasStopWords are the keywords that may follow a table reference, and therefore cannot be its alias.
+var aliasStopWords = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "full": true, "outer": true,
+	"cross": true, "natural": true, "on": true, "using": true, "group": true, "order": true, "having": true,
+	"limit": true, "offset": true, "union": true, "intersect": true, "except": true, "window": true,
+	"fetch": true, "for": true, "returning": true, "set": true, "values": true, "lateral": true,
+}
+
+// readTables returns the names of the tables that query reads from.
+func readTables(query string) []string {
+	tokens := lexSQL(query)
+	var tables []string
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].is("from") && !tokens[i].is("join") {
+			continue
+		}
+		j := i + 1
+		for j < len(tokens) {
+			var name string
+			name, j = tableName(tokens, j)
+			if name == "" {
+				break
+			}
+			tables = appendUnique(tables, name)
+			if j < len(tokens) && tokens[j].is("as") {
+				j++
+			}
+			if j < len(tokens) && (tokens[j].kind == tokIdent || tokens[j].kind == tokWord && !aliasStopWords[tokens[j].text]) {
+				j++
+			}
+			if j >= len(tokens) || !tokens[j].isPunct(",") {
+				break
+			}
+			j++
+		}
+	}
+	return tables
+}
+
+func appendUnique(ss []string, s string) []string {
+	for _, v := range ss {
+		if v == s {
+			return ss
+		}
+	}
+	return append(ss, s)
+}
+
+// splitPaging splits query into the part before its top-level ORDER BY, LIMIT, OFFSET or FETCH clauses, and the rest.
+// Those clauses of the last query of a UNION, INTERSECT or EXCEPT apply to the whole query, so they are split as well.
+func splitPaging(query string) (string, string) {
+	depth := 0
+	cut := -1
+	for _, t := range lexSQL(query) {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth != 0:
+		case t.is("union") || t.is("intersect") || t.is("except"):
+			cut = -1
+		case cut < 0 && (t.is("order") || t.is("limit") || t.is("offset") || t.is("fetch")):
+			cut = t.pos
+		}
+	}
+	if cut < 0 {
+		return query, ""
+	}
+	return strings.TrimSpace(query[:cut]), query[cut:]
+}
+
+// countParams returns the number of the arguments that query refers to by ? or $n placeholders.
+func countParams(query string) int {
+	n := 0
+	for _, t := range lexSQL(query) {
+		if t.kind != tokParam {
+			continue
+		}
+		if t.text == "?" {
+			n++
+		} else if t.text[0] == '$' {
+			if i, err := strconv.Atoi(t.text[1:]); err == nil && i > n {
+				n = i
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,74 @@
+package asynql_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWrittenTables(t *testing.T) {
+	for _, v := range []struct {
+		query    string
+		expected []string
+	}{
+		{`INSERT INTO users (id) VALUES (1)`, []string{"users"}},
+		{`insert or replace into "Users" values (?)`, []string{"users"}},
+		{`UPDATE public.users SET name = 'DELETE FROM x' WHERE id = $1`, []string{"users"}},
+		{`DELETE FROM users WHERE id IN (SELECT id FROM banned)`, []string{"users"}},
+		{`TRUNCATE TABLE a, b`, []string{"a", "b"}},
+		{`DROP TABLE IF EXISTS logs`, []string{"logs"}},
+		{`INSERT INTO a (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET id = 2`, []string{"a"}},
+		{`SELECT * FROM users FOR UPDATE`, nil},
+		{`-- UPDATE x SET y = 1
+		  SELECT 1`, nil},
+	} {
+		actual := asynql.WrittenTables(v.query)
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`writtenTables(%#v) => %#v; want %#v`, v.query, actual, v.expected)
+		}
+	}
+}
+
+func TestIsReadQuery(t *testing.T) {
+	for _, v := range []struct {
+		query    string
+		expected bool
+	}{
+		{`SELECT * FROM users`, true},
+		{`WITH t AS (SELECT 1) SELECT * FROM t`, true},
+		{`SELECT 'FOR UPDATE', 'INTO'; SELECT 2`, true},
+		{`INSERT INTO users (id) VALUES (1) RETURNING id`, false},
+		{`WITH d AS (DELETE FROM users RETURNING id) SELECT * FROM d`, false},
+		{`SELECT * FROM users FOR UPDATE`, false},
+		{`SELECT * FROM users FOR NO KEY UPDATE SKIP LOCKED`, false},
+		{`SELECT * FROM users LOCK IN SHARE MODE`, false},
+		{`SELECT * INTO backup FROM users`, false},
+		{`SELECT 1; CREATE TABLE zz (x INTEGER)`, false},
+		{`CALL refresh()`, false},
+		{``, false},
+	} {
+		actual := asynql.IsReadQuery(v.query)
+		if actual != v.expected {
+			t.Errorf(`isReadQuery(%#v) => %#v; want %#v`, v.query, actual, v.expected)
+		}
+	}
+}
+
+func TestReadTables(t *testing.T) {
+	for _, v := range []struct {
+		query    string
+		expected []string
+	}{
+		{`SELECT * FROM users`, []string{"users"}},
+		{`SELECT * FROM users u, "Orders" AS o WHERE u.id = o.user_id`, []string{"users", "orders"}},
+		{`SELECT * FROM a JOIN b ON a.id = b.id LEFT JOIN c USING (id)`, []string{"a", "b", "c"}},
+		{`SELECT * FROM (SELECT * FROM inner_table) AS t`, []string{"inner_table"}},
+		{`SELECT 'FROM x'`, nil},
+	} {
+		actual := asynql.ReadTables(v.query)
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`readTables(%#v) => %#v; want %#v`, v.query, actual, v.expected)
+		}
+	}
+}
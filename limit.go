@@ -0,0 +1,99 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrResultTooLarge is reported by the helpers that read rows into memory, such as Materialize, ExecReturning and QueryMapKV,
+// when the rows exceed the limit of WithMaxResultSize or WithResultLimit.
+var ErrResultTooLarge = errors.New("asynql: result is too large")
+
+// resultLimit is the maximum number of the rows and the maximum total size of their values. Zero means no limit.
+type resultLimit struct {
+	rows  int64
+	bytes int64
+}
+
+type resultLimitKey struct{}
+
+// WithMaxResultSize returns an Option that limits the rows that the helpers read into memory from a Query of the DB
+// and its transactions, connections and statements to maxRows rows and maxBytes bytes in total,
+// so that an unexpectedly large result fails with ErrResultTooLarge instead of consuming gigabytes of memory.
+// The size of a row is the total length of its strings and byte slices plus the size of its other values.
+// Zero means no limit. The rows iterated by Next are not limited, since they are not kept in memory.
+func WithMaxResultSize(maxRows int, maxBytes int64) Option {
+	return func(db *DB) {
+		db.resultLimit = resultLimit{rows: int64(maxRows), bytes: maxBytes}
+	}
+}
+
+// WithResultLimit returns a copy of ctx that limits the rows of the queries run with it likewise WithMaxResultSize,
+// in place of the limit of the DB.
+func WithResultLimit(ctx context.Context, maxRows int, maxBytes int64) context.Context {
+	return context.WithValue(ctx, resultLimitKey{}, resultLimit{rows: int64(maxRows), bytes: maxBytes})
+}
+
+// resultLimitOf returns the limit of the rows of the queries of db run with ctx.
+func (db *DB) resultLimitOf(ctx context.Context) resultLimit {
+	if l, ok := ctx.Value(resultLimitKey{}).(resultLimit); ok {
+		return l
+	}
+	if db == nil {
+		return resultLimit{}
+	}
+	return db.resultLimit
+}
+
+// limited reports whether the rows that are read into memory from rs are limited.
+func (rs *Rows) limited() bool {
+	return rs.limit != (resultLimit{})
+}
+
+// account counts a row of size bytes that is read into memory, and returns ErrResultTooLarge if the rows exceed the limit of rs.
+func (rs *Rows) account(size int64) error {
+	rs.read.rows++
+	rs.read.bytes += size
+	if rs.limit.rows > 0 && rs.read.rows > rs.limit.rows || rs.limit.bytes > 0 && rs.read.bytes > rs.limit.bytes {
+		return ErrResultTooLarge
+	}
+	return nil
+}
+
+// sizeOf returns the size of v in a row: the length of a string or a byte slice, or the sum of the sizes of the fields of a struct,
+// or the size of the type otherwise.
+func sizeOf(v interface{}) int64 {
+	if v == nil {
+		return 0
+	}
+	return sizeOfValue(reflect.ValueOf(v))
+}
+
+func sizeOfValue(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return int64(v.Len())
+		}
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return sizeOfValue(v.Elem())
+	case reflect.Struct:
+		var n int64
+		for i := 0; i < v.NumField(); i++ {
+			// The unexported fields, such as those of time.Time, are not of the row but of the type.
+			if !v.Type().Field(i).IsExported() {
+				n += int64(v.Field(i).Type().Size())
+				continue
+			}
+			n += sizeOfValue(v.Field(i))
+		}
+		return n
+	}
+	return int64(v.Type().Size())
+}
@@ -0,0 +1,91 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithMaxResultSize(t *testing.T) {
+	ctx := context.Background()
+	query := `SELECT name FROM test_table ORDER BY id`
+	t.Run("rows", func(t *testing.T) {
+		db := newTestDB(t, asynql.WithMaxResultSize(1, 0))
+		defer db.Close()
+		_, err := asynql.Materialize(<-db.QueryContext(ctx, query))
+		if !errors.Is(err, asynql.ErrResultTooLarge) {
+			t.Errorf(`asynql.Materialize(db.QueryContext(ctx, %#v)) => %#v; want %#v`, query, err, asynql.ErrResultTooLarge)
+		}
+		r := <-asynql.ExecReturning[string](ctx, db, query)
+		if err := r.Err(); !errors.Is(err, asynql.ErrResultTooLarge) {
+			t.Errorf(`asynql.ExecReturning[string](ctx, db, %#v).Err() => %#v; want %#v`, query, err, asynql.ErrResultTooLarge)
+		}
+		m := <-asynql.QueryMapKV[int64, string](ctx, db, `SELECT id, name FROM test_table`)
+		if err := m.Err(); !errors.Is(err, asynql.ErrResultTooLarge) {
+			t.Errorf(`asynql.QueryMapKV[int64, string](ctx, db, ...).Err() => %#v; want %#v`, err, asynql.ErrResultTooLarge)
+		}
+		rs := <-db.QueryContext(ctx, query)
+		if err := rs.Err(); err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = scanNames(t, rs)
+		var expected interface{} = []string{"alice", "bob"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`db.QueryContext(ctx, %#v) => %#v; want %#v`, query, actual, expected)
+		}
+	})
+	t.Run("bytes", func(t *testing.T) {
+		db := newTestDB(t, asynql.WithMaxResultSize(0, 7))
+		defer db.Close()
+		r := <-asynql.ExecReturning[string](ctx, db, query)
+		if err := r.Err(); !errors.Is(err, asynql.ErrResultTooLarge) {
+			t.Errorf(`asynql.ExecReturning[string](ctx, db, %#v).Err() => %#v; want %#v`, query, err, asynql.ErrResultTooLarge)
+		}
+		r = <-asynql.ExecReturning[string](ctx, db, query+` LIMIT 1`)
+		if err := r.Err(); err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = r.Values
+		var expected interface{} = []string{"alice"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`asynql.ExecReturning[string](ctx, db, %#v).Values => %#v; want %#v`, query+` LIMIT 1`, actual, expected)
+		}
+	})
+	t.Run("unlimited", func(t *testing.T) {
+		db := newTestDB(t)
+		defer db.Close()
+		snap, err := asynql.Materialize(<-db.QueryContext(ctx, query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = len(snap.Values)
+		var expected interface{} = 2
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`len(asynql.Materialize(db.QueryContext(ctx, %#v)).Values) => %#v; want %#v`, query, actual, expected)
+		}
+	})
+}
+
+func TestWithResultLimit(t *testing.T) {
+	db := newTestDB(t, asynql.WithMaxResultSize(1, 0))
+	defer db.Close()
+	query := `SELECT name FROM test_table ORDER BY id`
+	ctx := asynql.WithResultLimit(context.Background(), 2, 0)
+	r := <-asynql.ExecReturning[string](ctx, db, query)
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = r.Values
+	var expected interface{} = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.ExecReturning[string](ctx, db, %#v).Values => %#v; want %#v`, query, actual, expected)
+	}
+	ctx = asynql.WithResultLimit(context.Background(), 0, 4)
+	r = <-asynql.ExecReturning[string](ctx, db, query)
+	if err := r.Err(); !errors.Is(err, asynql.ErrResultTooLarge) {
+		t.Errorf(`asynql.ExecReturning[string](ctx, db, %#v).Err() => %#v; want %#v`, query, err, asynql.ErrResultTooLarge)
+	}
+}
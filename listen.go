@@ -0,0 +1,159 @@
+package asynql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrNotificationUnsupported is returned by Listen when the driver cannot wait for notifications.
+var ErrNotificationUnsupported = errors.New("asynql: driver does not support notifications")
+
+// Notification represents a notification received by Listen.
+type Notification struct {
+	// PID is the process ID of the server session that sent the notification.
+	PID uint32
+
+	// Channel is the name of the channel that the notification was sent to.
+	Channel string
+
+	// Payload is the payload of the notification.
+	Payload string
+
+	err error
+}
+
+// Err returns an error.
+func (n *Notification) Err() error {
+	return n.err
+}
+
+// NotificationWaiter is implemented by the driver connections that can receive notifications.
+//
+// The connections of github.com/jackc/pgx/v4/stdlib and github.com/jackc/pgx/v5/stdlib are supported
+// without implementing NotificationWaiter.
+type NotificationWaiter interface {
+	// WaitForNotification blocks until a notification is received or ctx is done.
+	WaitForNotification(ctx context.Context) (*Notification, error)
+}
+
+// Listen executes LISTEN on a dedicated connection, and then sends the notifications to channel on the returned channel.
+// channel is quoted as an identifier, so it is case-sensitive.
+// If receiving fails, a Notification with an error is sent and the channel is closed.
+// The channel is also closed when ctx is done, and the dedicated connection is discarded.
+func (db *DB) Listen(ctx context.Context, channel string) (<-chan *Notification, error) {
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Raw(func(dc interface{}) error {
+		if _, ok := notificationWaiterOf(dc); !ok {
+			return ErrNotificationUnsupported
+		}
+		return nil
+	}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `LISTEN "`+strings.ReplaceAll(channel, `"`, `""`)+`"`); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ch := make(chan *Notification)
+	go func() {
+		defer close(ch)
+		defer conn.Close()
+		conn.Raw(func(dc interface{}) error {
+			w, _ := notificationWaiterOf(dc)
+			for {
+				n, err := w.WaitForNotification(ctx)
+				if ctx.Err() != nil {
+					break
+				}
+				if err != nil {
+					n = &Notification{err: err}
+				}
+				select {
+				case ch <- n:
+				case <-ctx.Done():
+				}
+				if err != nil || ctx.Err() != nil {
+					break
+				}
+			}
+			// The connection is still listening, so it must not go back to the pool.
+			return driver.ErrBadConn
+		})
+	}()
+	return ch, nil
+}
+
+// notificationWaiterOf returns a NotificationWaiter that receives the notifications of the driver connection dc.
+func notificationWaiterOf(dc interface{}) (NotificationWaiter, bool) {
+	if c, ok := dc.(*locConn); ok {
+		dc = c.connWrapper
+	}
+	if c, ok := dc.(*connWrapper); ok {
+		dc = c.Conn
+	}
+	if w, ok := dc.(NotificationWaiter); ok {
+		return w, true
+	}
+	return pgxWaiterOf(dc)
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// pgxWaiterOf returns a NotificationWaiter for a connection of the stdlib package of pgx,
+// whose Conn method returns a *pgx.Conn that has the WaitForNotification method.
+// Reflection is used to avoid depending on pgx.
+func pgxWaiterOf(dc interface{}) (NotificationWaiter, bool) {
+	m := reflect.ValueOf(dc).MethodByName("Conn")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil, false
+	}
+	conn := m.Call(nil)[0]
+	wait := conn.MethodByName("WaitForNotification")
+	if !wait.IsValid() {
+		return nil, false
+	}
+	t := wait.Type()
+	if t.NumIn() != 1 || t.In(0) != contextType || t.NumOut() != 2 || t.Out(1) != errorType {
+		return nil, false
+	}
+	if n := t.Out(0); n.Kind() != reflect.Ptr || n.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	return pgxWaiter{wait: wait}, true
+}
+
+type pgxWaiter struct {
+	wait reflect.Value
+}
+
+func (w pgxWaiter) WaitForNotification(ctx context.Context) (*Notification, error) {
+	out := w.wait.Call([]reflect.Value{reflect.ValueOf(&ctx).Elem()})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return nil, err
+	}
+	if out[0].IsNil() {
+		return nil, errors.New("asynql: driver returned no notification")
+	}
+	v := out[0].Elem()
+	n := &Notification{}
+	if f := v.FieldByName("PID"); f.IsValid() && f.CanUint() {
+		n.PID = uint32(f.Uint())
+	}
+	if f := v.FieldByName("Channel"); f.IsValid() && f.Kind() == reflect.String {
+		n.Channel = f.String()
+	}
+	if f := v.FieldByName("Payload"); f.IsValid() && f.Kind() == reflect.String {
+		n.Payload = f.String()
+	}
+	return n, nil
+}
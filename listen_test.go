@@ -0,0 +1,119 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// notifyConn is a driver.Conn that delivers the notifications sent on its channel.
+type notifyConn struct {
+	notifications chan *asynql.Notification
+	listened      chan string
+}
+
+func (c *notifyConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *notifyConn) Close() error                        { return nil }
+func (c *notifyConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *notifyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.listened <- query
+	return driver.RowsAffected(0), nil
+}
+
+func (c *notifyConn) WaitForNotification(ctx context.Context) (*asynql.Notification, error) {
+	select {
+	case n := <-c.notifications:
+		return n, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type notifyConnector struct {
+	conn driver.Conn
+}
+
+func (c *notifyConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c *notifyConnector) Driver() driver.Driver                        { return nil }
+
+// pgxLikeConn mimics the connection of the stdlib package of pgx.
+type pgxLikeConn struct {
+	*notifyConn
+}
+
+func (c pgxLikeConn) Conn() *pgxLikeConnImpl {
+	return &pgxLikeConnImpl{c.notifyConn}
+}
+
+type pgxLikeConnImpl struct {
+	c *notifyConn
+}
+
+type pgxLikeNotification struct {
+	PID     uint32
+	Channel string
+	Payload string
+}
+
+func (c *pgxLikeConnImpl) WaitForNotification(ctx context.Context) (*pgxLikeNotification, error) {
+	n, err := c.c.WaitForNotification(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pgxLikeNotification{PID: n.PID, Channel: n.Channel, Payload: n.Payload}, nil
+}
+
+func TestDB_Listen(t *testing.T) {
+	for _, wrap := range []func(*notifyConn) driver.Conn{
+		func(c *notifyConn) driver.Conn { return c },
+		func(c *notifyConn) driver.Conn { return pgxLikeConn{c} },
+	} {
+		nc := &notifyConn{
+			notifications: make(chan *asynql.Notification, 1),
+			listened:      make(chan string, 1),
+		}
+		conn := wrap(nc)
+		db := asynql.OpenDB(&notifyConnector{conn: conn})
+		ctx, cancel := context.WithCancel(context.Background())
+		ch, err := db.Listen(ctx, `my"channel`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = <-nc.listened
+		var expected interface{} = `LISTEN "my""channel"`
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%T: db.Listen(ctx, "my\"channel") executed %#v; want %#v`, conn, actual, expected)
+		}
+		nc.notifications <- &asynql.Notification{PID: 42, Channel: `my"channel`, Payload: "hello"}
+		n := <-ch
+		if err := n.Err(); err != nil {
+			t.Fatal(err)
+		}
+		actual = []interface{}{n.PID, n.Channel, n.Payload}
+		expected = []interface{}{uint32(42), `my"channel`, "hello"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%T: <-db.Listen(ctx, "my\"channel") => %#v; want %#v`, conn, actual, expected)
+		}
+		cancel()
+		if n, ok := <-ch; ok {
+			t.Errorf(`%T: <-db.Listen(ctx, "my\"channel") after cancel => %#v; want closed`, conn, n)
+		}
+		db.Close()
+	}
+}
+
+func TestDB_Listen_Unsupported(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	_, err := db.Listen(context.Background(), "test")
+	var actual interface{} = err
+	var expected interface{} = asynql.ErrNotificationUnsupported
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Listen(ctx, "test") => _, %#v; want %#v`, actual, expected)
+	}
+}
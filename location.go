@@ -0,0 +1,243 @@
+package asynql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// WithLocation returns an Option that converts the time.Time values scanned from the database into loc,
+// and the time.Time arguments into loc before they are sent to the database.
+// It is useful for the drivers and the column types that don't keep the location, e.g. TIMESTAMP WITHOUT TIME ZONE,
+// DATETIME of MySQL and the times stored as text in SQLite, which are otherwise read in a location chosen by the driver.
+// A time is converted by time.Time.In, so it represents the same instant in loc.
+// WithLocation has no effect on New because the pool of the given *sql.DB already exists.
+//
+// ScanStruct converts the time.Time fields tagged with `db:"name,loc=Asia/Tokyo"` into the given location, regardless of WithLocation.
+func WithLocation(loc *time.Location) Option {
+	return func(db *DB) {
+		db.location = loc
+	}
+}
+
+// locConnector converts the times of the connections of Connector into loc.
+type locConnector struct {
+	driver.Connector
+
+	loc *time.Location
+}
+
+func (c *locConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &locConn{
+		connWrapper: wrapConn(dc),
+		loc:         c.loc,
+	}, nil
+}
+
+// locConn converts the time.Time arguments and the values of the rows into loc.
+type locConn struct {
+	*connWrapper
+
+	loc *time.Location
+}
+
+func (c *locConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.connWrapper.ExecContext(ctx, query, timeArgsIn(args, c.loc))
+}
+
+func (c *locConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.connWrapper.QueryContext(ctx, query, timeArgsIn(args, c.loc))
+	if err != nil {
+		return nil, err
+	}
+	return &locRows{Rows: rows, loc: c.loc}, nil
+}
+
+func (c *locConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.connWrapper.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &locStmt{Stmt: stmt, loc: c.loc}, nil
+}
+
+func (c *locConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// locStmt converts the time.Time arguments and the values of the rows of Stmt into loc.
+type locStmt struct {
+	driver.Stmt
+
+	loc *time.Location
+}
+
+func (s *locStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	args = timeArgsIn(args, s.loc)
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	values, err := namedValuesToValues(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values)
+}
+
+func (s *locStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	args = timeArgsIn(args, s.loc)
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			if err = ctx.Err(); err == nil {
+				rows, err = s.Stmt.Query(values)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &locRows{Rows: rows, loc: s.loc}, nil
+}
+
+func (s *locStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (s *locStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if c, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return c.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+// locRows converts the time.Time values of Rows into loc.
+type locRows struct {
+	driver.Rows
+
+	loc *time.Location
+}
+
+func (r *locRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for i, v := range dest {
+		if t, ok := v.(time.Time); ok {
+			dest[i] = t.In(r.loc)
+		}
+	}
+	return nil
+}
+
+func (r *locRows) HasNextResultSet() bool {
+	if m, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return m.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *locRows) NextResultSet() error {
+	if m, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return m.NextResultSet()
+	}
+	return fmt.Errorf("asynql: driver does not support multiple result sets")
+}
+
+func (r *locRows) ColumnTypeScanType(index int) reflect.Type {
+	if c, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return c.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *locRows) ColumnTypeDatabaseTypeName(index int) string {
+	if c, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return c.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *locRows) ColumnTypeLength(index int) (int64, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return c.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *locRows) ColumnTypeNullable(index int) (bool, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return c.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *locRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if c, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return c.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// timeArgsIn returns args whose time.Time values are converted into loc.
+// args is copied only if it has a time.Time value.
+func timeArgsIn(args []driver.NamedValue, loc *time.Location) []driver.NamedValue {
+	copied := false
+	for i, arg := range args {
+		t, ok := arg.Value.(time.Time)
+		if !ok {
+			continue
+		}
+		if !copied {
+			args = append([]driver.NamedValue(nil), args...)
+			copied = true
+		}
+		args[i].Value = t.In(loc)
+	}
+	return args
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("asynql: driver does not support the use of named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// timeField is a sql.Scanner that converts a time.Time into loc for the field of a struct tagged with `db:"name,loc=..."`.
+type timeField struct {
+	dest *time.Time
+	loc  *time.Location
+}
+
+func (f timeField) Scan(src interface{}) error {
+	var v Null[time.Time]
+	if err := v.Scan(src); err != nil {
+		return err
+	}
+	*f.dest = v.V
+	if v.Valid {
+		*f.dest = v.V.In(f.loc)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip(err)
+	}
+	db, err := asynql.Open("sqlite3", ":memory:", asynql.WithLocation(tokyo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE events (id INTEGER, at DATETIME)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := (<-db.ExecContext(ctx, `INSERT INTO events VALUES (1, ?)`, at)).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The time is sent in the location, which go-sqlite3 stores as it is.
+	var raw string
+	if err := (<-db.QueryRowContext(ctx, `SELECT CAST(at AS TEXT) FROM events`)).Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "2024-01-02 12:04:05+09:00"; raw != expected {
+		t.Errorf(`stored time => %#v; want %#v`, raw, expected)
+	}
+
+	stmt, err := db.Prepare(`SELECT at FROM events WHERE at = ?`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	for _, query := range []func() <-chan *asynql.Row{
+		func() <-chan *asynql.Row { return db.QueryRowContext(ctx, `SELECT at FROM events`) },
+		func() <-chan *asynql.Row { return stmt.QueryRow(at) },
+	} {
+		var scanned time.Time
+		if err := (<-query()).Scan(&scanned); err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = []interface{}{scanned.Location(), scanned.Equal(at)}
+		var expected interface{} = []interface{}{tokyo, true}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`scanned time location, equality => %#v; want %#v`, actual, expected)
+		}
+	}
+}
+
+func TestRows_ScanStruct_Location(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("X", 3600))
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE events (id INTEGER, at DATETIME)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-db.ExecContext(ctx, `INSERT INTO events VALUES (1, ?), (2, NULL)`, at)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	type event struct {
+		ID int
+		At *time.Time `db:"at,loc=UTC"`
+	}
+	rows := <-db.QueryContext(ctx, `SELECT id, at FROM events ORDER BY id`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var actual []interface{}
+	for rows.Next() {
+		var e event
+		if err := rows.ScanStruct(&e); err != nil {
+			t.Fatal(err)
+		}
+		if e.At == nil {
+			actual = append(actual, nil)
+			continue
+		}
+		actual = append(actual, e.At.Location().String(), e.At.Equal(at))
+	}
+	expected := []interface{}{"UTC", true, nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`rows.ScanStruct(&event{}) => %#v; want %#v`, actual, expected)
+	}
+
+	type invalid struct {
+		At time.Time `db:"at,loc=Nowhere/Invalid"`
+	}
+	rows = <-db.QueryContext(ctx, `SELECT at FROM events`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	rows.Next()
+	if err := rows.ScanStruct(&invalid{}); err == nil {
+		t.Errorf(`rows.ScanStruct(&invalid{}) => nil; want error`)
+	}
+}
@@ -0,0 +1,27 @@
+package asynql
+
+import (
+	"errors"
+)
+
+// ErrMaintenance is reported by the writes while the DB is in maintenance mode.
+var ErrMaintenance = errors.New("asynql: database is in maintenance mode")
+
+// SetMaintenance turns the maintenance mode of the DB on or off at runtime.
+// In maintenance mode, every Exec of the DB and its transactions, connections and statements,
+// and every Query and QueryRow that modifies a table such as INSERT ... RETURNING, fail with ErrMaintenance without running,
+// while the other queries continue, so that the writes can be drained before a maintenance window without a redeployment.
+// The operations that are waiting to run when the mode is turned on are rejected likewise, but the running ones are not affected.
+func (db *DB) SetMaintenance(on bool) {
+	db.maintenance.Store(on)
+}
+
+// InMaintenance reports whether the DB is in maintenance mode.
+func (db *DB) InMaintenance() bool {
+	return db.maintenance.Load()
+}
+
+// rejectsWrite reports whether db is in maintenance mode and the operation op of query writes.
+func (db *DB) rejectsWrite(op HookOp, query string) bool {
+	return db != nil && db.maintenance.Load() && (op == HookExec || len(writtenTables(query)) > 0)
+}
@@ -0,0 +1,53 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_SetMaintenance(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	db.SetMaintenance(true)
+	if !db.InMaintenance() {
+		t.Fatalf(`db.InMaintenance() after db.SetMaintenance(true) => false; want true`)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		name string
+		err  error
+	}{
+		{"db.ExecContext", (<-db.ExecContext(ctx, `UPDATE test_table SET name = ? WHERE id = 1`, "carol")).Err()},
+		{"tx.ExecContext", (<-tx.ExecContext(ctx, `DELETE FROM test_table`)).Err()},
+		{"db.QueryContext", (<-db.QueryContext(ctx, `DELETE FROM test_table RETURNING id`)).Err()},
+	} {
+		if !errors.Is(v.err, asynql.ErrMaintenance) {
+			t.Errorf(`%s(ctx, ...) in maintenance mode => %#v; want %#v`, v.name, v.err, asynql.ErrMaintenance)
+		}
+	}
+	var name string
+	if err := (<-tx.QueryRowContext(ctx, `SELECT name FROM test_table WHERE id = 1`)).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = scanNames(t, <-db.QueryContext(ctx, `SELECT name FROM test_table ORDER BY id`))
+	var expected interface{} = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryContext(ctx, ...) in maintenance mode => %#v; want %#v`, actual, expected)
+	}
+
+	db.SetMaintenance(false)
+	if err := (<-db.ExecContext(ctx, `UPDATE test_table SET name = ? WHERE id = 1`, "carol")).Err(); err != nil {
+		t.Fatalf(`db.ExecContext(ctx, ...) after db.SetMaintenance(false) => %#v; want nil`, err)
+	}
+}
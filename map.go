@@ -0,0 +1,78 @@
+package asynql
+
+import (
+	"context"
+)
+
+// MapResult represents a result of QueryMapKV and QueryMapFunc.
+type MapResult[K comparable, V any] struct {
+	Map map[K]V
+
+	err error
+}
+
+// Err returns an error.
+func (r *MapResult[K, V]) Err() error {
+	return r.err
+}
+
+// QueryMapKV executes a query that returns two columns and then sends a map from the values of the first column to the values of the second on the returned channel.
+// If several rows have the same key, the last one wins.
+func QueryMapKV[K comparable, V any](ctx context.Context, q Queryer, query string, args ...interface{}) <-chan *MapResult[K, V] {
+	return queryMap(ctx, q, query, args, func(rs *Rows) (K, V, error) {
+		var k K
+		var v V
+		err := rs.Scan(&k, &v)
+		return k, v, err
+	})
+}
+
+// QueryMapFunc executes a query and then sends a map of the scanned rows on the returned channel.
+// Each row is scanned into a V, by ScanStruct if V is a struct, and is keyed by keyFn.
+// If several rows have the same key, the last one wins.
+func QueryMapFunc[K comparable, V any](ctx context.Context, q Queryer, keyFn func(V) K, query string, args ...interface{}) <-chan *MapResult[K, V] {
+	return queryMap(ctx, q, query, args, func(rs *Rows) (K, V, error) {
+		var v V
+		if err := scanValue(rs, &v); err != nil {
+			var k K
+			return k, v, err
+		}
+		return keyFn(v), v, nil
+	})
+}
+
+func queryMap[K comparable, V any](ctx context.Context, q Queryer, query string, args []interface{}, scan func(*Rows) (K, V, error)) <-chan *MapResult[K, V] {
+	ch := make(chan *MapResult[K, V])
+	go func() {
+		m, err := materializeMap(<-q.QueryContext(ctx, query, args...), scan)
+		deliver(q, ch, &MapResult[K, V]{
+			Map: m,
+			err: err,
+		})
+	}()
+	return ch
+}
+
+func materializeMap[K comparable, V any](rs *Rows, scan func(*Rows) (K, V, error)) (map[K]V, error) {
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	m := make(map[K]V)
+	for rs.Next() {
+		k, v, err := scan(rs)
+		if err != nil {
+			return nil, err
+		}
+		if rs.limited() {
+			if err := rs.account(sizeOf(k) + sizeOf(v)); err != nil {
+				return nil, err
+			}
+		}
+		m[k] = v
+	}
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
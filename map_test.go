@@ -0,0 +1,53 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestQueryMapKV(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT id, name FROM test_table`
+	result := <-asynql.QueryMapKV[int, string](context.Background(), db, query)
+	if err := result.Err(); err != nil {
+		t.Fatalf(`asynql.QueryMapKV(ctx, db, %#v); MapResult.Err() => %#v; want nil`, query, err)
+	}
+	actual := result.Map
+	expected := map[int]string{1: "alice", 2: "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.QueryMapKV(ctx, db, %#v) => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestQueryMapKV_Error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT id, name FROM unknown_table`
+	result := <-asynql.QueryMapKV[int, string](context.Background(), db, query)
+	if result.Err() == nil {
+		t.Errorf(`asynql.QueryMapKV(ctx, db, %#v); MapResult.Err() => nil; want error`, query)
+	}
+}
+
+func TestQueryMapFunc(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	type user struct {
+		ID   int
+		Name string `db:"name"`
+	}
+	query := `SELECT id, name FROM test_table`
+	result := <-asynql.QueryMapFunc(context.Background(), db, func(u user) string { return u.Name }, query)
+	if err := result.Err(); err != nil {
+		t.Fatalf(`asynql.QueryMapFunc(ctx, db, keyFn, %#v); MapResult.Err() => %#v; want nil`, query, err)
+	}
+	actual := result.Map
+	expected := map[string]user{"alice": {1, "alice"}, "bob": {2, "bob"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.QueryMapFunc(ctx, db, keyFn, %#v) => %#v; want %#v`, query, actual, expected)
+	}
+}
@@ -0,0 +1,76 @@
+package asynql
+
+import (
+	"context"
+)
+
+// ResultSet represents a result set of QueryMulti.
+// The rows of a result set are read into memory, so it doesn't hold a connection.
+type ResultSet struct {
+	*Rows
+
+	// Index is the index of the result set, counting from 0.
+	Index int
+
+	// Len is the number of the rows in the result set.
+	Len int
+
+	err error
+}
+
+// Err returns an error.
+func (r *ResultSet) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
+
+// QueryMulti executes query with args, which returns several result sets such as the multiple statements of MySQL
+// or a stored procedure, and then sends the result sets on the returned channel in order.
+// Each result set is read into memory before it is sent, and the next one is read after it has been received.
+// If the query or reading a result set fails, a ResultSet with the error is sent.
+// The channel is closed after the last result set or the error, or when ctx is done.
+//
+// The Rows of the other methods also support multiple result sets by NextResultSet, but hold the connection until they are closed.
+func (db *DB) QueryMulti(ctx context.Context, query string, args ...interface{}) <-chan *ResultSet {
+	ch := make(chan *ResultSet)
+	go func() {
+		defer close(ch)
+		send := func(set *ResultSet) bool {
+			select {
+			case ch <- set:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		rs := <-db.QueryContext(ctx, query, args...)
+		if err := rs.Err(); err != nil {
+			send(&ResultSet{err: err})
+			return
+		}
+		defer rs.Close()
+		for i := 0; ; i++ {
+			snap, err := readResultSet(rs)
+			set := &ResultSet{
+				Index: i,
+				err:   err,
+			}
+			if err == nil {
+				set.Rows = snap.Rows()
+				set.Len = len(snap.Values)
+			}
+			if !send(set) || err != nil {
+				return
+			}
+			if !rs.NextResultSet() {
+				if err := rs.Err(); err != nil {
+					send(&ResultSet{Index: i + 1, err: err})
+				}
+				return
+			}
+		}
+	}()
+	return ch
+}
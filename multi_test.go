@@ -0,0 +1,93 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// multiConn returns sets as the result sets of any query.
+type multiConn struct {
+	sets [][]driver.Value
+}
+
+func (c *multiConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *multiConn) Close() error                        { return nil }
+func (c *multiConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *multiConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query == "fail" {
+		return nil, errors.New("failed")
+	}
+	return &multiRows{sets: c.sets}, nil
+}
+
+// multiRows has a column "v" and a row for each value of the current set.
+type multiRows struct {
+	sets [][]driver.Value
+	set  int
+	row  int
+}
+
+func (r *multiRows) Columns() []string      { return []string{"v"} }
+func (r *multiRows) Close() error           { return nil }
+func (r *multiRows) HasNextResultSet() bool { return r.set+1 < len(r.sets) }
+
+func (r *multiRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.set, r.row = r.set+1, 0
+	return nil
+}
+
+func (r *multiRows) Next(dest []driver.Value) error {
+	if r.row >= len(r.sets[r.set]) {
+		return io.EOF
+	}
+	dest[0] = r.sets[r.set][r.row]
+	r.row++
+	return nil
+}
+
+func TestDB_QueryMulti(t *testing.T) {
+	conn := &multiConn{sets: [][]driver.Value{{int64(1), int64(2)}, {}, {"a"}}}
+	db := asynql.OpenDB(&notifyConnector{conn: conn})
+	defer db.Close()
+	var actual [][]interface{}
+	for set := range db.QueryMulti(context.Background(), "CALL p()") {
+		snap, err := asynql.Materialize(set.Rows)
+		if err != nil || set.Err() != nil {
+			t.Fatal(err, set.Err())
+		}
+		var values []interface{}
+		for _, row := range snap.Values {
+			values = append(values, row[0])
+		}
+		actual = append(actual, []interface{}{set.Index, set.Len, values})
+	}
+	expected := [][]interface{}{
+		{0, 2, []interface{}{int64(1), int64(2)}},
+		{1, 0, []interface{}(nil)},
+		{2, 1, []interface{}{"a"}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryMulti(ctx, "CALL p()") => %#v; want %#v`, actual, expected)
+	}
+
+	var n int
+	for set := range db.QueryMulti(context.Background(), "fail") {
+		n++
+		if set.Err() == nil {
+			t.Errorf(`db.QueryMulti(ctx, "fail").Err() => nil; want error`)
+		}
+	}
+	if n != 1 {
+		t.Errorf(`len(db.QueryMulti(ctx, "fail")) => %#v; want 1`, n)
+	}
+}
@@ -0,0 +1,66 @@
+package asynql
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NamedArgs converts args into the arguments of sql.NamedArg sorted by name, which can be passed to the methods of DB as they are:
+//
+//	db.Query(`SELECT * FROM users WHERE name = @name AND age > @age`, asynql.NamedArgs(filters)...)
+//
+// The sorted order makes the arguments, and therefore the keys of the cache and singleflight, deterministic.
+// The driver must support named arguments; otherwise use Dialect.BindNamed.
+func NamedArgs(args map[string]interface{}) []interface{} {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	named := make([]interface{}, len(names))
+	for i, name := range names {
+		named[i] = sql.Named(name, args[name])
+	}
+	return named
+}
+
+// BindNamed rewrites the named parameters such as :name and @name in query into the placeholders of d,
+// and returns the rewritten query and the positional arguments taken from args.
+// It is useful for the drivers that don't support named arguments.
+// A parameter that appears several times is bound to the same placeholder for DialectPostgres, and is repeated for the others.
+// It is an error if a :name parameter is missing in args, while an @name parameter that is missing in args is left as it is,
+// since it may be a user variable of MySQL.
+// The parameters in string literals, quoted identifiers and comments are left as they are.
+func (d Dialect) BindNamed(query string, args map[string]interface{}) (string, []interface{}, error) {
+	var b strings.Builder
+	var bound []interface{}
+	positions := make(map[string]int)
+	last := 0
+	for _, t := range lexSQL(query) {
+		if t.kind != tokParam || (t.text[0] != ':' && t.text[0] != '@') {
+			continue
+		}
+		name := t.text[1:]
+		v, ok := args[name]
+		if !ok {
+			if t.text[0] == '@' {
+				continue
+			}
+			return "", nil, fmt.Errorf("asynql: missing argument for %s", t.text)
+		}
+		b.WriteString(query[last:t.pos])
+		last = t.end
+		if n, ok := positions[name]; ok && d == DialectPostgres {
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		bound = append(bound, v)
+		positions[name] = len(bound)
+		b.WriteString(d.Placeholder(len(bound)))
+	}
+	b.WriteString(query[last:])
+	return b.String(), bound, nil
+}
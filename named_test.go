@@ -0,0 +1,59 @@
+package asynql_test
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestNamedArgs(t *testing.T) {
+	actual := asynql.NamedArgs(map[string]interface{}{"name": "alice", "age": 20, "id": 1})
+	expected := []interface{}{sql.Named("age", 20), sql.Named("id", 1), sql.Named("name", "alice")}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`NamedArgs(...) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDialect_BindNamed(t *testing.T) {
+	args := map[string]interface{}{"name": "alice", "id": 1}
+	for _, v := range []struct {
+		dialect  asynql.Dialect
+		query    string
+		expected []interface{}
+	}{
+		{asynql.DialectPostgres, `SELECT * FROM t WHERE id = :id AND (name = :name OR alias = :name) AND s = ':id'`,
+			[]interface{}{`SELECT * FROM t WHERE id = $1 AND (name = $2 OR alias = $2) AND s = ':id'`, []interface{}{1, "alice"}}},
+		{asynql.DialectMySQL, `SELECT * FROM t WHERE id = @id AND (name = :name OR alias = :name) AND v = @var`,
+			[]interface{}{`SELECT * FROM t WHERE id = ? AND (name = ? OR alias = ?) AND v = @var`, []interface{}{1, "alice", "alice"}}},
+		{asynql.DialectSQLite, `SELECT x::text FROM t`,
+			[]interface{}{`SELECT x::text FROM t`, []interface{}(nil)}},
+	} {
+		query, bound, err := v.dialect.BindNamed(v.query, args)
+		if err != nil {
+			t.Errorf(`%v.BindNamed(%#v, args) => _, _, %#v; want nil`, v.dialect, v.query, err)
+			continue
+		}
+		var actual interface{} = []interface{}{query, bound}
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%v.BindNamed(%#v, args) => %#v; want %#v`, v.dialect, v.query, actual, expected)
+		}
+	}
+	if _, _, err := asynql.DialectSQLite.BindNamed(`SELECT :missing`, args); err == nil {
+		t.Errorf(`BindNamed("SELECT :missing", args) => _, _, nil; want error`)
+	}
+
+	db := newTestDB(t)
+	defer db.Close()
+	query, bound, err := db.Dialect().BindNamed(`SELECT name FROM test_table WHERE id = :id`, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := scanNames(t, <-db.Query(query, bound...))
+	expected := []string{"alice"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Query(BindNamed(...)) => %#v; want %#v`, actual, expected)
+	}
+}
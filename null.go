@@ -0,0 +1,84 @@
+package asynql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+)
+
+// Null is a value of T that may be NULL, which can be scanned and bound like sql.NullString for any T.
+// It is built on sql.Null, so it requires Go 1.22 or later.
+// T is converted from and to the values of the driver in the same way as the destinations of Scan and the arguments.
+//
+// A pointer such as *string is often simpler, which is scanned as nil for NULL by Scan and ScanStruct, and is bound as NULL if nil.
+type Null[T any] struct {
+	V     T
+	Valid bool
+}
+
+// NullFrom returns a Null of *p, which is not valid if p is nil.
+func NullFrom[T any](p *T) Null[T] {
+	if p == nil {
+		return Null[T]{}
+	}
+	return Null[T]{
+		V:     *p,
+		Valid: true,
+	}
+}
+
+// Ptr returns a pointer to a copy of n.V, or nil if n is not valid.
+func (n Null[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	v := n.V
+	return &v
+}
+
+// Scan implements sql.Scanner.
+func (n *Null[T]) Scan(src interface{}) error {
+	var v sql.Null[T]
+	if err := v.Scan(src); err != nil {
+		return err
+	}
+	n.V, n.Valid = v.V, v.Valid
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (n Null[T]) Value() (driver.Value, error) {
+	return sql.Null[T]{V: n.V, Valid: n.Valid}.Value()
+}
+
+// nullableField is a sql.Scanner that sets nil to a pointer field of a struct for NULL,
+// and otherwise sets a pointer to a new value that is scanned by the sql.Scanner made by scanner.
+type nullableField struct {
+	dest    reflect.Value
+	scanner func(dest interface{}) sql.Scanner
+}
+
+func (f nullableField) Scan(src interface{}) error {
+	if src == nil {
+		f.dest.SetZero()
+		return nil
+	}
+	p := reflect.New(f.dest.Type().Elem())
+	if err := f.scanner(p.Interface()).Scan(src); err != nil {
+		return err
+	}
+	f.dest.Set(p)
+	return nil
+}
+
+// fieldScanner returns the destination of Scan that scans into field by scanner,
+// or into a new value that field points to if ptr is true.
+func fieldScanner(field reflect.Value, ptr bool, scanner func(dest interface{}) sql.Scanner) interface{} {
+	if ptr {
+		return nullableField{
+			dest:    field,
+			scanner: scanner,
+		}
+	}
+	return scanner(field.Addr().Interface())
+}
@@ -0,0 +1,90 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestNull(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE people (id INTEGER, nickname TEXT, age INTEGER, born DATETIME)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := (<-db.ExecContext(ctx, `INSERT INTO people VALUES (1, ?, ?, ?), (2, ?, ?, ?)`,
+		asynql.Null[string]{V: "al", Valid: true}, asynql.NullFrom(new(int64)), asynql.Null[time.Time]{V: born, Valid: true},
+		asynql.Null[string]{}, (*int64)(nil), asynql.NullFrom[time.Time](nil))).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var nickname asynql.Null[string]
+	var age asynql.Null[int]
+	var bornAt asynql.Null[time.Time]
+	var actual []interface{}
+	for _, id := range []int{1, 2} {
+		if err := (<-db.QueryRowContext(ctx, `SELECT nickname, age, born FROM people WHERE id = ?`, id)).Scan(&nickname, &age, &bornAt); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, nickname, age, bornAt.Valid && bornAt.V.Equal(born))
+	}
+	expected := []interface{}{
+		asynql.Null[string]{V: "al", Valid: true}, asynql.Null[int]{V: 0, Valid: true}, true,
+		asynql.Null[string]{}, asynql.Null[int]{}, false,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`Scan(&Null[T]{}...) => %#v; want %#v`, actual, expected)
+	}
+	if p := nickname.Ptr(); p != nil {
+		t.Errorf(`Null[string]{}.Ptr() => %#v; want nil`, p)
+	}
+	if p := (asynql.Null[int]{V: 3, Valid: true}).Ptr(); p == nil || *p != 3 {
+		t.Errorf(`Null[int]{3, true}.Ptr() => %#v; want pointer to 3`, p)
+	}
+}
+
+func TestRows_ScanStruct_Nullable(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE things (id INTEGER, name TEXT, tags TEXT, ref BLOB, score REAL)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-db.ExecContext(ctx, `INSERT INTO things VALUES (1, 'a', '["x"]', ?, 1.5), (2, NULL, NULL, NULL, NULL)`,
+		asynql.BinaryUUID(exampleUUID))).Err(); err != nil {
+		t.Fatal(err)
+	}
+	type thing struct {
+		ID    int
+		Name  *string
+		Tags  *[]string
+		Ref   *testUUID
+		Score asynql.Null[float64]
+	}
+	rows := <-db.QueryContext(ctx, `SELECT id, name, tags, ref, score FROM things ORDER BY id`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var actual []thing
+	for rows.Next() {
+		var th thing
+		if err := rows.ScanStruct(&th); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, th)
+	}
+	name, tags, ref := "a", []string{"x"}, exampleUUID
+	expected := []thing{
+		{1, &name, &tags, &ref, asynql.Null[float64]{V: 1.5, Valid: true}},
+		{2, nil, nil, nil, asynql.Null[float64]{}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`rows.ScanStruct(&thing{}) => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,61 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Option configures a DB created by Open, OpenDB or New.
+type Option func(*DB)
+
+// WithConnInit returns an Option that runs fn once on each new connection of the pool,
+// before the connection serves any query.
+// It is useful to set up a session, e.g. `SET search_path TO app` or `PRAGMA foreign_keys = ON`.
+// If fn returns an error, the connection is discarded and the error is reported by the operation that needed the connection.
+// WithConnInit has no effect on New because the pool of the given *sql.DB already exists.
+func WithConnInit(fn func(ctx context.Context, conn *sql.Conn) error) Option {
+	return func(db *DB) {
+		db.connInit = fn
+	}
+}
+
+// WithSingleflight returns an Option that deduplicates concurrent DB.Query calls.
+// While a query is running, Query calls with the same query and arguments don't execute it again,
+// but share its result that is read into memory and is delivered to each of them as a separate *Rows.
+// Each caller stops waiting when its own context is done, but the shared query is canceled only when the contexts of all the callers are done,
+// so that it lasts until the latest deadline among them. The calls limited differently by WithResultLimit are not shared.
+// The queries that modify a table, such as INSERT ... RETURNING, or lock rows by FOR UPDATE or FOR SHARE are never shared.
+func WithSingleflight() Option {
+	return func(db *DB) {
+		db.flight = &flightGroup{}
+	}
+}
+
+// WithFIFO returns an Option that runs the asynchronous operations in the order they were called.
+// The operations of the DB and its statements are run one at a time by a single dispatcher,
+// and the operations of each Tx and Conn are ordered likewise, separately from those of the DB.
+// The results are still delivered on the channels, and an operation doesn't wait for the result of the previous one to be received.
+// It is useful for SQLite with SetMaxOpenConns(1), where the concurrent operations would otherwise interleave in an arbitrary order.
+//
+// Note that a Query is complete when its *Rows is ready, so the following operations of a DB with a single connection
+// cannot run until the rows are closed.
+func WithFIFO() Option {
+	return func(db *DB) {
+		db.fifo = true
+	}
+}
+
+// WithMaxConcurrency returns an Option that limits the number of the asynchronous operations of the DB and its statements
+// that run concurrently to n.
+// The other operations wait in the order of the priorities given by WithPriority.
+// It is unlike SetMaxOpenConns in that the waiting operations are prioritized, and a Query releases its slot when its *Rows is ready.
+// The operations of a Tx or Conn are not limited because they already have a connection.
+// If n <= 0, the number is unlimited.
+func WithMaxConcurrency(n int) Option {
+	return func(db *DB) {
+		db.limiter = nil
+		if n > 0 {
+			db.limiter = newLimiter(n)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithConnInit(t *testing.T) {
+	var calls int32
+	db, err := asynql.Open("sqlite3", ":memory:", asynql.WithConnInit(func(ctx context.Context, conn *sql.Conn) error {
+		atomic.AddInt32(&calls, 1)
+		_, err := conn.ExecContext(ctx, `CREATE TEMP TABLE session_table (id INTEGER)`)
+		return err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for i := 0; i < 2; i++ {
+		query := `SELECT COUNT(*) FROM session_table`
+		var n int
+		if err := (<-db.QueryRow(query)).Scan(&n); err != nil {
+			t.Fatalf(`db.QueryRow(%#v).Scan => %#v; want nil`, query, err)
+		}
+	}
+	var actual interface{} = atomic.LoadInt32(&calls)
+	var expected interface{} = int32(1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`connection initializer calls => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestWithConnInit_Error(t *testing.T) {
+	initErr := errors.New("init failed")
+	db, err := asynql.Open("sqlite3", ":memory:", asynql.WithConnInit(func(ctx context.Context, conn *sql.Conn) error {
+		return initErr
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	result := <-db.Exec(`SELECT 1`)
+	var actual interface{} = errors.Unwrap(result.Err())
+	var expected interface{} = initErr
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Exec("SELECT 1"); Result.Err() => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestWithFIFO(t *testing.T) {
+	db := newTestDB(t, asynql.WithFIFO())
+	defer db.Close()
+	if _, err := db.DB.Exec(`CREATE TABLE log (seq INTEGER PRIMARY KEY AUTOINCREMENT, n INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	var results []<-chan *asynql.Result
+	var expected []int
+	for i := 0; i < 50; i++ {
+		results = append(results, db.Exec(`INSERT INTO log (n) VALUES (?)`, i))
+		expected = append(expected, i)
+	}
+	// The results are received in reverse order, which must not affect the order of execution.
+	for i := len(results) - 1; i >= 0; i-- {
+		if err := (<-results[i]).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows := <-db.Query(`SELECT n FROM log ORDER BY seq`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var actual []int
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, n)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`order of db.Exec with WithFIFO => %#v; want %#v`, actual, expected)
+	}
+}
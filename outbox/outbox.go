@@ -0,0 +1,229 @@
+// Package outbox implements the transactional outbox pattern on top of asynql.
+//
+// The messages are appended to an outbox table in the same transaction as the writes they describe,
+// so they are published if and only if the transaction is committed.
+// A relay polls the table and hands the messages to a callback that publishes them, e.g. to a message broker,
+// and deletes them once the callback succeeds.
+// A message can be handed over more than once if the relay fails after publishing it, so the consumers should be idempotent.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// DefaultTable is the default name of the outbox table.
+const DefaultTable = "asynql_outbox"
+
+// Outbox is an outbox table.
+type Outbox struct {
+	db    *asynql.DB
+	table string
+}
+
+// Option configures an Outbox created by New.
+type Option func(*Outbox)
+
+// WithTable returns an Option that stores the messages in table instead of DefaultTable.
+// Append and the Relay must use the same table, e.g. "events.outbox" for a schema. The name is quoted by Dialect.QuoteIdent.
+func WithTable(table string) Option {
+	return func(o *Outbox) {
+		o.table = table
+	}
+}
+
+// New returns a new Outbox in db.
+func New(db *asynql.DB, opts ...Option) *Outbox {
+	o := &Outbox{
+		db:    db,
+		table: DefaultTable,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Message is a message in the outbox.
+type Message struct {
+	// ID identifies the message in the table. It increases in the order the messages were appended.
+	ID int64
+
+	// Topic is the destination of the message.
+	Topic string
+
+	// Payload is the content of the message.
+	Payload []byte
+
+	// CreatedAt is the time the message was appended.
+	CreatedAt time.Time
+}
+
+// CreateTable creates the outbox table if it doesn't exist.
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	d := o.db.Dialect()
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id %s,
+	topic VARCHAR(255) NOT NULL,
+	payload %s,
+	created_at BIGINT NOT NULL
+)`, d.QuoteIdent(o.table), d.IdentityColumn(), d.BlobType())
+	return (<-o.db.ExecContext(ctx, query)).Err()
+}
+
+// Append appends a message to topic with payload in tx.
+// tx must be a transaction of the DB of the outbox.
+func (o *Outbox) Append(ctx context.Context, tx *asynql.Tx, topic string, payload []byte) <-chan *asynql.Result {
+	query := o.sql(`INSERT INTO %s (topic, payload, created_at) VALUES (?, ?, ?)`)
+	return tx.ExecContext(ctx, query, topic, payload, time.Now().UnixNano()/int64(time.Millisecond))
+}
+
+// Handler publishes a batch of messages in the order of their IDs.
+// If it returns an error, the whole batch is handed over again later.
+type Handler func(ctx context.Context, msgs []*Message) error
+
+// Relay hands the messages in an outbox to a Handler in the background.
+type Relay struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu    sync.Mutex
+	stats RelayStats
+}
+
+// RelayStats is the statistics of a Relay.
+type RelayStats struct {
+	// Relayed is the number of the messages that have been handed over and deleted.
+	Relayed int64
+
+	// Failures is the number of the batches that have failed.
+	Failures int64
+
+	// LastErr is the error of the last batch, or nil if it succeeded.
+	LastErr error
+}
+
+// Relay starts a relay that polls the outbox every interval, and hands up to batchSize messages at a time to fn.
+// While the outbox has more messages than a batch, the batches are handed over without waiting for the interval.
+// The dialects that support it lock the batch with SELECT ... FOR UPDATE SKIP LOCKED until fn returns,
+// so that several relays can share an outbox. Otherwise, only one relay should run at a time.
+// The relay runs until ctx is done or Stop is called.
+func (o *Outbox) Relay(ctx context.Context, interval time.Duration, batchSize int, fn Handler) *Relay {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Relay{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go r.run(ctx, o, interval, batchSize, fn)
+	return r
+}
+
+// Stats returns the statistics of r.
+func (r *Relay) Stats() RelayStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Stop stops r, and waits for the batch in progress to finish.
+func (r *Relay) Stop() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *Relay) run(ctx context.Context, o *Outbox, interval time.Duration, batchSize int, fn Handler) {
+	defer close(r.done)
+	for {
+		n, err := o.relay(ctx, batchSize, fn)
+		if ctx.Err() != nil {
+			return
+		}
+		r.mu.Lock()
+		r.stats.Relayed += int64(n)
+		if err != nil {
+			r.stats.Failures++
+		}
+		r.stats.LastErr = err
+		r.mu.Unlock()
+		if err == nil && n == batchSize {
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// relay hands a batch to fn and deletes it, and returns the number of the messages in the batch.
+func (o *Outbox) relay(ctx context.Context, batchSize int, fn Handler) (int, error) {
+	if !o.db.Dialect().SupportsSkipLocked() {
+		return o.relayBatch(ctx, o.db, batchSize, "", fn)
+	}
+	tx, err := o.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	n, err := o.relayBatch(ctx, tx, batchSize, " FOR UPDATE SKIP LOCKED", fn)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (o *Outbox) relayBatch(ctx context.Context, db asynql.Querier, batchSize int, lock string, fn Handler) (int, error) {
+	query := o.sql(`SELECT id, topic, payload, created_at FROM %s ORDER BY id LIMIT `+strconv.Itoa(batchSize)) + lock
+	rows := <-db.QueryContext(ctx, query)
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	var msgs []*Message
+	for rows.Next() {
+		m := &Message{}
+		var created int64
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Payload, &created); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		m.CreatedAt = time.Unix(0, created*int64(time.Millisecond))
+		msgs = append(msgs, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+	if err := fn(ctx, msgs); err != nil {
+		return 0, err
+	}
+	ids := make([]interface{}, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+	query = o.sql(`DELETE FROM %s WHERE id IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + `)`)
+	if err := (<-db.ExecContext(ctx, query, ids...)).Err(); err != nil {
+		return 0, err
+	}
+	return len(msgs), nil
+}
+
+// sql returns query with the quoted table name embedded and the placeholders rebound to the dialect.
+func (o *Outbox) sql(query string) string {
+	d := o.db.Dialect()
+	return d.Rebind(fmt.Sprintf(query, d.QuoteIdent(o.table)))
+}
@@ -0,0 +1,95 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/naoina/asynql"
+	"github.com/naoina/asynql/outbox"
+)
+
+func newTestOutbox(t *testing.T) (*asynql.DB, *outbox.Outbox) {
+	db, err := asynql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	o := outbox.New(db)
+	if err := o.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db, o
+}
+
+func appendMessages(t *testing.T, db *asynql.DB, o *outbox.Outbox, commit bool, payloads ...string) {
+	ctx := context.Background()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range payloads {
+		if err := (<-o.Append(ctx, tx, "topic", []byte(p))).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if commit {
+		err = tx.Commit()
+	} else {
+		err = tx.Rollback()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutbox_Relay(t *testing.T) {
+	db, o := newTestOutbox(t)
+	defer db.Close()
+	appendMessages(t, db, o, true, "a", "b", "c")
+	appendMessages(t, db, o, false, "rolled back")
+
+	var mu sync.Mutex
+	var relayed []string
+	var batches int
+	fail := true
+	r := o.Relay(context.Background(), 10*time.Millisecond, 2, func(ctx context.Context, msgs []*outbox.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			fail = false
+			return errors.New("broker is down")
+		}
+		batches++
+		for _, m := range msgs {
+			relayed = append(relayed, string(m.Payload))
+		}
+		return nil
+	})
+	for i := 0; i < 100 && r.Stats().Relayed < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.Stop()
+	var actual interface{} = []interface{}{relayed, batches}
+	var expected interface{} = []interface{}{[]string{"a", "b", "c"}, 2}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`relayed messages => %#v; want %#v`, actual, expected)
+	}
+	stats := r.Stats()
+	actual = []interface{}{stats.Relayed, stats.Failures, stats.LastErr}
+	expected = []interface{}{int64(3), int64(1), nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`r.Stats() => %#v; want %#v`, actual, expected)
+	}
+	var count int
+	if err := (<-db.QueryRow(`SELECT COUNT(*) FROM ` + outbox.DefaultTable)).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf(`messages left in the outbox => %d; want 0`, count)
+	}
+}
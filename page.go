@@ -0,0 +1,74 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// ErrInvalidPageSize is reported by QueryPages when the page size is not positive.
+var ErrInvalidPageSize = errors.New("asynql: page size must be positive")
+
+// Page represents a page of the result of QueryPages.
+// The rows of a page are read into memory, so it doesn't hold a connection.
+type Page struct {
+	*Rows
+
+	// Number is the index of the page, counting from 0.
+	Number int
+
+	// Len is the number of the rows in the page.
+	Len int
+
+	err error
+}
+
+// Err returns an error.
+func (p *Page) Err() error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.Rows.Err()
+}
+
+// QueryPages executes query with args page by page, appending LIMIT and OFFSET to it, and then sends the pages on the returned channel.
+// query must have a deterministic ORDER BY clause and no LIMIT clause of its own.
+// The last page has fewer rows than pageSize, and it may be empty.
+// If a query fails, a Page with the error is sent.
+// The channel is closed after the last page or the error, or when ctx is done.
+//
+// Note that the pages can skip or repeat rows if the table is modified while it is paged.
+func (db *DB) QueryPages(ctx context.Context, query string, args []interface{}, pageSize int) <-chan *Page {
+	ch := make(chan *Page)
+	go func() {
+		defer close(ch)
+		if pageSize <= 0 {
+			select {
+			case ch <- &Page{err: ErrInvalidPageSize}:
+			case <-ctx.Done():
+			}
+			return
+		}
+		for n := 0; ; n++ {
+			q := query + " LIMIT " + strconv.Itoa(pageSize) + " OFFSET " + strconv.Itoa(n*pageSize)
+			snap, err := Materialize(<-db.QueryContext(ctx, q, args...))
+			page := &Page{
+				Number: n,
+				err:    err,
+			}
+			if err == nil {
+				page.Rows = snap.Rows()
+				page.Len = len(snap.Values)
+			}
+			select {
+			case ch <- page:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil || page.Len < pageSize {
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,52 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDB_QueryPages(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if err := (<-db.Exec(`INSERT INTO test_table (id, name) VALUES (3, "carol"), (4, "dave")`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		pageSize int
+		expected [][]string
+	}{
+		{3, [][]string{{"alice", "bob", "carol"}, {"dave"}}},
+		{2, [][]string{{"alice", "bob"}, {"carol", "dave"}, nil}},
+		{10, [][]string{{"alice", "bob", "carol", "dave"}}},
+	} {
+		var actual [][]string
+		for page := range db.QueryPages(context.Background(), `SELECT name FROM test_table ORDER BY id`, nil, v.pageSize) {
+			if err := page.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if page.Number != len(actual) {
+				t.Errorf(`page.Number => %#v; want %#v`, page.Number, len(actual))
+			}
+			actual = append(actual, scanNames(t, page.Rows))
+		}
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`db.QueryPages(ctx, query, nil, %v) => %#v; want %#v`, v.pageSize, actual, v.expected)
+		}
+	}
+}
+
+func TestDB_QueryPages_Error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	var errs int
+	for page := range db.QueryPages(context.Background(), `SELECT * FROM missing_table`, nil, 10) {
+		if page.Err() == nil {
+			t.Errorf(`page.Err() => nil; want error`)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf(`number of pages => %v; want 1`, errs)
+	}
+}
@@ -0,0 +1,137 @@
+package asynql
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultParallelLimit is the default maximum number of operations of a ParallelGroup that run at the same time.
+const DefaultParallelLimit = 8
+
+// ParallelGroup is a list of independent operations to be executed concurrently.
+// It is created by Parallel.
+type ParallelGroup struct {
+	ctx   context.Context
+	q     Querier
+	limit int
+	ops   []func(ctx context.Context) Errer
+}
+
+// Parallel returns a new ParallelGroup that executes operations on q with ctx.
+func Parallel(ctx context.Context, q Querier) *ParallelGroup {
+	return &ParallelGroup{
+		ctx:   ctx,
+		q:     q,
+		limit: DefaultParallelLimit,
+	}
+}
+
+// Limit sets the maximum number of operations that run at the same time.
+// n < 1 means no limit.
+func (p *ParallelGroup) Limit(n int) *ParallelGroup {
+	p.limit = n
+	return p
+}
+
+// Exec adds an operation that executes query with args, which yields a *Result.
+func (p *ParallelGroup) Exec(query string, args ...interface{}) *ParallelGroup {
+	p.ops = append(p.ops, func(ctx context.Context) Errer {
+		return <-p.q.ExecContext(ctx, query, args...)
+	})
+	return p
+}
+
+// Query adds an operation that executes a query with args, which yields a *Rows.
+func (p *ParallelGroup) Query(query string, args ...interface{}) *ParallelGroup {
+	p.ops = append(p.ops, func(ctx context.Context) Errer {
+		return <-p.q.QueryContext(ctx, query, args...)
+	})
+	return p
+}
+
+// QueryRow adds an operation that executes a query with args, which yields a *Row.
+func (p *ParallelGroup) QueryRow(query string, args ...interface{}) *ParallelGroup {
+	p.ops = append(p.ops, func(ctx context.Context) Errer {
+		return <-p.q.QueryRowContext(ctx, query, args...)
+	})
+	return p
+}
+
+// Wait executes the operations concurrently and waits for all of them.
+// Note that *Rows and *Row hold their connection until they are consumed,
+// so a group that has more of them than the connections of the pool blocks forever.
+// If any of them fails, Wait returns the error of the first failed operation in the order they were added.
+// In that case, the operations that have not started yet are skipped, and the *Rows and *Row of the succeeded ones are released.
+func (p *ParallelGroup) Wait() (*ParallelResults, error) {
+	values := make([]Errer, len(p.ops))
+	errs := make([]error, len(p.ops))
+	limit := p.limit
+	if limit < 1 || limit > len(p.ops) {
+		limit = len(p.ops)
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+	for i, op := range p.ops {
+		sem <- struct{}{}
+		mu.Lock()
+		skip := failed
+		mu.Unlock()
+		if skip {
+			<-sem
+			continue
+		}
+		wg.Add(1)
+		go func(i int, op func(ctx context.Context) Errer) {
+			defer wg.Done()
+			v := op(p.ctx)
+			<-sem
+			values[i] = v
+			if errs[i] = v.Err(); errs[i] != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(i, op)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			for _, v := range values {
+				if v != nil {
+					discard(v)
+				}
+			}
+			return nil, err
+		}
+	}
+	return &ParallelResults{
+		values: values,
+	}, nil
+}
+
+// ParallelResults holds the results of the operations of a ParallelGroup in the order they were added.
+type ParallelResults struct {
+	values []Errer
+}
+
+// Len returns the number of the results.
+func (r *ParallelResults) Len() int {
+	return len(r.values)
+}
+
+// Result returns the i-th result, which must be the result of an Exec.
+func (r *ParallelResults) Result(i int) *Result {
+	return r.values[i].(*Result)
+}
+
+// Rows returns the i-th result, which must be the result of a Query.
+func (r *ParallelResults) Rows(i int) *Rows {
+	return r.values[i].(*Rows)
+}
+
+// Row returns the i-th result, which must be the result of a QueryRow.
+func (r *ParallelResults) Row(i int) *Row {
+	return r.values[i].(*Row)
+}
@@ -0,0 +1,63 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestParallel(t *testing.T) {
+	db := newTestFileDB(t)
+	defer db.Close()
+	results, err := asynql.Parallel(context.Background(), db).
+		Exec(`INSERT INTO test_table (id, name) VALUES (3, "carol")`).
+		Query(`SELECT name FROM test_table WHERE id = 1`).
+		Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = results.Len()
+	var expected interface{} = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`results.Len() => %#v; want %#v`, actual, expected)
+	}
+	affected, err := results.Result(0).RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = affected
+	expected = int64(1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`results.Result(0).RowsAffected() => %#v; want %#v`, actual, expected)
+	}
+	actual = scanNames(t, results.Rows(1))
+	expected = []string{"alice"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`results.Rows(1) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestParallel_Error(t *testing.T) {
+	db := newTestFileDB(t)
+	defer db.Close()
+	results, err := asynql.Parallel(context.Background(), db).
+		Limit(1).
+		Query(`SELECT name FROM test_table`).
+		Exec(`INSERT INTO unknown_table (id) VALUES (1)`).
+		Exec(`INSERT INTO test_table (id, name) VALUES (3, "carol")`).
+		Wait()
+	if err == nil {
+		t.Fatalf(`Wait() => %#v, nil; want error`, results)
+	}
+	var count int
+	if err := (<-db.QueryRow(`SELECT COUNT(*) FROM test_table`)).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = count
+	var expected interface{} = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`count after failed Wait() => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,24 @@
+package asynql
+
+// Pause holds the operations of the DB and its statements that start to run after it, until Resume is called,
+// for a short freeze of the writes during a controlled failover or a schema change.
+// The held operations are not rejected, but wait in the queue and are counted in PoolStats.Queued,
+// or fail with the error of their contexts if the contexts are done while waiting.
+// The operations of the transactions and the connections are not held, so that the transactions in progress can finish.
+// The operations that are already running are not affected; Pause doesn't wait for them.
+func (db *DB) Pause() {
+	resumed := make(chan struct{})
+	db.paused.CompareAndSwap(nil, &resumed)
+}
+
+// Resume runs the operations held by Pause, and stops holding new ones.
+func (db *DB) Resume() {
+	if resumed := db.paused.Swap(nil); resumed != nil {
+		close(*resumed)
+	}
+}
+
+// Paused reports whether the DB is paused by Pause.
+func (db *DB) Paused() bool {
+	return db.paused.Load() != nil
+}
@@ -0,0 +1,62 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDB_Pause(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	db.Pause()
+	if !db.Paused() {
+		t.Fatalf(`db.Paused() after db.Pause() => false; want true`)
+	}
+	held := db.ExecContext(ctx, `UPDATE test_table SET name = ? WHERE id = 1`, "carol")
+	select {
+	case r := <-held:
+		t.Fatalf(`db.ExecContext(ctx, ...) while paused => %#v; want to be held`, r)
+	case <-time.After(50 * time.Millisecond):
+	}
+	var actual interface{} = db.PoolStats().Queued
+	var expected interface{} = int64(1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.PoolStats().Queued while paused => %#v; want %#v`, actual, expected)
+	}
+
+	// The operations of a transaction are not held.
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := (<-tx.QueryRowContext(ctx, `SELECT name FROM test_table WHERE id = 2`)).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := (<-db.ExecContext(timeout, `DELETE FROM test_table`)).Err(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(`db.ExecContext(ctx, ...) with a deadline while paused => %#v; want %#v`, err, context.DeadlineExceeded)
+	}
+
+	db.Resume()
+	if db.Paused() {
+		t.Errorf(`db.Paused() after db.Resume() => true; want false`)
+	}
+	if err := (<-held).Err(); err != nil {
+		t.Fatal(err)
+	}
+	actual = scanNames(t, <-db.QueryContext(ctx, `SELECT name FROM test_table ORDER BY id`))
+	expected = []string{"carol", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`names after db.Resume() => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,251 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// ArgsFunc computes the arguments of a node of a Pipeline from the outputs of the nodes that it depends on.
+type ArgsFunc func(out *PipelineOutputs) ([]interface{}, error)
+
+// Args returns an ArgsFunc that always returns args.
+func Args(args ...interface{}) ArgsFunc {
+	return func(*PipelineOutputs) ([]interface{}, error) {
+		return args, nil
+	}
+}
+
+// Pipeline runs a set of queries and statements whose arguments can depend on the outputs of the others.
+// The nodes that don't depend on each other run concurrently.
+//
+//	p := asynql.NewPipeline(db).
+//		Query("user", `SELECT id FROM users WHERE name = ?`, asynql.Args("alice")).
+//		Exec("order", `INSERT INTO orders (user_id) VALUES (?)`, func(out *asynql.PipelineOutputs) ([]interface{}, error) {
+//			return []interface{}{out.Get("user").Scalar()}, nil
+//		}, "user")
+//	r := <-p.Run(ctx)
+type Pipeline struct {
+	q     Querier
+	nodes []*pipelineNode
+	err   error
+}
+
+type pipelineNode struct {
+	name  string
+	query string
+	exec  bool
+	args  ArgsFunc
+	deps  []string
+}
+
+// NewPipeline returns a new empty Pipeline that runs its nodes on q.
+func NewPipeline(q Querier) *Pipeline {
+	return &Pipeline{
+		q: q,
+	}
+}
+
+// Query adds a node that executes query with the arguments computed by args after the nodes of deps have succeeded.
+// The rows are read into memory, and are available to the dependent nodes as a Snapshot.
+func (p *Pipeline) Query(name, query string, args ArgsFunc, deps ...string) *Pipeline {
+	return p.add(&pipelineNode{name: name, query: query, args: args, deps: deps})
+}
+
+// Exec adds a node that executes query with the arguments computed by args after the nodes of deps have succeeded.
+// Its sql.Result is available to the dependent nodes.
+func (p *Pipeline) Exec(name, query string, args ArgsFunc, deps ...string) *Pipeline {
+	return p.add(&pipelineNode{name: name, query: query, exec: true, args: args, deps: deps})
+}
+
+func (p *Pipeline) add(n *pipelineNode) *Pipeline {
+	if n.args == nil {
+		n.args = Args()
+	}
+	for _, v := range p.nodes {
+		if v.name == n.name && p.err == nil {
+			p.err = fmt.Errorf("asynql: duplicate pipeline node %q", n.name)
+		}
+	}
+	p.nodes = append(p.nodes, n)
+	return p
+}
+
+// PipelineOutput is the output of a node of a Pipeline.
+type PipelineOutput struct {
+	// Result is the result of an Exec node.
+	Result sql.Result
+
+	// Snapshot is the rows of a Query node.
+	Snapshot *Snapshot
+}
+
+// Scalar returns the value of the first column of the first row of a Query node, or nil if there are no rows.
+func (o *PipelineOutput) Scalar() interface{} {
+	if o == nil || o.Snapshot == nil || len(o.Snapshot.Values) == 0 || len(o.Snapshot.Values[0]) == 0 {
+		return nil
+	}
+	return o.Snapshot.Values[0][0]
+}
+
+// PipelineOutputs holds the outputs of the nodes of a Pipeline.
+type PipelineOutputs struct {
+	mu sync.Mutex
+	m  map[string]*PipelineOutput
+}
+
+// Get returns the output of the node of name, or nil if the node has not succeeded.
+func (o *PipelineOutputs) Get(name string) *PipelineOutput {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.m[name]
+}
+
+func (o *PipelineOutputs) set(name string, out *PipelineOutput) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.m[name] = out
+}
+
+// PipelineResult represents a result of Pipeline.Run.
+type PipelineResult struct {
+	// Outputs is the outputs of the nodes that have succeeded.
+	Outputs *PipelineOutputs
+
+	err error
+}
+
+// Err returns the error of the first node that failed, in the order the nodes were added, as a *PipelineError.
+func (r *PipelineResult) Err() error {
+	return r.err
+}
+
+// PipelineError is an error of a node of a Pipeline.
+type PipelineError struct {
+	Node string
+	Err  error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("asynql: pipeline node %q: %v", e.Node, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Run runs the nodes, and then sends the outputs on the returned channel.
+// A node runs as soon as all the nodes that it depends on have succeeded.
+// If a node fails, the nodes that depend on it directly or indirectly are skipped, while the others still run.
+// Run reports an error without running any node if a dependency is unknown or cyclic.
+func (p *Pipeline) Run(ctx context.Context) <-chan *PipelineResult {
+	ch := make(chan *PipelineResult)
+	go func() {
+		deliver(nil, ch, p.run(ctx))
+	}()
+	return ch
+}
+
+func (p *Pipeline) run(ctx context.Context) *PipelineResult {
+	out := &PipelineOutputs{
+		m: make(map[string]*PipelineOutput),
+	}
+	if err := p.validate(); err != nil {
+		return &PipelineResult{Outputs: out, err: err}
+	}
+	done := make(map[string]chan struct{}, len(p.nodes))
+	for _, n := range p.nodes {
+		done[n.name] = make(chan struct{})
+	}
+	errs := make([]error, len(p.nodes))
+	var wg sync.WaitGroup
+	for i, n := range p.nodes {
+		wg.Add(1)
+		go func(i int, n *pipelineNode) {
+			defer wg.Done()
+			defer close(done[n.name])
+			for _, dep := range n.deps {
+				<-done[dep]
+				if out.Get(dep) == nil {
+					return
+				}
+			}
+			o, err := n.run(ctx, p.q, out)
+			if err != nil {
+				errs[i] = &PipelineError{Node: n.name, Err: err}
+				return
+			}
+			out.set(n.name, o)
+		}(i, n)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return &PipelineResult{Outputs: out, err: err}
+		}
+	}
+	return &PipelineResult{Outputs: out}
+}
+
+func (n *pipelineNode) run(ctx context.Context, q Querier, out *PipelineOutputs) (*PipelineOutput, error) {
+	args, err := n.args(out)
+	if err != nil {
+		return nil, err
+	}
+	if n.exec {
+		r := <-q.ExecContext(ctx, n.query, args...)
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		return &PipelineOutput{Result: r.Result}, nil
+	}
+	snap, err := Materialize(<-q.QueryContext(ctx, n.query, args...))
+	if err != nil {
+		return nil, err
+	}
+	return &PipelineOutput{Snapshot: snap}, nil
+}
+
+// validate checks that the dependencies are known and acyclic.
+func (p *Pipeline) validate() error {
+	if p.err != nil {
+		return p.err
+	}
+	nodes := make(map[string]*pipelineNode, len(p.nodes))
+	for _, n := range p.nodes {
+		nodes[n.name] = n
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(p.nodes))
+	var visit func(n *pipelineNode) error
+	visit = func(n *pipelineNode) error {
+		switch state[n.name] {
+		case visiting:
+			return fmt.Errorf("asynql: pipeline node %q depends on itself", n.name)
+		case visited:
+			return nil
+		}
+		state[n.name] = visiting
+		for _, dep := range n.deps {
+			d, ok := nodes[dep]
+			if !ok {
+				return fmt.Errorf("asynql: pipeline node %q depends on unknown node %q", n.name, dep)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[n.name] = visited
+		return nil
+	}
+	for _, n := range p.nodes {
+		if err := visit(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestPipeline(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	p := asynql.NewPipeline(db).
+		Query("max", `SELECT MAX(id) FROM test_table`, nil).
+		Exec("insert", `INSERT INTO test_table (id, name) VALUES (?, ?)`, func(out *asynql.PipelineOutputs) ([]interface{}, error) {
+			return []interface{}{out.Get("max").Scalar().(int64) + 1, "carol"}, nil
+		}, "max").
+		Query("names", `SELECT name FROM test_table ORDER BY id`, nil, "insert")
+	r := <-p.Run(context.Background())
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := r.Outputs.Get("insert").Result.RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = n
+	var expected interface{} = int64(1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`RowsAffected of "insert" => %#v; want %#v`, actual, expected)
+	}
+	actual = r.Outputs.Get("names").Snapshot.Values
+	expected = [][]interface{}{{"alice"}, {"bob"}, {"carol"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`output of "names" => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestPipeline_Failure(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	p := asynql.NewPipeline(db).
+		Query("bad", `SELECT * FROM missing_table`, nil).
+		Exec("dependent", `DELETE FROM test_table`, nil, "bad").
+		Query("independent", `SELECT COUNT(*) FROM test_table`, nil)
+	r := <-p.Run(context.Background())
+	var perr *asynql.PipelineError
+	if err := r.Err(); !errors.As(err, &perr) || perr.Node != "bad" {
+		t.Fatalf(`p.Run(ctx).Err() => %#v; want *PipelineError of "bad"`, err)
+	}
+	var actual interface{} = []interface{}{r.Outputs.Get("dependent") == nil, r.Outputs.Get("independent").Scalar()}
+	var expected interface{} = []interface{}{true, int64(2)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`outputs => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestPipeline_Invalid(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	for _, p := range []*asynql.Pipeline{
+		asynql.NewPipeline(db).Query("a", `SELECT 1`, nil, "b").Query("b", `SELECT 1`, nil, "a"),
+		asynql.NewPipeline(db).Query("a", `SELECT 1`, nil, "missing"),
+		asynql.NewPipeline(db).Query("a", `SELECT 1`, nil).Query("a", `SELECT 2`, nil),
+	} {
+		if err := (<-p.Run(context.Background())).Err(); err == nil {
+			t.Errorf(`p.Run(ctx).Err() => nil; want error`)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDenied is reported by the statements denied by a Policy.
+// The errors of the built-in policies wrap it, and so should those of the other policies.
+var ErrDenied = errors.New("asynql: statement denied by policy")
+
+// Policy decides whether a statement may run.
+type Policy interface {
+	// Check returns an error if the operation op of query with args must not run.
+	Check(ctx context.Context, op HookOp, query string, args []interface{}) error
+}
+
+// PolicyFunc is an adapter to use a function as a Policy.
+type PolicyFunc func(ctx context.Context, op HookOp, query string, args []interface{}) error
+
+// Check returns f(ctx, op, query, args).
+func (f PolicyFunc) Check(ctx context.Context, op HookOp, query string, args []interface{}) error {
+	return f(ctx, op, query, args)
+}
+
+// WithPolicy returns an Option that consults p before each operation of the DB and its transactions, connections and statements,
+// as a safety net for the tools shared by many users. If p returns an error, the operation fails with it without running.
+// The policies are consulted in the order they were added, and the first error is reported.
+func WithPolicy(p Policy) Option {
+	return func(db *DB) {
+		db.policies = append(db.policies, p)
+	}
+}
+
+// DenyStatements returns a Policy that denies the statements that begin with any of verbs, such as "DROP" and "TRUNCATE".
+// The verbs are case-insensitive. A query of stacked statements is denied if any of them is.
+// A statement with a WITH clause is told by the statement that follows the clause and by those of its common table expressions,
+// so WITH x AS (SELECT 1) DELETE FROM t is a DELETE statement.
+func DenyStatements(verbs ...string) Policy {
+	denied := make(map[string]bool, len(verbs))
+	for _, verb := range verbs {
+		denied[strings.ToLower(verb)] = true
+	}
+	return PolicyFunc(func(ctx context.Context, op HookOp, query string, args []interface{}) error {
+		for _, stmt := range splitStatements(query) {
+			for _, part := range statementParts(lexSQL(stmt)) {
+				if verb := statementVerb(part); denied[verb] {
+					return fmt.Errorf("%w: %s statement", ErrDenied, strings.ToUpper(verb))
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// AllowStatements returns a Policy that denies the statements other than those that begin with any of verbs,
+// such as "SELECT", "INSERT" and "UPDATE". The verbs are case-insensitive. A query of stacked statements is denied if any of them is.
+// A statement with a WITH clause is told as DenyStatements does, so "WITH" need not be allowed.
+func AllowStatements(verbs ...string) Policy {
+	allowed := make(map[string]bool, len(verbs))
+	for _, verb := range verbs {
+		allowed[strings.ToLower(verb)] = true
+	}
+	return PolicyFunc(func(ctx context.Context, op HookOp, query string, args []interface{}) error {
+		stmts := splitStatements(query)
+		if len(stmts) == 0 {
+			stmts = []string{query}
+		}
+		for _, stmt := range stmts {
+			for _, part := range statementParts(lexSQL(stmt)) {
+				if verb := statementVerb(part); !allowed[verb] {
+					return fmt.Errorf("%w: %s statement is not allowed", ErrDenied, strings.ToUpper(verb))
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DenyUnqualifiedWrites returns a Policy that denies the DELETE and UPDATE statements without a WHERE clause,
+// which modify all the rows of a table. The WHERE of a subquery doesn't qualify the statement,
+// and a query of stacked statements is denied if any of them is.
+// The statements that follow a WITH clause and those of its common table expressions are checked as DenyStatements tells them.
+func DenyUnqualifiedWrites() Policy {
+	return PolicyFunc(func(ctx context.Context, op HookOp, query string, args []interface{}) error {
+		for _, stmt := range splitStatements(query) {
+			for _, part := range statementParts(lexSQL(stmt)) {
+				switch verb := statementVerb(part); verb {
+				case "delete", "update":
+					if !hasTopLevelWhere(part) {
+						return fmt.Errorf("%w: %s without WHERE", ErrDenied, strings.ToUpper(verb))
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// hasTopLevelWhere reports whether tokens have a WHERE outside of the parentheses, which is the WHERE of the statement itself.
+func hasTopLevelWhere(tokens []token) bool {
+	depth := 0
+	for _, t := range tokens {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.is("where"):
+			return true
+		}
+	}
+	return false
+}
+
+// statementParts returns the tokens of the statement that follows the WITH clause of the statement of tokens,
+// preceded by those of the statements of its common table expressions, such as the DELETE of WITH d AS (DELETE ... RETURNING *) SELECT ....
+// It returns tokens as they are if the statement has no WITH clause.
+func statementParts(tokens []token) [][]token {
+	if len(tokens) == 0 || !tokens[0].is("with") {
+		return [][]token{tokens}
+	}
+	var parts [][]token
+	i := 1
+	if i < len(tokens) && tokens[i].is("recursive") {
+		i++
+	}
+	for i < len(tokens) {
+		// name [(columns)] AS [[NOT] MATERIALIZED] (statement)
+		for i < len(tokens) && !tokens[i].is("as") {
+			if tokens[i].isPunct("(") {
+				i = closingParen(tokens, i)
+			}
+			i++
+		}
+		for i < len(tokens) && !tokens[i].isPunct("(") {
+			i++
+		}
+		if i >= len(tokens) {
+			break
+		}
+		end := closingParen(tokens, i)
+		parts = append(parts, statementParts(tokens[i+1:end])...)
+		if i = end + 1; i >= len(tokens) || !tokens[i].isPunct(",") {
+			break
+		}
+		i++
+	}
+	if i < len(tokens) {
+		parts = append(parts, tokens[i:])
+	}
+	return parts
+}
+
+// closingParen returns the index of the parenthesis that closes the one at tokens[i], or len(tokens) if it is not closed.
+func closingParen(tokens []token, i int) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch {
+		case tokens[i].isPunct("("):
+			depth++
+		case tokens[i].isPunct(")"):
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// checkPolicies returns the error of the first policy of db that denies the operation.
+func (db *DB) checkPolicies(ctx context.Context, op HookOp, query string, args []interface{}) error {
+	if db == nil {
+		return nil
+	}
+	for _, p := range db.policies {
+		if err := p.Check(ctx, op, query, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithPolicy(t *testing.T) {
+	errCustom := errors.New("no args")
+	var checked []string
+	db := newTestDB(t,
+		asynql.WithPolicy(asynql.DenyStatements("drop", "TRUNCATE")),
+		asynql.WithPolicy(asynql.DenyUnqualifiedWrites()),
+		asynql.WithPolicy(asynql.PolicyFunc(func(ctx context.Context, op asynql.HookOp, query string, args []interface{}) error {
+			checked = append(checked, query)
+			if op == asynql.HookExec && len(args) == 0 {
+				return errCustom
+			}
+			return nil
+		})),
+	)
+	defer db.Close()
+	ctx := context.Background()
+	for _, v := range []struct {
+		query    string
+		args     []interface{}
+		expected error
+	}{
+		{`DROP TABLE test_table`, nil, asynql.ErrDenied},
+		{`delete from test_table`, nil, asynql.ErrDenied},
+		{`UPDATE test_table SET name = ?`, []interface{}{"carol"}, asynql.ErrDenied},
+		{`DELETE FROM test_table WHERE id = 3`, nil, errCustom},
+		{`UPDATE test_table SET name = ? WHERE id = ?`, []interface{}{"carol", 1}, nil},
+	} {
+		if err := (<-db.ExecContext(ctx, v.query, v.args...)).Err(); !errors.Is(err, v.expected) {
+			t.Errorf(`db.ExecContext(ctx, %#v) => %#v; want %#v`, v.query, err, v.expected)
+		}
+	}
+	var actual interface{} = checked
+	var expected interface{} = []string{`DELETE FROM test_table WHERE id = 3`, `UPDATE test_table SET name = ? WHERE id = ?`}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`queries checked by the last policy => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestAllowStatements(t *testing.T) {
+	db := newTestDB(t, asynql.WithPolicy(asynql.AllowStatements("SELECT", "UPDATE")))
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `DELETE FROM test_table WHERE id = 1`)).Err(); !errors.Is(err, asynql.ErrDenied) {
+		t.Errorf(`DELETE => %#v; want %#v`, err, asynql.ErrDenied)
+	}
+	if err := (<-db.ExecContext(ctx, `UPDATE test_table SET name = 'carol' WHERE id = 1`)).Err(); err != nil {
+		t.Errorf(`UPDATE => %#v; want nil`, err)
+	}
+	var actual interface{} = scanNames(t, <-db.QueryContext(ctx, `SELECT name FROM test_table ORDER BY id`))
+	var expected interface{} = []string{"carol", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`SELECT => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestWithPolicy_Stacked(t *testing.T) {
+	db := newTestDB(t,
+		asynql.WithPolicy(asynql.DenyStatements("drop")),
+		asynql.WithPolicy(asynql.DenyUnqualifiedWrites()),
+	)
+	defer db.Close()
+	ctx := context.Background()
+	for _, v := range []struct {
+		query    string
+		expected error
+	}{
+		{`SELECT 1; DROP TABLE test_table`, asynql.ErrDenied},
+		{`DELETE FROM test_table WHERE id = 3; DELETE FROM test_table`, asynql.ErrDenied},
+		{`UPDATE test_table SET name = (SELECT name FROM test_table WHERE id = 2)`, asynql.ErrDenied},
+		{`DELETE FROM test_table WHERE id IN (SELECT id FROM test_table WHERE id = 3)`, nil},
+	} {
+		if err := (<-db.ExecContext(ctx, v.query)).Err(); !errors.Is(err, v.expected) {
+			t.Errorf(`db.ExecContext(ctx, %#v) => %#v; want %#v`, v.query, err, v.expected)
+		}
+	}
+	var actual interface{} = scanNames(t, <-db.QueryContext(ctx, `SELECT name FROM test_table ORDER BY id`))
+	var expected interface{} = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`SELECT => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestAllowStatements_Stacked(t *testing.T) {
+	db := newTestDB(t, asynql.WithPolicy(asynql.AllowStatements("SELECT")))
+	defer db.Close()
+	query := `SELECT 1; DELETE FROM test_table WHERE id = 1`
+	if err := (<-db.ExecContext(context.Background(), query)).Err(); !errors.Is(err, asynql.ErrDenied) {
+		t.Errorf(`db.ExecContext(ctx, %#v) => %#v; want %#v`, query, err, asynql.ErrDenied)
+	}
+}
+
+func TestWithPolicy_CTE(t *testing.T) {
+	ctx := context.Background()
+	for _, v := range []struct {
+		policy   asynql.Policy
+		query    string
+		expected error
+	}{
+		{asynql.DenyStatements("DELETE"), `WITH x AS (SELECT 1) DELETE FROM test_table WHERE id = 1`, asynql.ErrDenied},
+		{asynql.DenyStatements("DELETE"), `WITH RECURSIVE x (n) AS (SELECT 1), y AS MATERIALIZED (SELECT 2) DELETE FROM test_table WHERE id = 1`, asynql.ErrDenied},
+		{asynql.DenyStatements("DELETE"), `WITH d AS (DELETE FROM test_table RETURNING id) SELECT * FROM d`, asynql.ErrDenied},
+		{asynql.DenyStatements("DELETE"), `WITH x AS (SELECT 1) SELECT * FROM x`, nil},
+		{asynql.DenyUnqualifiedWrites(), `WITH x AS (SELECT 1 WHERE 1 = 1) DELETE FROM test_table`, asynql.ErrDenied},
+		{asynql.DenyUnqualifiedWrites(), `WITH x AS (SELECT 1) UPDATE test_table SET name = 'carol'`, asynql.ErrDenied},
+		{asynql.DenyUnqualifiedWrites(), `WITH x AS (SELECT 3 AS id) DELETE FROM test_table WHERE id IN (SELECT id FROM x)`, nil},
+		{asynql.AllowStatements("SELECT"), `WITH x AS (SELECT 1) DELETE FROM test_table WHERE id = 1`, asynql.ErrDenied},
+		{asynql.AllowStatements("SELECT"), `WITH x AS (SELECT 1) SELECT * FROM x`, nil},
+	} {
+		db := newTestDB(t, asynql.WithPolicy(v.policy))
+		if err := (<-db.ExecContext(ctx, v.query)).Err(); !errors.Is(err, v.expected) {
+			t.Errorf(`db.ExecContext(ctx, %#v) => %#v; want %#v`, v.query, err, v.expected)
+		}
+		db.Close()
+	}
+}
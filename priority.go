@@ -0,0 +1,105 @@
+package asynql
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority is the priority of an operation that waits for a slot of a DB with a concurrency limit.
+type Priority int
+
+const (
+	// PriorityLow is for bulk and background work.
+	PriorityLow Priority = iota - 1
+
+	// PriorityNormal is the default priority.
+	PriorityNormal
+
+	// PriorityHigh is for latency-sensitive operations.
+	PriorityHigh
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx that gives the operations using it the priority p.
+// When the concurrency limit set by WithMaxConcurrency is reached, the waiting operations are started
+// in the order of their priorities, and in the order they were called within the same priority.
+// The priority has no effect on a DB without a concurrency limit.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// priorityOf returns the priority given to ctx by WithPriority.
+func priorityOf(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// limiter limits the number of the operations that run concurrently.
+// The slots are handed to the waiting operations in the order of their priorities.
+type limiter struct {
+	mu      sync.Mutex
+	n       int
+	running int
+	waiting [PriorityHigh - PriorityLow + 1][]chan struct{}
+}
+
+func newLimiter(n int) *limiter {
+	return &limiter{
+		n: n,
+	}
+}
+
+// acquire waits for a slot, and reports whether it got one.
+// It returns false if ctx is done first, in which case the operation should still run
+// so that it fails with the error of ctx.
+func (l *limiter) acquire(ctx context.Context, p Priority) bool {
+	if p < PriorityLow {
+		p = PriorityLow
+	}
+	if p > PriorityHigh {
+		p = PriorityHigh
+	}
+	l.mu.Lock()
+	if l.running < l.n {
+		l.running++
+		l.mu.Unlock()
+		return true
+	}
+	ready := make(chan struct{})
+	q := &l.waiting[p-PriorityLow]
+	*q = append(*q, ready)
+	l.mu.Unlock()
+	select {
+	case <-ready:
+		return true
+	case <-ctx.Done():
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, c := range *q {
+		if c == ready {
+			*q = append((*q)[:i], (*q)[i+1:]...)
+			return false
+		}
+	}
+	// The slot has been handed over while ctx was being done.
+	return true
+}
+
+// release hands the slot over to the waiting operation with the highest priority, or frees it.
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.waiting) - 1; i >= 0; i-- {
+		if q := l.waiting[i]; len(q) > 0 {
+			close(q[0])
+			q[0] = nil
+			l.waiting[i] = q[1:]
+			return
+		}
+	}
+	l.running--
+}
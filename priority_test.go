@@ -0,0 +1,94 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// orderConn is a driver.Conn that records the order of the executed queries.
+// The query "block" blocks until release is closed.
+type orderConn struct {
+	mu      sync.Mutex
+	queries []string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *orderConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *orderConn) Close() error                        { return nil }
+func (c *orderConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *orderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "block" {
+		close(c.started)
+		<-c.release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+	return driver.RowsAffected(0), nil
+}
+
+func TestWithPriority(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithMaxConcurrency(1))
+	defer db.Close()
+	ctx := context.Background()
+	results := []<-chan *asynql.Result{db.ExecContext(ctx, "block")}
+	<-conn.started
+	for _, v := range []struct {
+		query    string
+		priority asynql.Priority
+	}{
+		{"low", asynql.PriorityLow},
+		{"normal1", asynql.PriorityNormal},
+		{"high", asynql.PriorityHigh},
+		{"normal2", asynql.PriorityNormal},
+	} {
+		results = append(results, db.ExecContext(asynql.WithPriority(ctx, v.priority), v.query))
+		// Wait for the operation to be queued.
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(conn.release)
+	if _, err := asynql.WaitAll(results...); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = conn.queries
+	var expected interface{} = []string{"block", "high", "normal1", "normal2", "low"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`executed queries => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestWithPriority_Canceled(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithMaxConcurrency(1))
+	defer db.Close()
+	blocked := db.Exec("block")
+	<-conn.started
+	ctx, cancel := context.WithCancel(context.Background())
+	result := db.ExecContext(ctx, "canceled")
+	cancel()
+	var actual interface{} = errors.Unwrap((<-result).Err())
+	var expected interface{} = context.Canceled
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.ExecContext(canceled ctx, "canceled").Err() => %#v; want %#v`, actual, expected)
+	}
+	close(conn.release)
+	if err := (<-blocked).Err(); err != nil {
+		t.Fatal(err)
+	}
+}
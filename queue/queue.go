@@ -0,0 +1,373 @@
+// Package queue implements a durable job queue on top of asynql.
+//
+// The jobs are stored in a table, so they can be enqueued in the same transaction as the writes they belong to.
+// A worker leases jobs for a while, and then acknowledges them when done, or retries them later when failed.
+// A job whose lease expires is leased again by another worker, and a job that fails too many times is moved to the dead letters.
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+const (
+	// DefaultTable is the default name of the table of jobs.
+	DefaultTable = "asynql_jobs"
+
+	// DefaultMaxAttempts is the default number of the attempts of a job before it is moved to the dead letters.
+	DefaultMaxAttempts = 5
+
+	// DefaultLeaseTimeout is the default duration of a lease.
+	DefaultLeaseTimeout = 30 * time.Second
+)
+
+// ErrLeaseLost is returned when a job is acknowledged or retried after its lease has expired
+// and the job has been leased again or moved to the dead letters.
+var ErrLeaseLost = errors.New("queue: lease has been lost")
+
+const (
+	statusReady  = "ready"
+	statusLeased = "leased"
+	statusDead   = "dead"
+)
+
+// Queue is a durable job queue stored in a table.
+// Several queues can share the same table as long as they have different names.
+type Queue struct {
+	db           *asynql.DB
+	name         string
+	table        string
+	maxAttempts  int
+	leaseTimeout time.Duration
+}
+
+// Option configures a Queue created by New.
+type Option func(*Queue)
+
+// WithTable returns an Option that stores the jobs in table instead of DefaultTable.
+// The queues of different names can share the table.
+func WithTable(table string) Option {
+	return func(q *Queue) {
+		q.table = table
+	}
+}
+
+// WithMaxAttempts returns an Option that moves a job to the dead letters when its n-th attempt fails.
+func WithMaxAttempts(n int) Option {
+	return func(q *Queue) {
+		q.maxAttempts = n
+	}
+}
+
+// WithLeaseTimeout returns an Option that sets the duration of a lease.
+func WithLeaseTimeout(d time.Duration) Option {
+	return func(q *Queue) {
+		q.leaseTimeout = d
+	}
+}
+
+// New returns a new Queue of the given name in db.
+// The SQL is generated for the dialect of db.
+func New(db *asynql.DB, name string, opts ...Option) *Queue {
+	q := &Queue{
+		db:           db,
+		name:         name,
+		table:        DefaultTable,
+		maxAttempts:  DefaultMaxAttempts,
+		leaseTimeout: DefaultLeaseTimeout,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Result represents a result of an operation on a job.
+type Result struct {
+	err error
+}
+
+// Err returns an error.
+func (r *Result) Err() error {
+	return r.err
+}
+
+// Lease represents a result of Queue.Lease.
+type Lease struct {
+	// Jobs is the leased jobs. It is empty if no job is ready.
+	Jobs []*Job
+
+	err error
+}
+
+// Err returns an error.
+func (l *Lease) Err() error {
+	return l.err
+}
+
+// Job is a leased job.
+type Job struct {
+	// ID identifies the job in the table.
+	ID int64
+
+	// Payload is the payload given to Enqueue.
+	Payload []byte
+
+	// Attempts is the number of the attempts including the current one.
+	Attempts int
+
+	q     *Queue
+	token string
+}
+
+// DeadJob is a job that has been moved to the dead letters.
+type DeadJob struct {
+	ID        int64
+	Payload   []byte
+	Attempts  int
+	LastError string
+}
+
+// DeadJobs represents a result of Queue.DeadJobs.
+type DeadJobs struct {
+	Jobs []*DeadJob
+
+	err error
+}
+
+// Err returns an error.
+func (d *DeadJobs) Err() error {
+	return d.err
+}
+
+// CreateTable creates the table of jobs if it doesn't exist.
+func (q *Queue) CreateTable(ctx context.Context) error {
+	d := q.db.Dialect()
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id %s,
+	queue VARCHAR(255) NOT NULL,
+	payload %s,
+	status VARCHAR(16) NOT NULL,
+	attempts INTEGER NOT NULL,
+	run_at BIGINT NOT NULL,
+	lease_until BIGINT NOT NULL,
+	lease_token VARCHAR(64) NOT NULL,
+	last_error TEXT
+)`, d.QuoteIdent(q.table), d.IdentityColumn(), d.BlobType())
+	return (<-q.db.ExecContext(ctx, query)).Err()
+}
+
+// Enqueue adds a job with payload to the queue.
+func (q *Queue) Enqueue(ctx context.Context, payload []byte) <-chan *asynql.Result {
+	return q.enqueue(ctx, q.db, payload, time.Now())
+}
+
+// EnqueueAfter is similar to Enqueue, but the job cannot be leased until delay has passed.
+func (q *Queue) EnqueueAfter(ctx context.Context, delay time.Duration, payload []byte) <-chan *asynql.Result {
+	return q.enqueue(ctx, q.db, payload, time.Now().Add(delay))
+}
+
+// EnqueueTx is similar to Enqueue, but adds the job in tx, so that the job is available only if tx is committed.
+// tx must be a transaction of the DB of the queue.
+func (q *Queue) EnqueueTx(ctx context.Context, tx *asynql.Tx, payload []byte) <-chan *asynql.Result {
+	return q.enqueue(ctx, tx, payload, time.Now())
+}
+
+func (q *Queue) enqueue(ctx context.Context, e asynql.Execer, payload []byte, runAt time.Time) <-chan *asynql.Result {
+	query := q.sql(`INSERT INTO %s (queue, payload, status, attempts, run_at, lease_until, lease_token, last_error) VALUES (?, ?, ?, 0, ?, 0, '', '')`)
+	return e.ExecContext(ctx, query, q.name, payload, statusReady, millis(runAt))
+}
+
+// Lease leases up to n jobs that are ready, in the order they became ready.
+// The jobs must be acknowledged by Job.Ack or retried by Job.Retry before the lease expires,
+// otherwise they are leased again by another call of Lease.
+// The dialects that support it use SELECT ... FOR UPDATE SKIP LOCKED, so that concurrent workers don't contend for the same jobs.
+func (q *Queue) Lease(ctx context.Context, n int) <-chan *Lease {
+	ch := make(chan *Lease)
+	go func() {
+		jobs, err := q.lease(ctx, n)
+		asynql.Deliver(q.db, ch, &Lease{
+			Jobs: jobs,
+			err:  err,
+		})
+	}()
+	return ch
+}
+
+func (q *Queue) lease(ctx context.Context, n int) ([]*Job, error) {
+	now := millis(time.Now())
+	// The jobs whose lease expired on their last attempt are never retried.
+	query := q.sql(`UPDATE %s SET status = ?, last_error = 'lease expired' WHERE queue = ? AND status = ? AND lease_until <= ? AND attempts >= ?`)
+	if err := (<-q.db.ExecContext(ctx, query, statusDead, q.name, statusLeased, now, q.maxAttempts)).Err(); err != nil {
+		return nil, err
+	}
+	if !q.db.Dialect().SupportsSkipLocked() {
+		return q.leaseJobs(ctx, q.db, n, now, "")
+	}
+	tx, err := q.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := q.leaseJobs(ctx, tx, n, now, " FOR UPDATE SKIP LOCKED")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// leaseJobs selects the candidates, and then leases each of them unless another worker has leased it in the meantime.
+func (q *Queue) leaseJobs(ctx context.Context, db asynql.Querier, n int, now int64, lock string) ([]*Job, error) {
+	const ready = `queue = ? AND ((status = ? AND run_at <= ?) OR (status = ? AND lease_until <= ?))`
+	query := q.sql(`SELECT id, payload, attempts FROM %s WHERE `+ready+` ORDER BY run_at, id LIMIT `+strconv.Itoa(n)) + lock
+	rows := <-db.QueryContext(ctx, query, q.name, statusReady, now, statusLeased, now)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var candidates []*Job
+	for rows.Next() {
+		j := &Job{q: q}
+		if err := rows.Scan(&j.ID, &j.Payload, &j.Attempts); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		candidates = append(candidates, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var jobs []*Job
+	query = q.sql(`UPDATE %s SET status = ?, attempts = attempts + 1, lease_until = ?, lease_token = ? WHERE id = ? AND ` + ready)
+	until := now + q.leaseTimeout.Milliseconds()
+	for _, j := range candidates {
+		token, err := newToken()
+		if err != nil {
+			return nil, err
+		}
+		r := <-db.ExecContext(ctx, query, statusLeased, until, token, j.ID, q.name, statusReady, now, statusLeased, now)
+		if err := r.Err(); err != nil {
+			return nil, err
+		}
+		if n, err := r.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			continue
+		}
+		j.Attempts++
+		j.token = token
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// Ack acknowledges that the job has been done, and removes it from the queue.
+// It reports ErrLeaseLost if the lease has expired and the job has been leased again.
+func (j *Job) Ack(ctx context.Context) <-chan *Result {
+	query := j.q.sql(`DELETE FROM %s WHERE id = ? AND status = ? AND lease_token = ?`)
+	return j.update(ctx, query, j.ID, statusLeased, j.token)
+}
+
+// Retry releases the job so that it is leased again after delay, recording cause as its last error.
+// If the job has been attempted as many times as the maximum, it is moved to the dead letters instead.
+// It reports ErrLeaseLost if the lease has expired and the job has been leased again.
+func (j *Job) Retry(ctx context.Context, delay time.Duration, cause error) <-chan *Result {
+	lastError := ""
+	if cause != nil {
+		lastError = cause.Error()
+	}
+	if j.Attempts >= j.q.maxAttempts {
+		query := j.q.sql(`UPDATE %s SET status = ?, last_error = ? WHERE id = ? AND status = ? AND lease_token = ?`)
+		return j.update(ctx, query, statusDead, lastError, j.ID, statusLeased, j.token)
+	}
+	query := j.q.sql(`UPDATE %s SET status = ?, run_at = ?, last_error = ? WHERE id = ? AND status = ? AND lease_token = ?`)
+	return j.update(ctx, query, statusReady, millis(time.Now().Add(delay)), lastError, j.ID, statusLeased, j.token)
+}
+
+func (j *Job) update(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	return exec(ctx, j.q.db, query, args, ErrLeaseLost)
+}
+
+// DeadJobs returns up to n jobs that have been moved to the dead letters.
+func (q *Queue) DeadJobs(ctx context.Context, n int) <-chan *DeadJobs {
+	ch := make(chan *DeadJobs)
+	go func() {
+		jobs, err := q.deadJobs(ctx, n)
+		asynql.Deliver(q.db, ch, &DeadJobs{
+			Jobs: jobs,
+			err:  err,
+		})
+	}()
+	return ch
+}
+
+func (q *Queue) deadJobs(ctx context.Context, n int) ([]*DeadJob, error) {
+	query := q.sql(`SELECT id, payload, attempts, last_error FROM %s WHERE queue = ? AND status = ? ORDER BY id LIMIT ` + strconv.Itoa(n))
+	rows := <-q.db.QueryContext(ctx, query, q.name, statusDead)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var jobs []*DeadJob
+	for rows.Next() {
+		j := &DeadJob{}
+		if err := rows.Scan(&j.ID, &j.Payload, &j.Attempts, &j.LastError); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// Requeue moves the dead job of id back to the queue, resetting its attempts.
+func (q *Queue) Requeue(ctx context.Context, id int64) <-chan *Result {
+	query := q.sql(`UPDATE %s SET status = ?, attempts = 0, run_at = ?, last_error = '' WHERE id = ? AND queue = ? AND status = ?`)
+	return exec(ctx, q.db, query, []interface{}{statusReady, millis(time.Now()), id, q.name, statusDead}, fmt.Errorf("queue: no dead job %d", id))
+}
+
+// exec executes query, and reports errNotFound if it affects no rows.
+func exec(ctx context.Context, db *asynql.DB, query string, args []interface{}, errNotFound error) <-chan *Result {
+	ch := make(chan *Result)
+	go func() {
+		r := <-db.ExecContext(ctx, query, args...)
+		err := r.Err()
+		if err == nil {
+			var n int64
+			if n, err = r.RowsAffected(); err == nil && n == 0 {
+				err = errNotFound
+			}
+		}
+		asynql.Deliver(db, ch, &Result{
+			err: err,
+		})
+	}()
+	return ch
+}
+
+// sql returns query with the quoted table name embedded and the placeholders rebound to the dialect.
+func (q *Queue) sql(query string) string {
+	d := q.db.Dialect()
+	return d.Rebind(fmt.Sprintf(query, d.QuoteIdent(q.table)))
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
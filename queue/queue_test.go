@@ -0,0 +1,190 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/naoina/asynql"
+	"github.com/naoina/asynql/queue"
+)
+
+func newTestQueue(t *testing.T, opts ...queue.Option) (*asynql.DB, *queue.Queue) {
+	db, err := asynql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	q := queue.New(db, "test", opts...)
+	if err := q.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db, q
+}
+
+func lease(t *testing.T, q *queue.Queue, n int) []*queue.Job {
+	l := <-q.Lease(context.Background(), n)
+	if err := l.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return l.Jobs
+}
+
+func payloads(jobs []*queue.Job) []string {
+	var s []string
+	for _, j := range jobs {
+		s = append(s, string(j.Payload))
+	}
+	return s
+}
+
+func TestQueue(t *testing.T) {
+	db, q := newTestQueue(t, queue.WithMaxAttempts(2))
+	defer db.Close()
+	ctx := context.Background()
+	for _, p := range []string{"a", "b", "c"} {
+		if err := (<-q.Enqueue(ctx, []byte(p))).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs := lease(t, q, 2)
+	var actual interface{} = payloads(jobs)
+	var expected interface{} = []string{"a", "b"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf(`q.Lease(ctx, 2) => %#v; want %#v`, actual, expected)
+	}
+	if err := (<-jobs[0].Ack(ctx)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-jobs[1].Retry(ctx, 0, errors.New("failed"))).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The retried job becomes ready at the time of the retry, which may or may not be the same millisecond as
+	// the job that is already waiting, so the jobs are looked up by their payloads instead of their order.
+	leased := map[string]*queue.Job{}
+	for _, j := range lease(t, q, 10) {
+		leased[string(j.Payload)] = j
+	}
+	if len(leased) != 2 || leased["b"] == nil || leased["c"] == nil {
+		t.Fatalf(`q.Lease(ctx, 10) after retry => %#v; want jobs "b" and "c"`, leased)
+	}
+	actual = leased["b"].Attempts
+	expected = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf(`q.Lease(ctx, 10) after retry => attempts %#v; want %#v`, actual, expected)
+	}
+	// The second attempt is the last one.
+	if err := (<-leased["b"].Retry(ctx, 0, errors.New("failed again"))).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-leased["c"].Ack(ctx)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	actual = payloads(lease(t, q, 10))
+	expected = []string(nil)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`q.Lease(ctx, 10) after dead-lettering => %#v; want %#v`, actual, expected)
+	}
+
+	dead := <-q.DeadJobs(ctx, 10)
+	if err := dead.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(dead.Jobs) != 1 {
+		t.Fatalf(`q.DeadJobs(ctx, 10) => %d jobs; want 1`, len(dead.Jobs))
+	}
+	actual = []interface{}{string(dead.Jobs[0].Payload), dead.Jobs[0].Attempts, dead.Jobs[0].LastError}
+	expected = []interface{}{"b", 2, "failed again"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`q.DeadJobs(ctx, 10) => %#v; want %#v`, actual, expected)
+	}
+	if err := (<-q.Requeue(ctx, dead.Jobs[0].ID)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	jobs = lease(t, q, 10)
+	actual = []interface{}{payloads(jobs), jobs[0].Attempts}
+	expected = []interface{}{[]string{"b"}, 1}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`q.Lease(ctx, 10) after requeue => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestQueue_LeaseExpired(t *testing.T) {
+	db, q := newTestQueue(t, queue.WithLeaseTimeout(10*time.Millisecond))
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-q.Enqueue(ctx, []byte("a"))).Err(); err != nil {
+		t.Fatal(err)
+	}
+	stale := lease(t, q, 1)
+	if len(stale) != 1 {
+		t.Fatalf(`q.Lease(ctx, 1) => %d jobs; want 1`, len(stale))
+	}
+	if jobs := lease(t, q, 1); len(jobs) != 0 {
+		t.Errorf(`q.Lease(ctx, 1) while leased => %d jobs; want 0`, len(jobs))
+	}
+	time.Sleep(20 * time.Millisecond)
+	jobs := lease(t, q, 1)
+	var actual interface{} = payloads(jobs)
+	var expected interface{} = []string{"a"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf(`q.Lease(ctx, 1) after expiry => %#v; want %#v`, actual, expected)
+	}
+	actual = (<-stale[0].Ack(ctx)).Err()
+	expected = queue.ErrLeaseLost
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`stale job.Ack(ctx) => %#v; want %#v`, actual, expected)
+	}
+	if err := (<-jobs[0].Ack(ctx)).Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueue_EnqueueTx(t *testing.T) {
+	db, q := newTestQueue(t)
+	defer db.Close()
+	ctx := context.Background()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-q.EnqueueTx(ctx, tx, []byte("a"))).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if jobs := lease(t, q, 1); len(jobs) != 0 {
+		t.Errorf(`q.Lease(ctx, 1) after rollback => %d jobs; want 0`, len(jobs))
+	}
+}
+
+func TestQueue_ChannelClosed(t *testing.T) {
+	db, q := newTestQueue(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-q.Enqueue(ctx, []byte("a"))).Err(); err != nil {
+		t.Fatal(err)
+	}
+	leases := q.Lease(ctx, 1)
+	jobs := (<-leases).Jobs
+	if l, ok := <-leases; l != nil || ok {
+		t.Errorf(`second receive of q.Lease => %#v, %v; want nil, false`, l, ok)
+	}
+	acks := jobs[0].Ack(ctx)
+	if err := (<-acks).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := <-acks; r != nil || ok {
+		t.Errorf(`second receive of job.Ack => %#v, %v; want nil, false`, r, ok)
+	}
+	dead := q.DeadJobs(ctx, 1)
+	<-dead
+	if r, ok := <-dead; r != nil || ok {
+		t.Errorf(`second receive of q.DeadJobs => %#v, %v; want nil, false`, r, ok)
+	}
+}
@@ -0,0 +1,101 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrQuotaExceeded is reported by the operations of a tenant that already has the maximum number of the waiting operations
+// allowed by WithTenantQuota.
+var ErrQuotaExceeded = errors.New("asynql: tenant quota exceeded")
+
+// WithTenantQuota returns an Option that limits the asynchronous operations of each tenant, which is identified by tenantOf from the context of an operation,
+// so that a noisy tenant cannot starve the others in a multi-tenant service.
+// Each tenant runs up to limit operations concurrently, and the others wait in the order they were called,
+// apart from the operations of the other tenants, so that they don't hold a worker of WithWorkers or a slot of WithMaxConcurrency.
+// If the tenant already has queue operations waiting, an operation fails with ErrQuotaExceeded without running.
+// The operations whose contexts have no tenant, for which tenantOf returns "", are not limited.
+// If limit <= 0, WithTenantQuota has no effect.
+//
+//	db, err := asynql.Open("postgres", dsn, asynql.WithTenantQuota(asynql.TenantOf, 4, 16))
+//	rows := <-db.QueryContext(asynql.WithTenant(ctx, "acme"), query)
+func WithTenantQuota(tenantOf func(ctx context.Context) string, limit, queue int) Option {
+	return func(db *DB) {
+		db.quota = nil
+		if limit > 0 {
+			db.quota = &tenantQuota{
+				tenant: tenantOf,
+				limit:  limit,
+				queue:  max(queue, 0),
+			}
+		}
+	}
+}
+
+// tenantQuota limits the operations of each tenant.
+type tenantQuota struct {
+	tenant func(ctx context.Context) string
+	limit  int
+	queue  int
+
+	mu      sync.Mutex
+	tenants map[string]*tenantState
+}
+
+type tenantState struct {
+	running int
+	waiting []func()
+}
+
+// tenantOf returns the tenant of ctx, or "" if q is nil.
+func (q *tenantQuota) tenantOf(ctx context.Context) string {
+	if q == nil {
+		return ""
+	}
+	return q.tenant(ctx)
+}
+
+// admit reports whether tenant has a free slot, which is taken by the caller that starts its operation now.
+// Otherwise, start is called when a slot is released, or ErrQuotaExceeded is returned if the queue of tenant is full.
+func (q *tenantQuota) admit(tenant string, start func()) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.tenants == nil {
+		q.tenants = make(map[string]*tenantState)
+	}
+	t, ok := q.tenants[tenant]
+	if !ok {
+		t = &tenantState{}
+		q.tenants[tenant] = t
+	}
+	if t.running < q.limit {
+		t.running++
+		return true, nil
+	}
+	if len(t.waiting) >= q.queue {
+		return false, ErrQuotaExceeded
+	}
+	t.waiting = append(t.waiting, start)
+	return false, nil
+}
+
+// release hands the slot of tenant over to its first waiting operation, or frees it.
+func (q *tenantQuota) release(tenant string) {
+	q.mu.Lock()
+	t := q.tenants[tenant]
+	if len(t.waiting) == 0 {
+		t.running--
+		if t.running == 0 {
+			delete(q.tenants, tenant)
+		}
+		q.mu.Unlock()
+		return
+	}
+	start := t.waiting[0]
+	t.waiting[0] = nil
+	t.waiting = t.waiting[1:]
+	q.mu.Unlock()
+	// release is called by the goroutine of the operation, which can be a worker that would wait for itself to queue start.
+	go start()
+}
@@ -0,0 +1,65 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithTenantQuota(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithTenantQuota(asynql.TenantOf, 1, 1))
+	defer db.Close()
+	a := asynql.WithTenant(context.Background(), "a")
+	b := asynql.WithTenant(context.Background(), "b")
+	blocked := db.ExecContext(a, "block")
+	<-conn.started
+	waiting := db.ExecContext(a, "a")
+	if err := (<-db.ExecContext(a, "rejected")).Err(); !errors.Is(err, asynql.ErrQuotaExceeded) {
+		t.Errorf(`db.ExecContext(a, "rejected"); Result.Err() => %#v; want %#v`, err, asynql.ErrQuotaExceeded)
+	}
+	if _, err := db.TrySubmitExec(a, "rejected"); !errors.Is(err, asynql.ErrQuotaExceeded) {
+		t.Errorf(`db.TrySubmitExec(a, "rejected") => %#v; want %#v`, err, asynql.ErrQuotaExceeded)
+	}
+
+	// The other tenants are not blocked by a.
+	if err := (<-db.ExecContext(b, "b")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-db.Exec("none")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	close(conn.release)
+	if _, err := asynql.WaitAll(blocked, waiting); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = conn.queries
+	var expected interface{} = []string{"b", "none", "block", "a"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`executed queries => %#v; want %#v`, actual, expected)
+	}
+	actual = db.PoolStats().Queued
+	expected = int64(0)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.PoolStats().Queued => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestWithTenantQuota_Workers(t *testing.T) {
+	db := newTestDB(t, asynql.WithWorkers(1, 0), asynql.WithTenantQuota(asynql.TenantOf, 1, 8))
+	defer db.Close()
+	ctx := asynql.WithTenant(context.Background(), "a")
+	results := make([]<-chan *asynql.Result, 8)
+	for i := range results {
+		results[i] = db.ExecContext(ctx, `UPDATE test_table SET name = name`)
+	}
+	if _, err := asynql.WaitAll(results...); err != nil {
+		t.Fatal(err)
+	}
+}
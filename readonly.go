@@ -0,0 +1,165 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrReadOnly is reported by the statements other than SELECT on a DB returned by ReadOnly.
+var ErrReadOnly = errors.New("asynql: only SELECT statements are allowed on a read-only DB")
+
+// readOnlyVerbs is the first keywords of the statements that a read-only DB allows, unless they modify a table.
+var readOnlyVerbs = map[string]bool{
+	"select":   true,
+	"with":     true,
+	"values":   true,
+	"table":    true,
+	"show":     true,
+	"describe": true,
+	"desc":     true,
+	"explain":  true,
+	"declare":  true,
+	"fetch":    true,
+}
+
+// ReadOnly returns a facade of db whose operations, including those of its transactions, connections and statements,
+// refuse the statements other than SELECT with ErrReadOnly without running them,
+// so that it can be handed to the code such as reports with confidence that it can't modify the data.
+// A statement is told by its first keyword and the tables that it modifies, e.g. WITH ... DELETE and SELECT ... INTO are refused.
+// Each of the stacked statements of a query is checked, so SELECT 1; DELETE FROM t is refused as a whole.
+//
+// The facade shares the connection pool, the options and the result cache of WithCache with db,
+// but it has its own statistics, FIFO order, Pause and maintenance mode, and doesn't use the statements of WithStmtCache.
+func (db *DB) ReadOnly() *ReadOnlyDB {
+	if db.primary != nil {
+		return &ReadOnlyDB{db: db}
+	}
+	ro := &DB{
+		DB:      db.DB,
+		config:  db.config,
+		dialect: db.Dialect(),
+		primary: db,
+	}
+	ro.dialectOnce.Do(func() {})
+	return &ReadOnlyDB{db: ro}
+}
+
+// ReadOnlyDB is a read-only facade of a DB returned by ReadOnly.
+// Unlike a DB, it doesn't expose the sql.DB, so the connection pool can't be used or reconfigured through it.
+// ReadOnlyDB implements Querier, so it can be used in place of a DB.
+type ReadOnlyDB struct {
+	db *DB
+}
+
+// Exec is similar to DB.Exec, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) Exec(query string, args ...interface{}) <-chan *Result {
+	return ro.db.Exec(query, args...)
+}
+
+// ExecContext is similar to DB.ExecContext, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	return ro.db.ExecContext(ctx, query, args...)
+}
+
+// Query is similar to DB.Query, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) Query(query string, args ...interface{}) <-chan *Rows {
+	return ro.db.Query(query, args...)
+}
+
+// QueryContext is similar to DB.QueryContext, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	return ro.db.QueryContext(ctx, query, args...)
+}
+
+// QueryRow is similar to DB.QueryRow, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) QueryRow(query string, args ...interface{}) <-chan *Row {
+	return ro.db.QueryRow(query, args...)
+}
+
+// QueryRowContext is similar to DB.QueryRowContext, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) <-chan *Row {
+	return ro.db.QueryRowContext(ctx, query, args...)
+}
+
+// CachedQuery is similar to DB.CachedQuery, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) CachedQuery(query string, args []interface{}, ttl time.Duration) <-chan *Rows {
+	return ro.db.CachedQuery(query, args, ttl)
+}
+
+// CachedQueryContext is similar to DB.CachedQueryContext, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) CachedQueryContext(ctx context.Context, query string, args []interface{}, ttl time.Duration) <-chan *Rows {
+	return ro.db.CachedQueryContext(ctx, query, args, ttl)
+}
+
+// QueryMulti is similar to DB.QueryMulti, but refuses the statements other than SELECT.
+func (ro *ReadOnlyDB) QueryMulti(ctx context.Context, query string, args ...interface{}) <-chan *ResultSet {
+	return ro.db.QueryMulti(ctx, query, args...)
+}
+
+// ExecMulti is similar to DB.ExecMulti, but refuses the scripts that have statements other than SELECT.
+func (ro *ReadOnlyDB) ExecMulti(ctx context.Context, script string) <-chan *ScriptResult {
+	return ro.db.ExecMulti(ctx, script)
+}
+
+// Begin is similar to DB.Begin, but the statements of the transaction other than SELECT are refused.
+func (ro *ReadOnlyDB) Begin() (*Tx, error) {
+	return ro.db.Begin()
+}
+
+// BeginTx is similar to sql.DB.BeginTx, but returns an *asynql.Tx whose statements other than SELECT are refused.
+func (ro *ReadOnlyDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := ro.db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newTx(ctx, ro.db, tx), nil
+}
+
+// Conn is similar to DB.Conn, but the statements of the connection other than SELECT are refused.
+func (ro *ReadOnlyDB) Conn(ctx context.Context) (*Conn, error) {
+	return ro.db.Conn(ctx)
+}
+
+// Prepare is similar to DB.Prepare, but the executions of the statement are refused if it is not a SELECT statement.
+func (ro *ReadOnlyDB) Prepare(query string) (*Stmt, error) {
+	return ro.db.Prepare(query)
+}
+
+// PrepareContext is similar to DB.PrepareContext, but the executions of the statement are refused if it is not a SELECT statement.
+func (ro *ReadOnlyDB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	return ro.db.PrepareContext(ctx, query)
+}
+
+// Dialect returns the dialect of the DB.
+func (ro *ReadOnlyDB) Dialect() Dialect {
+	return ro.db.Dialect()
+}
+
+// rejectsStatement reports whether db is read-only and any statement of query is not a SELECT statement.
+func (db *DB) rejectsStatement(query string) bool {
+	if db == nil || db.primary == nil {
+		return false
+	}
+	stmts := splitStatements(query)
+	for _, stmt := range stmts {
+		if writesStatement(stmt, lexSQL(stmt)) {
+			return true
+		}
+	}
+	return len(stmts) == 0
+}
+
+// statementVerb returns the first keyword of the statement of tokens, which tells its class, or "" if there is none.
+func statementVerb(tokens []token) string {
+	for _, t := range tokens {
+		if t.kind == tokWord {
+			return t.text
+		}
+		if !t.isPunct("(") {
+			break
+		}
+	}
+	return ""
+}
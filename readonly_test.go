@@ -0,0 +1,92 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_ReadOnly(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ro := db.ReadOnly()
+	ctx := context.Background()
+	for _, query := range []string{
+		`UPDATE test_table SET name = 'carol'`,
+		`WITH t AS (SELECT 1) DELETE FROM test_table`,
+		`CREATE TABLE t (id INTEGER)`,
+		`SELECT 1; CREATE TABLE zz (x INTEGER)`,
+		`SELECT * INTO zz FROM test_table`,
+	} {
+		if err := (<-ro.ExecContext(ctx, query)).Err(); !errors.Is(err, asynql.ErrReadOnly) {
+			t.Errorf(`ro.ExecContext(ctx, %#v) => %#v; want %#v`, query, err, asynql.ErrReadOnly)
+		}
+	}
+	if err := (<-ro.QueryContext(ctx, `DELETE FROM test_table RETURNING id`)).Err(); !errors.Is(err, asynql.ErrReadOnly) {
+		t.Errorf(`ro.QueryContext(ctx, DELETE ... RETURNING) => %#v; want %#v`, err, asynql.ErrReadOnly)
+	}
+	tx, err := ro.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-tx.ExecContext(ctx, `DELETE FROM test_table`)).Err(); !errors.Is(err, asynql.ErrReadOnly) {
+		t.Errorf(`tx.ExecContext(ctx, DELETE) of ro => %#v; want %#v`, err, asynql.ErrReadOnly)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual interface{} = scanNames(t, <-ro.QueryContext(ctx, `/* report */ SELECT name FROM test_table ORDER BY id`))
+	var expected interface{} = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`ro.QueryContext(ctx, SELECT) => %#v; want %#v`, actual, expected)
+	}
+
+	tx, err = ro.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-tx.ExecContext(ctx, `DELETE FROM test_table`)).Err(); !errors.Is(err, asynql.ErrReadOnly) {
+		t.Errorf(`tx.ExecContext(ctx, DELETE) of ro.BeginTx => %#v; want %#v`, err, asynql.ErrReadOnly)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ro.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-conn.ExecContext(ctx, `DELETE FROM test_table`)).Err(); !errors.Is(err, asynql.ErrReadOnly) {
+		t.Errorf(`conn.ExecContext(ctx, DELETE) of ro.Conn => %#v; want %#v`, err, asynql.ErrReadOnly)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (<-db.ExecContext(ctx, `UPDATE test_table SET name = 'carol' WHERE id = 1`)).Err(); err != nil {
+		t.Fatalf(`db.ExecContext(ctx, UPDATE) => %#v; want nil`, err)
+	}
+	var name string
+	if err := (<-ro.QueryRowContext(ctx, `SELECT name FROM test_table WHERE id = 1`)).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	actual = name
+	expected = "carol"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`ro.QueryRowContext(ctx, SELECT) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDB_ReadOnly_Dialect(t *testing.T) {
+	db := newTestDB(t, asynql.WithDialect(asynql.DialectPostgres))
+	defer db.Close()
+	var actual interface{} = db.ReadOnly().Dialect()
+	var expected interface{} = asynql.DialectPostgres
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.ReadOnly().Dialect() => %#v; want %#v`, actual, expected)
+	}
+	var _ asynql.Querier = db.ReadOnly()
+}
@@ -0,0 +1,245 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// Register registers a driver that wraps base as name, so that the hooks, the retries and the connection options of asynql
+// apply to the code that uses database/sql directly, such as the embedded *sql.DB of a DB and third-party libraries:
+//
+//	asynql.Register("asynql-postgres", &pq.Driver{}, asynql.WithHook(logQuery), asynql.WithBadConnRetry())
+//	db, err := sql.Open("asynql-postgres", dsn)
+//
+// Only the options that are about the queries and the connections take effect, which are WithHook, WithBadConnRetry,
+// WithBusyRetry, WithConnInit and WithLocation. The others configure the asynchronous operations of a DB, so they are ignored.
+// A query is reported to the hooks each time the driver runs it, and a query that database/sql runs again on another connection
+// is reported again.
+// The retries of WithBusyRetry and WithBadConnRetry are not applied in a transaction, as in a DB.
+// WithBadConnRetry makes the driver report a stale connection as driver.ErrBadConn, by which database/sql runs the query again on a new connection,
+// only for the queries that read and the Execs whose context is marked by WithIdempotent, as in a DB.
+// A query that modifies a table or locks rows, such as INSERT ... RETURNING, fails with the stale connection instead,
+// since database/sql would run it up to three times.
+//
+// As sql.Register does, Register panics if it is called twice with the same name.
+func Register(name string, base driver.Driver, opts ...Option) {
+	sql.Register(name, &hookDriver{
+		base: base,
+		db:   newDB(opts),
+	})
+}
+
+// hookDriver is a driver.Driver that wraps the connections of base by the options of db.
+type hookDriver struct {
+	base driver.Driver
+	db   *DB
+}
+
+func (d *hookDriver) Open(dsn string) (driver.Conn, error) {
+	c, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+func (d *hookDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	c, err := connectorOf(d.base, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &hookConnector{
+		Connector: d.db.wrapConnector(c),
+		driver:    d,
+	}, nil
+}
+
+type hookConnector struct {
+	driver.Connector
+
+	driver *hookDriver
+}
+
+func (c *hookConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &hookConn{
+		connWrapper: wrapConn(dc),
+		db:          c.driver.db,
+	}, nil
+}
+
+func (c *hookConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// hookConn reports the queries to the hooks of db, and retries them by the options of db.
+// database/sql uses a connection from one goroutine at a time, so inTx needs no lock.
+type hookConn struct {
+	*connWrapper
+
+	db   *DB
+	inTx bool
+}
+
+func (c *hookConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return runHooked(ctx, c, HookExec, query, args, isIdempotent(ctx), func() (driver.Result, error) {
+		return c.connWrapper.ExecContext(ctx, query, args)
+	})
+}
+
+func (c *hookConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return runHooked(ctx, c, HookQuery, query, args, isReadQuery(query), func() (driver.Rows, error) {
+		return c.connWrapper.QueryContext(ctx, query, args)
+	})
+}
+
+func (c *hookConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.connWrapper.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &hookStmt{
+		Stmt:  stmt,
+		conn:  c,
+		query: query,
+	}, nil
+}
+
+func (c *hookConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *hookConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx, err := c.connWrapper.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &hookTx{
+		Tx:   tx,
+		conn: c,
+	}, nil
+}
+
+func (c *hookConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// hookTx tells its connection the end of the transaction.
+type hookTx struct {
+	driver.Tx
+
+	conn *hookConn
+}
+
+func (tx *hookTx) Commit() error {
+	tx.conn.inTx = false
+	return tx.Tx.Commit()
+}
+
+func (tx *hookTx) Rollback() error {
+	tx.conn.inTx = false
+	return tx.Tx.Rollback()
+}
+
+// hookStmt reports the queries of Stmt to the hooks of the DB of conn, and retries them likewise.
+type hookStmt struct {
+	driver.Stmt
+
+	conn  *hookConn
+	query string
+}
+
+func (s *hookStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return runHooked(ctx, s.conn, HookExec, s.query, args, isIdempotent(ctx), func() (driver.Result, error) {
+		if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+			return e.ExecContext(ctx, args)
+		}
+		values, err := driverValues(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Stmt.Exec(values)
+	})
+}
+
+func (s *hookStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return runHooked(ctx, s.conn, HookQuery, s.query, args, isReadQuery(s.query), func() (driver.Rows, error) {
+		if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			return q.QueryContext(ctx, args)
+		}
+		values, err := driverValues(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return s.Stmt.Query(values)
+	})
+}
+
+func (s *hookStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// driverValues converts args for the drivers that don't support the context, as database/sql does.
+func driverValues(ctx context.Context, args []driver.NamedValue) ([]driver.Value, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("asynql: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// runHooked runs fn, which runs query on conn, retries it by the options of the DB of conn, and reports it to the hooks.
+// If retry is true, a stale connection is reported as driver.ErrBadConn so that database/sql runs the query again.
+func runHooked[T any](ctx context.Context, conn *hookConn, op HookOp, query string, args []driver.NamedValue, retry bool, fn func() (T, error)) (T, error) {
+	db := conn.db
+	start := time.Now()
+	v, err := fn()
+	if err == driver.ErrSkip {
+		return v, err
+	}
+	for i := 0; !conn.inTx && i < db.busyRetries && isBusy(err); i++ {
+		if sleepContext(ctx, jitter(db.busyBackoff<<min(i, maxBusyBackoffShift))) != nil {
+			break
+		}
+		v, err = fn()
+	}
+	if len(db.hooks) > 0 {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			values[i] = arg.Value
+		}
+		e := &HookEvent{
+			Op:       op,
+			Query:    query,
+			Name:     queryNameOf(ctx),
+			Tags:     tagsOf(ctx),
+			Labels:   labelsOf(ctx),
+			Args:     values,
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		for _, hook := range db.hooks {
+			hook(ctx, e)
+		}
+	}
+	if retry && !conn.inTx && db.badConnRetry && isBadConn(err) {
+		err = driver.ErrBadConn
+	}
+	return v, err
+}
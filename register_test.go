@@ -0,0 +1,129 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+var registered atomic.Int64
+
+// register registers base by asynql.Register with a new name, since a name cannot be registered twice.
+func register(base driver.Driver, opts ...asynql.Option) string {
+	name := fmt.Sprintf("asynql-test-%d", registered.Add(1))
+	asynql.Register(name, base, opts...)
+	return name
+}
+
+type flakyDriver struct {
+	conn *flakyConn
+}
+
+func (d flakyDriver) Open(string) (driver.Conn, error) {
+	return d.conn, nil
+}
+
+func TestRegister(t *testing.T) {
+	sqlite, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := sqlite.Driver()
+	sqlite.Close()
+	var mu sync.Mutex
+	var events []string
+	name := register(base, asynql.WithHook(func(ctx context.Context, e *asynql.HookEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf("%v %s %v %v", e.Op, e.Query, e.Args, e.Err))
+	}))
+	db, err := sql.Open(name, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE t (id INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare(`INSERT INTO t (id) VALUES (?)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Exec(1); err != nil {
+		t.Fatal(err)
+	}
+	stmt.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM t WHERE id = ?`, 1).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = events
+	var expected interface{} = []string{
+		"exec CREATE TABLE t (id INTEGER) [] <nil>",
+		"exec INSERT INTO t (id) VALUES (?) [1] <nil>",
+		"query SELECT COUNT(*) FROM t WHERE id = ? [1] <nil>",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`events => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestRegister_BadConnRetry(t *testing.T) {
+	for _, v := range []struct {
+		name     string
+		run      func(db *sql.DB) error
+		expected bool
+	}{
+		{"Query", func(db *sql.DB) error {
+			rows, err := db.Query("SELECT")
+			if err == nil {
+				rows.Close()
+			}
+			return err
+		}, true},
+		{"Query RETURNING", func(db *sql.DB) error {
+			rows, err := db.Query("INSERT INTO t VALUES (1) RETURNING id")
+			if err == nil {
+				rows.Close()
+			}
+			return err
+		}, false},
+		{"Exec", func(db *sql.DB) error {
+			_, err := db.Exec("UPDATE")
+			return err
+		}, false},
+		{"idempotent Exec", func(db *sql.DB) error {
+			_, err := db.ExecContext(asynql.WithIdempotent(context.Background()), "UPDATE")
+			return err
+		}, true},
+	} {
+		conn := &flakyConn{failures: 1, err: io.ErrUnexpectedEOF}
+		db, err := sql.Open(register(flakyDriver{conn}, asynql.WithBadConnRetry()), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = v.run(db)
+		db.Close()
+		var actual interface{} = err == nil
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%s: error => %#v; want success %#v`, v.name, err, expected)
+		}
+	}
+}
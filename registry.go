@@ -0,0 +1,134 @@
+package asynql
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Registry holds named query templates, which are run by the NamedQuery methods of DB.
+// The templates use named parameters such as :name, which are bound by Dialect.BindNamed.
+// A Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	queries map[string]string
+}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		queries: make(map[string]string),
+	}
+}
+
+// Register registers query as the template of name, replacing the template of the same name if any.
+func (r *Registry) Register(name, query string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries[name] = query
+}
+
+// LoadFS registers the files in fsys that match pattern, e.g. an embed.FS and "queries/*.sql".
+// The name of a template is the base name of its file without the extension.
+// It is an error if two files have the same name.
+func (r *Registry) LoadFS(fsys fs.FS, pattern string) error {
+	paths, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	queries := make(map[string]string, len(paths))
+	for _, p := range paths {
+		name := strings.TrimSuffix(path.Base(p), path.Ext(p))
+		if _, ok := queries[name]; ok {
+			return fmt.Errorf("asynql: duplicate query template %q in %s", name, p)
+		}
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		queries[name] = string(b)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, query := range queries {
+		r.queries[name] = query
+	}
+	return nil
+}
+
+// Lookup returns the template of name.
+func (r *Registry) Lookup(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	query, ok := r.queries[name]
+	return query, ok
+}
+
+// WithRegistry returns an Option that runs the templates of r by the NamedQuery methods of DB.
+func WithRegistry(r *Registry) Option {
+	return func(db *DB) {
+		db.registry = r
+	}
+}
+
+// bindTemplate returns the query and the arguments of the template of name bound with args,
+// and the context that carries the name to the hooks.
+func (db *DB) bindTemplate(ctx context.Context, name string, args map[string]interface{}) (context.Context, string, []interface{}, error) {
+	if db.registry == nil {
+		return ctx, "", nil, fmt.Errorf("asynql: no registry for query template %q", name)
+	}
+	tmpl, ok := db.registry.Lookup(name)
+	if !ok {
+		return ctx, "", nil, fmt.Errorf("asynql: unknown query template %q", name)
+	}
+	query, bound, err := db.Dialect().BindNamed(tmpl, args)
+	if err != nil {
+		return ctx, "", nil, fmt.Errorf("asynql: query template %q: %w", name, err)
+	}
+	return WithQueryName(ctx, name), query, bound, nil
+}
+
+// ExecNamedQuery is similar to Exec, but executes the template of name in the registry with args.
+func (db *DB) ExecNamedQuery(name string, args map[string]interface{}) <-chan *Result {
+	return db.ExecNamedQueryContext(context.Background(), name, args)
+}
+
+// ExecNamedQueryContext is similar to ExecContext, but executes the template of name in the registry with args.
+func (db *DB) ExecNamedQueryContext(ctx context.Context, name string, args map[string]interface{}) <-chan *Result {
+	ctx, query, bound, err := db.bindTemplate(ctx, name, args)
+	if err != nil {
+		return sendResult(db, &Result{err: err})
+	}
+	return db.ExecContext(ctx, query, bound...)
+}
+
+// QueryNamedQuery is similar to Query, but executes the template of name in the registry with args.
+func (db *DB) QueryNamedQuery(name string, args map[string]interface{}) <-chan *Rows {
+	return db.QueryNamedQueryContext(context.Background(), name, args)
+}
+
+// QueryNamedQueryContext is similar to QueryContext, but executes the template of name in the registry with args.
+func (db *DB) QueryNamedQueryContext(ctx context.Context, name string, args map[string]interface{}) <-chan *Rows {
+	ctx, query, bound, err := db.bindTemplate(ctx, name, args)
+	if err != nil {
+		return sendResult(db, &Rows{err: err})
+	}
+	return db.QueryContext(ctx, query, bound...)
+}
+
+// QueryRowNamedQuery is similar to QueryRow, but executes the template of name in the registry with args.
+func (db *DB) QueryRowNamedQuery(name string, args map[string]interface{}) <-chan *Row {
+	return db.QueryRowNamedQueryContext(context.Background(), name, args)
+}
+
+// QueryRowNamedQueryContext is similar to QueryRowContext, but executes the template of name in the registry with args.
+func (db *DB) QueryRowNamedQueryContext(ctx context.Context, name string, args map[string]interface{}) <-chan *Row {
+	ctx, query, bound, err := db.bindTemplate(ctx, name, args)
+	if err != nil {
+		return sendResult(db, &Row{err: err})
+	}
+	return db.QueryRowContext(ctx, query, bound...)
+}
@@ -0,0 +1,66 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/naoina/asynql"
+)
+
+func TestRegistry(t *testing.T) {
+	r := asynql.NewRegistry()
+	fsys := fstest.MapFS{
+		"queries/insert_user.sql": {Data: []byte(`INSERT INTO test_table (id, name) VALUES (:id, :name)`)},
+		"queries/find_user.sql":   {Data: []byte(`SELECT name FROM test_table WHERE id = :id`)},
+		"queries/README.md":       {Data: []byte(`not a query`)},
+	}
+	if err := r.LoadFS(fsys, "queries/*.sql"); err != nil {
+		t.Fatal(err)
+	}
+	r.Register("list_users", `SELECT name FROM test_table ORDER BY id`)
+	if _, ok := r.Lookup("README"); ok {
+		t.Errorf(`r.Lookup("README") => _, true; want false`)
+	}
+
+	var names []string
+	db := newTestDB(t, asynql.WithRegistry(r), asynql.WithHook(func(ctx context.Context, e *asynql.HookEvent) {
+		names = append(names, e.Name)
+	}))
+	defer db.Close()
+	if err := (<-db.ExecNamedQuery("insert_user", map[string]interface{}{"id": 3, "name": "carol"})).Err(); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := (<-db.QueryRowNamedQuery("find_user", map[string]interface{}{"id": 3})).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = name
+	var expected interface{} = "carol"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryRowNamedQuery("find_user", ...).Scan(&name) => %#v; want %#v`, actual, expected)
+	}
+	actual = scanNames(t, <-db.QueryNamedQuery("list_users", nil))
+	expected = []string{"alice", "bob", "carol"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryNamedQuery("list_users", nil) => %#v; want %#v`, actual, expected)
+	}
+	actual = names
+	expected = []string{"insert_user", "find_user", "list_users"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`HookEvent.Name => %#v; want %#v`, actual, expected)
+	}
+
+	for _, v := range []struct {
+		name string
+		args map[string]interface{}
+	}{
+		{"unknown", nil},
+		{"find_user", nil},
+	} {
+		if err := (<-db.ExecNamedQuery(v.name, v.args)).Err(); err == nil {
+			t.Errorf(`db.ExecNamedQuery(%#v, %#v).Err() => nil; want error`, v.name, v.args)
+		}
+	}
+}
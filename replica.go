@@ -0,0 +1,428 @@
+package asynql
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// primaryToken is the token of a session that has written to a primary whose replication position cannot be tracked,
+// so that the session reads from the primary.
+const primaryToken = "primary"
+
+// DefaultProbeInterval is the minimum interval between the queries of the replication position of a replica, unless it is set by SetProbeInterval.
+const DefaultProbeInterval = 100 * time.Millisecond
+
+// ReplicaSet is a primary database and its read replicas.
+// Exec of ReplicaSet is run on the primary, and Query and QueryRow are run on the replicas in turn,
+// except that a query that modifies a table or locks rows, such as INSERT ... RETURNING and SELECT ... FOR UPDATE,
+// is run on the primary, as is a query of stacked statements any of which does.
+//
+// Since a replica lags behind the primary, a read that follows a write can miss the write.
+// The reads with a context of WithSession see the writes made with the same session:
+// a write records the replication position of the primary after it, i.e. the LSN of PostgreSQL or the GTID set of MySQL,
+// and a read is run on a replica that has replayed up to the position, or on the primary if no replica has.
+// For the other dialects, the reads of a session that has written are run on the primary.
+// The position of each replica is cached, and is queried again only when it is behind the position that a read waits for,
+// concurrently for the replicas and once at a time for each of them, and at most once in the interval of SetProbeInterval.
+// A read that finds no replica caught up within the interval is run on the primary.
+type ReplicaSet struct {
+	primary   *DB
+	replicas  []*DB
+	positions []replicaPosition
+	next      atomic.Uint64
+	interval  atomic.Int64
+}
+
+// replicaPosition is the last observed replication position of a replica.
+type replicaPosition struct {
+	// probe is held while the position is queried, so that concurrent reads share a query.
+	probe chan struct{}
+
+	mu       sync.Mutex
+	position string
+	probed   time.Time
+}
+
+// NewReplicaSet returns a new ReplicaSet of primary and replicas.
+// If there are no replicas, all the operations are run on the primary.
+func NewReplicaSet(primary *DB, replicas ...*DB) *ReplicaSet {
+	rs := &ReplicaSet{
+		primary:   primary,
+		replicas:  replicas,
+		positions: make([]replicaPosition, len(replicas)),
+	}
+	for i := range rs.positions {
+		rs.positions[i].probe = make(chan struct{}, 1)
+	}
+	rs.interval.Store(int64(DefaultProbeInterval))
+	return rs
+}
+
+// SetProbeInterval sets the minimum interval between the queries of the replication position of each replica.
+// A shorter interval lets the reads of a session move to the replicas sooner after a write, at the cost of more queries to the replicas.
+// A non-positive d lets each read that waits for a replica query its position.
+func (rs *ReplicaSet) SetProbeInterval(d time.Duration) {
+	rs.interval.Store(int64(d))
+}
+
+// Primary returns the primary database, e.g. to begin a transaction.
+// The writes in the transactions of the primary are not recorded to the sessions.
+func (rs *ReplicaSet) Primary() *DB {
+	return rs.primary
+}
+
+// Replicas returns the replicas.
+func (rs *ReplicaSet) Replicas() []*DB {
+	return rs.replicas
+}
+
+// Close closes the primary and the replicas.
+func (rs *ReplicaSet) Close() error {
+	err := rs.primary.Close()
+	for _, db := range rs.replicas {
+		if e := db.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// session is a read-your-writes session of WithSession.
+type session struct {
+	mu    sync.Mutex
+	token string
+}
+
+type sessionKey struct{}
+
+// WithSession returns a copy of ctx that carries a read-your-writes session for ReplicaSet,
+// which resumes token, the token of SessionToken of an earlier session, or starts a new session if token is empty,
+// so that a session can span several requests of a client by carrying its token, e.g. in a cookie.
+// The operations with the returned context and its descendants share the session.
+func WithSession(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{token: token})
+}
+
+// SessionToken returns the token of the session of ctx, which records the last write of the session, or empty if there is none.
+// The token is opaque, and is valid only for the same ReplicaSet.
+func SessionToken(ctx context.Context) string {
+	s := sessionOf(ctx)
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+func sessionOf(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+// Exec is similar to DB.Exec, but executes query on the primary.
+func (rs *ReplicaSet) Exec(query string, args ...interface{}) <-chan *Result {
+	return rs.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext is similar to DB.ExecContext, but executes query on the primary,
+// and then records the replication position after it to the session of ctx if any.
+func (rs *ReplicaSet) ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	s := sessionOf(ctx)
+	if s == nil {
+		return rs.primary.ExecContext(ctx, query, args...)
+	}
+	return routed(ctx, rs, rs.primary, s, func(db *DB) <-chan *Result {
+		return db.ExecContext(ctx, query, args...)
+	})
+}
+
+// Query is similar to DB.Query, but executes query on a replica.
+func (rs *ReplicaSet) Query(query string, args ...interface{}) <-chan *Rows {
+	return rs.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is similar to DB.QueryContext, but executes query on a replica that has caught up with the session of ctx,
+// or on the primary if query modifies a table, locks rows or no replica has caught up.
+func (rs *ReplicaSet) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	if !isReadQuery(query) {
+		return routed(ctx, rs, rs.primary, sessionOf(ctx), func(db *DB) <-chan *Rows {
+			return db.QueryContext(ctx, query, args...)
+		})
+	}
+	return routed(ctx, rs, nil, nil, func(db *DB) <-chan *Rows {
+		return db.QueryContext(ctx, query, args...)
+	})
+}
+
+// QueryRow is similar to DB.QueryRow, but executes query on a replica.
+func (rs *ReplicaSet) QueryRow(query string, args ...interface{}) <-chan *Row {
+	return rs.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext is similar to DB.QueryRowContext, but executes query on a replica as QueryContext does.
+func (rs *ReplicaSet) QueryRowContext(ctx context.Context, query string, args ...interface{}) <-chan *Row {
+	if !isReadQuery(query) {
+		return routed(ctx, rs, rs.primary, sessionOf(ctx), func(db *DB) <-chan *Row {
+			return db.QueryRowContext(ctx, query, args...)
+		})
+	}
+	return routed(ctx, rs, nil, nil, func(db *DB) <-chan *Row {
+		return db.QueryRowContext(ctx, query, args...)
+	})
+}
+
+// routed runs start on db, or on the database that rs chooses for a read with ctx if db is nil,
+// and then records the replication position to s if s is not nil and the operation has succeeded.
+func routed[T Errer](ctx context.Context, rs *ReplicaSet, db *DB, s *session, start func(db *DB) <-chan T) <-chan T {
+	ch := make(chan T)
+	go func() {
+		target := db
+		if target == nil {
+			target = rs.reader(ctx)
+		}
+		v := <-start(target)
+		if s != nil && v.Err() == nil {
+			if err := rs.record(ctx, s); err != nil {
+				discard(v)
+				v, _ = errResult[T](err)
+			}
+		}
+		deliver(target, ch, v)
+	}()
+	return ch
+}
+
+// reader returns the database that a read with ctx is run on.
+func (rs *ReplicaSet) reader(ctx context.Context) *DB {
+	if len(rs.replicas) == 0 {
+		return rs.primary
+	}
+	start := int(rs.next.Add(1) - 1)
+	token := SessionToken(ctx)
+	if token == "" {
+		return rs.replicas[start%len(rs.replicas)]
+	}
+	if token == primaryToken {
+		return rs.primary
+	}
+	var behind []int
+	interval := time.Duration(rs.interval.Load())
+	for i := range rs.replicas {
+		j := (start + i) % len(rs.replicas)
+		p := &rs.positions[j]
+		if rs.covers(p.get(), token) {
+			return rs.replicas[j]
+		}
+		if p.due(interval) {
+			behind = append(behind, j)
+		}
+	}
+	// Query the positions of the replicas that are behind concurrently, and take the first one that has caught up.
+	caughtUp := make(chan int, len(behind))
+	for _, j := range behind {
+		go func(j int) {
+			if rs.caughtUp(ctx, j, token) {
+				caughtUp <- j
+				return
+			}
+			caughtUp <- -1
+		}(j)
+	}
+	for range behind {
+		if j := <-caughtUp; j >= 0 {
+			return rs.replicas[j]
+		}
+	}
+	return rs.primary
+}
+
+// record records the replication position of the primary to s.
+func (rs *ReplicaSet) record(ctx context.Context, s *session) error {
+	var token string
+	switch rs.primary.Dialect() {
+	case DialectPostgres:
+		if err := (<-rs.primary.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text")).Scan(&token); err != nil {
+			return err
+		}
+	case DialectMySQL:
+		if err := (<-rs.primary.QueryRowContext(ctx, "SELECT @@GLOBAL.gtid_executed")).Scan(&token); err != nil {
+			return err
+		}
+	default:
+		token = primaryToken
+	}
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+	return nil
+}
+
+// caughtUp queries the position of the i-th replica, and reports whether it has replayed the writes up to token.
+// If the position is being queried by another read, caughtUp waits for it or ctx instead,
+// and queries again only if it is still behind and the interval of SetProbeInterval has passed since the last query.
+// A replica whose position cannot be queried is regarded as not caught up.
+func (rs *ReplicaSet) caughtUp(ctx context.Context, i int, token string) bool {
+	p := &rs.positions[i]
+	select {
+	case p.probe <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	defer func() { <-p.probe }()
+	if rs.covers(p.get(), token) {
+		return true
+	}
+	p.mu.Lock()
+	if !p.dueLocked(time.Duration(rs.interval.Load())) {
+		p.mu.Unlock()
+		return false
+	}
+	p.probed = time.Now()
+	p.mu.Unlock()
+	var query string
+	switch rs.primary.Dialect() {
+	case DialectPostgres:
+		query = "SELECT COALESCE(pg_last_wal_replay_lsn()::text, '')"
+	case DialectMySQL:
+		query = "SELECT @@GLOBAL.gtid_executed"
+	default:
+		return false
+	}
+	var position string
+	if err := (<-rs.replicas[i].QueryRowContext(ctx, query)).Scan(&position); err != nil {
+		return false
+	}
+	p.mu.Lock()
+	p.position = position
+	p.mu.Unlock()
+	return rs.covers(position, token)
+}
+
+func (p *replicaPosition) get() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.position
+}
+
+// due reports whether interval has passed since the position was last queried.
+func (p *replicaPosition) due(interval time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dueLocked(interval)
+}
+
+func (p *replicaPosition) dueLocked(interval time.Duration) bool {
+	return interval <= 0 || time.Since(p.probed) >= interval
+}
+
+// covers reports whether the replication position includes the writes up to token, which is a position of the primary.
+func (rs *ReplicaSet) covers(position, token string) bool {
+	if position == "" {
+		return false
+	}
+	switch rs.primary.Dialect() {
+	case DialectPostgres:
+		p, ok := parseLSN(position)
+		t, ok2 := parseLSN(token)
+		return ok && ok2 && p >= t
+	case DialectMySQL:
+		return gtidSubset(token, position)
+	}
+	return false
+}
+
+// parseLSN parses an LSN of PostgreSQL in the form of two hexadecimal numbers separated by a slash.
+func parseLSN(s string) (uint64, bool) {
+	hi, lo, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, false
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	l, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return h<<32 | l, true
+}
+
+// gtidInterval is an interval of the transaction numbers of a GTID set, including both ends.
+type gtidInterval struct {
+	start, end uint64
+}
+
+// parseGTIDSet parses a GTID set of MySQL, e.g. "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:7,...",
+// into the intervals of each source, which is a UUID optionally followed by a tag.
+func parseGTIDSet(s string) (map[string][]gtidInterval, bool) {
+	set := map[string][]gtidInterval{}
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if fields[0] == "" {
+			continue
+		}
+		source := strings.ToLower(fields[0])
+		for _, f := range fields[1:] {
+			first, last, isRange := strings.Cut(f, "-")
+			start, err := strconv.ParseUint(first, 10, 64)
+			if err != nil {
+				// A tag, which qualifies the following intervals.
+				source = strings.ToLower(fields[0]) + ":" + strings.ToLower(f)
+				continue
+			}
+			end := start
+			if isRange {
+				if end, err = strconv.ParseUint(last, 10, 64); err != nil {
+					return nil, false
+				}
+			}
+			set[source] = append(set[source], gtidInterval{start, end})
+		}
+	}
+	return set, true
+}
+
+// gtidSubset reports whether the GTID set sub is a subset of set, as GTID_SUBSET of MySQL does.
+func gtidSubset(sub, set string) bool {
+	want, ok := parseGTIDSet(sub)
+	if !ok {
+		return false
+	}
+	have, ok := parseGTIDSet(set)
+	if !ok {
+		return false
+	}
+	for source, intervals := range want {
+		merged := mergeIntervals(have[source])
+		for _, iv := range intervals {
+			i := sort.Search(len(merged), func(i int) bool { return merged[i].end >= iv.end })
+			if i == len(merged) || merged[i].start > iv.start {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mergeIntervals sorts intervals and merges the adjacent and overlapping ones.
+func mergeIntervals(intervals []gtidInterval) []gtidInterval {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start < intervals[j].start })
+	var merged []gtidInterval
+	for _, iv := range intervals {
+		if n := len(merged); n > 0 && iv.start <= merged[n-1].end+1 {
+			if iv.end > merged[n-1].end {
+				merged[n-1].end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
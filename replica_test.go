@@ -0,0 +1,248 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// replicationConn is a driver.Conn of a PostgreSQL server whose replication position is lsn.
+// A primary advances lsn by each Exec, and a replica reports lsn as its replayed position.
+type replicationConn struct {
+	name    string
+	primary bool
+
+	mu     sync.Mutex
+	lsn    int64
+	probes int
+}
+
+func (c *replicationConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *replicationConn) Close() error              { return nil }
+func (c *replicationConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *replicationConn) setLSN(lsn int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lsn = lsn
+}
+
+func (c *replicationConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.primary {
+		return nil, errors.New("cannot execute in a read-only transaction")
+	}
+	c.lsn++
+	return driver.RowsAffected(1), nil
+}
+
+func (c *replicationConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch query {
+	case "SELECT pg_current_wal_lsn()::text":
+		return &loRows{v: fmt.Sprintf("0/%X", c.lsn)}, nil
+	case "SELECT COALESCE(pg_last_wal_replay_lsn()::text, '')":
+		c.probes++
+		if c.primary {
+			return &loRows{v: ""}, nil
+		}
+		return &loRows{v: fmt.Sprintf("0/%X", c.lsn)}, nil
+	case "SELECT name", "SELECT name FOR UPDATE", "SELECT name; DELETE FROM users":
+		return &nameRows{names: []string{c.name}}, nil
+	}
+	return nil, fmt.Errorf("unexpected query %q", query)
+}
+
+func TestReplicaSet(t *testing.T) {
+	conns := []*replicationConn{{name: "primary", primary: true}, {name: "replica1"}, {name: "replica2"}}
+	var dbs []*asynql.DB
+	for _, conn := range conns {
+		dbs = append(dbs, asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithDialect(asynql.DialectPostgres)))
+	}
+	rs := asynql.NewReplicaSet(dbs[0], dbs[1:]...)
+	defer rs.Close()
+	name := func(ctx context.Context) string {
+		t.Helper()
+		var name string
+		if err := (<-rs.QueryRowContext(ctx, "SELECT name")).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	// The reads without a session are balanced over the replicas.
+	var actual []string
+	for i := 0; i < 2; i++ {
+		actual = append(actual, name(context.Background()))
+	}
+	if actual[0] == actual[1] || actual[0] == "primary" || actual[1] == "primary" {
+		t.Errorf(`names of the reads without a session => %#v; want both of the replicas`, actual)
+	}
+
+	ctx := asynql.WithSession(context.Background(), "")
+	if n := name(ctx); n == "primary" {
+		t.Errorf(`name of the read before a write => %#v; want a replica`, n)
+	}
+	if err := (<-rs.ExecContext(ctx, "UPDATE users SET name = 'carol'")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if token := asynql.SessionToken(ctx); token != "0/1" {
+		t.Errorf(`asynql.SessionToken(ctx) => %#v; want %#v`, token, "0/1")
+	}
+	if n := name(ctx); n != "primary" {
+		t.Errorf(`name of the read before the replicas catch up => %#v; want %#v`, n, "primary")
+	}
+	conns[2].setLSN(1)
+	// The positions have just been queried by the read above.
+	time.Sleep(asynql.DefaultProbeInterval)
+	for i := 0; i < 2; i++ {
+		if n := name(ctx); n != "replica2" {
+			t.Errorf(`name of the read after replica2 catches up => %#v; want %#v`, n, "replica2")
+		}
+	}
+
+	// A session can be resumed by its token.
+	resumed := asynql.WithSession(context.Background(), asynql.SessionToken(ctx))
+	if err := (<-rs.ExecContext(resumed, "UPDATE users SET name = 'dave'")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n := name(resumed); n != "primary" {
+		t.Errorf(`name of the read of the resumed session => %#v; want %#v`, n, "primary")
+	}
+}
+
+func TestReplicaSet_Untracked(t *testing.T) {
+	primary := newTestDB(t)
+	replica := newTestDB(t)
+	rs := asynql.NewReplicaSet(primary, replica)
+	defer rs.Close()
+	query := `SELECT name FROM test_table WHERE id = ?`
+	ctx := asynql.WithSession(context.Background(), "")
+	if err := (<-rs.ExecContext(ctx, `UPDATE test_table SET name = ? WHERE id = ?`, "carol", 1)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		ctx      context.Context
+		expected string
+	}{
+		{context.Background(), "alice"},
+		{ctx, "carol"},
+	} {
+		var name string
+		if err := (<-rs.QueryRowContext(v.ctx, query, 1)).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		if name != v.expected {
+			t.Errorf(`rs.QueryRowContext(ctx, %#v, 1) => %#v; want %#v`, query, name, v.expected)
+		}
+	}
+}
+
+func TestReplicaSet_CachedPosition(t *testing.T) {
+	conns := []*replicationConn{{name: "primary", primary: true}, {name: "replica1"}, {name: "replica2"}}
+	var dbs []*asynql.DB
+	for _, conn := range conns {
+		dbs = append(dbs, asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithDialect(asynql.DialectPostgres)))
+	}
+	rs := asynql.NewReplicaSet(dbs[0], dbs[1:]...)
+	defer rs.Close()
+	ctx := asynql.WithSession(context.Background(), "")
+	if err := (<-rs.ExecContext(ctx, "UPDATE users SET name = 'carol'")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	conns[1].setLSN(1)
+	conns[2].setLSN(1)
+	for i := 0; i < 4; i++ {
+		var name string
+		if err := (<-rs.QueryRowContext(ctx, "SELECT name")).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		if name == "primary" {
+			t.Errorf(`name of the read after the replicas catch up => %#v; want a replica`, name)
+		}
+	}
+	for _, conn := range conns[1:] {
+		conn.mu.Lock()
+		probes := conn.probes
+		conn.mu.Unlock()
+		if probes > 1 {
+			t.Errorf(`positions of %s queried %d times; want at most once`, conn.name, probes)
+		}
+	}
+
+	// The queries that write or lock rows are run on the primary.
+	for _, query := range []string{"SELECT name FOR UPDATE", "SELECT name; DELETE FROM users"} {
+		var name string
+		if err := (<-rs.QueryRowContext(context.Background(), query)).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		if name != "primary" {
+			t.Errorf(`rs.QueryRowContext(ctx, %#v) is run on %#v; want %#v`, query, name, "primary")
+		}
+	}
+}
+
+func TestReplicaSet_ProbeInterval(t *testing.T) {
+	conns := []*replicationConn{{name: "primary", primary: true}, {name: "replica1"}, {name: "replica2"}}
+	var dbs []*asynql.DB
+	for _, conn := range conns {
+		dbs = append(dbs, asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithDialect(asynql.DialectPostgres)))
+	}
+	rs := asynql.NewReplicaSet(dbs[0], dbs[1:]...)
+	defer rs.Close()
+	rs.SetProbeInterval(time.Hour)
+	ctx := asynql.WithSession(context.Background(), "")
+	if err := (<-rs.ExecContext(ctx, "UPDATE users SET name = 'carol'")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		var name string
+		if err := (<-rs.QueryRowContext(ctx, "SELECT name")).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		if name != "primary" {
+			t.Errorf(`name of the read before the replicas catch up => %#v; want %#v`, name, "primary")
+		}
+	}
+	for _, conn := range conns[1:] {
+		conn.mu.Lock()
+		probes := conn.probes
+		conn.mu.Unlock()
+		if probes != 1 {
+			t.Errorf(`positions of %s queried %d times within the interval; want once`, conn.name, probes)
+		}
+	}
+}
+
+func TestGTIDSubset(t *testing.T) {
+	const a, b = "3e11fa47-71ca-11e1-9e33-c80aa9429562", "4e11fa47-71ca-11e1-9e33-c80aa9429562"
+	for _, v := range []struct {
+		sub, set string
+		expected bool
+	}{
+		{a + ":1-5", a + ":1-10", true},
+		{a + ":1-5", a + ":1-3:4-10", true},
+		{a + ":1-5:8", a + ":1-6,\n" + b + ":1-2", false},
+		{a + ":7", a + ":1-6", false},
+		{b + ":1", a + ":1-6", false},
+		{a + ":1-3," + b + ":2", strings.ToUpper(a) + ":1-6," + b + ":1-2", true},
+		{a + ":tag:1-3", a + ":1-6", false},
+		{a + ":tag:1-3", a + ":1-6:tag:1-4", true},
+		{"", a + ":1-6", true},
+	} {
+		if actual := asynql.GTIDSubset(v.sub, v.set); actual != v.expected {
+			t.Errorf(`GTIDSubset(%#v, %#v) => %v; want %v`, v.sub, v.set, actual, v.expected)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package asynql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"syscall"
+	"time"
+)
+
+type idempotentKey struct{}
+
+// WithIdempotent returns a copy of ctx that marks the Exec run with it as idempotent,
+// so that it can be retried by WithBadConnRetry.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(ctx context.Context) bool {
+	v, _ := ctx.Value(idempotentKey{}).(bool)
+	return v
+}
+
+// WithBadConnRetry returns an Option that retries the Query and QueryRow of the DB once on a new connection
+// if they fail with a stale connection, e.g. driver.ErrBadConn, a reset connection or an unexpected EOF.
+// Only the queries that read are retried: a query that modifies a table or locks rows, such as INSERT ... RETURNING
+// or SELECT ... FOR UPDATE, is not, nor is an Exec unless its context is marked by WithIdempotent,
+// because it might have been executed before the connection broke.
+// database/sql retries only driver.ErrBadConn that is returned before a query is sent,
+// and the operations of a Tx or Conn are not retried since their connection cannot be replaced.
+func WithBadConnRetry() Option {
+	return func(db *DB) {
+		db.badConnRetry = true
+	}
+}
+
+// WithBusyRetry returns an Option that retries the Exec, Query and QueryRow of the DB up to n times
+// if they fail with SQLITE_BUSY or SQLITE_LOCKED, which the concurrent operations on a SQLite database often cause.
+// The retries wait for a random duration up to backoff, which is doubled on each retry, or until the context is done.
+// An Exec is retried regardless of WithIdempotent, since SQLite rejects a busy statement before executing it,
+// but a Query is retried only if it fails before its rows are ready.
+// The errors of the other drivers are not retried, nor are the operations of a Tx or Conn,
+// whose transaction may need to start over to see the changes of the others.
+func WithBusyRetry(n int, backoff time.Duration) Option {
+	return func(db *DB) {
+		db.busyRetries = n
+		db.busyBackoff = backoff
+	}
+}
+
+// maxBusyBackoffShift limits the growth of the backoff of WithBusyRetry.
+const maxBusyBackoffShift = 10
+
+// retryOp runs fn, and runs it again if it fails with a stale connection and retry is true,
+// or if it fails with a busy SQLite database.
+func retryOp[T interface{ Err() error }](ctx context.Context, db *DB, retry bool, fn func() T) T {
+	v := fn()
+	if retry && db.badConnRetry && isBadConn(v.Err()) {
+		v = fn()
+	}
+	for i := 0; i < db.busyRetries && isBusy(v.Err()); i++ {
+		if err := sleepContext(ctx, jitter(db.busyBackoff<<min(i, maxBusyBackoffShift))); err != nil {
+			break
+		}
+		v = fn()
+	}
+	return v
+}
+
+// isBusy reports whether err is SQLITE_BUSY or SQLITE_LOCKED, except SQLITE_BUSY_SNAPSHOT,
+// which needs the transaction to start over.
+func isBusy(err error) bool {
+	return classifyError(err, func(c errorCode) bool {
+		code := c.sqlite & 0xff
+		return (code == 5 || code == 6) && c.sqlite != 517
+	})
+}
+
+// jitter returns a random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// sleepContext waits for d, or returns the error of ctx if it is done before that.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isBadConn reports whether err is caused by a stale connection.
+func isBadConn(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
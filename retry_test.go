@@ -0,0 +1,178 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// flakyConn fails the first failures operations with err.
+type flakyConn struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	calls    int
+}
+
+func (c *flakyConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *flakyConn) Close() error                        { return nil }
+func (c *flakyConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *flakyConn) call() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.calls <= c.failures {
+		return c.err
+	}
+	return nil
+}
+
+func (c *flakyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *flakyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.call(); err != nil {
+		return nil, err
+	}
+	return &flakyRows{}, nil
+}
+
+type flakyRows struct{}
+
+func (flakyRows) Columns() []string              { return []string{"n"} }
+func (flakyRows) Close() error                   { return nil }
+func (flakyRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestWithBadConnRetry(t *testing.T) {
+	for _, v := range []struct {
+		name     string
+		err      error
+		retry    bool
+		run      func(db *asynql.DB) error
+		expected bool
+	}{
+		{"Query", io.ErrUnexpectedEOF, true, func(db *asynql.DB) error {
+			rows := <-db.Query("SELECT")
+			if rows.Err() == nil {
+				rows.Close()
+			}
+			return rows.Err()
+		}, true},
+		{"QueryRow", io.ErrUnexpectedEOF, true, func(db *asynql.DB) error {
+			if _, err := (<-db.QueryRow("SELECT")).Exists(new(int)); err != nil {
+				return err
+			}
+			return nil
+		}, true},
+		{"Exec", io.ErrUnexpectedEOF, true, func(db *asynql.DB) error {
+			return (<-db.Exec("UPDATE")).Err()
+		}, false},
+		{"idempotent Exec", io.ErrUnexpectedEOF, true, func(db *asynql.DB) error {
+			return (<-db.ExecContext(asynql.WithIdempotent(context.Background()), "UPDATE")).Err()
+		}, true},
+		{"other error", errors.New("syntax error"), true, func(db *asynql.DB) error {
+			return (<-db.Exec("UPDATE")).Err()
+		}, false},
+		{"no option", io.ErrUnexpectedEOF, false, func(db *asynql.DB) error {
+			return (<-db.Query("SELECT")).Err()
+		}, false},
+	} {
+		conn := &flakyConn{failures: 1, err: v.err}
+		var opts []asynql.Option
+		if v.retry {
+			opts = append(opts, asynql.WithBadConnRetry())
+		}
+		db := asynql.OpenDB(&notifyConnector{conn: conn}, opts...)
+		err := v.run(db)
+		db.Close()
+		var actual interface{} = err == nil
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%s: error => %#v; want success %#v`, v.name, err, expected)
+		}
+	}
+}
+
+func TestWithBadConnRetry_Write(t *testing.T) {
+	for _, query := range []string{
+		`INSERT INTO t (name) VALUES ('a') RETURNING id`,
+		`UPDATE t SET name = 'a' RETURNING id`,
+		`SELECT id FROM t FOR UPDATE`,
+	} {
+		conn := &flakyConn{failures: 1, err: syscall.ECONNRESET}
+		db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithBadConnRetry())
+		for _, run := range []func() error{
+			func() error { return (<-db.Query(query)).Err() },
+			func() error { return (<-db.QueryRow(query)).Scan(new(int)) },
+		} {
+			conn.mu.Lock()
+			conn.calls = 0
+			conn.mu.Unlock()
+			err := run()
+			conn.mu.Lock()
+			calls := conn.calls
+			conn.mu.Unlock()
+			var actual interface{} = []interface{}{errors.Is(err, syscall.ECONNRESET), calls}
+			var expected interface{} = []interface{}{true, 1}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf(`%#v with a reset connection => (failed, executions) %#v; want %#v`, query, actual, expected)
+			}
+		}
+		db.Close()
+	}
+}
+
+func TestWithBusyRetry(t *testing.T) {
+	for _, retry := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "test.db")
+		var opts []asynql.Option
+		if retry {
+			opts = append(opts, asynql.WithBusyRetry(10, 10*time.Millisecond))
+		}
+		db, err := asynql.Open("sqlite3", path+"?_busy_timeout=0", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := (<-db.Exec(`CREATE TABLE test_table (id INTEGER)`)).Err(); err != nil {
+			t.Fatal(err)
+		}
+		locker, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := locker.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Exec(`INSERT INTO test_table (id) VALUES (1)`); err != nil {
+			t.Fatal(err)
+		}
+		time.AfterFunc(50*time.Millisecond, func() {
+			tx.Commit()
+		})
+		err = (<-db.Exec(`INSERT INTO test_table (id) VALUES (2)`)).Err()
+		var actual interface{} = err == nil
+		var expected interface{} = retry
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`with retry %v: db.Exec on a locked database => %#v; want success %#v`, retry, err, expected)
+		}
+		time.Sleep(50 * time.Millisecond)
+		locker.Close()
+		db.Close()
+	}
+}
@@ -0,0 +1,146 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoTenant is reported by a Router for a context that has no tenant.
+var ErrNoTenant = errors.New("asynql: no tenant in context")
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx that carries tenant, which is returned by TenantOf.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantOf returns the tenant carried by ctx, or "" if ctx has no tenant.
+// It can be passed to NewRouter and WithTenantQuota.
+func TenantOf(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// Router routes queries to the database of each tenant, for the database-per-tenant pattern.
+// The tenant of a query is identified by the tenantOf function of the Router from the context of the query.
+// Router implements Querier, so it can be used in place of a DB.
+// A Router is safe for concurrent use.
+type Router struct {
+	tenantOf func(ctx context.Context) string
+
+	mu  sync.RWMutex
+	dbs map[string]*DB
+}
+
+// NewRouter returns a new Router that has no tenants, which identifies the tenant of a query by tenantOf, e.g. TenantOf.
+func NewRouter(tenantOf func(ctx context.Context) string) *Router {
+	return &Router{
+		tenantOf: tenantOf,
+		dbs:      make(map[string]*DB),
+	}
+}
+
+// Add adds db as the database of tenant, replacing the database of tenant if any.
+// The replaced database is not closed.
+func (r *Router) Add(tenant string, db *DB) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dbs[tenant] = db
+}
+
+// Remove removes the database of tenant from the router, and returns it so that the caller can close it.
+// It returns nil if tenant has no database.
+func (r *Router) Remove(tenant string) *DB {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	db := r.dbs[tenant]
+	delete(r.dbs, tenant)
+	return db
+}
+
+// DB returns the database of tenant.
+func (r *Router) DB(tenant string) (*DB, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	db, ok := r.dbs[tenant]
+	if !ok {
+		return nil, fmt.Errorf("asynql: unknown tenant %q", tenant)
+	}
+	return db, nil
+}
+
+// Route returns the database of the tenant of ctx.
+func (r *Router) Route(ctx context.Context) (*DB, error) {
+	tenant := r.tenantOf(ctx)
+	if tenant == "" {
+		return nil, ErrNoTenant
+	}
+	return r.DB(tenant)
+}
+
+// Close closes the databases of all the tenants.
+func (r *Router) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var errs []error
+	for _, db := range r.dbs {
+		errs = append(errs, db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// ExecContext is similar to DB.ExecContext, but executes query on the database of the tenant of ctx.
+// If the routing fails, the error is sent on the returned channel.
+func (r *Router) ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	db, err := r.Route(ctx)
+	if err != nil {
+		return sendResult(nil, &Result{err: err})
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext is similar to DB.QueryContext, but executes query on the database of the tenant of ctx.
+// If the routing fails, the error is sent on the returned channel.
+func (r *Router) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	db, err := r.Route(ctx)
+	if err != nil {
+		return sendResult(nil, &Rows{err: err})
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext is similar to DB.QueryRowContext, but executes query on the database of the tenant of ctx.
+// If the routing fails, the error is sent on the returned channel.
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...interface{}) <-chan *Row {
+	db, err := r.Route(ctx)
+	if err != nil {
+		return sendResult(nil, &Row{err: err})
+	}
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx is similar to sql.DB.BeginTx, but starts a transaction on the database of the tenant of ctx, and returns an *asynql.Tx.
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	db, err := r.Route(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newTx(ctx, db, tx), nil
+}
+
+// Conn is similar to DB.Conn, but returns a connection of the database of the tenant of ctx.
+func (r *Router) Conn(ctx context.Context) (*Conn, error) {
+	db, err := r.Route(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.Conn(ctx)
+}
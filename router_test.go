@@ -0,0 +1,65 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestRouter(t *testing.T) {
+	dbs := newShardDBs(t, []string{"alice"}, []string{"bob"})
+	router := asynql.NewRouter(asynql.TenantOf)
+	defer router.Close()
+	router.Add("a", dbs[0])
+	router.Add("b", dbs[1])
+	a := asynql.WithTenant(context.Background(), "a")
+	b := asynql.WithTenant(context.Background(), "b")
+	if err := (<-router.ExecContext(b, `INSERT INTO test_table (id, name) VALUES (3, 'carol')`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	query := `SELECT name FROM test_table ORDER BY id`
+	for _, v := range []struct {
+		ctx      context.Context
+		expected []string
+	}{
+		{a, []string{"alice"}},
+		{b, []string{"bob", "carol"}},
+	} {
+		actual := scanNames(t, <-router.QueryContext(v.ctx, query))
+		if !reflect.DeepEqual(actual, v.expected) {
+			t.Errorf(`router.QueryContext(%s, %#v) => %#v; want %#v`, asynql.TenantOf(v.ctx), query, actual, v.expected)
+		}
+	}
+
+	tx, err := router.BeginTx(a, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := (<-tx.QueryRow(`SELECT name FROM test_table`)).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = name
+	var expected interface{} = "alice"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`router.BeginTx(a, nil); tx.QueryRow(...) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestRouter_RoutingError(t *testing.T) {
+	router := asynql.NewRouter(asynql.TenantOf)
+	defer router.Close()
+	query := `SELECT 1`
+	if err := (<-router.QueryRowContext(context.Background(), query)).Err(); !errors.Is(err, asynql.ErrNoTenant) {
+		t.Errorf(`router.QueryRowContext(ctx without tenant, %#v); Row.Err() => %#v; want %#v`, query, err, asynql.ErrNoTenant)
+	}
+	if err := (<-router.QueryRowContext(asynql.WithTenant(context.Background(), "unknown"), query)).Err(); err == nil {
+		t.Errorf(`router.QueryRowContext(unknown tenant, %#v); Row.Err() => nil; want error`, query)
+	}
+}
@@ -0,0 +1,169 @@
+package asynql
+
+import (
+	"context"
+	"strconv"
+)
+
+// Saga executes a sequence of statements that span resources which cannot share a transaction,
+// such as several databases, and undoes the completed ones by their compensating statements when one fails.
+//
+//	saga := asynql.NewSaga().
+//		Step("debit", db1, `UPDATE accounts SET balance = balance - ? WHERE id = ?`, 100, 1).
+//		Compensate(`UPDATE accounts SET balance = balance + ? WHERE id = ?`, 100, 1).
+//		Step("credit", db2, `UPDATE accounts SET balance = balance + ? WHERE id = ?`, 100, 2)
+//	for ev := range saga.Run(ctx) {
+//		log.Println(ev)
+//	}
+type Saga struct {
+	steps []*sagaStep
+}
+
+type sagaStep struct {
+	name  string
+	e     Execer
+	query string
+	args  []interface{}
+
+	compensate bool
+	compQuery  string
+	compArgs   []interface{}
+}
+
+// NewSaga returns a new empty Saga.
+func NewSaga() *Saga {
+	return &Saga{}
+}
+
+// Step appends a step that executes query with args on e.
+func (s *Saga) Step(name string, e Execer, query string, args ...interface{}) *Saga {
+	s.steps = append(s.steps, &sagaStep{
+		name:  name,
+		e:     e,
+		query: query,
+		args:  args,
+	})
+	return s
+}
+
+// Compensate declares the statement that undoes the last step, which is executed on the same Execer as the step.
+// A step without a compensation is left as it is when a later step fails.
+// Compensate panics if s has no steps.
+func (s *Saga) Compensate(query string, args ...interface{}) *Saga {
+	if len(s.steps) == 0 {
+		panic("asynql: Compensate is called before Step")
+	}
+	step := s.steps[len(s.steps)-1]
+	step.compensate = true
+	step.compQuery = query
+	step.compArgs = args
+	return s
+}
+
+// SagaEventKind is the kind of a SagaEvent.
+type SagaEventKind int
+
+const (
+	// SagaStepDone means that a step has succeeded.
+	SagaStepDone SagaEventKind = iota
+
+	// SagaStepFailed means that a step has failed, and the compensations start.
+	SagaStepFailed
+
+	// SagaCompensated means that the compensation of a step has succeeded.
+	SagaCompensated
+
+	// SagaCompensationFailed means that the compensation of a step has failed.
+	// The remaining compensations are executed nevertheless.
+	SagaCompensationFailed
+
+	// SagaCompleted means that all the steps have succeeded. It is the last event.
+	SagaCompleted
+
+	// SagaAborted means that a step has failed and the compensations have finished. It is the last event.
+	SagaAborted
+)
+
+var sagaEventKindNames = [...]string{
+	SagaStepDone:           "step done",
+	SagaStepFailed:         "step failed",
+	SagaCompensated:        "compensated",
+	SagaCompensationFailed: "compensation failed",
+	SagaCompleted:          "completed",
+	SagaAborted:            "aborted",
+}
+
+// String returns the description of k.
+func (k SagaEventKind) String() string {
+	if k < 0 || int(k) >= len(sagaEventKindNames) {
+		return "SagaEventKind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return sagaEventKindNames[k]
+}
+
+// SagaEvent reports the progress of a Saga.
+type SagaEvent struct {
+	Kind SagaEventKind
+
+	// Step is the name of the step, or empty for SagaCompleted and SagaAborted.
+	Step string
+
+	err error
+}
+
+// Err returns the error of a failed step or compensation.
+// For SagaAborted, it is the error of the step that failed.
+func (ev *SagaEvent) Err() error {
+	return ev.err
+}
+
+// String returns the description of ev.
+func (ev *SagaEvent) String() string {
+	s := ev.Kind.String()
+	if ev.Step != "" {
+		s = ev.Step + ": " + s
+	}
+	if ev.err != nil {
+		s += ": " + ev.err.Error()
+	}
+	return s
+}
+
+// Run executes the steps in order, and sends the progress on the returned channel, which is closed at the end.
+// When a step fails, the compensations of the completed steps are executed in reverse order.
+// The compensations are executed even if ctx is canceled.
+// The channel must be received until it is closed, otherwise the saga doesn't proceed.
+func (s *Saga) Run(ctx context.Context) <-chan *SagaEvent {
+	ch := make(chan *SagaEvent)
+	go func() {
+		defer close(ch)
+		for i, step := range s.steps {
+			err := (<-step.e.ExecContext(ctx, step.query, step.args...)).Err()
+			if err == nil {
+				ch <- &SagaEvent{Kind: SagaStepDone, Step: step.name}
+				continue
+			}
+			ch <- &SagaEvent{Kind: SagaStepFailed, Step: step.name, err: err}
+			s.compensate(context.WithoutCancel(ctx), i, ch)
+			ch <- &SagaEvent{Kind: SagaAborted, err: err}
+			return
+		}
+		ch <- &SagaEvent{Kind: SagaCompleted}
+	}()
+	return ch
+}
+
+// compensate executes the compensations of the steps before the n-th in reverse order.
+func (s *Saga) compensate(ctx context.Context, n int, ch chan<- *SagaEvent) {
+	for i := n - 1; i >= 0; i-- {
+		step := s.steps[i]
+		if !step.compensate {
+			continue
+		}
+		if err := (<-step.e.ExecContext(ctx, step.compQuery, step.compArgs...)).Err(); err != nil {
+			ch <- &SagaEvent{Kind: SagaCompensationFailed, Step: step.name, err: err}
+			continue
+		}
+		ch <- &SagaEvent{Kind: SagaCompensated, Step: step.name}
+	}
+}
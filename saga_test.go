@@ -0,0 +1,64 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func runSaga(saga *asynql.Saga) []string {
+	var events []string
+	for ev := range saga.Run(context.Background()) {
+		events = append(events, ev.Kind.String()+" "+ev.Step)
+	}
+	return events
+}
+
+func TestSaga(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	saga := asynql.NewSaga().
+		Step("rename", db, `UPDATE test_table SET name = ? WHERE id = ?`, "carol", 1).
+		Compensate(`UPDATE test_table SET name = ? WHERE id = ?`, "alice", 1).
+		Step("insert", db, `INSERT INTO test_table (id, name) VALUES (?, ?)`, 3, "dave")
+	var actual interface{} = runSaga(saga)
+	var expected interface{} = []string{"step done rename", "step done insert", "completed "}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`saga.Run(ctx) => %#v; want %#v`, actual, expected)
+	}
+	actual = scanNames(t, <-db.Query(`SELECT name FROM test_table ORDER BY id`))
+	expected = []string{"carol", "bob", "dave"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`names after saga => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestSaga_Compensate(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	saga := asynql.NewSaga().
+		Step("rename", db, `UPDATE test_table SET name = ? WHERE id = ?`, "carol", 1).
+		Compensate(`UPDATE test_table SET name = ? WHERE id = ?`, "alice", 1).
+		Step("insert", db, `INSERT INTO test_table (id, name) VALUES (?, ?)`, 3, "dave").
+		Compensate(`DELETE FROM test_table WHERE id = ?`, 3).
+		Step("fail", db, `INSERT INTO missing_table (id) VALUES (1)`)
+	var actual interface{} = runSaga(saga)
+	var expected interface{} = []string{
+		"step done rename",
+		"step done insert",
+		"step failed fail",
+		"compensated insert",
+		"compensated rename",
+		"aborted ",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`saga.Run(ctx) => %#v; want %#v`, actual, expected)
+	}
+	actual = scanNames(t, <-db.Query(`SELECT name FROM test_table ORDER BY id`))
+	expected = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`names after compensation => %#v; want %#v`, actual, expected)
+	}
+}
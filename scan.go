@@ -0,0 +1,192 @@
+package asynql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanStruct copies the columns in the current row into the fields of the struct pointed to by dest.
+// A column is assigned to the field whose `db` struct tag matches the column name,
+// or to the field whose name matches the column name case-insensitively if the field has no tag.
+// Fields tagged with `db:"-"` are ignored, as are columns that have no corresponding field.
+// A NULL is scanned into a pointer field as nil, and into a Null field as invalid.
+// A field of a slice of numbers, strings or booleans is scanned from a PostgreSQL array or a JSON array as by Array,
+// a field tagged with `db:"name,json"` is unmarshaled from a JSON column as by JSON,
+// a field of a UUID is scanned from a uuid, string or binary(16) column as by UUID,
+// a string or big.Rat field tagged with `db:"name,decimal"` is scanned from a NUMERIC column without loss as by Decimal,
+// and a time.Time field tagged with `db:"name,loc=UTC"` is converted into the location.
+func (rs *Rows) ScanStruct(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("asynql: ScanStruct: dest must be a non-nil pointer to a struct, got %T", dest)
+	}
+	columns, err := rs.Columns()
+	if err != nil {
+		return err
+	}
+	v = v.Elem()
+	fields := structFieldsOf(v.Type())
+	dests := make([]interface{}, len(columns))
+	for i, column := range columns {
+		f, ok := fields[strings.ToLower(column)]
+		if !ok {
+			dests[i] = new(interface{})
+			continue
+		}
+		field := v.FieldByIndex(f.index)
+		switch {
+		case f.locErr != nil:
+			return f.locErr
+		case f.loc != nil:
+			dests[i] = fieldScanner(field, f.ptr, func(dest interface{}) sql.Scanner { return timeField{dest.(*time.Time), f.loc} })
+		case f.decimal:
+			dests[i] = decimalField{field}
+		case f.uuid:
+			dests[i] = fieldScanner(field, f.ptr, func(dest interface{}) sql.Scanner { return UUID(dest) })
+		case f.json:
+			dests[i] = jsonField{field.Addr().Interface()}
+		case f.array:
+			dests[i] = fieldScanner(field, f.ptr, func(dest interface{}) sql.Scanner { return Array(dest) })
+		default:
+			dests[i] = field.Addr().Interface()
+		}
+	}
+	return rs.Scan(dests...)
+}
+
+// scanValue scans the current row of rs into dest.
+// If dest points to a struct that doesn't implement sql.Scanner, it is filled by ScanStruct.
+// Otherwise, the row must consist of a single column.
+func scanValue(rs *Rows, dest interface{}) error {
+	if isStructDest(dest) {
+		return rs.ScanStruct(dest)
+	}
+	return rs.Scan(dest)
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+func isStructDest(dest interface{}) bool {
+	t := reflect.TypeOf(dest)
+	if t == nil || t.Kind() != reflect.Ptr || t.Implements(scannerType) {
+		return false
+	}
+	t = t.Elem()
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// structField represents a field of a struct that a column can be scanned into.
+type structField struct {
+	index []int
+
+	// array reports whether the field is scanned by Array.
+	array bool
+
+	// json reports whether the field is tagged with the json option.
+	json bool
+
+	// uuid reports whether the field is scanned by UUID.
+	uuid bool
+
+	// decimal reports whether the field is tagged with the decimal option.
+	decimal bool
+
+	// loc is the location given by the loc option of a time.Time field, or locErr is the error of loading it.
+	loc    *time.Location
+	locErr error
+
+	// ptr reports whether the field is a pointer to the type that array, uuid or loc is about.
+	ptr bool
+}
+
+var structFieldsCache sync.Map // map[reflect.Type]map[string]structField
+
+func structFieldsOf(t reflect.Type) map[string]structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.(map[string]structField)
+	}
+	fields := make(map[string]structField)
+	collectStructFields(fields, t, nil)
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+func collectStructFields(fields map[string]structField, t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+		idx := append(append([]int(nil), index...), i)
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			collectStructFields(fields, f.Type, idx)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		name = strings.ToLower(name)
+		if _, exists := fields[name]; exists && len(index) > 0 {
+			// A field of the outer struct takes precedence over a promoted one.
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer && ft != reflect.PointerTo(ratType) {
+			ft = ft.Elem()
+		}
+		var loc *time.Location
+		var locErr error
+		if s, ok := tagOptionValue(opts, "loc"); ok && ft == timeType {
+			if loc, locErr = time.LoadLocation(s); locErr != nil {
+				locErr = fmt.Errorf("asynql: ScanStruct: invalid location of field %s: %w", f.Name, locErr)
+			}
+		}
+		fields[name] = structField{
+			index:  idx,
+			loc:    loc,
+			locErr: locErr,
+			array:  isArrayType(ft),
+			json:   hasTagOption(opts, "json"),
+			uuid: isUUIDType(ft) && !reflect.PointerTo(ft).Implements(scannerType) ||
+				ft.Kind() == reflect.String && hasTagOption(opts, "uuid"),
+			decimal: isDecimalFieldType(f.Type) && hasTagOption(opts, "decimal"),
+			ptr:     ft != f.Type,
+		}
+	}
+}
+
+// hasTagOption reports whether opts, the comma-separated options of a struct tag, has opt.
+func hasTagOption(opts, opt string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// tagOptionValue returns the value of the option key=value in opts, the comma-separated options of a struct tag.
+func tagOptionValue(opts, key string) (string, bool) {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if k, v, ok := strings.Cut(o, "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,55 @@
+package asynql_test
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRows_ScanStruct(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	type base struct {
+		ID int
+	}
+	type user struct {
+		base
+		UserName string `db:"name"`
+		Ignored  string `db:"-"`
+	}
+	query := `SELECT id, name, 'extra' AS extra FROM test_table ORDER BY id`
+	rows := <-db.Query(query)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var actual []user
+	for rows.Next() {
+		var u user
+		if err := rows.ScanStruct(&u); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, u)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []user{{base{1}, "alice", ""}, {base{2}, "bob", ""}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Query(%#v); rows.ScanStruct => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestRows_ScanStruct_InvalidDest(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	rows := <-db.Query(`SELECT id FROM test_table`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	rows.Next()
+	var id int
+	if err := rows.ScanStruct(&id); err == nil {
+		t.Errorf(`rows.ScanStruct(&id) => nil; want error`)
+	}
+}
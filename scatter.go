@@ -0,0 +1,257 @@
+package asynql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MergeLess reports whether the row a sorts before the row b.
+// The rows hold the values of the columns in the order of the query.
+type MergeLess func(a, b []interface{}) bool
+
+// OrderBy returns a MergeLess that sorts rows in ascending order of the values of the given columns.
+// The columns are specified by their 0-based index.
+// NULL sorts before any other value.
+func OrderBy(columns ...int) MergeLess {
+	return func(a, b []interface{}) bool {
+		for _, i := range columns {
+			if c := compareValues(a[i], b[i]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}
+}
+
+// ScatterQuery executes a query with args on all of dbs concurrently,
+// and then sends a *Rows that streams the rows of all of them on the returned channel.
+// The rows of dbs[0] come first, then the rows of dbs[1], and so on.
+// If any of the queries fails, the error is reported and the other results are discarded.
+func ScatterQuery(ctx context.Context, dbs []*DB, query string, args ...interface{}) <-chan *Rows {
+	return scatter(ctx, dbs, nil, query, args)
+}
+
+// ScatterQueryOrdered is the same as ScatterQuery, but merges the rows of dbs in the order of less.
+// Each query must return its rows in the order of less, typically with an ORDER BY clause.
+func ScatterQueryOrdered(ctx context.Context, dbs []*DB, less MergeLess, query string, args ...interface{}) <-chan *Rows {
+	return scatter(ctx, dbs, less, query, args)
+}
+
+func scatter(ctx context.Context, dbs []*DB, less MergeLess, query string, args []interface{}) <-chan *Rows {
+	ch := make(chan *Rows)
+	go func() {
+		chs := make([]<-chan *Rows, len(dbs))
+		for i, db := range dbs {
+			chs[i] = db.QueryContext(ctx, query, args...)
+		}
+		rows, err := WaitAll(chs...)
+		if err != nil {
+			for _, rs := range rows {
+				discard(rs)
+			}
+			deliver(nil, ch, &Rows{err: err})
+			return
+		}
+		src, err := newMergeSource(rows, less)
+		if err != nil {
+			deliver(nil, ch, &Rows{err: err})
+			return
+		}
+		deliver(nil, ch, newVirtualRows(src))
+	}()
+	return ch
+}
+
+// mergeSource is a rowSource that merges the rows of several *Rows.
+type mergeSource struct {
+	columns []string
+	rows    []*Rows
+	less    MergeLess
+
+	// cur is the index of the *Rows being read if less is nil.
+	cur int
+
+	// heads is the next row of each *Rows if less is not nil.
+	// The head of an exhausted *Rows is nil.
+	heads   [][]interface{}
+	started bool
+}
+
+func newMergeSource(rows []*Rows, less MergeLess) (*mergeSource, error) {
+	s := &mergeSource{
+		rows: rows,
+		less: less,
+	}
+	for _, rs := range rows {
+		columns, err := rs.Columns()
+		if err == nil && s.columns != nil && len(columns) != len(s.columns) {
+			err = fmt.Errorf("asynql: results have different numbers of columns: %d and %d", len(s.columns), len(columns))
+		}
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.columns = columns
+	}
+	return s, nil
+}
+
+func (s *mergeSource) Columns() []string {
+	return s.columns
+}
+
+func (s *mergeSource) Next(dest []driver.Value) error {
+	if s.less == nil {
+		return s.nextConcat(dest)
+	}
+	return s.nextMerged(dest)
+}
+
+func (s *mergeSource) nextConcat(dest []driver.Value) error {
+	for ; s.cur < len(s.rows); s.cur++ {
+		row, err := s.fetch(s.rows[s.cur])
+		if err != nil {
+			return err
+		}
+		if row != nil {
+			copyValues(dest, row)
+			return nil
+		}
+	}
+	return io.EOF
+}
+
+func (s *mergeSource) nextMerged(dest []driver.Value) error {
+	if !s.started {
+		s.started = true
+		s.heads = make([][]interface{}, len(s.rows))
+		for i, rs := range s.rows {
+			row, err := s.fetch(rs)
+			if err != nil {
+				return err
+			}
+			s.heads[i] = row
+		}
+	}
+	min := -1
+	for i, head := range s.heads {
+		if head != nil && (min < 0 || s.less(head, s.heads[min])) {
+			min = i
+		}
+	}
+	if min < 0 {
+		return io.EOF
+	}
+	copyValues(dest, s.heads[min])
+	row, err := s.fetch(s.rows[min])
+	if err != nil {
+		return err
+	}
+	s.heads[min] = row
+	return nil
+}
+
+// fetch reads the next row of rs.
+// It returns nil without an error if rs has no more rows.
+func (s *mergeSource) fetch(rs *Rows) ([]interface{}, error) {
+	if !rs.Next() {
+		return nil, rs.Err()
+	}
+	row := make([]interface{}, len(s.columns))
+	dests := make([]interface{}, len(row))
+	for i := range row {
+		dests[i] = &row[i]
+	}
+	if err := rs.Scan(dests...); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func (s *mergeSource) Close() error {
+	var err error
+	for _, rs := range s.rows {
+		if e := rs.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// compareValues compares a and b, which hold values of the types that a driver.Value can hold.
+// It returns -1, 0 or +1. NULL sorts before any other value.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			return compareOrdered(a, b)
+		case float64:
+			return compareOrdered(float64(a), b)
+		}
+	case float64:
+		switch b := b.(type) {
+		case float64:
+			return compareOrdered(a, b)
+		case int64:
+			return compareOrdered(a, float64(b))
+		}
+	case string:
+		switch b := b.(type) {
+		case string:
+			return compareOrdered(a, b)
+		case []byte:
+			return compareOrdered(a, string(b))
+		}
+	case []byte:
+		switch b := b.(type) {
+		case []byte:
+			return bytes.Compare(a, b)
+		case string:
+			return compareOrdered(string(a), b)
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0
+			case !a:
+				return -1
+			}
+			return 1
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			switch {
+			case a.Before(b):
+				return -1
+			case a.After(b):
+				return 1
+			}
+			return 0
+		}
+	}
+	return compareOrdered(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareOrdered[T int64 | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
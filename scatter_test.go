@@ -0,0 +1,92 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func newShardDBs(t *testing.T, shards ...[]string) []*asynql.DB {
+	var dbs []*asynql.DB
+	id := 0
+	for _, names := range shards {
+		db, err := asynql.Open("sqlite3", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		if err := (<-db.Exec(`CREATE TABLE test_table (id INTEGER, name TEXT)`)).Err(); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			id++
+			if err := (<-db.Exec(`INSERT INTO test_table (id, name) VALUES (?, ?)`, id, name)).Err(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dbs = append(dbs, db)
+	}
+	return dbs
+}
+
+func closeDBs(dbs []*asynql.DB) {
+	for _, db := range dbs {
+		db.Close()
+	}
+}
+
+func scanNames(t *testing.T, rows *asynql.Rows) []string {
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestScatterQuery(t *testing.T) {
+	dbs := newShardDBs(t, []string{"alice", "bob"}, []string{"carol"})
+	defer closeDBs(dbs)
+	query := `SELECT name FROM test_table ORDER BY id`
+	actual := scanNames(t, <-asynql.ScatterQuery(context.Background(), dbs, query))
+	expected := []string{"alice", "bob", "carol"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.ScatterQuery(ctx, dbs, %#v) => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestScatterQueryOrdered(t *testing.T) {
+	dbs := newShardDBs(t, []string{"bob", "erin", "alice"}, []string{"dave", "carol"})
+	defer closeDBs(dbs)
+	query := `SELECT name FROM test_table ORDER BY name`
+	actual := scanNames(t, <-asynql.ScatterQueryOrdered(context.Background(), dbs, asynql.OrderBy(0), query))
+	expected := []string{"alice", "bob", "carol", "dave", "erin"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.ScatterQueryOrdered(ctx, dbs, OrderBy(0), %#v) => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestScatterQuery_Error(t *testing.T) {
+	dbs := newShardDBs(t, []string{"alice"}, []string{"bob"})
+	defer closeDBs(dbs)
+	if err := (<-dbs[1].Exec(`DROP TABLE test_table`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows := <-asynql.ScatterQuery(context.Background(), dbs, `SELECT name FROM test_table`)
+	if rows.Err() == nil {
+		rows.Close()
+		t.Errorf(`asynql.ScatterQuery(ctx, dbs, query); rows.Err() => nil; want error`)
+	}
+}
@@ -0,0 +1,157 @@
+package asynql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ExecAt is similar to Exec, but executes query with args at t.
+func (db *DB) ExecAt(t time.Time, query string, args ...interface{}) <-chan *Result {
+	return db.ExecAtContext(context.Background(), t, query, args...)
+}
+
+// ExecAtContext is similar to ExecContext, but executes query with args at t.
+// If ctx is done before t, the query is not executed and the error of ctx is sent instead.
+func (db *DB) ExecAtContext(ctx context.Context, t time.Time, query string, args ...interface{}) <-chan *Result {
+	ch := make(chan *Result)
+	go func() {
+		timer := time.NewTimer(time.Until(t))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			deliver(db, ch, <-db.ExecContext(ctx, query, args...))
+		case <-ctx.Done():
+			deliver(db, ch, &Result{
+				err: ctx.Err(),
+			})
+		}
+	}()
+	return ch
+}
+
+// ExecAfter is similar to Exec, but executes query with args after d.
+func (db *DB) ExecAfter(d time.Duration, query string, args ...interface{}) <-chan *Result {
+	return db.ExecAfterContext(context.Background(), d, query, args...)
+}
+
+// ExecAfterContext is similar to ExecContext, but executes query with args after d.
+// If ctx is done before that, the query is not executed and the error of ctx is sent instead.
+func (db *DB) ExecAfterContext(ctx context.Context, d time.Duration, query string, args ...interface{}) <-chan *Result {
+	return db.ExecAtContext(ctx, time.Now().Add(d), query, args...)
+}
+
+// Job is a statement that runs on a schedule, which is returned by DB.Schedule.
+type Job struct {
+	results chan *Result
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu    sync.Mutex
+	stats JobStats
+}
+
+// JobStats is the statistics of the runs of a Job.
+type JobStats struct {
+	// Successes is the number of the runs that succeeded.
+	Successes int64
+
+	// Failures is the number of the runs that failed.
+	Failures int64
+
+	// LastRun is the start time of the last run.
+	LastRun time.Time
+
+	// LastDuration is the duration of the last run.
+	LastDuration time.Duration
+
+	// LastErr is the error of the last run, or nil if it succeeded.
+	LastErr error
+
+	// Next is the time of the next run, or the zero time if there is none.
+	Next time.Time
+}
+
+// Schedule executes query with args repeatedly on the schedule of spec until the returned Job is stopped.
+// spec is a crontab expression of five fields, minute, hour, day of month, month and day of week, in the local time,
+// such as "30 3 * * *" or "*/15 9-17 * * mon-fri".
+// It can also be one of "@yearly", "@monthly", "@weekly", "@daily", "@hourly", or "@every <duration>" such as "@every 1h30m".
+// It is useful for periodic maintenance statements such as VACUUM, rollups and purges.
+func (db *DB) Schedule(spec string, query string, args ...interface{}) (*Job, error) {
+	sched, err := parseSchedule(spec)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{
+		results: make(chan *Result, 1),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go j.run(ctx, db, sched, query, args)
+	return j, nil
+}
+
+// Results returns a channel that receives the result of each run.
+// A result is dropped if the previous one has not been received yet, but it is still counted in Stats.
+// The channel is closed when the job is stopped.
+func (j *Job) Results() <-chan *Result {
+	return j.results
+}
+
+// Stats returns the statistics of the runs of the job.
+func (j *Job) Stats() JobStats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.stats
+}
+
+// Stop stops the job, canceling the run in progress, and waits for it to finish.
+func (j *Job) Stop() {
+	j.cancel()
+	<-j.done
+}
+
+func (j *Job) run(ctx context.Context, db *DB, sched schedule, query string, args []interface{}) {
+	defer close(j.done)
+	defer close(j.results)
+	for {
+		next := sched.next(time.Now())
+		j.mu.Lock()
+		j.stats.Next = next
+		j.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		start := time.Now()
+		r := <-db.ExecContext(ctx, query, args...)
+		if ctx.Err() != nil {
+			return
+		}
+		j.record(start, r.Err())
+		select {
+		case j.results <- r:
+		default:
+		}
+	}
+}
+
+func (j *Job) record(start time.Time, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		j.stats.Failures++
+	} else {
+		j.stats.Successes++
+	}
+	j.stats.LastRun = start
+	j.stats.LastDuration = time.Since(start)
+	j.stats.LastErr = err
+}
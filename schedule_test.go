@@ -0,0 +1,94 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDB_ExecAfter(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	d := 50 * time.Millisecond
+	query := `DELETE FROM test_table WHERE id = 1`
+	start := time.Now()
+	result := db.ExecAfter(d, query)
+	var count int
+	if err := (<-db.QueryRow(`SELECT COUNT(*) FROM test_table`)).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = count
+	var expected interface{} = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`COUNT(*) before db.ExecAfter(%v, %#v) => %#v; want %#v`, d, query, actual, expected)
+	}
+	r := <-result
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < d {
+		t.Errorf(`db.ExecAfter(%v, %#v) executed after %v; want >= %v`, d, query, elapsed, d)
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = n
+	expected = int64(1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.ExecAfter(%v, %#v).RowsAffected() => %#v; want %#v`, d, query, actual, expected)
+	}
+}
+
+func TestDB_ExecAtContext_Cancel(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	query := `DELETE FROM test_table`
+	result := db.ExecAtContext(ctx, time.Now().Add(time.Hour), query)
+	cancel()
+	var actual interface{} = (<-result).Err()
+	var expected interface{} = context.Canceled
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.ExecAtContext(ctx, 1h later, %#v).Err() after cancel => %#v; want %#v`, query, actual, expected)
+	}
+	var count int
+	if err := (<-db.QueryRow(`SELECT COUNT(*) FROM test_table`)).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	actual = count
+	expected = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`COUNT(*) after cancel => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDB_Schedule(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	job, err := db.Schedule("@every 10ms", `INSERT INTO test_table (id, name) VALUES (3, "carol")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := (<-job.Results()).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	job.Stop()
+	for range job.Results() {
+	}
+	stats := job.Stats()
+	if stats.Successes < 2 || stats.Failures != 0 || stats.LastErr != nil {
+		t.Errorf(`job.Stats() => %+v; want at least 2 successes and no failures`, stats)
+	}
+}
+
+func TestDB_Schedule_Invalid(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if _, err := db.Schedule("* * *", `SELECT 1`); err == nil {
+		t.Errorf(`db.Schedule("* * *", "SELECT 1") => nil; want error`)
+	}
+}
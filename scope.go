@@ -0,0 +1,99 @@
+package asynql
+
+import (
+	"context"
+	"sync"
+)
+
+// Scoped is the handle of a scope that is passed to the function given to Scope.
+// The operations launched through a Scoped are bound to the scope and never outlive it.
+type Scoped struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// Scope calls fn with a new scope, and then waits for all the operations launched through the scope before returning.
+// If fn returns an error, the operations that are still running are canceled.
+// The context of the scope is canceled when Scope returns, so *Rows obtained in the scope must be consumed within fn.
+// Scope returns the error of fn, or otherwise the first error returned by a function passed to Scoped.Go.
+func Scope(ctx context.Context, fn func(s *Scoped) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := &Scoped{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	err := fn(s)
+	if err != nil {
+		cancel()
+	}
+	s.wg.Wait()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Context returns the context of the scope.
+// It is canceled when the scope exits or fails.
+func (s *Scoped) Context() context.Context {
+	return s.ctx
+}
+
+// Go calls fn in a new goroutine with the context of the scope.
+// If fn returns an error, the scope is canceled and the error is returned by Scope.
+func (s *Scoped) Go(fn func(ctx context.Context) error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := fn(s.ctx); err != nil {
+			s.mu.Lock()
+			if s.err == nil {
+				s.err = err
+			}
+			s.mu.Unlock()
+			s.cancel()
+		}
+	}()
+}
+
+// Exec is similar to e.ExecContext, but the execution is bound to the scope.
+func (s *Scoped) Exec(e Execer, query string, args ...interface{}) <-chan *Result {
+	return scoped(s, e, func(ctx context.Context) <-chan *Result {
+		return e.ExecContext(ctx, query, args...)
+	})
+}
+
+// Query is similar to q.QueryContext, but the execution is bound to the scope.
+func (s *Scoped) Query(q Queryer, query string, args ...interface{}) <-chan *Rows {
+	return scoped(s, q, func(ctx context.Context) <-chan *Rows {
+		return q.QueryContext(ctx, query, args...)
+	})
+}
+
+// QueryRow is similar to q.QueryRowContext, but the execution is bound to the scope.
+func (s *Scoped) QueryRow(q RowQueryer, query string, args ...interface{}) <-chan *Row {
+	return scoped(s, q, func(ctx context.Context) <-chan *Row {
+		return q.QueryRowContext(ctx, query, args...)
+	})
+}
+
+// scoped starts an operation of q with the context of s, and keeps s open until the operation completes,
+// regardless of whether the caller receives its result.
+func scoped[T any](s *Scoped, q interface{}, start func(ctx context.Context) <-chan T) <-chan T {
+	s.wg.Add(1)
+	in := start(s.ctx)
+	ch := make(chan T)
+	go func() {
+		v := <-in
+		s.wg.Done()
+		deliver(q, ch, v)
+	}()
+	return ch
+}
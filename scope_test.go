@@ -0,0 +1,74 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestScope(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	var r1, r2 <-chan *asynql.Result
+	err := asynql.Scope(context.Background(), func(s *asynql.Scoped) error {
+		r1 = s.Exec(db, `INSERT INTO test_table (id, name) VALUES (3, "jack")`)
+		r2 = s.Exec(db, `INSERT INTO test_table (id, name) VALUES (4, "sara")`)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf(`asynql.Scope(ctx, fn) => %#v; want nil`, err)
+	}
+	if _, err := asynql.WaitAll(r1, r2); err != nil {
+		t.Error(err)
+	}
+	var count int
+	if err := (<-db.QueryRow(`SELECT COUNT(*) FROM test_table`)).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = count
+	var expected interface{} = 4
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`count after scope => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestScope_Error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	fnErr := errors.New("failed")
+	var ch <-chan *asynql.Rows
+	err := asynql.Scope(context.Background(), func(s *asynql.Scoped) error {
+		ch = s.Query(db, `SELECT id FROM test_table`)
+		return fnErr
+	})
+	var actual interface{} = err
+	var expected interface{} = fnErr
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Scope(ctx, fn) => %#v; want %#v`, actual, expected)
+	}
+	if rows := <-ch; rows.Err() == nil {
+		rows.Close()
+	}
+}
+
+func TestScoped_Go(t *testing.T) {
+	goErr := errors.New("failed")
+	err := asynql.Scope(context.Background(), func(s *asynql.Scoped) error {
+		s.Go(func(ctx context.Context) error {
+			return goErr
+		})
+		s.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		return nil
+	})
+	var actual interface{} = err
+	var expected interface{} = goErr
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Scope(ctx, fn) => %#v; want %#v`, actual, expected)
+	}
+}
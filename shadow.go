@@ -0,0 +1,99 @@
+package asynql
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// MaxShadowQueries is the number of the shadow queries of WithShadow that run at once.
+// While that many are running, the following queries are not duplicated, so that a slow shadow doesn't pile up goroutines.
+const MaxShadowQueries = 16
+
+// ShadowReport is the comparison of a query on the primary DB and its shadow, which is reported by WithShadow.
+type ShadowReport struct {
+	// Query is the query, and Args is its arguments.
+	Query string
+	Args  []interface{}
+
+	// Primary is the result of the primary DB, or nil if PrimaryErr is not nil.
+	Primary         *Snapshot
+	PrimaryErr      error
+	PrimaryDuration time.Duration
+
+	// Shadow is the result of the shadow, or nil if ShadowErr is not nil.
+	Shadow         *Snapshot
+	ShadowErr      error
+	ShadowDuration time.Duration
+}
+
+// Match reports whether the primary DB and the shadow returned the same columns and values, or both failed.
+// The values are compared strictly, so the results of different engines may differ in the types of the values
+// such as int64 and []byte even if they look the same.
+func (r *ShadowReport) Match() bool {
+	if r.PrimaryErr != nil || r.ShadowErr != nil {
+		return r.PrimaryErr != nil && r.ShadowErr != nil
+	}
+	return reflect.DeepEqual(r.Primary.Columns, r.Shadow.Columns) && reflect.DeepEqual(r.Primary.Values, r.Shadow.Values)
+}
+
+// WithShadow returns an Option that duplicates each Query of the DB to shadow,
+// e.g. a new schema or a database engine under evaluation, and calls report with the comparison of the results.
+// The shadow query runs concurrently with the primary one, and report is called in another goroutine after both have completed,
+// so neither the shadow nor report affects the result delivered to the caller.
+// The shadow query is not canceled with the context of the caller.
+//
+// The rows of the primary query are read into memory to compare them.
+// The queries of a Tx or Conn, and QueryRow, are not duplicated, nor are the queries that modify a table or lock rows,
+// such as INSERT ... RETURNING and SELECT ... FOR UPDATE.
+func WithShadow(shadow Queryer, report func(ctx context.Context, r *ShadowReport)) Option {
+	return func(db *DB) {
+		db.shadow = &shadowQueryer{
+			q:      shadow,
+			report: report,
+			sem:    make(chan struct{}, MaxShadowQueries),
+		}
+	}
+}
+
+type shadowQueryer struct {
+	q      Queryer
+	report func(ctx context.Context, r *ShadowReport)
+
+	// sem holds a slot for each shadow query that is running.
+	sem chan struct{}
+}
+
+// query runs query on the primary db and the shadow, and returns the result of db.
+func (s *shadowQueryer) query(ctx context.Context, db *DB, query string, args []interface{}) *Rows {
+	if !isReadQuery(query) {
+		return db.queryPrimary(ctx, query, args)
+	}
+	select {
+	case s.sem <- struct{}{}:
+	default:
+		return db.queryPrimary(ctx, query, args)
+	}
+	r := &ShadowReport{
+		Query: query,
+		Args:  args,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer func() { <-s.sem }()
+		ctx := context.WithoutCancel(ctx)
+		start := time.Now()
+		r.Shadow, r.ShadowErr = Materialize(<-s.q.QueryContext(ctx, query, args...))
+		r.ShadowDuration = time.Since(start)
+		<-done
+		s.report(ctx, r)
+	}()
+	start := time.Now()
+	snap, err := Materialize(db.queryPrimary(ctx, query, args))
+	r.Primary, r.PrimaryErr, r.PrimaryDuration = snap, err, time.Since(start)
+	close(done)
+	if err != nil {
+		return &Rows{err: err}
+	}
+	return snap.Rows()
+}
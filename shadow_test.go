@@ -0,0 +1,78 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithShadow(t *testing.T) {
+	shadow := newTestDB(t)
+	defer shadow.Close()
+	if err := (<-shadow.Exec(`UPDATE test_table SET name = 'bobby' WHERE id = 2`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	reports := make(chan *asynql.ShadowReport, 2)
+	db := newTestDB(t, asynql.WithShadow(shadow, func(ctx context.Context, r *asynql.ShadowReport) {
+		reports <- r
+	}))
+	defer db.Close()
+
+	for _, v := range []struct {
+		query    string
+		expected bool
+	}{
+		{`SELECT name FROM test_table WHERE id = 1`, true},
+		{`SELECT name FROM test_table ORDER BY id`, false},
+	} {
+		actual := scanNames(t, <-db.Query(v.query))
+		if len(actual) == 0 || actual[len(actual)-1] == "bobby" {
+			t.Errorf(`db.Query(%#v) => %#v; want the result of the primary`, v.query, actual)
+		}
+		select {
+		case r := <-reports:
+			if r.Query != v.query {
+				t.Errorf(`ShadowReport.Query => %#v; want %#v`, r.Query, v.query)
+			}
+			var actual interface{} = r.Match()
+			var expected interface{} = v.expected
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf(`ShadowReport.Match() for %#v => %#v; want %#v`, v.query, actual, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf(`no ShadowReport for %#v`, v.query)
+		}
+	}
+}
+
+func TestWithShadow_Write(t *testing.T) {
+	shadow := newTestDB(t)
+	defer shadow.Close()
+	reports := make(chan *asynql.ShadowReport, 2)
+	db := newTestDB(t, asynql.WithShadow(shadow, func(ctx context.Context, r *asynql.ShadowReport) {
+		reports <- r
+	}))
+	defer db.Close()
+	insert := `INSERT INTO test_table (id, name) VALUES (3, "carol") RETURNING name`
+	if actual, expected := scanNames(t, <-db.Query(insert)), []string{"carol"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Query(%#v) => %#v; want %#v`, insert, actual, expected)
+	}
+	query := `SELECT name FROM test_table ORDER BY id`
+	scanNames(t, <-db.Query(query))
+	select {
+	case r := <-reports:
+		if r.Query != query {
+			t.Errorf(`ShadowReport.Query => %#v; want %#v`, r.Query, query)
+		}
+		var actual interface{} = r.Shadow.Values
+		var expected interface{} = [][]interface{}{{"alice"}, {"bob"}}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`ShadowReport.Shadow.Values after db.Query(%#v) => %#v; want %#v`, insert, actual, expected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf(`no ShadowReport for %#v`, query)
+	}
+}
@@ -0,0 +1,103 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// flightGroup deduplicates concurrent executions of the same query.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is a query execution shared by concurrent callers.
+type flightCall struct {
+	done chan struct{}
+	snap *Snapshot
+	err  error
+
+	// waiters is the number of the callers waiting for the result, and cancel cancels the execution when all of them have gone.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightKey returns the key of a query execution that is shared by singleflight.
+// Queries that differ only in whitespace and comments share the same key,
+// unless their results are limited differently by WithResultLimit.
+func flightKey(query string, args []interface{}, limit resultLimit) string {
+	return fmt.Sprintf("%s\x00%#v\x00%d\x00%d", canonicalQuery(query), args, limit.rows, limit.bytes)
+}
+
+func (g *flightGroup) query(ctx context.Context, db *sql.DB, query string, args []interface{}, limit resultLimit) *Rows {
+	snap, err := g.do(ctx, flightKey(query, args, limit), func(ctx context.Context) (*Snapshot, error) {
+		rows, err := db.QueryContext(ctx, query, args...)
+		return Materialize(&Rows{
+			Rows:  rows,
+			err:   err,
+			limit: limit,
+		})
+	})
+	if err != nil {
+		return &Rows{err: err}
+	}
+	return snap.Rows()
+}
+
+// do calls fn unless a call with the same key is in progress, and returns its result.
+// fn is called with a context that carries the values of ctx of the first caller, but is not canceled when ctx is canceled
+// as long as another caller waits for the result: it is canceled when the contexts of all the callers are done,
+// so that the call lasts until the latest deadline of the callers.
+// do returns ctx.Err() if ctx is done before fn returns.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (*Snapshot, error)) (*Snapshot, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flightCall{
+			done: make(chan struct{}),
+		}
+		var runCtx context.Context
+		runCtx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		g.calls[key] = c
+		go g.run(runCtx, c, key, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+	select {
+	case <-c.done:
+		return c.snap, c.err
+	case <-ctx.Done():
+		g.leave(c, key)
+		return nil, ctx.Err()
+	}
+}
+
+// leave removes a caller that has stopped waiting for c, and cancels c if it was the last one.
+// A canceled call is forgotten at once, so that a later caller runs the query again instead of sharing the cancellation.
+func (g *flightGroup) leave(c *flightCall, key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c.waiters--; c.waiters > 0 {
+		return
+	}
+	c.cancel()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, c *flightCall, key string, fn func(ctx context.Context) (*Snapshot, error)) {
+	c.snap, c.err = fn(ctx)
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	c.cancel()
+	close(c.done)
+}
@@ -0,0 +1,122 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithSingleflight(t *testing.T) {
+	db := newTestDB(t, asynql.WithSingleflight())
+	defer db.Close()
+	// The rows are read into memory, so they don't hold the only connection
+	// even though the second query is sent before the first rows are consumed.
+	query := `SELECT name FROM test_table WHERE id = ?`
+	rows, err := asynql.WaitAll(db.Query(query, 1), db.Query(query, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, rs := range rows {
+		actual := scanNames(t, rs)
+		expected := []string{"alice"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`#%d: db.Query(%#v, 1) => %#v; want %#v`, i, query, actual, expected)
+		}
+	}
+}
+
+func TestWithSingleflight_Write(t *testing.T) {
+	db := newTestFileDB(t, asynql.WithSingleflight())
+	defer db.Close()
+	query := `INSERT INTO test_table (id, name) VALUES (?, ?) RETURNING id`
+	rows, err := asynql.WaitAll(db.Query(query, 3, "carol"), db.Query(query, 3, "carol"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rs := range rows {
+		for rs.Next() {
+		}
+		rs.Close()
+	}
+	var n int
+	if err := (<-db.QueryRow(`SELECT COUNT(*) FROM test_table WHERE id = 3`)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = n
+	var expected interface{} = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`rows inserted by 2 concurrent db.Query(%#v, 3, "carol") => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestWithSingleflight_Context(t *testing.T) {
+	db, err := asynql.Open("sqlite3_pause", filepath.Join(t.TempDir(), "test.db")+"?_journal_mode=WAL&_busy_timeout=5000", asynql.WithSingleflight())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`CREATE TABLE test_table (id INTEGER, name TEXT)`,
+		`INSERT INTO test_table (id, name) VALUES (1, "alice")`,
+		`INSERT INTO test_table (id, name) VALUES (2, "bob")`,
+	} {
+		if _, err := db.DB.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	query := `SELECT name FROM test_table WHERE pause() = 0 ORDER BY id`
+	receive := func(ch <-chan *asynql.Rows) *asynql.Rows {
+		t.Helper()
+		select {
+		case rs := <-ch:
+			return rs
+		case <-time.After(time.Second):
+			t.Fatal(`the query has joined the paused one`)
+			return nil
+		}
+	}
+	pause := func() (entered, resume chan struct{}) {
+		entered, resume = make(chan struct{}), make(chan struct{})
+		pauseHook.Lock()
+		pauseHook.fn = func() {
+			close(entered)
+			<-resume
+		}
+		pauseHook.Unlock()
+		return entered, resume
+	}
+
+	// The calls limited differently don't share a query.
+	entered, resume := pause()
+	limited := db.QueryContext(asynql.WithResultLimit(context.Background(), 1, 0), query)
+	<-entered
+	actual := scanNames(t, receive(db.QueryContext(context.Background(), query)))
+	expected := []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryContext(ctx, %#v) => %#v; want %#v`, query, actual, expected)
+	}
+	close(resume)
+	if err := (<-limited).Err(); !errors.Is(err, asynql.ErrResultTooLarge) {
+		t.Errorf(`db.QueryContext(limited ctx, %#v) => %#v; want %#v`, query, err, asynql.ErrResultTooLarge)
+	}
+
+	// A query whose callers have all gone is not shared by a later call.
+	entered, resume = pause()
+	defer close(resume)
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := db.QueryContext(ctx, query)
+	<-entered
+	cancel()
+	if err := (<-canceled).Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf(`db.QueryContext(canceled ctx, %#v) => %#v; want %#v`, query, err, context.Canceled)
+	}
+	actual = scanNames(t, receive(db.QueryContext(context.Background(), query)))
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryContext(ctx, %#v) after a canceled one => %#v; want %#v`, query, actual, expected)
+	}
+}
@@ -0,0 +1,117 @@
+package asynql
+
+import "context"
+
+// Snapshot is an in-memory copy of a result set.
+type Snapshot struct {
+	// Columns is the names of the columns.
+	Columns []string
+
+	// Values is the values of each row, in the order of Columns.
+	// Each value is one of the types that a driver.Value can hold.
+	Values [][]interface{}
+}
+
+// Materialize reads all the remaining rows of rs into a Snapshot.
+// rs is closed when Materialize returns, so only the current result set is read if rs has several of them.
+// Use QueryMulti to read all the result sets.
+// A nil rs, which is received from a channel closed without a result, is reported as ErrChannelClosed.
+func Materialize(rs *Rows) (*Snapshot, error) {
+	if rs == nil {
+		return nil, ErrChannelClosed
+	}
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	return readResultSet(rs)
+}
+
+// Tee receives the rows of ch, reads them into a Snapshot, and then sends a *Rows of the snapshot on each of the n returned channels,
+// so that n consumers can iterate over the same result independently without querying it again.
+// The connection of the rows is released as soon as they have been read.
+// If the query or reading the rows fails, a *Rows with the error is sent on each channel.
+func Tee(ch <-chan *Rows, n int) []<-chan *Rows {
+	chs := make([]chan *Rows, n)
+	outs := make([]<-chan *Rows, n)
+	for i := range chs {
+		chs[i] = make(chan *Rows)
+		outs[i] = chs[i]
+	}
+	go func() {
+		snap, err := Materialize(<-ch)
+		for _, c := range chs {
+			go func(c chan<- *Rows) {
+				if err != nil {
+					deliver(nil, c, &Rows{err: err})
+					return
+				}
+				deliver(nil, c, snap.Rows())
+			}(c)
+		}
+	}()
+	return outs
+}
+
+// readResultSet reads the remaining rows of the current result set of rs into a Snapshot.
+func readResultSet(rs *Rows) (*Snapshot, error) {
+	columns, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{
+		Columns: columns,
+	}
+	for rs.Next() {
+		values, err := scanRow(rs, len(columns))
+		if err != nil {
+			return nil, err
+		}
+		if rs.limited() {
+			var size int64
+			for _, v := range values {
+				size += sizeOf(v)
+			}
+			if err := rs.account(size); err != nil {
+				return nil, err
+			}
+		}
+		s.Values = append(s.Values, values)
+	}
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// scanRow scans the current row of rs, which has n columns, into the values that a driver.Value can hold.
+func scanRow(rs *Rows, n int) ([]interface{}, error) {
+	values := make([]interface{}, n)
+	dests := make([]interface{}, n)
+	for i := range values {
+		dests[i] = &values[i]
+	}
+	if err := rs.Scan(dests...); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Rows returns a new *Rows that iterates over the rows of the snapshot.
+// A Snapshot can be iterated any number of times, also concurrently.
+func (s *Snapshot) Rows() *Rows {
+	return newVirtualRows(&sliceSource{
+		columns: s.Columns,
+		values:  s.Values,
+	})
+}
+
+// Row returns a new *Row of the first row of the snapshot, which reports sql.ErrNoRows by Scan if the snapshot has no rows.
+func (s *Snapshot) Row() *Row {
+	return &Row{
+		Row: virtualDB.QueryRowContext(context.Background(), "", virtualArg{src: &sliceSource{
+			columns: s.Columns,
+			values:  s.Values,
+		}}),
+	}
+}
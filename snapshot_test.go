@@ -0,0 +1,129 @@
+package asynql_test
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestMaterialize(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT id, name FROM test_table ORDER BY id`
+	snap, err := asynql.Materialize(<-db.Query(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = snap.Columns
+	var expected interface{} = []string{"id", "name"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Materialize(db.Query(%#v)).Columns => %#v; want %#v`, query, actual, expected)
+	}
+	actual = len(snap.Values)
+	expected = 2
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`len(asynql.Materialize(db.Query(%#v)).Values) => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestSnapshot_Rows(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	snap, err := asynql.Materialize(<-db.Query(`SELECT id, name FROM test_table ORDER BY id`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		rows := snap.Rows()
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		var results []interface{}
+		for rows.Next() {
+			var id int
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, id, name)
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = results
+		var expected interface{} = []interface{}{1, "alice", 2, "bob"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`#%d: snap.Rows() => %#v; want %#v`, i, actual, expected)
+		}
+	}
+}
+
+func TestSnapshot_Row(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	snap, err := asynql.Materialize(<-db.Query(`SELECT id, name FROM test_table ORDER BY id`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	var name string
+	err = snap.Row().Scan(&id, &name)
+	var actual interface{} = []interface{}{id, name, err}
+	var expected interface{} = []interface{}{int64(1), "alice", nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`snap.Row().Scan(&id, &name) => %#v; want %#v`, actual, expected)
+	}
+	actual = (&asynql.Snapshot{Columns: snap.Columns}).Row().Scan(&id, &name)
+	expected = sql.ErrNoRows
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`Row().Scan(&id, &name) of an empty snapshot => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestTee(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT name FROM test_table ORDER BY id`
+	chs := asynql.Tee(db.Query(query), 3)
+	results := make(chan []string, len(chs))
+	for _, ch := range chs {
+		go func(ch <-chan *asynql.Rows) {
+			var names []string
+			rows := <-ch
+			defer rows.Close()
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					t.Error(err)
+				}
+				names = append(names, name)
+			}
+			results <- names
+		}(ch)
+	}
+	for range chs {
+		var actual interface{} = <-results
+		var expected interface{} = []string{"alice", "bob"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`asynql.Tee(db.Query(%#v), 3) => %#v; want %#v`, query, actual, expected)
+		}
+	}
+
+	// The connection has been released.
+	if err := (<-db.Exec(`DELETE FROM test_table`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTee_Error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT name FROM unknown_table`
+	for i, ch := range asynql.Tee(db.Query(query), 2) {
+		if (<-ch).Err() == nil {
+			t.Errorf(`asynql.Tee(db.Query(%#v), 2)[%d]; Rows.Err() => nil; want error`, query, i)
+		}
+	}
+}
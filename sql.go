@@ -2,24 +2,167 @@
 package asynql
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// Execer is the interface that wraps the asynchronous ExecContext method.
+// *DB, *Tx and *Conn implement it.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result
+}
+
+// Queryer is the interface that wraps the asynchronous QueryContext method.
+// *DB, *Tx and *Conn implement it.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows
+}
+
+// RowQueryer is the interface that wraps the asynchronous QueryRowContext method.
+// *DB, *Tx and *Conn implement it.
+type RowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) <-chan *Row
+}
+
+// Querier is the interface that groups the Execer, Queryer and RowQueryer interfaces.
+// *DB, *Tx and *Conn implement it.
+type Querier interface {
+	Execer
+	Queryer
+	RowQueryer
+}
+
 // DB is same the sql.DB, but some methods have been provided as asynchronous implementation.
 type DB struct {
 	*sql.DB
+	config
+
+	stmtCache bool
+	stmtMu    sync.Mutex
+	stmts     map[string]*Stmt
+
+	statsMu    sync.Mutex
+	stmtStats  map[string]*stmtStats
+	labelStats map[string]*labelStats
+
+	dialect     Dialect
+	dialectOnce sync.Once
+
+	multiStmtMu sync.Mutex
+	multiStmt   *bool
+
+	cache       Cache
+	cacheOnce   sync.Once
+	cacheFlight flightGroup
+	cacheMu     sync.Mutex
+	cacheTables map[string]map[string]struct{}
+	cacheGen    map[string]*cacheGeneration
+	cacheKeys   map[string]*cachedKey
+
+	evictMu        sync.Mutex
+	evictedEntries []evictedEntry
+
+	lanes  laneSet
+	paused atomic.Pointer[chan struct{}]
+	ops    opCounter
+
+	maintenance atomic.Bool
+	txWarning   atomic.Pointer[txWarning]
+
+	// primary is the DB of which the DB is a facade returned by ReadOnly.
+	primary *DB
+}
+
+// config is the configuration of a DB set by the Options, which is shared by a facade returned by ReadOnly.
+type config struct {
+	connInit func(ctx context.Context, conn *sql.Conn) error
+	flight   *flightGroup
+	fifo     bool
+	limiter  *limiter
+	quota    *tenantQuota
+	dryRun   bool
+	hooks    []Hook
+	policies []Policy
+	shadow   *shadowQueryer
+	registry *Registry
+
+	badConnRetry bool
+	busyRetries  int
+	busyBackoff  time.Duration
+
+	warmConns   int
+	location    *time.Location
+	resultLimit resultLimit
+
+	errorHandler func(ctx context.Context, err error)
+	leaks        leakDetector
+	openChannels bool
+
+	workers *workerPool
+	kill    *killSwitch
 }
 
 // Open is the same as sql.Open, but returns an *asynql.DB instead.
-func Open(driverName, dataSourceName string) (*DB, error) {
+func Open(driverName, dataSourceName string, opts ...Option) (*DB, error) {
 	db, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
-	return &DB{
-		DB: db,
-	}, nil
+	d := newDB(opts)
+	if d.wrapsConnector() {
+		c, err := connectorOf(db.Driver(), dataSourceName)
+		db.Close()
+		if err != nil {
+			return nil, err
+		}
+		db = sql.OpenDB(d.wrapConnector(c))
+	}
+	d.DB = db
+	if err := d.warmConnections(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// OpenDB is the same as sql.OpenDB, but returns an *asynql.DB instead.
+// It is useful for drivers that are configured programmatically rather than by a data source name.
+func OpenDB(c driver.Connector, opts ...Option) *DB {
+	d := newDB(opts)
+	d.DB = sql.OpenDB(d.wrapConnector(c))
+	d.warmConnections(context.Background())
+	return d
+}
+
+// New returns an *asynql.DB that wraps an existing *sql.DB.
+// It is useful when the *sql.DB has been constructed by another library or with a custom driver.Connector.
+// Closing the returned DB also closes db.
+func New(db *sql.DB, opts ...Option) *DB {
+	d := newDB(opts)
+	d.DB = db
+	return d
+}
+
+func newDB(opts []Option) *DB {
+	d := &DB{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Close resumes the operations held by Pause, stops the workers of WithWorkers, closes the statements cached by WithStmtCache, and then closes the database as sql.DB.Close does.
+func (db *DB) Close() error {
+	db.Resume()
+	if db.workers != nil {
+		db.workers.close()
+	}
+	return errors.Join(db.closeStmts(), db.DB.Close())
 }
 
 // Begin starts a transaction and returns an *asynql.Tx instead of an *sql.Tx.
@@ -28,68 +171,143 @@ func (db *DB) Begin() (*Tx, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Tx{
-		Tx: tx,
-	}, nil
+	return newTx(context.Background(), db, tx), nil
 }
 
 // Exec is similar to sql.DB.Exec, but returns a channel of *asynql.Result.
 // Exec executes query with args and then sends the result on the returned channel.
 func (db *DB) Exec(query string, args ...interface{}) <-chan *Result {
-	ch := make(chan *Result)
-	go func() {
-		result, err := db.DB.Exec(query, args...)
-		ch <- &Result{
-			Result: result,
-			err:    err,
-		}
-	}()
-	return ch
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext is similar to sql.DB.ExecContext, but returns a channel of *asynql.Result.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	ctx = killable(ctx, db)
+	return dispatch(ctx, db, db, nil, db.exec(ctx, query, args))
+}
+
+// exec returns the function that executes query with args for ExecContext.
+func (db *DB) exec(ctx context.Context, query string, args []interface{}) func() *Result {
+	return instrument(ctx, db, HookExec, query, args, func() *Result {
+		return retryOp(ctx, db, isIdempotent(ctx), func() *Result {
+			if db.dryRun {
+				return dryExec(ctx, db.DB, query, args)
+			}
+			var result sql.Result
+			var err error
+			if stmt := db.cachedStmt(query); stmt != nil {
+				start := time.Now()
+				result, err = stmt.Stmt.ExecContext(ctx, args...)
+				stmt.record(time.Since(start), err)
+			} else {
+				result, err = db.DB.ExecContext(ctx, query, args...)
+			}
+			if err == nil {
+				db.invalidateCache(query)
+			}
+			return &Result{
+				Result: result,
+				err:    err,
+			}
+		})
+	})
 }
 
 // Prepare is the same as sql.DB.Prepare, but returns a *asynql.Stmt instead.
 func (db *DB) Prepare(query string) (*Stmt, error) {
-	stmt, err := db.DB.Prepare(query)
+	return db.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext is the same as sql.DB.PrepareContext, but returns a *asynql.Stmt instead.
+func (db *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	stmt, err := db.DB.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	return &Stmt{
-		Stmt: stmt,
+		Stmt:  stmt,
+		db:    db,
+		query: query,
+		stats: newStmtStats(query),
 	}, nil
 }
 
 // Query is similar to sql.DB.Query, but returns a channel of *asynql.Rows.
 // Query executes a query with args and then sends the result on the returned channel.
 func (db *DB) Query(query string, args ...interface{}) <-chan *Rows {
-	ch := make(chan *Rows)
-	go func() {
-		rows, err := db.DB.Query(query, args...)
-		ch <- &Rows{
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is similar to sql.DB.QueryContext, but returns a channel of *asynql.Rows.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	ctx = killable(ctx, db)
+	return dispatch(ctx, db, db, nil, instrument(ctx, db, HookQuery, query, args, func() *Rows {
+		return retryOp(ctx, db, db.badConnRetry && isReadQuery(query), func() *Rows {
+			return db.query(ctx, query, args)
+		})
+	}))
+}
+
+func (db *DB) query(ctx context.Context, query string, args []interface{}) *Rows {
+	if db.shadow != nil {
+		return db.shadow.query(ctx, db, query, args)
+	}
+	return db.queryPrimary(ctx, query, args)
+}
+
+func (db *DB) queryPrimary(ctx context.Context, query string, args []interface{}) *Rows {
+	if db.flight != nil && isReadQuery(query) {
+		return db.flight.query(ctx, db.DB, query, args, db.resultLimitOf(ctx))
+	}
+	if stmt := db.cachedStmt(query); stmt != nil {
+		start := time.Now()
+		rows, err := stmt.Stmt.QueryContext(ctx, args...)
+		stmt.record(time.Since(start), err)
+		return &Rows{
 			Rows: rows,
 			err:  err,
 		}
-	}()
-	return ch
+	}
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	return &Rows{
+		Rows: rows,
+		err:  err,
+	}
 }
 
 // QueryRow is similar to sql.DB.QueryRow, but returns a channel of *asynql.Row.
 // QueryRow executes a query with args and then sends the result on the returned channel.
 func (db *DB) QueryRow(query string, args ...interface{}) <-chan *Row {
-	ch := make(chan *Row)
-	go func() {
-		row := db.DB.QueryRow(query, args...)
-		ch <- &Row{
-			Row: row,
-		}
-	}()
-	return ch
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext is similar to sql.DB.QueryRowContext, but returns a channel of *asynql.Row.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) <-chan *Row {
+	ctx = killable(ctx, db)
+	return dispatch(ctx, db, db, nil, instrument(ctx, db, HookQueryRow, query, args, func() *Row {
+		return retryOp(ctx, db, db.badConnRetry && isReadQuery(query), func() *Row {
+			if stmt := db.cachedStmt(query); stmt != nil {
+				start := time.Now()
+				row := stmt.Stmt.QueryRowContext(ctx, args...)
+				stmt.record(time.Since(start), row.Err())
+				return &Row{
+					Row: row,
+				}
+			}
+			return &Row{
+				Row: db.DB.QueryRowContext(ctx, query, args...),
+			}
+		})
+	}))
 }
 
 // Result represents a result of Exec.
 type Result struct {
 	sql.Result
+	abandonTimer
 
-	err error
+	err       error
+	queueWait time.Duration
 }
 
 // Err returns an error.
@@ -97,16 +315,124 @@ func (r *Result) Err() error {
 	return r.err
 }
 
+// QueueWait returns the time that the operation waited to run, for the concurrency limit, the workers or the preceding operations.
+// It tells the time spent in asynql from the time spent in the database.
+func (r *Result) QueueWait() time.Duration {
+	return r.queueWait
+}
+
+func (r *Result) setQueueWait(d time.Duration) {
+	r.queueWait = d
+}
+
+func (r *Result) wrapErr(query string, args []interface{}, d time.Duration) error {
+	err := r.err
+	if err != nil {
+		r.err = newQueryError(query, args, d, err)
+	}
+	return err
+}
+
 // Row represents a result of QueryRow.
 type Row struct {
 	*sql.Row
+	abandonTimer
+
+	err       error
+	queueWait time.Duration
+
+	// release releases the context of the query of WithKillSwitch when the row is scanned.
+	release func()
+}
+
+// Err returns the error of the query, if any.
+// Unlike Scan, it returns nil if the query returned no rows.
+func (r *Row) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.Row == nil {
+		return nil
+	}
+	return r.Row.Err()
+}
+
+// QueueWait returns the time that the query waited to run, as Result.QueueWait does.
+func (r *Row) QueueWait() time.Duration {
+	return r.queueWait
+}
+
+func (r *Row) setQueueWait(d time.Duration) {
+	r.queueWait = d
+}
+
+func (r *Row) wrapErr(query string, args []interface{}, d time.Duration) error {
+	err := r.Err()
+	if err != nil {
+		r.err = newQueryError(query, args, d, err)
+	}
+	return err
+}
+
+// Scan is the same as sql.Row.Scan, but returns the error of Err if any.
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.release != nil {
+		defer r.release()
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return r.Row.Scan(dest...)
+}
+
+// Exists is similar to Scan, but reports whether the query returned a row.
+// If the query returned no rows, Exists returns false and a nil error instead of sql.ErrNoRows,
+// so that the absence of the row can be told from the other errors without comparing the error.
+func (r *Row) Exists(dest ...interface{}) (bool, error) {
+	switch err := r.Scan(dest...); err {
+	case nil:
+		return true, nil
+	case sql.ErrNoRows:
+		return false, nil
+	default:
+		return false, err
+	}
 }
 
 // Rows represents a result of a query.
 type Rows struct {
 	*sql.Rows
+	abandonTimer
+
+	err       error
+	queueWait time.Duration
+
+	// release releases the context of the query of WithKillSwitch and stops the timer of WithLeakDetector when the rows are closed.
+	release func()
+
+	// limit is the limit of the rows that are read into memory, and read is the rows that have been read.
+	limit resultLimit
+	read  resultLimit
+}
+
+// Close is the same as sql.Rows.Close, but also finishes the query for DB.CancelAll and WithLeakDetector.
+func (rs *Rows) Close() error {
+	if rs.release != nil {
+		defer rs.release()
+	}
+	return rs.Rows.Close()
+}
 
-	err error
+// Next is the same as sql.Rows.Next, but also finishes the query as Close does when there are no more rows,
+// since database/sql closes the rows then.
+func (rs *Rows) Next() bool {
+	if rs.Rows.Next() {
+		return true
+	}
+	if rs.release != nil {
+		rs.release()
+	}
+	return false
 }
 
 // Err returns an error.
@@ -117,99 +443,208 @@ func (rs *Rows) Err() error {
 	return rs.Rows.Err()
 }
 
+// QueueWait returns the time that the query waited to run, as Result.QueueWait does.
+func (rs *Rows) QueueWait() time.Duration {
+	return rs.queueWait
+}
+
+func (rs *Rows) setQueueWait(d time.Duration) {
+	rs.queueWait = d
+}
+
+func (rs *Rows) wrapErr(query string, args []interface{}, d time.Duration) error {
+	err := rs.err
+	if err != nil {
+		rs.err = newQueryError(query, args, d, err)
+	}
+	return err
+}
+
 // Stmt is same the sql.Stmt, but some methods have been provided as asynchronous implementation.
 type Stmt struct {
 	*sql.Stmt
 
-	wg *sync.WaitGroup
+	db    *DB
+	tx    *Tx
+	conn  *Conn
+	query string
+	wg    *sync.WaitGroup
+	stats *stmtStats
 }
 
 // Exec is similar to sql.Stmt.Exec, but returns a channel of *asynql.Result.
 // Exec executes query with args and then sends the result on the returned channel.
 func (s *Stmt) Exec(args ...interface{}) <-chan *Result {
-	if s.wg != nil {
-		s.wg.Add(1)
+	return s.ExecContext(context.Background(), args...)
+}
+
+// ExecContext is similar to sql.Stmt.ExecContext, but returns a channel of *asynql.Result.
+func (s *Stmt) ExecContext(ctx context.Context, args ...interface{}) <-chan *Result {
+	if s.tx != nil {
+		if err := s.tx.enter(); err != nil {
+			return sendResult(s.tx, &Result{err: err})
+		}
+		defer s.tx.leave()
 	}
-	ch := make(chan *Result)
-	go func() {
-		result, err := s.Stmt.Exec(args...)
-		ch <- &Result{
+	ctx = killable(ctx, s.db)
+	return dispatch(ctx, s.db, s.owner(), s.wg, txOp(s.tx, s.query, instrument(ctx, s.db, HookExec, s.query, args, observe(s, func() *Result {
+		if dryRunOf(s.db) {
+			return s.dryExec(ctx, args)
+		}
+		result, err := s.Stmt.ExecContext(ctx, args...)
+		if err == nil {
+			s.written()
+		}
+		return &Result{
 			Result: result,
 			err:    err,
 		}
-		if s.wg != nil {
-			s.wg.Done()
-		}
-	}()
-	return ch
+	}))))
 }
 
 // Query is similar to sql.Stmt.Query, but returns a channel of *asynql.Rows.
 // Query executes a query with args and then sends the result on the returned channel.
 func (s *Stmt) Query(args ...interface{}) <-chan *Rows {
-	if s.wg != nil {
-		s.wg.Add(1)
+	return s.QueryContext(context.Background(), args...)
+}
+
+// QueryContext is similar to sql.Stmt.QueryContext, but returns a channel of *asynql.Rows.
+func (s *Stmt) QueryContext(ctx context.Context, args ...interface{}) <-chan *Rows {
+	if s.tx != nil {
+		if err := s.tx.enter(); err != nil {
+			return sendResult(s.tx, &Rows{err: err})
+		}
+		defer s.tx.leave()
 	}
-	ch := make(chan *Rows)
-	go func() {
-		rows, err := s.Stmt.Query(args...)
-		ch <- &Rows{
+	ctx = killable(ctx, s.db)
+	return dispatch(ctx, s.db, s.owner(), s.wg, txOp(s.tx, s.query, instrument(ctx, s.db, HookQuery, s.query, args, observe(s, func() *Rows {
+		rows, err := s.Stmt.QueryContext(ctx, args...)
+		return &Rows{
 			Rows: rows,
 			err:  err,
 		}
-		if s.wg != nil {
-			s.wg.Done()
-		}
-	}()
-	return ch
+	}))))
 }
 
 // QueryRow is similar to sql.Stmt.QueryRow, but returns a channel of *asynql.Row.
 // QueryRow executes a query with args and then sends the result on the returned channel.
 func (s *Stmt) QueryRow(args ...interface{}) <-chan *Row {
-	if s.wg != nil {
-		s.wg.Add(1)
-	}
-	ch := make(chan *Row)
-	go func() {
-		row := s.Stmt.QueryRow(args...)
-		ch <- &Row{
-			Row: row,
+	return s.QueryRowContext(context.Background(), args...)
+}
+
+// QueryRowContext is similar to sql.Stmt.QueryRowContext, but returns a channel of *asynql.Row.
+func (s *Stmt) QueryRowContext(ctx context.Context, args ...interface{}) <-chan *Row {
+	if s.tx != nil {
+		if err := s.tx.enter(); err != nil {
+			return sendResult(s.tx, &Row{err: err})
 		}
-		if s.wg != nil {
-			s.wg.Done()
+		defer s.tx.leave()
+	}
+	ctx = killable(ctx, s.db)
+	return dispatch(ctx, s.db, s.owner(), s.wg, txOp(s.tx, s.query, instrument(ctx, s.db, HookQueryRow, s.query, args, observe(s, func() *Row {
+		return &Row{
+			Row: s.Stmt.QueryRowContext(ctx, args...),
 		}
-	}()
-	return ch
+	}))))
 }
 
 // Tx is same the sql.Tx, but some methods have been provided as asynchronous implementation.
 type Tx struct {
 	*sql.Tx
 
+	db *DB
 	wg sync.WaitGroup
+
+	mu       sync.Mutex
+	written  []string
+	finished bool
+
+	// done is closed and outcome is set when the transaction has ended. ended reports whether it has ended.
+	done    chan struct{}
+	ended   bool
+	outcome error
+
+	// ctx is the context that the transaction has begun with, and stats is its statistics.
+	ctx   context.Context
+	stats TxStats
+
+	// warn is the timer of SetTxWarnThreshold, or nil.
+	warn *time.Timer
 }
 
 // Commit is same the sql.Tx.Commit, but waits the end of the all queries.
+// A query ends when its result is received, not when its rows are read: as sql.Tx.Commit does,
+// Commit closes the rows of the transaction that are still open, so they must be read before Commit is called.
+// In dry run mode, Commit rolls back the transaction instead.
+// Commit returns sql.ErrTxDone if the transaction has already been committed or rolled back.
 func (tx *Tx) Commit() error {
+	if !tx.finish() {
+		return sql.ErrTxDone
+	}
+	return tx.commit()
+}
+
+// CommitAsync is similar to Commit, but commits the transaction in the background and then sends the error of Commit on the returned channel,
+// so that the latency of the commit can overlap with other work.
+// The transaction is ended when CommitAsync is called, so the operations submitted after it fail with sql.ErrTxDone.
+func (tx *Tx) CommitAsync() <-chan error {
+	if !tx.finish() {
+		return sendResult[error](tx, sql.ErrTxDone)
+	}
+	ch := make(chan error)
+	go func() {
+		deliver[error](tx, ch, tx.commit())
+	}()
+	return ch
+}
+
+// commit waits for the operations of tx, and then commits it.
+func (tx *Tx) commit() error {
 	tx.wg.Wait()
-	return tx.Tx.Commit()
+	if dryRunOf(tx.db) {
+		err := tx.Tx.Rollback()
+		tx.end(HookCommit, err)
+		return err
+	}
+	if err := tx.Tx.Commit(); err != nil {
+		tx.end(HookCommit, err)
+		return err
+	}
+	tx.mu.Lock()
+	for _, query := range tx.written {
+		tx.db.invalidateCache(query)
+	}
+	tx.mu.Unlock()
+	tx.end(HookCommit, nil)
+	return nil
 }
 
 // Exec is similar to sql.Tx.Exec, but returns a channel of *asynql.Result.
 // Exec executes query with args and then sends the result on the returned channel.
 func (tx *Tx) Exec(query string, args ...interface{}) <-chan *Result {
-	tx.wg.Add(1)
-	ch := make(chan *Result)
-	go func() {
-		result, err := tx.Tx.Exec(query, args...)
-		ch <- &Result{
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext is similar to sql.Tx.ExecContext, but returns a channel of *asynql.Result.
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) <-chan *Result {
+	if err := tx.enter(); err != nil {
+		return sendResult(tx, &Result{err: err})
+	}
+	defer tx.leave()
+	ctx = killable(ctx, tx.db)
+	return dispatch(ctx, tx.db, tx, &tx.wg, txOp(tx, query, instrument(ctx, tx.db, HookExec, query, args, func() *Result {
+		if dryRunOf(tx.db) {
+			return dryExecTx(ctx, tx.Tx, query, args)
+		}
+		result, err := tx.Tx.ExecContext(ctx, query, args...)
+		if err == nil {
+			tx.write(query)
+		}
+		return &Result{
 			Result: result,
 			err:    err,
 		}
-		tx.wg.Done()
-	}()
-	return ch
+	})))
 }
 
 // Prepare is the same as sql.Tx.Prepare, but returns a *asynql.Stmt instead.
@@ -219,52 +654,174 @@ func (tx *Tx) Prepare(query string) (*Stmt, error) {
 		return nil, err
 	}
 	return &Stmt{
-		Stmt: stmt,
-		wg:   &tx.wg,
+		Stmt:  stmt,
+		db:    tx.db,
+		tx:    tx,
+		query: query,
+		stats: newStmtStats(query),
+		wg:    &tx.wg,
 	}, nil
 }
 
 // Query is similar to sql.Tx.Query, but returns a channel of *asynql.Rows.
 // Query executes a query with args and then sends the result on the returned channel.
 func (tx *Tx) Query(query string, args ...interface{}) <-chan *Rows {
-	tx.wg.Add(1)
-	ch := make(chan *Rows)
-	go func() {
-		rows, err := tx.Tx.Query(query, args...)
-		ch <- &Rows{
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext is similar to sql.Tx.QueryContext, but returns a channel of *asynql.Rows.
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) <-chan *Rows {
+	if err := tx.enter(); err != nil {
+		return sendResult(tx, &Rows{err: err})
+	}
+	defer tx.leave()
+	ctx = killable(ctx, tx.db)
+	return dispatch(ctx, tx.db, tx, &tx.wg, txOp(tx, query, instrument(ctx, tx.db, HookQuery, query, args, func() *Rows {
+		rows, err := tx.Tx.QueryContext(ctx, query, args...)
+		return &Rows{
 			Rows: rows,
 			err:  err,
 		}
-		tx.wg.Done()
-	}()
-	return ch
+	})))
 }
 
 // QueryRow is similar to sql.Tx.QueryRow, but returns a channel of *asynql.Row.
 // QueryRow executes a query with args and then sends the result on the returned channel.
 func (tx *Tx) QueryRow(query string, args ...interface{}) <-chan *Row {
-	tx.wg.Add(1)
-	ch := make(chan *Row)
-	go func() {
-		row := tx.Tx.QueryRow(query, args...)
-		ch <- &Row{
-			Row: row,
+	return tx.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext is similar to sql.Tx.QueryRowContext, but returns a channel of *asynql.Row.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) <-chan *Row {
+	if err := tx.enter(); err != nil {
+		return sendResult(tx, &Row{err: err})
+	}
+	defer tx.leave()
+	ctx = killable(ctx, tx.db)
+	return dispatch(ctx, tx.db, tx, &tx.wg, txOp(tx, query, instrument(ctx, tx.db, HookQueryRow, query, args, func() *Row {
+		return &Row{
+			Row: tx.Tx.QueryRowContext(ctx, query, args...),
 		}
-		tx.wg.Done()
-	}()
-	return ch
+	})))
 }
 
 // Rollback is same the sql.Tx.Rollback, but waits the end of the all queries.
+// It closes the rows of the transaction that are still open as Commit does, so it never waits for them to be read.
+// Rollback returns sql.ErrTxDone if the transaction has already been committed or rolled back.
 func (tx *Tx) Rollback() error {
+	if !tx.finish() {
+		return sql.ErrTxDone
+	}
+	return tx.rollback()
+}
+
+// RollbackAsync is similar to Rollback, but rolls back the transaction in the background and then sends the error of Rollback on the returned channel.
+// The transaction is ended when RollbackAsync is called, so the operations submitted after it fail with sql.ErrTxDone.
+func (tx *Tx) RollbackAsync() <-chan error {
+	if !tx.finish() {
+		return sendResult[error](tx, sql.ErrTxDone)
+	}
+	ch := make(chan error)
+	go func() {
+		deliver[error](tx, ch, tx.rollback())
+	}()
+	return ch
+}
+
+// rollback waits for the operations of tx, and then rolls it back.
+func (tx *Tx) rollback() error {
 	tx.wg.Wait()
-	return tx.Tx.Rollback()
+	err := tx.Tx.Rollback()
+	tx.end(HookRollback, err)
+	return err
 }
 
 // Stmt is same the sql.Tx.Stmt, but returns a *asynql.Stmt.
 func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
 	return &Stmt{
-		Stmt: tx.Tx.Stmt(stmt.Stmt),
-		wg:   &tx.wg,
+		Stmt:  tx.Tx.Stmt(stmt.Stmt),
+		db:    tx.db,
+		tx:    tx,
+		query: stmt.query,
+		stats: newStmtStats(stmt.query),
+		wg:    &tx.wg,
+	}
+}
+
+// enter holds tx open for an operation that is about to be dispatched, or returns sql.ErrTxDone if tx has been committed or rolled back,
+// so that Commit and Rollback wait for the operations that have been submitted before them, and the later ones fail cleanly.
+// The operation must call leave once it has been dispatched.
+func (tx *Tx) enter() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.finished {
+		return sql.ErrTxDone
+	}
+	tx.wg.Add(1)
+	return nil
+}
+
+// leave releases the hold of enter.
+func (tx *Tx) leave() {
+	tx.wg.Done()
+}
+
+// finish marks tx as committed or rolled back, and reports whether it has not been marked yet.
+func (tx *Tx) finish() bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.finished {
+		return false
+	}
+	tx.finished = true
+	return true
+}
+
+// write records that query has been executed in the transaction,
+// so that the cached results that it affects are invalidated on commit.
+func (tx *Tx) write(query string) {
+	if tx.db == nil {
+		return
+	}
+	tx.mu.Lock()
+	tx.written = append(tx.written, query)
+	tx.mu.Unlock()
+}
+
+// owner returns the object whose operations the operations of the statement are ordered with in FIFO mode.
+func (s *Stmt) owner() interface{} {
+	switch {
+	case s.tx != nil:
+		return s.tx
+	case s.conn != nil:
+		return s.conn
+	}
+	return s.db
+}
+
+// dryExec executes the statement with args in a transaction that is rolled back.
+// A statement of a Tx is executed in the Tx, which is rolled back on Commit.
+func (s *Stmt) dryExec(ctx context.Context, args []interface{}) *Result {
+	if s.tx != nil {
+		result, err := s.Stmt.ExecContext(ctx, args...)
+		return &Result{
+			Result: result,
+			err:    err,
+		}
+	}
+	var b beginner = s.db.DB
+	if s.conn != nil {
+		b = s.conn.Conn
+	}
+	return dryExecStmt(ctx, b, s.Stmt, args)
+}
+
+// written invalidates the cached results that the statement affects.
+func (s *Stmt) written() {
+	switch {
+	case s.tx != nil:
+		s.tx.write(s.query)
+	case s.db != nil:
+		s.db.invalidateCache(s.query)
 	}
 }
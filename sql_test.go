@@ -1,16 +1,22 @@
 package asynql_test
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/naoina/asynql"
 )
 
-func newTestDB(t *testing.T) *asynql.DB {
-	db, err := asynql.Open("sqlite3", ":memory:")
+func newTestDB(t *testing.T, opts ...asynql.Option) *asynql.DB {
+	db, err := asynql.Open("sqlite3", ":memory:", opts...)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -27,6 +33,80 @@ func newTestDB(t *testing.T) *asynql.DB {
 	return db
 }
 
+type testConnector struct {
+	d   driver.Driver
+	dsn string
+}
+
+func (c *testConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.d.Open(c.dsn)
+}
+
+func (c *testConnector) Driver() driver.Driver {
+	return c.d
+}
+
+// newTestFileDB is similar to newTestDB, but returns a DB backed by a file so that it can have multiple connections.
+func newTestFileDB(t *testing.T, opts ...asynql.Option) *asynql.DB {
+	db, err := asynql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_busy_timeout=5000", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		`CREATE TABLE test_table (id INTEGER, name TEXT)`,
+		`INSERT INTO test_table (id, name) VALUES (1, "alice")`,
+		`INSERT INTO test_table (id, name) VALUES (2, "bob")`,
+	} {
+		if _, err := db.DB.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestNew(t *testing.T) {
+	sqldb, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := asynql.New(sqldb)
+	defer db.Close()
+	var actual interface{} = db.DB
+	var expected interface{} = sqldb
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.New(%#v).DB => %#v; want %#v`, sqldb, actual, expected)
+	}
+	var n int
+	if err := (<-db.QueryRow(`SELECT 1`)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	actual = n
+	expected = 1
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryRow("SELECT 1") => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestOpenDB(t *testing.T) {
+	sqldb, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	connector := &testConnector{d: sqldb.Driver(), dsn: ":memory:"}
+	sqldb.Close()
+	db := asynql.OpenDB(connector)
+	defer db.Close()
+	var n int
+	if err := (<-db.QueryRow(`SELECT 1`)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = n
+	var expected interface{} = 1
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.QueryRow("SELECT 1") => %#v; want %#v`, actual, expected)
+	}
+}
+
 func TestDB_Exec(t *testing.T) {
 	db := newTestDB(t)
 	defer db.Close()
@@ -49,6 +129,20 @@ func TestDB_Exec(t *testing.T) {
 	}
 }
 
+func TestDB_ExecContext(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	query := `INSERT INTO test_table (id, name) VALUES (3, "jack")`
+	result := <-db.ExecContext(ctx, query)
+	var actual interface{} = errors.Unwrap(result.Err())
+	var expected interface{} = context.Canceled
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.ExecContext(canceledCtx, %#v); Result.Err() => %#v; want %#v`, query, actual, expected)
+	}
+}
+
 func TestDB_Query(t *testing.T) {
 	db := newTestDB(t)
 	defer db.Close()
@@ -117,6 +211,43 @@ func TestDB_QueryRow(t *testing.T) {
 	wg.Wait()
 }
 
+func TestRow_Exists(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT name FROM test_table WHERE id = ?`
+	for _, v := range []struct {
+		id       int
+		expected []interface{}
+	}{
+		{1, []interface{}{true, "alice"}},
+		{3, []interface{}{false, ""}},
+	} {
+		row := <-db.QueryRow(query, v.id)
+		if err := row.Err(); err != nil {
+			t.Errorf(`db.QueryRow(%#v, %#v).Err() => %#v; want nil`, query, v.id, err)
+		}
+		var name string
+		ok, err := row.Exists(&name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = []interface{}{ok, name}
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`db.QueryRow(%#v, %#v).Exists(&name) => %#v; want %#v`, query, v.id, actual, expected)
+		}
+	}
+
+	query = `SELECT name FROM missing_table`
+	row := <-db.QueryRow(query)
+	if err := row.Err(); err == nil {
+		t.Errorf(`db.QueryRow(%#v).Err() => nil; want error`, query)
+	}
+	if ok, err := row.Exists(new(string)); ok || err == nil {
+		t.Errorf(`db.QueryRow(%#v).Exists(&name) => %#v, %#v; want false, error`, query, ok, err)
+	}
+}
+
 func TestStmt_Exec(t *testing.T) {
 	db := newTestDB(t)
 	defer db.Close()
@@ -251,6 +382,150 @@ func TestTx_Exec(t *testing.T) {
 	}
 }
 
+func TestTx_Done(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	for _, finish := range []func(tx *asynql.Tx) error{(*asynql.Tx).Commit, (*asynql.Tx).Rollback} {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmt, err := tx.Prepare(`SELECT name FROM test_table WHERE id = ?`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := finish(tx); err != nil {
+			t.Fatal(err)
+		}
+		for _, err := range []error{
+			tx.Commit(),
+			tx.Rollback(),
+			(<-tx.Exec(`UPDATE test_table SET name = ?`, "carol")).Err(),
+			(<-tx.Query(`SELECT name FROM test_table`)).Err(),
+			(<-tx.QueryRow(`SELECT name FROM test_table`)).Err(),
+			(<-stmt.Query(1)).Err(),
+		} {
+			if !errors.Is(err, sql.ErrTxDone) {
+				t.Errorf(`error after the end of the transaction => %#v; want %#v`, err, sql.ErrTxDone)
+			}
+		}
+	}
+}
+
+func TestTx_Done_Concurrent(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := (<-tx.Exec(`UPDATE test_table SET name = name`)).Err(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+				t.Errorf(`tx.Exec(...).Err() => %#v; want nil or %#v`, err, sql.ErrTxDone)
+			}
+		}()
+	}
+	errs := make(chan error, 2)
+	go func() { errs <- tx.Commit() }()
+	go func() { errs <- tx.Rollback() }()
+	var done int
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err == nil {
+			done++
+		} else if !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf(`tx.Commit() or tx.Rollback() => %#v; want nil or %#v`, err, sql.ErrTxDone)
+		}
+	}
+	if done != 1 {
+		t.Errorf(`number of successful tx.Commit() and tx.Rollback() => %v; want 1`, done)
+	}
+	wg.Wait()
+}
+
+func TestTx_CommitAsync(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	for _, v := range []struct {
+		finish   func(tx *asynql.Tx) <-chan error
+		name     string
+		expected []string
+	}{
+		{(*asynql.Tx).CommitAsync, "carol", []string{"carol", "bob"}},
+		{(*asynql.Tx).RollbackAsync, "dave", []string{"carol", "bob"}},
+	} {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch := tx.Exec(`UPDATE test_table SET name = ? WHERE id = ?`, v.name, 1)
+		done := v.finish(tx)
+		if err := (<-ch).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		for _, err := range []error{
+			<-tx.CommitAsync(),
+			<-tx.RollbackAsync(),
+			(<-tx.Exec(`UPDATE test_table SET name = ?`, "dave")).Err(),
+		} {
+			if !errors.Is(err, sql.ErrTxDone) {
+				t.Errorf(`error after the end of the transaction => %#v; want %#v`, err, sql.ErrTxDone)
+			}
+		}
+		var actual interface{} = scanNames(t, <-db.Query(`SELECT name FROM test_table ORDER BY id`))
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`names => %#v; want %#v`, actual, expected)
+		}
+	}
+}
+
+func TestTx_OpenRows(t *testing.T) {
+	for _, v := range []struct {
+		name string
+		end  func(tx *asynql.Tx) error
+	}{
+		{"Commit", (*asynql.Tx).Commit},
+		{"Rollback", (*asynql.Tx).Rollback},
+	} {
+		db := newTestDB(t)
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := <-tx.Query(`SELECT name FROM test_table ORDER BY id`)
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		row := <-tx.QueryRow(`SELECT name FROM test_table WHERE id = ?`, 2)
+		errs := make(chan error, 1)
+		go func() { errs <- v.end(tx) }()
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Errorf(`tx.%s() with unread rows => %#v; want nil`, v.name, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf(`tx.%s() with unread rows doesn't return`, v.name)
+		}
+		if rows.Next() {
+			t.Errorf(`rows.Next() after tx.%s() => true; want false`, v.name)
+		}
+		var name string
+		if err := row.Scan(&name); err == nil {
+			t.Errorf(`row.Scan(&name) after tx.%s() => nil; want an error`, v.name)
+		}
+		rows.Close()
+		db.Close()
+	}
+}
+
 func TestTx_Query(t *testing.T) {
 	db := newTestDB(t)
 	defer db.Close()
@@ -0,0 +1,51 @@
+package asynql
+
+import (
+	"context"
+)
+
+// Sqlizer is the interface of the query builders, such as those of goqu, which build a query and its arguments.
+type Sqlizer interface {
+	ToSQL() (query string, args []interface{}, err error)
+}
+
+// SqlizerFunc is an adapter to use a function as a Sqlizer.
+// It plugs in the builders whose method has another name, such as those of Squirrel:
+//
+//	asynql.ExecSQL(ctx, db, asynql.SqlizerFunc(sq.Update("users").Set("name", "carol").Where(sq.Eq{"id": 1}).ToSql))
+type SqlizerFunc func() (string, []interface{}, error)
+
+// ToSQL returns f().
+func (f SqlizerFunc) ToSQL() (string, []interface{}, error) {
+	return f()
+}
+
+// ExecSQL executes the query built by s on e, which is a *DB, *Tx or *Conn.
+// If s fails to build the query, a Result with the error is sent.
+func ExecSQL(ctx context.Context, e Execer, s Sqlizer) <-chan *Result {
+	query, args, err := s.ToSQL()
+	if err != nil {
+		return sendResult(e, &Result{err: err})
+	}
+	return e.ExecContext(ctx, query, args...)
+}
+
+// QuerySQL executes the query built by s on q, which is a *DB, *Tx or *Conn.
+// If s fails to build the query, a Rows with the error is sent.
+func QuerySQL(ctx context.Context, q Queryer, s Sqlizer) <-chan *Rows {
+	query, args, err := s.ToSQL()
+	if err != nil {
+		return sendResult(q, &Rows{err: err})
+	}
+	return q.QueryContext(ctx, query, args...)
+}
+
+// QueryRowSQL executes the query built by s on q, which is a *DB, *Tx or *Conn.
+// If s fails to build the query, a Row with the error is sent.
+func QueryRowSQL(ctx context.Context, q RowQueryer, s Sqlizer) <-chan *Row {
+	query, args, err := s.ToSQL()
+	if err != nil {
+		return sendResult(q, &Row{err: err})
+	}
+	return q.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,71 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+type testBuilder struct {
+	query string
+	args  []interface{}
+	err   error
+}
+
+func (b testBuilder) ToSQL() (string, []interface{}, error) {
+	return b.query, b.args, b.err
+}
+
+func TestSqlizer(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-asynql.ExecSQL(ctx, db, testBuilder{query: `INSERT INTO test_table (id, name) VALUES (?, ?)`, args: []interface{}{3, "carol"}})).Err(); err != nil {
+		t.Fatal(err)
+	}
+	rows := <-asynql.QuerySQL(ctx, db, testBuilder{query: `SELECT name FROM test_table WHERE id > ? ORDER BY id`, args: []interface{}{1}})
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = scanNames(t, rows)
+	var expected interface{} = []string{"bob", "carol"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.QuerySQL(ctx, db, b) => %#v; want %#v`, actual, expected)
+	}
+	// SqlizerFunc adapts the builders such as those of Squirrel, whose method is ToSql.
+	toSql := testBuilder{query: `SELECT name FROM test_table WHERE id = ?`, args: []interface{}{3}}.ToSQL
+	var name string
+	if err := (<-asynql.QueryRowSQL(ctx, db, asynql.SqlizerFunc(toSql))).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	actual = name
+	expected = "carol"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.QueryRowSQL(ctx, db, b) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestSqlizer_Error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	errBuild := errors.New("build failed")
+	b := testBuilder{err: errBuild}
+	for _, v := range []struct {
+		name string
+		err  error
+	}{
+		{"ExecSQL", (<-asynql.ExecSQL(ctx, db, b)).Err()},
+		{"QuerySQL", (<-asynql.QuerySQL(ctx, db, b)).Err()},
+		{"QueryRowSQL", (<-asynql.QueryRowSQL(ctx, db, b)).Err()},
+	} {
+		var actual interface{} = v.err
+		var expected interface{} = errBuild
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`asynql.%s(ctx, db, b) => %#v; want %#v`, v.name, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// PoolStats is the statistics of the connection pool of a DB and of the asynchronous operations on it.
+type PoolStats struct {
+	sql.DBStats
+
+	// Time is the time when the statistics were taken.
+	Time time.Time
+
+	// InFlight is the number of the asynchronous operations that are running.
+	InFlight int64
+
+	// Queued is the number of the asynchronous operations that are waiting to run,
+	// for WithMaxConcurrency, for a worker of WithWorkers, or behind the preceding operations of WithFIFO.
+	Queued int64
+
+	// MaxQueued is the maximum of Queued since the DB was created.
+	MaxQueued int64
+
+	// Expired is the number of the operations that were not run because their contexts were done while they were waiting to run.
+	Expired int64
+
+	// QueueWait is the total time that the completed operations waited to run.
+	// The time of each operation is reported by QueueWait of its result.
+	QueueWait time.Duration
+}
+
+// opCounter counts the asynchronous operations of a DB.
+type opCounter struct {
+	inFlight  atomic.Int64
+	queued    atomic.Int64
+	maxQueued atomic.Int64
+	expired   atomic.Int64
+
+	// waited is the total time that the operations waited to run in nanoseconds.
+	waited atomic.Int64
+
+	// done is the number of the completed operations, and busy is their total duration in nanoseconds.
+	done atomic.Int64
+	busy atomic.Int64
+}
+
+// enqueue counts an operation as queued, and returns the time when it was queued.
+func (c *opCounter) enqueue() time.Time {
+	n := c.queued.Add(1)
+	for m := c.maxQueued.Load(); n > m && !c.maxQueued.CompareAndSwap(m, n); m = c.maxQueued.Load() {
+	}
+	return time.Now()
+}
+
+// track runs fn, which has been counted as queued since queued, counting it as in flight instead.
+// The time that the operation waited is set to its result.
+func track[T any](c *opCounter, queued time.Time, fn func() T) T {
+	c.queued.Add(-1)
+	c.inFlight.Add(1)
+	start := time.Now()
+	wait := start.Sub(queued)
+	c.waited.Add(int64(wait))
+	defer func() {
+		c.busy.Add(int64(time.Since(start)))
+		c.done.Add(1)
+		c.inFlight.Add(-1)
+	}()
+	v := fn()
+	if r, ok := interface{}(v).(queueWaiter); ok {
+		r.setQueueWait(wait)
+	}
+	return v
+}
+
+// queueWaiter is implemented by the results that report the time that their operations waited to run.
+type queueWaiter interface {
+	setQueueWait(d time.Duration)
+}
+
+// PoolStats returns the statistics of the connection pool and the asynchronous operations.
+func (db *DB) PoolStats() *PoolStats {
+	return &PoolStats{
+		DBStats:  db.DB.Stats(),
+		Time:     time.Now(),
+		InFlight: db.ops.inFlight.Load(),
+		Queued:   db.ops.queued.Load(),
+
+		MaxQueued: db.ops.maxQueued.Load(),
+		Expired:   db.ops.expired.Load(),
+		QueueWait: time.Duration(db.ops.waited.Load()),
+	}
+}
+
+// DefaultStatsStreamInterval is the interval of StatsStream when the given one is not positive.
+const DefaultStatsStreamInterval = 10 * time.Second
+
+// StatsStream sends the statistics of the connection pool and the asynchronous operations on the returned channel
+// immediately and then every interval, for feeding the utilization of the pool into a monitoring system.
+// The statistics are dropped while the receiver is not ready, so a slow receiver gets the latest ones.
+// The channel is closed when ctx is done.
+// If interval is not positive, DefaultStatsStreamInterval is used instead.
+func (db *DB) StatsStream(ctx context.Context, interval time.Duration) <-chan *PoolStats {
+	if interval <= 0 {
+		interval = DefaultStatsStreamInterval
+	}
+	ch := make(chan *PoolStats)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case ch <- db.PoolStats():
+			case <-ticker.C:
+				continue
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,113 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_PoolStats(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithMaxConcurrency(1))
+	defer db.Close()
+	results := []<-chan *asynql.Result{db.Exec("block")}
+	<-conn.started
+	results = append(results, db.Exec("a"), db.Exec("b"))
+	var stats *asynql.PoolStats
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if stats = db.PoolStats(); stats.Queued == 2 {
+			break
+		}
+	}
+	var actual interface{} = []interface{}{stats.InFlight, stats.Queued, stats.InUse}
+	var expected interface{} = []interface{}{int64(1), int64(2), 1}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.PoolStats() InFlight, Queued, InUse => %#v; want %#v`, actual, expected)
+	}
+	close(conn.release)
+	if _, err := asynql.WaitAll(results...); err != nil {
+		t.Fatal(err)
+	}
+	stats = db.PoolStats()
+	actual = []interface{}{stats.InFlight, stats.Queued}
+	expected = []interface{}{int64(0), int64(0)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.PoolStats() InFlight, Queued after WaitAll => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDB_PoolStats_QueueWait(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithWorkers(1, 2))
+	defer db.Close()
+	blocked := db.Exec("block")
+	<-conn.started
+	queued := []<-chan *asynql.Result{db.Exec("a"), db.Exec("b")}
+	const wait = 20 * time.Millisecond
+	time.Sleep(wait)
+	close(conn.release)
+	if err := (<-blocked).Err(); err != nil {
+		t.Fatal(err)
+	}
+	results, err := asynql.WaitAll(queued...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if d := r.QueueWait(); d < wait {
+			t.Errorf(`results[%d].QueueWait() => %v; want >= %v`, i, d, wait)
+		}
+	}
+	stats := db.PoolStats()
+	var actual interface{} = []interface{}{stats.Queued, stats.MaxQueued}
+	var expected interface{} = []interface{}{int64(0), int64(2)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.PoolStats() Queued, MaxQueued => %#v; want %#v`, actual, expected)
+	}
+	if stats.QueueWait < 2*wait {
+		t.Errorf(`db.PoolStats().QueueWait => %v; want >= %v`, stats.QueueWait, 2*wait)
+	}
+}
+
+func TestDB_StatsStream(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := db.StatsStream(ctx, 10*time.Millisecond)
+	var prev time.Time
+	for i := 0; i < 3; i++ {
+		stats, ok := <-ch
+		if !ok {
+			t.Fatalf(`db.StatsStream(ctx, 10ms) is closed after %d stats; want open`, i)
+		}
+		if !stats.Time.After(prev) || stats.MaxOpenConnections != 1 {
+			t.Errorf(`db.StatsStream(ctx, 10ms) => %#v; want later stats of the pool`, stats)
+		}
+		prev = stats.Time
+	}
+	cancel()
+	for range ch {
+	}
+}
+
+func TestDB_StatsStream_DefaultInterval(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := db.StatsStream(ctx, 0)
+	if _, ok := <-ch; !ok {
+		t.Errorf(`db.StatsStream(ctx, 0) is closed; want the first stats`)
+	}
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,122 @@
+package asynql
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// StmtStats is the execution statistics of a prepared statement, or of all the statements of a query.
+type StmtStats struct {
+	// Query is the query of the statement.
+	Query string
+
+	// Executions is the number of the executions, and Errors is the number of those that failed.
+	Executions int64
+	Errors     int64
+
+	// TotalDuration, MinDuration and MaxDuration summarize the durations of the executions.
+	// For a Query, the duration is the time until the rows are ready, not until they are read.
+	TotalDuration time.Duration
+	MinDuration   time.Duration
+	MaxDuration   time.Duration
+}
+
+// MeanDuration returns the mean duration of the executions.
+func (s StmtStats) MeanDuration() time.Duration {
+	if s.Executions == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Executions)
+}
+
+// stmtStats accumulates StmtStats.
+type stmtStats struct {
+	mu    sync.Mutex
+	stats StmtStats
+}
+
+func newStmtStats(query string) *stmtStats {
+	return &stmtStats{
+		stats: StmtStats{Query: query},
+	}
+}
+
+func (s *stmtStats) record(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &s.stats
+	st.Executions++
+	if err != nil {
+		st.Errors++
+	}
+	st.TotalDuration += d
+	if st.Executions == 1 || d < st.MinDuration {
+		st.MinDuration = d
+	}
+	if d > st.MaxDuration {
+		st.MaxDuration = d
+	}
+}
+
+func (s *stmtStats) snapshot() StmtStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Stats returns the execution statistics of the statement.
+func (s *Stmt) Stats() StmtStats {
+	return s.stats.snapshot()
+}
+
+// observe returns a function that runs fn, which executes the statement, and records its duration and error.
+func observe[T interface{ Err() error }](s *Stmt, fn func() T) func() T {
+	return func() T {
+		start := time.Now()
+		v := fn()
+		s.record(time.Since(start), v.Err())
+		return v
+	}
+}
+
+func (s *Stmt) record(d time.Duration, err error) {
+	s.stats.record(d, err)
+	if s.db != nil {
+		s.db.queryStats(s.query).record(d, err)
+	}
+}
+
+// queryStats returns the statistics of all the statements of query.
+func (db *DB) queryStats(query string) *stmtStats {
+	db.statsMu.Lock()
+	defer db.statsMu.Unlock()
+	if s, ok := db.stmtStats[query]; ok {
+		return s
+	}
+	if db.stmtStats == nil {
+		db.stmtStats = make(map[string]*stmtStats)
+	}
+	s := newStmtStats(query)
+	db.stmtStats[query] = s
+	return s
+}
+
+// StatementStats returns the execution statistics of the prepared statements of the DB and its transactions and connections,
+// including the statements cached by WithStmtCache, aggregated by query like pg_stat_statements.
+// The statistics are sorted in the descending order of the total duration.
+func (db *DB) StatementStats() []StmtStats {
+	db.statsMu.Lock()
+	stats := make([]StmtStats, 0, len(db.stmtStats))
+	for _, s := range db.stmtStats {
+		stats = append(stats, s.snapshot())
+	}
+	db.statsMu.Unlock()
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalDuration != stats[j].TotalDuration {
+			return stats[i].TotalDuration > stats[j].TotalDuration
+		}
+		return stats[i].Query < stats[j].Query
+	})
+	return stats
+}
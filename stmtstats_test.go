@@ -0,0 +1,77 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestStmt_Stats(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT name FROM test_table WHERE id = ?`
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	for _, id := range []interface{}{1, 2, 3} {
+		var name string
+		(<-stmt.QueryRow(id)).Scan(&name)
+	}
+	if err := (<-stmt.Exec(1, 2)).Err(); err == nil {
+		t.Fatalf(`stmt.Exec(1, 2) => nil; want error`)
+	}
+	stats := stmt.Stats()
+	var actual interface{} = []interface{}{stats.Query, stats.Executions, stats.Errors}
+	var expected interface{} = []interface{}{query, int64(4), int64(1)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`stmt.Stats() => %#v; want %#v`, actual, expected)
+	}
+	if stats.MinDuration > stats.MaxDuration || stats.MeanDuration() > stats.MaxDuration || stats.TotalDuration < stats.MaxDuration {
+		t.Errorf(`stmt.Stats() => %#v; want consistent durations`, stats)
+	}
+}
+
+func TestDB_StatementStats(t *testing.T) {
+	db := newTestDB(t, asynql.WithStmtCache())
+	defer db.Close()
+	query := `SELECT name FROM test_table WHERE id = ?`
+	other := `SELECT name FROM test_table ORDER BY id`
+	if err := db.Warmup(context.Background(), query); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	var name string
+	if err := (<-stmt.QueryRow(1)).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-db.QueryRow(query, 2)).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	scanNames(t, <-db.Query(query, 1))
+	stmt2, err := db.Prepare(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt2.Close()
+	scanNames(t, <-stmt2.Query())
+
+	var actual interface{} = map[string]int64{}
+	for _, s := range db.StatementStats() {
+		actual.(map[string]int64)[s.Query] = s.Executions
+	}
+	var expected interface{} = map[string]int64{query: 3, other: 1}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.StatementStats() executions => %#v; want %#v`, actual, expected)
+	}
+	if actual, expected := stmt.Stats().Executions, int64(1); actual != expected {
+		t.Errorf(`stmt.Stats().Executions => %#v; want %#v`, actual, expected)
+	}
+}
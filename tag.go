@@ -0,0 +1,50 @@
+package asynql
+
+import (
+	"context"
+	"maps"
+)
+
+type queryNameKey struct{}
+
+type tagsKey struct{}
+
+// WithQueryName returns a copy of ctx that names the queries run with it, such as "get_user",
+// so that the hooks can tell the queries apart by HookEvent.Name without parsing them.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// QueryName returns the name given by WithQueryName, or the name of the query template that ctx runs, if any.
+func QueryName(ctx context.Context) string {
+	return queryNameOf(ctx)
+}
+
+// queryNameOf returns the name of the query that ctx runs, if any.
+func queryNameOf(ctx context.Context) string {
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	return name
+}
+
+// WithTag returns a copy of ctx that tags the queries run with it by key and value, such as the ID of the request,
+// which are exposed to the hooks by HookEvent.Tags for the attribution of the queries in logs, metrics and traces.
+// The tags of ctx are inherited, and a tag of the same key is replaced.
+func WithTag(ctx context.Context, key, value string) context.Context {
+	tags := maps.Clone(tagsOf(ctx))
+	if tags == nil {
+		tags = make(map[string]string, 1)
+	}
+	tags[key] = value
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// Tags returns a copy of the tags given by WithTag, or nil if there are none.
+func Tags(ctx context.Context) map[string]string {
+	return maps.Clone(tagsOf(ctx))
+}
+
+// tagsOf returns the tags of ctx, which must not be modified.
+func tagsOf(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey{}).(map[string]string)
+	return tags
+}
@@ -0,0 +1,43 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithTag(t *testing.T) {
+	var rec hookRecorder
+	db := newTestDB(t, asynql.WithHook(rec.hook))
+	defer db.Close()
+	ctx := asynql.WithTag(context.Background(), "request_id", "r1")
+	ctx = asynql.WithQueryName(ctx, "rename")
+	child := asynql.WithTag(ctx, "user", "alice")
+	if err := (<-db.ExecContext(child, `UPDATE test_table SET name = ? WHERE id = 1`, "carol")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-db.ExecContext(ctx, `UPDATE test_table SET name = ? WHERE id = 2`, "dave")).Err(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = []interface{}{rec.events[0].Name, rec.events[0].Tags, rec.events[1].Name, rec.events[1].Tags}
+	var expected interface{} = []interface{}{
+		"rename", map[string]string{"request_id": "r1", "user": "alice"},
+		"rename", map[string]string{"request_id": "r1"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`hook events Name and Tags => %#v; want %#v`, actual, expected)
+	}
+
+	actual = []interface{}{asynql.QueryName(child), asynql.Tags(child)}
+	expected = []interface{}{"rename", map[string]string{"request_id": "r1", "user": "alice"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.QueryName(ctx), asynql.Tags(ctx) => %#v; want %#v`, actual, expected)
+	}
+	actual = asynql.Tags(context.Background())
+	expected = map[string]string(nil)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Tags(context.Background()) => %#v; want %#v`, actual, expected)
+	}
+}
@@ -0,0 +1,78 @@
+package asynql
+
+// Outcome represents a result of Go, Then and Catch.
+type Outcome[T any] struct {
+	// V is the value returned by the function of Go, Then or Catch.
+	V T
+
+	err error
+}
+
+// Err returns an error.
+func (o *Outcome[T]) Err() error {
+	return o.err
+}
+
+// Go runs fn in the background, and then sends its result on the returned channel,
+// so that a synchronous function, such as a method generated by sqlc, runs concurrently with the asynchronous operations.
+func Go[T any](fn func() (T, error)) <-chan *Outcome[T] {
+	out := make(chan *Outcome[T])
+	go func() {
+		o := &Outcome[T]{}
+		o.V, o.err = fn()
+		deliver(nil, out, o)
+	}()
+	return out
+}
+
+// Then waits for the value of ch in the background, passes it to fn, and then sends the result of fn on the returned channel,
+// so that a query that depends on the result of another one can be pipelined without a goroutine of its own:
+//
+//	id := asynql.Then(db.QueryRowContext(ctx, `SELECT id FROM users WHERE name = ?`, "alice"), func(row *asynql.Row) (int64, error) {
+//		var id int64
+//		return id, row.Scan(&id)
+//	})
+//	orders := asynql.Then(id, func(id *asynql.Outcome[int64]) (*asynql.Snapshot, error) {
+//		return asynql.Materialize(<-db.QueryContext(ctx, `SELECT * FROM orders WHERE user_id = ?`, id.V))
+//	})
+//
+// If the value has an error, fn is not called and the Outcome has the error.
+// If ch is closed without a value, fn is not called and the Outcome has ErrChannelClosed.
+// A *Rows passed to fn is closed after fn returns.
+func Then[In Errer, Out any](ch <-chan In, fn func(In) (Out, error)) <-chan *Outcome[Out] {
+	out := make(chan *Outcome[Out])
+	go func() {
+		v, err := receive(ch)
+		o := &Outcome[Out]{err: err}
+		if o.err == nil {
+			o.V, o.err = fn(v)
+			if rs, ok := interface{}(v).(*Rows); ok {
+				rs.Close()
+			}
+		}
+		deliver(nil, out, o)
+	}()
+	return out
+}
+
+// Catch waits for the value of ch in the background, and then sends it on the returned channel.
+// If the value has an error, the result of handler called with the error is sent instead,
+// so that handler can recover from the error by returning a nil error, or replace it with another one.
+func Catch[T any](ch <-chan *Outcome[T], handler func(error) (T, error)) <-chan *Outcome[T] {
+	out := make(chan *Outcome[T])
+	go func() {
+		o, ok := <-ch
+		if !ok {
+			o = &Outcome[T]{err: ErrChannelClosed}
+		}
+		if o.err != nil {
+			v, err := handler(o.err)
+			o = &Outcome[T]{
+				V:   v,
+				err: err,
+			}
+		}
+		deliver(nil, out, o)
+	}()
+	return out
+}
@@ -0,0 +1,137 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestGo(t *testing.T) {
+	o := <-asynql.Go(func() (string, error) {
+		return "alice", nil
+	})
+	var actual interface{} = []interface{}{o.V, o.Err()}
+	var expected interface{} = []interface{}{"alice", nil}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Go(fn) => %#v; want %#v`, actual, expected)
+	}
+
+	errFn := errors.New("fn failed")
+	actual = (<-asynql.Go(func() (string, error) {
+		return "", errFn
+	})).Err()
+	expected = errFn
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Go(fn).Err() => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestThen(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	id := asynql.Then(db.QueryRowContext(ctx, `SELECT id FROM test_table WHERE name = ?`, "bob"), func(row *asynql.Row) (int64, error) {
+		var id int64
+		return id, row.Scan(&id)
+	})
+	name := asynql.Then(id, func(id *asynql.Outcome[int64]) (string, error) {
+		var name string
+		return name, (<-db.QueryRowContext(ctx, `SELECT name FROM test_table WHERE id = ?`, id.V-1)).Scan(&name)
+	})
+	o := <-name
+	if err := o.Err(); err != nil {
+		t.Fatalf(`asynql.Then(...); Outcome.Err() => %#v; want nil`, err)
+	}
+	var actual interface{} = o.V
+	var expected interface{} = "alice"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Then(...) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestThen_Rows(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	names := asynql.Then(db.QueryContext(ctx, `SELECT name FROM test_table ORDER BY id`), func(rs *asynql.Rows) ([]string, error) {
+		var names []string
+		for rs.Next() {
+			var name string
+			if err := rs.Scan(&name); err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		return names, rs.Err()
+	})
+	o := <-names
+	if err := o.Err(); err != nil {
+		t.Fatalf(`asynql.Then(...); Outcome.Err() => %#v; want nil`, err)
+	}
+	var actual interface{} = o.V
+	var expected interface{} = []string{"alice", "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Then(...) => %#v; want %#v`, actual, expected)
+	}
+
+	// The Rows have been closed, so the connection is free for another query.
+	var n int
+	if err := (<-db.QueryRowContext(ctx, `SELECT COUNT(*) FROM test_table`)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThen_Error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	called := false
+	o := <-asynql.Then(db.ExecContext(context.Background(), `DELETE FROM unknown_table`), func(r *asynql.Result) (int64, error) {
+		called = true
+		return 0, nil
+	})
+	if o.Err() == nil {
+		t.Errorf(`asynql.Then(...); Outcome.Err() => nil; want error`)
+	}
+	if called {
+		t.Errorf(`asynql.Then(...); fn was called; want not called`)
+	}
+}
+
+func TestCatch(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	failed := asynql.Then(db.QueryRowContext(ctx, `SELECT id FROM test_table WHERE name = ?`, "carol"), func(row *asynql.Row) (int64, error) {
+		var id int64
+		return id, row.Scan(&id)
+	})
+	var caught error
+	o := <-asynql.Catch(failed, func(err error) (int64, error) {
+		caught = err
+		return -1, nil
+	})
+	if err := o.Err(); err != nil {
+		t.Fatalf(`asynql.Catch(...); Outcome.Err() => %#v; want nil`, err)
+	}
+	var actual interface{} = o.V
+	var expected interface{} = int64(-1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.Catch(...) => %#v; want %#v`, actual, expected)
+	}
+	if caught == nil {
+		t.Errorf(`asynql.Catch(...); handler was called with nil; want error`)
+	}
+
+	errReplaced := errors.New("replaced")
+	o = <-asynql.Catch(asynql.Then(db.ExecContext(ctx, `DELETE FROM unknown_table`), func(r *asynql.Result) (int64, error) {
+		return r.RowsAffected()
+	}), func(err error) (int64, error) {
+		return 0, errReplaced
+	})
+	if err := o.Err(); !errors.Is(err, errReplaced) {
+		t.Errorf(`asynql.Catch(...); Outcome.Err() => %#v; want %#v`, err, errReplaced)
+	}
+}
@@ -0,0 +1,152 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultAutoTuneInterval is the default interval of AutoTune.
+const DefaultAutoTuneInterval = 10 * time.Second
+
+// DefaultAutoTuneWaitRatio is the default ratio of the wait for a connection to the query latency, above which AutoTune grows the pool.
+const DefaultAutoTuneWaitRatio = 0.1
+
+// ErrInvalidAutoTuneBounds is reported by AutoTune when the bounds of the pool size are invalid.
+var ErrInvalidAutoTuneBounds = errors.New("asynql: AutoTune needs 0 < MinOpenConns <= MaxOpenConns")
+
+// AutoTuneOptions configures AutoTune.
+type AutoTuneOptions struct {
+	// MinOpenConns and MaxOpenConns are the bounds of the maximum number of the open connections.
+	// MinOpenConns defaults to 1.
+	MinOpenConns int
+	MaxOpenConns int
+
+	// Interval is the interval of the adjustments. It defaults to DefaultAutoTuneInterval.
+	Interval time.Duration
+
+	// WaitRatio is the ratio of the mean wait for a connection per operation to the mean latency of the operations,
+	// above which the pool is grown. It defaults to DefaultAutoTuneWaitRatio.
+	WaitRatio float64
+}
+
+// Tuning represents an adjustment of the pool made by AutoTune.
+type Tuning struct {
+	// Time is the time of the adjustment.
+	Time time.Time
+
+	// From and To are the maximum numbers of the open connections before and after the adjustment.
+	// The maximum number of the idle connections is set to To as well.
+	From int
+	To   int
+
+	// WaitCount and WaitDuration are the waits for a connection in the last interval.
+	WaitCount    int64
+	WaitDuration time.Duration
+
+	// Latency is the mean latency of the asynchronous operations in the last interval.
+	Latency time.Duration
+
+	err error
+}
+
+// Err returns an error.
+func (t *Tuning) Err() error {
+	return t.err
+}
+
+// AutoTune adjusts SetMaxOpenConns and SetMaxIdleConns of the DB every interval within the bounds given by opts,
+// and sends the adjustments on the returned channel.
+// The pool is grown by a quarter when the operations spent a significant time waiting for a connection relative to their latency,
+// and it is shrunk by one connection when nothing waited and less than half of the connections were in use.
+// The pool starts at the current maximum clamped to the bounds, or at MaxOpenConns if it is unlimited.
+// If the bounds are invalid, a Tuning with ErrInvalidAutoTuneBounds is sent.
+// The channel is closed when ctx is done, and the pool keeps its last size.
+//
+// The adjustments must be received, or the controller stops until they are.
+func (db *DB) AutoTune(ctx context.Context, opts AutoTuneOptions) <-chan *Tuning {
+	ch := make(chan *Tuning)
+	go func() {
+		defer close(ch)
+		send := func(t *Tuning) bool {
+			select {
+			case ch <- t:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if opts.MinOpenConns == 0 {
+			opts.MinOpenConns = 1
+		}
+		if opts.MinOpenConns < 0 || opts.MaxOpenConns < opts.MinOpenConns {
+			send(&Tuning{err: ErrInvalidAutoTuneBounds})
+			return
+		}
+		if opts.Interval <= 0 {
+			opts.Interval = DefaultAutoTuneInterval
+		}
+		if opts.WaitRatio <= 0 {
+			opts.WaitRatio = DefaultAutoTuneWaitRatio
+		}
+		stats := db.Stats()
+		from := stats.MaxOpenConnections
+		to := from
+		if to <= 0 || to > opts.MaxOpenConns {
+			to = opts.MaxOpenConns
+		}
+		if to < opts.MinOpenConns {
+			to = opts.MinOpenConns
+		}
+		db.resize(to)
+		if to != from && !send(&Tuning{Time: time.Now(), From: from, To: to}) {
+			return
+		}
+		done, busy := db.ops.done.Load(), db.ops.busy.Load()
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			prev := stats
+			stats = db.Stats()
+			t := &Tuning{
+				From:         to,
+				To:           to,
+				WaitCount:    stats.WaitCount - prev.WaitCount,
+				WaitDuration: stats.WaitDuration - prev.WaitDuration,
+			}
+			n, d := db.ops.done.Load(), db.ops.busy.Load()
+			if n > done {
+				t.Latency = time.Duration((d - busy) / (n - done))
+			}
+			switch {
+			case t.WaitCount > 0 && n > done &&
+				float64(t.WaitDuration)/float64(n-done) > float64(t.Latency)*opts.WaitRatio:
+				t.To = min(to+(to+3)/4, opts.MaxOpenConns)
+			case t.WaitCount == 0 && stats.InUse*2 < to:
+				t.To = max(to-1, opts.MinOpenConns)
+			}
+			done, busy = n, d
+			if t.To == to {
+				continue
+			}
+			to = t.To
+			db.resize(to)
+			t.Time = time.Now()
+			if !send(t) {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// resize sets the maximum numbers of the open and idle connections to n.
+func (db *DB) resize(n int) {
+	db.SetMaxOpenConns(n)
+	db.SetMaxIdleConns(n)
+}
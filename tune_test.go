@@ -0,0 +1,79 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+// sleepConn is a driver.Conn whose Exec takes a while.
+type sleepConn struct {
+	d time.Duration
+}
+
+func (c sleepConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c sleepConn) Close() error                        { return nil }
+func (c sleepConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c sleepConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	time.Sleep(c.d)
+	return driver.RowsAffected(0), nil
+}
+
+func TestDB_AutoTune(t *testing.T) {
+	db := asynql.OpenDB(&notifyConnector{conn: sleepConn{d: 5 * time.Millisecond}})
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	load, stop := context.WithCancel(ctx)
+	go func() {
+		for load.Err() == nil {
+			results := make([]<-chan *asynql.Result, 8)
+			for i := range results {
+				results[i] = db.Exec("sleep")
+			}
+			asynql.WaitAll(results...)
+		}
+	}()
+	ch := db.AutoTune(ctx, asynql.AutoTuneOptions{MaxOpenConns: 4, Interval: 20 * time.Millisecond})
+	var sizes []int
+	for tuning := range ch {
+		if err := tuning.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if tuning.From >= tuning.To {
+			t.Fatalf(`db.AutoTune(...) => %#v under load; want growth`, tuning)
+		}
+		sizes = append(sizes, tuning.To)
+		if tuning.To == 4 {
+			break
+		}
+	}
+	var actual interface{} = sizes
+	var expected interface{} = []int{2, 3, 4}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.AutoTune(...) sizes under load => %#v; want %#v`, actual, expected)
+	}
+	if actual, expected := db.Stats().MaxOpenConnections, 4; actual != expected {
+		t.Errorf(`db.Stats().MaxOpenConnections => %#v; want %#v`, actual, expected)
+	}
+	stop()
+	if tuning := <-ch; tuning.From != 4 || tuning.To != 3 {
+		t.Errorf(`db.AutoTune(...) => %#v without load; want shrink from 4 to 3`, tuning)
+	}
+}
+
+func TestDB_AutoTune_InvalidBounds(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	tuning := <-db.AutoTune(context.Background(), asynql.AutoTuneOptions{MinOpenConns: 2, MaxOpenConns: 1})
+	if err := tuning.Err(); !errors.Is(err, asynql.ErrInvalidAutoTuneBounds) {
+		t.Errorf(`db.AutoTune(ctx, {2, 1}).Err() => %#v; want %#v`, err, asynql.ErrInvalidAutoTuneBounds)
+	}
+}
@@ -0,0 +1,167 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrTwoPCUnsupported is returned by BeginTwoPC when a DB has a dialect other than DialectPostgres and DialectMySQL.
+var ErrTwoPCUnsupported = errors.New("asynql: two-phase commit is not supported by the dialect")
+
+// TwoPC is a distributed transaction across several DBs that is committed by the two-phase commit protocol,
+// using PREPARE TRANSACTION of PostgreSQL or XA transactions of MySQL.
+// Each DB has a branch of the transaction on a dedicated connection, which is returned by Conn.
+//
+// If the coordinator crashes between the phases, the prepared branches remain in the databases
+// and must be resolved manually, e.g. by looking up pg_prepared_xacts or XA RECOVER for the global transaction ID.
+type TwoPC struct {
+	branches []*twoPCBranch
+}
+
+type twoPCBranch struct {
+	conn     *Conn
+	dialect  Dialect
+	xid      string
+	prepared bool
+}
+
+// BeginTwoPC starts a distributed transaction identified by gid across dbs.
+// The branch of the i-th DB is identified by gid followed by "-i", which must be unique among the in-doubt transactions of the server.
+// PostgreSQL needs max_prepared_transactions to be greater than zero.
+func BeginTwoPC(ctx context.Context, gid string, dbs ...*DB) (*TwoPC, error) {
+	t := &TwoPC{}
+	for i, db := range dbs {
+		d := db.Dialect()
+		if d != DialectPostgres && d != DialectMySQL {
+			t.close()
+			return nil, ErrTwoPCUnsupported
+		}
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.close()
+			return nil, err
+		}
+		b := &twoPCBranch{
+			conn:    conn,
+			dialect: d,
+			xid:     gid + "-" + strconv.Itoa(i),
+		}
+		t.branches = append(t.branches, b)
+		begin := "BEGIN"
+		if d == DialectMySQL {
+			begin = "XA START " + b.quotedXID()
+		}
+		if err := (<-conn.ExecContext(ctx, begin)).Err(); err != nil {
+			t.branches = t.branches[:i]
+			conn.Close()
+			t.Rollback(ctx)
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Conn returns the connection of the branch of the i-th DB given to BeginTwoPC.
+// The statements of the branch must be executed on it, and it must not be closed.
+func (t *TwoPC) Conn(i int) *Conn {
+	return t.branches[i].conn
+}
+
+// Commit prepares all the branches, and then commits them if all of them have been prepared.
+// If any of them fails to prepare, all the branches are rolled back and the error is returned.
+// Commit waits for the operations of the branches to finish before preparing.
+// The connections are released when Commit returns.
+//
+// ctx bounds only the prepare phase. Once all the branches are prepared, the decision is to commit,
+// so they are committed even if ctx is done meanwhile; likewise a failed prepare is rolled back regardless of ctx.
+func (t *TwoPC) Commit(ctx context.Context) error {
+	if err := t.each(func(b *twoPCBranch) error { return b.prepare(ctx) }); err != nil {
+		t.Rollback(context.WithoutCancel(ctx))
+		return err
+	}
+	defer t.close()
+	ctx = context.WithoutCancel(ctx)
+	return t.each(func(b *twoPCBranch) error { return b.commit(ctx) })
+}
+
+// Rollback rolls back all the branches, whether or not they have been prepared.
+// The connections are released when Rollback returns.
+func (t *TwoPC) Rollback(ctx context.Context) error {
+	defer t.close()
+	return t.each(func(b *twoPCBranch) error { return b.rollback(ctx) })
+}
+
+// each calls fn for each branch concurrently, and returns the errors joined.
+func (t *TwoPC) each(fn func(b *twoPCBranch) error) error {
+	errs := make([]error, len(t.branches))
+	var wg sync.WaitGroup
+	for i, b := range t.branches {
+		wg.Add(1)
+		go func(i int, b *twoPCBranch) {
+			defer wg.Done()
+			errs[i] = fn(b)
+		}(i, b)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (t *TwoPC) close() {
+	for _, b := range t.branches {
+		b.conn.Close()
+	}
+}
+
+func (b *twoPCBranch) quotedXID() string {
+	return "'" + strings.ReplaceAll(b.xid, "'", "''") + "'"
+}
+
+func (b *twoPCBranch) exec(ctx context.Context, queries ...string) error {
+	for _, query := range queries {
+		if err := (<-b.conn.ExecContext(ctx, query)).Err(); err != nil {
+			return fmt.Errorf("asynql: %s: %w", query, err)
+		}
+	}
+	return nil
+}
+
+func (b *twoPCBranch) prepare(ctx context.Context) error {
+	b.conn.wg.Wait()
+	var err error
+	if b.dialect == DialectMySQL {
+		err = b.exec(ctx, "XA END "+b.quotedXID(), "XA PREPARE "+b.quotedXID())
+	} else {
+		err = b.exec(ctx, "PREPARE TRANSACTION "+b.quotedXID())
+	}
+	if err == nil {
+		b.prepared = true
+	}
+	return err
+}
+
+func (b *twoPCBranch) commit(ctx context.Context) error {
+	if b.dialect == DialectMySQL {
+		return b.exec(ctx, "XA COMMIT "+b.quotedXID())
+	}
+	return b.exec(ctx, "COMMIT PREPARED "+b.quotedXID())
+}
+
+func (b *twoPCBranch) rollback(ctx context.Context) error {
+	b.conn.wg.Wait()
+	ctx = context.WithoutCancel(ctx)
+	switch {
+	case b.dialect == DialectMySQL && b.prepared:
+		return b.exec(ctx, "XA ROLLBACK "+b.quotedXID())
+	case b.dialect == DialectMySQL:
+		// XA END fails if the branch has already ended, in which case XA ROLLBACK still works.
+		b.exec(ctx, "XA END "+b.quotedXID())
+		return b.exec(ctx, "XA ROLLBACK "+b.quotedXID())
+	case b.prepared:
+		return b.exec(ctx, "ROLLBACK PREPARED "+b.quotedXID())
+	}
+	return b.exec(ctx, "ROLLBACK")
+}
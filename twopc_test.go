@@ -0,0 +1,147 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// scriptConn is a driver.Conn that records the executed statements, and fails those in fail.
+// hook is called with each statement after it is recorded, if not nil.
+type scriptConn struct {
+	mu      sync.Mutex
+	queries []string
+	fail    map[string]error
+	hook    func(query string)
+}
+
+func (c *scriptConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *scriptConn) Close() error                        { return nil }
+func (c *scriptConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *scriptConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries = append(c.queries, query)
+	if c.hook != nil {
+		c.hook(query)
+	}
+	if err := c.fail[query]; err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestTwoPC(t *testing.T) {
+	for _, v := range []struct {
+		dialect  asynql.Dialect
+		expected []string
+	}{
+		{asynql.DialectPostgres, []string{"BEGIN", "UPDATE t SET x = 1", "PREPARE TRANSACTION 'tx-0'", "COMMIT PREPARED 'tx-0'"}},
+		{asynql.DialectMySQL, []string{"XA START 'tx-0'", "UPDATE t SET x = 1", "XA END 'tx-0'", "XA PREPARE 'tx-0'", "XA COMMIT 'tx-0'"}},
+	} {
+		c1, c2 := &scriptConn{}, &scriptConn{}
+		db1 := asynql.OpenDB(&notifyConnector{conn: c1}, asynql.WithDialect(v.dialect))
+		db2 := asynql.OpenDB(&notifyConnector{conn: c2}, asynql.WithDialect(v.dialect))
+		ctx := context.Background()
+		tp, err := asynql.BeginTwoPC(ctx, "tx", db1, db2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := asynql.WaitAll(
+			tp.Conn(0).ExecContext(ctx, "UPDATE t SET x = 1"),
+			tp.Conn(1).ExecContext(ctx, "UPDATE t SET x = 1"),
+		); err != nil {
+			t.Fatal(err)
+		}
+		if err := tp.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = c1.queries
+		var expected interface{} = v.expected
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`%v: executed statements => %#v; want %#v`, v.dialect, actual, expected)
+		}
+		db1.Close()
+		db2.Close()
+	}
+}
+
+func TestTwoPC_PrepareFailed(t *testing.T) {
+	prepareErr := errors.New("prepare failed")
+	c1 := &scriptConn{}
+	c2 := &scriptConn{fail: map[string]error{"PREPARE TRANSACTION 'tx-1'": prepareErr}}
+	db1 := asynql.OpenDB(&notifyConnector{conn: c1}, asynql.WithDialect(asynql.DialectPostgres))
+	defer db1.Close()
+	db2 := asynql.OpenDB(&notifyConnector{conn: c2}, asynql.WithDialect(asynql.DialectPostgres))
+	defer db2.Close()
+	ctx := context.Background()
+	tp, err := asynql.BeginTwoPC(ctx, "tx", db1, db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tp.Commit(ctx); !errors.Is(err, prepareErr) {
+		t.Errorf(`tp.Commit(ctx) => %#v; want %#v`, err, prepareErr)
+	}
+	var actual interface{} = [][]string{c1.queries, c2.queries}
+	var expected interface{} = [][]string{
+		{"BEGIN", "PREPARE TRANSACTION 'tx-0'", "ROLLBACK PREPARED 'tx-0'"},
+		{"BEGIN", "PREPARE TRANSACTION 'tx-1'", "ROLLBACK"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`executed statements => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestTwoPC_CanceledAfterPrepare(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	prepared := 0
+	hook := func(query string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(query, "PREPARE TRANSACTION") {
+			if prepared++; prepared == 2 {
+				cancel()
+			}
+		}
+	}
+	c1, c2 := &scriptConn{hook: hook}, &scriptConn{hook: hook}
+	db1 := asynql.OpenDB(&notifyConnector{conn: c1}, asynql.WithDialect(asynql.DialectPostgres))
+	defer db1.Close()
+	db2 := asynql.OpenDB(&notifyConnector{conn: c2}, asynql.WithDialect(asynql.DialectPostgres))
+	defer db2.Close()
+	tp, err := asynql.BeginTwoPC(ctx, "tx", db1, db2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tp.Commit(ctx); err != nil {
+		t.Errorf(`tp.Commit(ctx) canceled after prepare => %#v; want nil`, err)
+	}
+	var actual interface{} = [][]string{c1.queries, c2.queries}
+	var expected interface{} = [][]string{
+		{"BEGIN", "PREPARE TRANSACTION 'tx-0'", "COMMIT PREPARED 'tx-0'"},
+		{"BEGIN", "PREPARE TRANSACTION 'tx-1'", "COMMIT PREPARED 'tx-1'"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`executed statements => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestBeginTwoPC_Unsupported(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	_, err := asynql.BeginTwoPC(context.Background(), "tx", db)
+	var actual interface{} = err
+	var expected interface{} = asynql.ErrTwoPCUnsupported
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.BeginTwoPC(ctx, "tx", db) => %#v; want %#v`, actual, expected)
+	}
+}
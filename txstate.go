@@ -0,0 +1,57 @@
+package asynql
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRolledBack is reported by Tx.Err when the transaction has been rolled back.
+var ErrRolledBack = errors.New("asynql: transaction has been rolled back")
+
+// Done returns a channel that is closed when the transaction has ended by Commit or Rollback,
+// so that the goroutines that have received the results of the operations of tx can wait for its fate.
+// The channel is closed after Commit or Rollback has finished, not when it is called.
+func (tx *Tx) Done() <-chan struct{} {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done == nil {
+		tx.done = make(chan struct{})
+		if tx.ended {
+			close(tx.done)
+		}
+	}
+	return tx.done
+}
+
+// Err returns nil if the transaction has been committed or has not ended yet.
+// Otherwise, it returns ErrRolledBack if the transaction has been rolled back, including by Commit in dry run mode,
+// or the error of Commit or Rollback if it has failed.
+// Use Done to tell whether the transaction has ended.
+//
+// Note that a transaction that is rolled back by database/sql when its context is done is not reported until Commit or Rollback is called.
+func (tx *Tx) Err() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.outcome
+}
+
+// end records that the transaction has ended by op, which is HookCommit or HookRollback, with err, which is the error of op,
+// and reports it to the hooks.
+func (tx *Tx) end(op HookOp, err error) {
+	outcome := err
+	if err == nil && (op == HookRollback || dryRunOf(tx.db)) {
+		outcome = ErrRolledBack
+	}
+	tx.mu.Lock()
+	tx.ended = true
+	tx.outcome = outcome
+	tx.stats.Ended = time.Now()
+	if tx.warn != nil {
+		tx.warn.Stop()
+	}
+	if tx.done != nil {
+		close(tx.done)
+	}
+	tx.mu.Unlock()
+	tx.report(op, err)
+}
@@ -0,0 +1,69 @@
+package asynql_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestTx_Err(t *testing.T) {
+	for _, v := range []struct {
+		name     string
+		opts     []asynql.Option
+		finish   func(tx *asynql.Tx) error
+		expected error
+	}{
+		{"commit", nil, (*asynql.Tx).Commit, nil},
+		{"rollback", nil, (*asynql.Tx).Rollback, asynql.ErrRolledBack},
+		{"dry run", []asynql.Option{asynql.WithDryRun()}, (*asynql.Tx).Commit, asynql.ErrRolledBack},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			db := newTestDB(t, v.opts...)
+			defer db.Close()
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			done := tx.Done()
+			select {
+			case <-done:
+				t.Fatal(`tx.Done() is closed before the end of the transaction`)
+			default:
+			}
+			if err := tx.Err(); err != nil {
+				t.Errorf(`tx.Err() before the end => %#v; want nil`, err)
+			}
+			fate := make(chan error)
+			go func() {
+				<-done
+				fate <- tx.Err()
+			}()
+			if err := (<-tx.Exec(`UPDATE test_table SET name = ? WHERE id = ?`, "carol", 1)).Err(); err != nil {
+				t.Fatal(err)
+			}
+			if err := v.finish(tx); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case err := <-fate:
+				if !errors.Is(err, v.expected) {
+					t.Errorf(`tx.Err() after tx.Done() => %#v; want %#v`, err, v.expected)
+				}
+			case <-time.After(time.Second):
+				t.Fatal(`tx.Done() is not closed`)
+			}
+			select {
+			case <-tx.Done():
+			default:
+				t.Errorf(`tx.Done() after the end is not closed`)
+			}
+			// A second Commit or Rollback doesn't change the fate of the transaction.
+			tx.Rollback()
+			if err := tx.Err(); !errors.Is(err, v.expected) {
+				t.Errorf(`tx.Err() after a second tx.Rollback() => %#v; want %#v`, err, v.expected)
+			}
+		})
+	}
+}
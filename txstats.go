@@ -0,0 +1,103 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// MaxTxStatements is the maximum number of the queries that TxStats.Statements records.
+const MaxTxStatements = 1000
+
+// TxStats is the statistics of a transaction, for finding the transactions that are held long enough to block others,
+// e.g. by holding locks or by keeping VACUUM from removing dead rows.
+type TxStats struct {
+	// Began is the time when the transaction began, and Ended is the time when it ended, or zero if it has not ended.
+	Began time.Time
+	Ended time.Time
+
+	// Ops is the number of the asynchronous operations run in the transaction, and OpDuration is their total duration.
+	Ops        int64
+	OpDuration time.Duration
+
+	// Statements is the queries of the operations in the order they were run, up to MaxTxStatements.
+	Statements []string
+}
+
+// Held returns the time from the beginning to the end of the transaction, or until now if it has not ended.
+func (s *TxStats) Held() time.Duration {
+	if s.Ended.IsZero() {
+		return time.Since(s.Began)
+	}
+	return s.Ended.Sub(s.Began)
+}
+
+// newTx returns a *Tx of tx, which has begun on db with ctx.
+func newTx(ctx context.Context, db *DB, tx *sql.Tx) *Tx {
+	t := &Tx{
+		Tx:  tx,
+		db:  db,
+		ctx: ctx,
+		stats: TxStats{
+			Began: time.Now(),
+		},
+	}
+	t.watchLong()
+	return t
+}
+
+// Stats returns the statistics of the transaction.
+// It can be called while the transaction is running, and also after it has ended.
+// The end of the transaction is also reported to the hooks by a HookEvent of HookCommit or HookRollback with the statistics.
+func (tx *Tx) Stats() *TxStats {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	s := tx.stats
+	s.Statements = append([]string(nil), s.Statements...)
+	return &s
+}
+
+// txOp returns a function that runs fn, which is an operation of query, and records it to the statistics of tx if tx is not nil.
+func txOp[T any](tx *Tx, query string, fn func() T) func() T {
+	if tx == nil {
+		return fn
+	}
+	return func() T {
+		start := time.Now()
+		v := fn()
+		d := time.Since(start)
+		tx.mu.Lock()
+		tx.stats.Ops++
+		tx.stats.OpDuration += d
+		if len(tx.stats.Statements) < MaxTxStatements {
+			tx.stats.Statements = append(tx.stats.Statements, query)
+		}
+		tx.mu.Unlock()
+		return v
+	}
+}
+
+// report reports the end of the transaction by op with err, which is the error of op, to the hooks.
+func (tx *Tx) report(op HookOp, err error) {
+	if tx.db == nil || len(tx.db.hooks) == 0 {
+		return
+	}
+	ctx := tx.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	stats := tx.Stats()
+	e := &HookEvent{
+		Op:       op,
+		Name:     queryNameOf(ctx),
+		Tags:     tagsOf(ctx),
+		Labels:   labelsOf(ctx),
+		DryRun:   tx.db.dryRun,
+		Duration: stats.Held(),
+		Err:      err,
+		Tx:       stats,
+	}
+	for _, hook := range tx.db.hooks {
+		hook(ctx, e)
+	}
+}
@@ -0,0 +1,87 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestTx_Stats(t *testing.T) {
+	for _, v := range []struct {
+		finish func(tx *asynql.Tx) error
+		op     asynql.HookOp
+	}{
+		{(*asynql.Tx).Commit, asynql.HookCommit},
+		{(*asynql.Tx).Rollback, asynql.HookRollback},
+	} {
+		var rec hookRecorder
+		db := newTestDB(t, asynql.WithHook(rec.hook))
+		defer db.Close()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmt, err := tx.Prepare(`SELECT name FROM test_table WHERE id = ?`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := (<-tx.Exec(`UPDATE test_table SET name = ? WHERE id = ?`, "carol", 1)).Err(); err != nil {
+			t.Fatal(err)
+		}
+		scanNames(t, <-tx.Query(`SELECT name FROM test_table`))
+		var name string
+		if err := (<-stmt.QueryRow(2)).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		if s := tx.Stats(); !s.Ended.IsZero() || s.Ops != 3 {
+			t.Errorf(`tx.Stats() before the end => Ended %v, Ops %v; want zero, 3`, s.Ended, s.Ops)
+		}
+		if err := v.finish(tx); err != nil {
+			t.Fatal(err)
+		}
+		s := tx.Stats()
+		var actual interface{} = []interface{}{s.Ops, s.Statements}
+		var expected interface{} = []interface{}{int64(3), []string{
+			`UPDATE test_table SET name = ? WHERE id = ?`,
+			`SELECT name FROM test_table`,
+			`SELECT name FROM test_table WHERE id = ?`,
+		}}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`tx.Stats() => %#v; want %#v`, actual, expected)
+		}
+		if s.Ended.Before(s.Began) || s.OpDuration <= 0 || s.OpDuration > s.Held() {
+			t.Errorf(`tx.Stats() => Began %v, Ended %v, OpDuration %v; want Began <= Ended and 0 < OpDuration <= Held`, s.Began, s.Ended, s.OpDuration)
+		}
+		e := rec.events[len(rec.events)-1]
+		actual = []interface{}{e.Op, e.Query, e.Err, e.Duration, e.Tx}
+		expected = []interface{}{v.op, "", nil, s.Held(), s}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`hook event of the end => %#v; want %#v`, actual, expected)
+		}
+	}
+}
+
+func TestTx_Stats_Context(t *testing.T) {
+	var rec hookRecorder
+	db := newTestDB(t, asynql.WithHook(rec.hook))
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	tx, err := conn.BeginTx(asynql.WithLabel(context.Background(), "job", "report"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = rec.events[len(rec.events)-1].Labels
+	var expected interface{} = map[string]string{"job": "report"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`labels of the hook event of the end => %#v; want %#v`, actual, expected)
+	}
+}
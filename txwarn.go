@@ -0,0 +1,56 @@
+package asynql
+
+import (
+	"context"
+	"time"
+)
+
+// txWarning is the configuration of SetTxWarnThreshold.
+type txWarning struct {
+	threshold time.Duration
+	fn        func(ctx context.Context, s *TxStats)
+}
+
+// SetTxWarnThreshold sets fn to be called when a transaction of the DB stays open longer than d without being committed or rolled back,
+// with the context that the transaction has begun with and its statistics, including the statements it has run so far,
+// so that a transaction that is held accidentally, e.g. by an operation whose result is never received, is noticed before it blocks others.
+// fn is called in its own goroutine at most once for each transaction.
+// It applies to the transactions that begin after it is called, including those of the facades returned by ReadOnly.
+// A non-positive d or a nil fn turns the warning off.
+func (db *DB) SetTxWarnThreshold(d time.Duration, fn func(ctx context.Context, s *TxStats)) {
+	if d <= 0 || fn == nil {
+		db.txWarning.Store(nil)
+		return
+	}
+	db.txWarning.Store(&txWarning{
+		threshold: d,
+		fn:        fn,
+	})
+}
+
+// watchLong starts the timer of SetTxWarnThreshold for tx.
+func (tx *Tx) watchLong() {
+	db := tx.db
+	if db == nil {
+		return
+	}
+	if db.primary != nil {
+		db = db.primary
+	}
+	w := db.txWarning.Load()
+	if w == nil {
+		return
+	}
+	ctx := tx.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tx.warn = time.AfterFunc(w.threshold, func() {
+		tx.mu.Lock()
+		ended := tx.ended
+		tx.mu.Unlock()
+		if !ended {
+			w.fn(ctx, tx.Stats())
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_SetTxWarnThreshold(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	warnings := make(chan *asynql.TxStats, 10)
+	db.SetTxWarnThreshold(20*time.Millisecond, func(ctx context.Context, s *asynql.TxStats) {
+		warnings <- s
+	})
+	quick, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := quick.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	query := `UPDATE test_table SET name = ? WHERE id = ?`
+	if err := (<-tx.Exec(query, "carol", 1)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case s := <-warnings:
+		var actual interface{} = []interface{}{s.Ended.IsZero(), s.Statements}
+		var expected interface{} = []interface{}{true, []string{query}}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`warning => %#v; want %#v`, actual, expected)
+		}
+		if s.Held() < 20*time.Millisecond {
+			t.Errorf(`warning.Held() => %v; want >= %v`, s.Held(), 20*time.Millisecond)
+		}
+	case <-time.After(time.Second):
+		t.Fatal(`long transaction is not warned`)
+	}
+	select {
+	case s := <-warnings:
+		t.Errorf(`another warning => %#v; want none`, s)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	db.SetTxWarnThreshold(0, nil)
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	off, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer off.Rollback()
+	select {
+	case s := <-warnings:
+		t.Errorf(`warning after turning it off => %#v; want none`, s)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
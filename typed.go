@@ -0,0 +1,141 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RowOf represents a row of QueryOf and QueryRowOf, scanned into a T.
+// The names Row and Result are taken by the untyped payloads, so the typed ones are RowOf and ResultOf.
+type RowOf[T any] struct {
+	// V is the scanned row.
+	V T
+
+	err error
+}
+
+// Err returns an error.
+func (r *RowOf[T]) Err() error {
+	return r.err
+}
+
+// ResultOf represents a result of ExecReturning.
+type ResultOf[T any] struct {
+	// Values is the values returned by the RETURNING clause, one for each affected row.
+	Values []T
+
+	err error
+}
+
+// Err returns an error.
+func (r *ResultOf[T]) Err() error {
+	return r.err
+}
+
+// RowsAffected returns the number of the rows affected, which is the number of the returned values.
+func (r *ResultOf[T]) RowsAffected() int64 {
+	return int64(len(r.Values))
+}
+
+// QueryOf executes a query and then sends each row on the returned channel, scanned into a T by ScanStruct if T is a struct,
+// or by Scan otherwise, so that T must match the columns of the query.
+// If the query or scanning a row fails, a RowOf with the error is sent.
+// The channel is closed after the last row or the error, or when ctx is done.
+func QueryOf[T any](ctx context.Context, q Queryer, query string, args ...interface{}) <-chan *RowOf[T] {
+	ch := make(chan *RowOf[T])
+	go func() {
+		defer close(ch)
+		send := func(r *RowOf[T]) bool {
+			select {
+			case ch <- r:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		rs := <-q.QueryContext(ctx, query, args...)
+		if err := rs.Err(); err != nil {
+			send(&RowOf[T]{err: err})
+			return
+		}
+		defer rs.Close()
+		for rs.Next() {
+			r := &RowOf[T]{}
+			r.err = scanValue(rs, &r.V)
+			if !send(r) || r.err != nil {
+				return
+			}
+		}
+		if err := rs.Err(); err != nil {
+			send(&RowOf[T]{err: err})
+		}
+	}()
+	return ch
+}
+
+// QueryRowOf executes a query that is expected to return at most one row, and then sends the row scanned into a T on the returned channel.
+// If the query returns no rows, a RowOf with sql.ErrNoRows is sent.
+func QueryRowOf[T any](ctx context.Context, q Queryer, query string, args ...interface{}) <-chan *RowOf[T] {
+	ch := make(chan *RowOf[T])
+	go func() {
+		r := &RowOf[T]{}
+		r.err = queryFirst(<-q.QueryContext(ctx, query, args...), &r.V)
+		deliver(q, ch, r)
+	}()
+	return ch
+}
+
+// ExecReturning executes a statement with a RETURNING clause, such as INSERT ... RETURNING id,
+// and then sends the returned values scanned into Ts on the returned channel.
+func ExecReturning[T any](ctx context.Context, q Queryer, query string, args ...interface{}) <-chan *ResultOf[T] {
+	ch := make(chan *ResultOf[T])
+	go func() {
+		values, err := materializeValues[T](<-q.QueryContext(ctx, query, args...))
+		deliver(q, ch, &ResultOf[T]{
+			Values: values,
+			err:    err,
+		})
+	}()
+	return ch
+}
+
+func queryFirst(rs *Rows, dest interface{}) error {
+	if err := rs.Err(); err != nil {
+		return err
+	}
+	defer rs.Close()
+	if !rs.Next() {
+		if err := rs.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := scanValue(rs, dest); err != nil {
+		return err
+	}
+	return rs.Close()
+}
+
+func materializeValues[T any](rs *Rows) ([]T, error) {
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	var values []T
+	for rs.Next() {
+		var v T
+		if err := scanValue(rs, &v); err != nil {
+			return nil, err
+		}
+		if rs.limited() {
+			if err := rs.account(sizeOf(v)); err != nil {
+				return nil, err
+			}
+		}
+		values = append(values, v)
+	}
+	if err := rs.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
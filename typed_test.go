@@ -0,0 +1,86 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestQueryOf(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	type user struct {
+		ID   int64
+		Name string `db:"name"`
+	}
+	query := `SELECT id, name FROM test_table ORDER BY id`
+	var users []user
+	for r := range asynql.QueryOf[user](context.Background(), db, query) {
+		if err := r.Err(); err != nil {
+			t.Fatalf(`asynql.QueryOf(ctx, db, %#v); RowOf.Err() => %#v; want nil`, query, err)
+		}
+		users = append(users, r.V)
+	}
+	var actual interface{} = users
+	var expected interface{} = []user{{1, "alice"}, {2, "bob"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.QueryOf(ctx, db, %#v) => %#v; want %#v`, query, actual, expected)
+	}
+}
+
+func TestQueryOf_Error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT id FROM unknown_table`
+	var errs []error
+	for r := range asynql.QueryOf[int64](context.Background(), db, query) {
+		errs = append(errs, r.Err())
+	}
+	if len(errs) != 1 || errs[0] == nil {
+		t.Errorf(`asynql.QueryOf(ctx, db, %#v); errors => %#v; want one error`, query, errs)
+	}
+}
+
+func TestQueryRowOf(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT name FROM test_table WHERE id = ?`
+	r := <-asynql.QueryRowOf[string](context.Background(), db, query, 2)
+	if err := r.Err(); err != nil {
+		t.Fatalf(`asynql.QueryRowOf(ctx, db, %#v, 2); RowOf.Err() => %#v; want nil`, query, err)
+	}
+	var actual interface{} = r.V
+	var expected interface{} = "bob"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.QueryRowOf(ctx, db, %#v, 2) => %#v; want %#v`, query, actual, expected)
+	}
+
+	r = <-asynql.QueryRowOf[string](context.Background(), db, query, 3)
+	if err := r.Err(); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf(`asynql.QueryRowOf(ctx, db, %#v, 3); RowOf.Err() => %#v; want %#v`, query, err, sql.ErrNoRows)
+	}
+}
+
+func TestExecReturning(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `INSERT INTO test_table (id, name) VALUES (3, 'carol'), (4, 'dave') RETURNING id`
+	r := <-asynql.ExecReturning[int64](context.Background(), db, query)
+	if err := r.Err(); err != nil {
+		t.Fatalf(`asynql.ExecReturning(ctx, db, %#v); ResultOf.Err() => %#v; want nil`, query, err)
+	}
+	var actual interface{} = r.Values
+	var expected interface{} = []int64{3, 4}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.ExecReturning(ctx, db, %#v) => %#v; want %#v`, query, actual, expected)
+	}
+	actual = r.RowsAffected()
+	expected = int64(2)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.ExecReturning(ctx, db, %#v); RowsAffected() => %#v; want %#v`, query, actual, expected)
+	}
+}
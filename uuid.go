@@ -0,0 +1,133 @@
+package asynql
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// UUIDValue is a sql.Scanner and driver.Valuer of a UUID, which is returned by UUID and BinaryUUID.
+type UUIDValue struct {
+	v      reflect.Value
+	binary bool
+}
+
+// UUID returns a UUIDValue of u, which is a UUID of any library whose type is a [16]byte such as uuid.UUID of github.com/google/uuid,
+// or a string in the canonical form, or a pointer to them.
+// It binds u as a string in the canonical form, and scans a uuid column of PostgreSQL, a string of any form,
+// or the 16 bytes of a binary(16) column into u, which must be a pointer to scan.
+// A NULL is scanned as the zero value.
+//
+// ScanStruct scans the columns into the fields of the types of [16]byte that don't implement sql.Scanner by themselves
+// in the same way without UUID, and into the string fields tagged with `db:"name,uuid"` as well.
+func UUID(u interface{}) *UUIDValue {
+	return &UUIDValue{
+		v: reflect.ValueOf(u),
+	}
+}
+
+// BinaryUUID is the same as UUID, but binds u as 16 bytes for a binary(16) column of MySQL.
+func BinaryUUID(u interface{}) *UUIDValue {
+	return &UUIDValue{
+		v:      reflect.ValueOf(u),
+		binary: true,
+	}
+}
+
+// Scan implements sql.Scanner.
+func (u *UUIDValue) Scan(src interface{}) error {
+	if u.v.Kind() != reflect.Pointer || u.v.IsNil() || !isUUIDType(u.v.Type().Elem()) && u.v.Type().Elem().Kind() != reflect.String {
+		return fmt.Errorf("asynql: cannot scan a UUID into %v", u.v.Type())
+	}
+	v := u.v.Elem()
+	var b [16]byte
+	switch src := src.(type) {
+	case nil:
+		v.SetZero()
+		return nil
+	case []byte:
+		if len(src) == 16 {
+			copy(b[:], src)
+			break
+		}
+		var err error
+		if b, err = parseUUID(string(src)); err != nil {
+			return err
+		}
+	case string:
+		var err error
+		if b, err = parseUUID(src); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("asynql: cannot scan %T into %v as a UUID", src, v.Type())
+	}
+	if v.Kind() == reflect.String {
+		v.SetString(formatUUID(b))
+		return nil
+	}
+	reflect.Copy(v, reflect.ValueOf(b))
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (u *UUIDValue) Value() (driver.Value, error) {
+	v := u.v
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	var b [16]byte
+	switch {
+	case isUUIDType(v.Type()):
+		reflect.Copy(reflect.ValueOf(&b).Elem(), v)
+	case v.Kind() == reflect.String:
+		var err error
+		if b, err = parseUUID(v.String()); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("asynql: cannot bind %v as a UUID", u.v.Type())
+	}
+	if u.binary {
+		return b[:], nil
+	}
+	return formatUUID(b), nil
+}
+
+// isUUIDType reports whether t is a type of [16]byte.
+func isUUIDType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// parseUUID parses a UUID in the canonical form, with or without the hyphens, braces or the urn:uuid: prefix.
+func parseUUID(s string) ([16]byte, error) {
+	var b [16]byte
+	t := strings.TrimPrefix(strings.ToLower(s), "urn:uuid:")
+	if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+		t = t[1 : len(t)-1]
+	}
+	if len(t) == 36 {
+		if t[8] != '-' || t[13] != '-' || t[18] != '-' || t[23] != '-' {
+			return b, fmt.Errorf("asynql: invalid UUID %q", s)
+		}
+		t = t[:8] + t[9:13] + t[14:18] + t[19:23] + t[24:]
+	}
+	if len(t) != 32 {
+		return b, fmt.Errorf("asynql: invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(b[:], []byte(t)); err != nil {
+		return b, fmt.Errorf("asynql: invalid UUID %q", s)
+	}
+	return b, nil
+}
+
+// formatUUID returns b in the canonical form.
+func formatUUID(b [16]byte) string {
+	s := hex.EncodeToString(b[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
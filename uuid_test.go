@@ -0,0 +1,86 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+// testUUID mimics the UUID types of the UUID libraries.
+type testUUID [16]byte
+
+var exampleUUID = testUUID{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+func TestUUID_Scan(t *testing.T) {
+	for _, src := range []interface{}{
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		[]byte("6BA7B810-9DAD-11D1-80B4-00C04FD430C8"),
+		"{6ba7b810-9dad-11d1-80b4-00c04fd430c8}",
+		"urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b8109dad11d180b400c04fd430c8",
+		exampleUUID[:],
+	} {
+		var u testUUID
+		var s string
+		for _, dest := range []interface{}{&u, &s} {
+			if err := asynql.UUID(dest).Scan(src); err != nil {
+				t.Errorf(`asynql.UUID(%T).Scan(%#v) => %#v; want nil`, dest, src, err)
+			}
+		}
+		var actual interface{} = []interface{}{u, s}
+		var expected interface{} = []interface{}{exampleUUID, "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`asynql.UUID(dest).Scan(%#v) => %#v; want %#v`, src, actual, expected)
+		}
+	}
+
+	u := exampleUUID
+	if err := asynql.UUID(&u).Scan(nil); err != nil || u != (testUUID{}) {
+		t.Errorf(`asynql.UUID(&u).Scan(nil) => %#v, %#v; want zero, nil`, u, err)
+	}
+	for _, src := range []interface{}{"6ba7b810-9dad-11d1-80b4", "6ba7b810x9dad-11d1-80b4-00c04fd430c8", "zba7b8109dad11d180b400c04fd430c8", 1} {
+		if err := asynql.UUID(&u).Scan(src); err == nil {
+			t.Errorf(`asynql.UUID(&u).Scan(%#v) => nil; want error`, src)
+		}
+	}
+}
+
+func TestUUID_ScanStruct(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `CREATE TABLE items (id BLOB, ref TEXT, ext TEXT)`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (<-db.ExecContext(ctx, `INSERT INTO items VALUES (?, ?, ?), (NULL, NULL, 'x')`,
+		asynql.BinaryUUID(exampleUUID), asynql.UUID(&exampleUUID), asynql.UUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8"))).Err(); err != nil {
+		t.Fatal(err)
+	}
+	type item struct {
+		ID  testUUID
+		Ref string `db:"ref,uuid"`
+		Ext string
+	}
+	rows := <-db.QueryContext(ctx, `SELECT id, ref, ext FROM items ORDER BY ext`)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var actual []item
+	for rows.Next() {
+		var it item
+		if err := rows.ScanStruct(&it); err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, it)
+	}
+	expected := []item{
+		{exampleUUID, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{testUUID{}, "", "x"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`rows.ScanStruct(&item{}) => %#v; want %#v`, actual, expected)
+	}
+}
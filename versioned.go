@@ -0,0 +1,68 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrStaleVersion is reported by UpdateVersioned when the row doesn't have the expected version,
+// because it has been updated or deleted by another one since it was read.
+var ErrStaleVersion = errors.New("asynql: row has been updated by another one")
+
+// UpdateVersioned updates the columns of the row of table that match where to the values of set, only if the version column of the row is expectedVersion,
+// and increments the version, and then sends the result on the returned channel.
+// It is a building block of optimistic concurrency control:
+//
+//	r := <-db.UpdateVersioned(ctx, "users", map[string]interface{}{"name": "carol"}, map[string]interface{}{"id": 1}, "version", 3)
+//	if errors.Is(r.Err(), asynql.ErrStaleVersion) {
+//		// Read the row again, and retry.
+//	}
+//
+// The columns of set and where are compared for equality, joined by AND, in the order of their names.
+// If no rows are updated, a Result with ErrStaleVersion is sent.
+// If where is empty, a Result with ErrNoWhere is sent without updating, since the UPDATE would update all the rows of the version.
+// Only the values are bound as arguments: table, versionCol and the keys of set and where are written into the UPDATE as SQL.
+func (db *DB) UpdateVersioned(ctx context.Context, table string, set, where map[string]interface{}, versionCol string, expectedVersion int64) <-chan *Result {
+	ch := make(chan *Result)
+	go func() {
+		if len(where) == 0 {
+			deliver(db, ch, &Result{err: ErrNoWhere})
+			return
+		}
+		var b strings.Builder
+		args := make([]interface{}, 0, len(set)+len(where)+1)
+		b.WriteString("UPDATE " + table + " SET ")
+		for _, col := range sortedKeys(set) {
+			b.WriteString(col + " = ?, ")
+			args = append(args, set[col])
+		}
+		b.WriteString(versionCol + " = " + versionCol + " + 1 WHERE ")
+		for _, col := range sortedKeys(where) {
+			b.WriteString(col + " = ? AND ")
+			args = append(args, where[col])
+		}
+		b.WriteString(versionCol + " = ?")
+		args = append(args, expectedVersion)
+		r := <-db.ExecContext(ctx, db.Dialect().Rebind(b.String()), args...)
+		if r.err == nil {
+			if n, err := r.RowsAffected(); err != nil {
+				r.err = err
+			} else if n == 0 {
+				r.err = ErrStaleVersion
+			}
+		}
+		deliver(db, ch, r)
+	}()
+	return ch
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,73 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_UpdateVersioned(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `ALTER TABLE test_table ADD COLUMN version INTEGER NOT NULL DEFAULT 1`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]interface{}{"name": "carol"}
+	where := map[string]interface{}{"id": 1}
+	r := <-db.UpdateVersioned(ctx, "test_table", set, where, "version", 1)
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	var version int64
+	if err := (<-db.QueryRowContext(ctx, `SELECT name, version FROM test_table WHERE id = 1`)).Scan(&name, &version); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = []interface{}{name, version}
+	var expected interface{} = []interface{}{"carol", int64(2)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`row after db.UpdateVersioned(ctx, "test_table", %#v, %#v, "version", 1) => %#v; want %#v`, set, where, actual, expected)
+	}
+
+	set = map[string]interface{}{"name": "dave"}
+	actual = (<-db.UpdateVersioned(ctx, "test_table", set, where, "version", 1)).Err()
+	expected = asynql.ErrStaleVersion
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.UpdateVersioned(ctx, "test_table", %#v, %#v, "version", 1) => %#v; want %#v`, set, where, actual, expected)
+	}
+	if err := (<-db.QueryRowContext(ctx, `SELECT name FROM test_table WHERE id = 1`)).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	actual = name
+	expected = "carol"
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`name after the stale update => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestDB_UpdateVersioned_NoWhere(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	if err := (<-db.ExecContext(ctx, `ALTER TABLE test_table ADD COLUMN version INTEGER NOT NULL DEFAULT 1`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]interface{}{"name": "carol"}
+	for _, where := range []map[string]interface{}{nil, {}} {
+		var actual interface{} = (<-db.UpdateVersioned(ctx, "test_table", set, where, "version", 1)).Err()
+		var expected interface{} = asynql.ErrNoWhere
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`db.UpdateVersioned(ctx, "test_table", %#v, %#v, "version", 1) => %#v; want %#v`, set, where, actual, expected)
+		}
+	}
+	var n int
+	if err := (<-db.QueryRowContext(ctx, `SELECT COUNT(*) FROM test_table WHERE name = 'carol' OR version <> 1`)).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf(`%d rows are updated by db.UpdateVersioned with no where; want 0`, n)
+	}
+}
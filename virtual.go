@@ -0,0 +1,120 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// rowSource produces the rows of a result set that is generated in-process rather than by a database.
+type rowSource interface {
+	// Columns returns the names of the columns.
+	Columns() []string
+
+	// Next populates dest with the values of the next row.
+	// It returns io.EOF when there are no more rows.
+	Next(dest []driver.Value) error
+
+	// Close releases the resources of the source.
+	Close() error
+}
+
+// virtualDB is used to expose a rowSource as *sql.Rows so that it gets the same scanning semantics as the rows of a real database.
+var virtualDB = sql.OpenDB(virtualConnector{})
+
+// newVirtualRows returns a *Rows that iterates over the rows produced by src.
+func newVirtualRows(src rowSource) *Rows {
+	rows, err := virtualDB.QueryContext(context.Background(), "", virtualArg{src: src})
+	if err != nil {
+		src.Close()
+	}
+	return &Rows{
+		Rows: rows,
+		err:  err,
+	}
+}
+
+// virtualArg smuggles a rowSource through database/sql to virtualConn.
+type virtualArg struct {
+	src rowSource
+}
+
+type virtualConnector struct{}
+
+func (virtualConnector) Connect(context.Context) (driver.Conn, error) { return virtualConn{}, nil }
+func (virtualConnector) Driver() driver.Driver                        { return virtualDriver{} }
+
+type virtualDriver struct{}
+
+func (virtualDriver) Open(string) (driver.Conn, error) { return virtualConn{}, nil }
+
+var errVirtualConn = errors.New("asynql: operation is not supported on in-process rows")
+
+type virtualConn struct{}
+
+func (virtualConn) Prepare(string) (driver.Stmt, error) { return nil, errVirtualConn }
+func (virtualConn) Close() error                        { return nil }
+func (virtualConn) Begin() (driver.Tx, error)           { return nil, errVirtualConn }
+
+func (virtualConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(virtualArg); ok {
+		return nil
+	}
+	return errVirtualConn
+}
+
+func (virtualConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 1 {
+		return nil, errVirtualConn
+	}
+	arg, ok := args[0].Value.(virtualArg)
+	if !ok {
+		return nil, errVirtualConn
+	}
+	return virtualRows{arg.src}, nil
+}
+
+type virtualRows struct {
+	src rowSource
+}
+
+func (r virtualRows) Columns() []string              { return r.src.Columns() }
+func (r virtualRows) Close() error                   { return r.src.Close() }
+func (r virtualRows) Next(dest []driver.Value) error { return r.src.Next(dest) }
+
+// sliceSource is a rowSource that iterates over values held in memory.
+type sliceSource struct {
+	columns []string
+	values  [][]interface{}
+	i       int
+}
+
+func (s *sliceSource) Columns() []string {
+	return s.columns
+}
+
+func (s *sliceSource) Next(dest []driver.Value) error {
+	if s.i >= len(s.values) {
+		return io.EOF
+	}
+	copyValues(dest, s.values[s.i])
+	s.i++
+	return nil
+}
+
+func (s *sliceSource) Close() error {
+	return nil
+}
+
+// copyValues copies row to dest.
+// Byte slices are copied as well, so that a consumer that modifies them cannot corrupt row.
+func copyValues(dest []driver.Value, row []interface{}) {
+	for i, v := range row {
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		dest[i] = v
+	}
+}
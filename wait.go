@@ -0,0 +1,194 @@
+package asynql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Errer is the interface that wraps the Err method.
+// The values sent on the channels of asynql, such as *Result, *Rows and *Row, implement it.
+type Errer interface {
+	Err() error
+}
+
+// WaitAll waits for the values of all of chs, and returns them in the same order as chs.
+// The returned error is the first non-nil error of the values in the order of chs.
+// The value of a channel that is closed without a value is nil, and its error is ErrChannelClosed.
+// Note that *Rows and *Row hold their connection until they are consumed,
+// so waiting for more of them than the connections of the pool blocks forever.
+func WaitAll[T Errer](chs ...<-chan T) ([]T, error) {
+	vs := make([]T, len(chs))
+	var err error
+	for i, ch := range chs {
+		var e error
+		if vs[i], e = receive(ch); e != nil && err == nil {
+			err = e
+		}
+	}
+	return vs, err
+}
+
+// WaitAny waits until any of chs sends a value, and returns the index of the channel, the value and its error.
+// If ctx is done first, WaitAny returns -1 and ctx.Err().
+// The values of the other channels are received in the background and discarded; *Rows and *Row among them are released.
+func WaitAny[T Errer](ctx context.Context, chs ...<-chan T) (int, T, error) {
+	fanin := fanIn(chs)
+	select {
+	case v := <-fanin:
+		go discardN(fanin, len(chs)-1)
+		return v.i, v.v, v.err
+	case <-ctx.Done():
+		go discardN(fanin, len(chs))
+		var zero T
+		return -1, zero, ctx.Err()
+	}
+}
+
+// First waits for the first value of chs that has no error, and returns it.
+// If all the values have errors, First returns the error of the last one.
+// If ctx is done first, First returns ctx.Err().
+// The values of the other channels are received in the background and discarded; *Rows and *Row among them are released.
+func First[T Errer](ctx context.Context, chs ...<-chan T) (T, error) {
+	var zero T
+	if len(chs) == 0 {
+		return zero, nil
+	}
+	fanin := fanIn(chs)
+	var err error
+	for n := len(chs); n > 0; n-- {
+		select {
+		case v := <-fanin:
+			if err = v.err; err == nil {
+				go discardN(fanin, n-1)
+				return v.v, nil
+			}
+		case <-ctx.Done():
+			go discardN(fanin, n)
+			return zero, ctx.Err()
+		}
+	}
+	return zero, err
+}
+
+// Selection represents a value chosen by SelectAny.
+type Selection struct {
+	// Index is the index of the channel that sent Value.
+	Index int
+
+	// Value is the value sent on the channel, or nil if the channel was closed.
+	Value Errer
+}
+
+// Err returns the error of Value, or ErrChannelClosed if the channel was closed.
+func (s *Selection) Err() error {
+	if s.Value == nil {
+		if s.Index < 0 {
+			return nil
+		}
+		return ErrChannelClosed
+	}
+	return s.Value.Err()
+}
+
+// Result returns Value if it is a *Result, or nil otherwise.
+func (s *Selection) Result() *Result {
+	r, _ := s.Value.(*Result)
+	return r
+}
+
+// Rows returns Value if it is a *Rows, or nil otherwise.
+func (s *Selection) Rows() *Rows {
+	rs, _ := s.Value.(*Rows)
+	return rs
+}
+
+// Row returns Value if it is a *Row, or nil otherwise.
+func (s *Selection) Row() *Row {
+	r, _ := s.Value.(*Row)
+	return r
+}
+
+// SelectAny is the same as WaitAny, but chs can be the channels of different types, such as <-chan *Result, <-chan *Rows and <-chan *Row.
+// Each of chs must be a channel whose values implement Errer.
+// It returns the Selection of the first value and its error, or ctx.Err() if ctx is done first.
+//
+//	s, err := asynql.SelectAny(ctx, db.ExecContext(ctx, query1), db.QueryContext(ctx, query2))
+//	switch s.Index {
+//	case 0:
+//		n, err := s.Result().RowsAffected()
+//	case 1:
+//		defer s.Rows().Close()
+//	}
+func SelectAny(ctx context.Context, chs ...interface{}) (*Selection, error) {
+	cases := make([]reflect.SelectCase, len(chs)+1)
+	errerType := reflect.TypeOf((*Errer)(nil)).Elem()
+	for i, ch := range chs {
+		v := reflect.ValueOf(ch)
+		if v.Kind() != reflect.Chan || v.Type().ChanDir()&reflect.RecvDir == 0 || !v.Type().Elem().Implements(errerType) {
+			return nil, fmt.Errorf("asynql: SelectAny: argument %d is %T; want a channel of an Errer", i, ch)
+		}
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: v}
+	}
+	cases[len(chs)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+	chosen, v, ok := reflect.Select(cases)
+	for i, c := range cases[:len(chs)] {
+		if i != chosen {
+			go func(ch reflect.Value) {
+				if v, ok := ch.Recv(); ok {
+					discard(v.Interface())
+				}
+			}(c.Chan)
+		}
+	}
+	if chosen == len(chs) {
+		return &Selection{Index: -1}, ctx.Err()
+	}
+	s := &Selection{Index: chosen}
+	if ok {
+		s.Value = v.Interface().(Errer)
+	}
+	return s, s.Err()
+}
+
+type indexed[T any] struct {
+	i   int
+	v   T
+	err error
+}
+
+// fanIn receives the values of chs concurrently, and sends them to the returned channel in the order of arrival.
+// The returned channel is buffered so that the values that nobody waits for don't leak goroutines.
+func fanIn[T Errer](chs []<-chan T) <-chan indexed[T] {
+	fanin := make(chan indexed[T], len(chs))
+	for i, ch := range chs {
+		go func(i int, ch <-chan T) {
+			v, err := receive(ch)
+			fanin <- indexed[T]{i: i, v: v, err: err}
+		}(i, ch)
+	}
+	return fanin
+}
+
+func discardN[T any](ch <-chan indexed[T], n int) {
+	for ; n > 0; n-- {
+		if v := <-ch; v.err != ErrChannelClosed {
+			discard(v.v)
+		}
+	}
+}
+
+// discard releases the resources held by v, which is a value nobody will receive.
+// A *Row holds its connection until it is scanned, so it is scanned into nothing.
+func discard(v interface{}) {
+	if r, ok := v.(*Row); ok {
+		r.Scan()
+		return
+	}
+	if e, ok := v.(Errer); ok && e.Err() != nil {
+		return
+	}
+	if c, ok := v.(interface{ Close() error }); ok {
+		c.Close()
+	}
+}
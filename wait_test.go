@@ -0,0 +1,198 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWaitAll(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	results, err := asynql.WaitAll(
+		db.Exec(`INSERT INTO test_table (id, name) VALUES (3, "jack")`),
+		db.Exec(`INSERT INTO unknown_table (id) VALUES (4)`),
+		db.Exec(`INSERT INTO test_table (id, name) VALUES (5, "sara")`),
+	)
+	var actual interface{} = len(results)
+	var expected interface{} = 3
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`len(asynql.WaitAll(...)) => %#v; want %#v`, actual, expected)
+	}
+	actual = err
+	expected = results[1].Err()
+	if err == nil || !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.WaitAll(...) error => %#v; want %#v`, actual, expected)
+	}
+	for _, i := range []int{0, 2} {
+		if err := results[i].Err(); err != nil {
+			t.Errorf(`results[%d].Err() => %#v; want nil`, i, err)
+		}
+	}
+}
+
+func TestWaitAny(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	never := make(chan *asynql.Rows)
+	i, rows, err := asynql.WaitAny(context.Background(), never, db.Query(`SELECT id FROM test_table`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	var actual interface{} = i
+	var expected interface{} = 1
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.WaitAny(ctx, never, db.Query(...)) index => %#v; want %#v`, actual, expected)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	i, _, err = asynql.WaitAny(ctx, never)
+	actual = []interface{}{i, err}
+	expected = []interface{}{-1, context.DeadlineExceeded}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.WaitAny(ctx, never) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestWaitAny_Row(t *testing.T) {
+	db := newTestFileDB(t)
+	defer db.Close()
+	query := `SELECT name FROM test_table WHERE id = ?`
+	_, row, err := asynql.WaitAny(context.Background(), db.QueryRow(query, 1), db.QueryRow(query, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := row.Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	// The losing row is released in the background.
+	deadline := time.Now().Add(time.Second)
+	for db.Stats().InUse != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	var actual interface{} = db.Stats().InUse
+	var expected interface{} = 0
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Stats().InUse after asynql.WaitAny(ctx, db.QueryRow(...), db.QueryRow(...)) => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestSelectAny(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	never := make(chan *asynql.Result)
+	s, err := asynql.SelectAny(ctx, never, db.QueryRowContext(ctx, `SELECT name FROM test_table WHERE id = 2`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := s.Row().Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = []interface{}{s.Index, s.Result(), s.Rows() == nil, name}
+	var expected interface{} = []interface{}{1, (*asynql.Result)(nil), true, "bob"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.SelectAny(ctx, never, db.QueryRowContext(...)) => %#v; want %#v`, actual, expected)
+	}
+
+	s, err = asynql.SelectAny(ctx, never, db.QueryContext(ctx, `SELECT id FROM unknown_table`))
+	if err == nil || s.Rows() == nil {
+		t.Errorf(`asynql.SelectAny(ctx, never, db.QueryContext(...)) => %#v, %#v; want *Rows with error`, s, err)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	s, err = asynql.SelectAny(tctx, never)
+	actual = []interface{}{s.Index, err}
+	expected = []interface{}{-1, context.DeadlineExceeded}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.SelectAny(ctx, never) => %#v; want %#v`, actual, expected)
+	}
+
+	if _, err := asynql.SelectAny(ctx, make(chan int)); err == nil {
+		t.Errorf(`asynql.SelectAny(ctx, make(chan int)) => nil; want error`)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	rows, err := asynql.First(context.Background(),
+		db.Query(`SELECT id FROM unknown_table`),
+		db.Query(`SELECT name FROM test_table WHERE id = 1`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	var actual interface{} = names
+	var expected interface{} = []string{"alice"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`asynql.First(ctx, ...) => %#v; want %#v`, actual, expected)
+	}
+
+	_, err = asynql.First(context.Background(), db.Query(`SELECT id FROM unknown_table`))
+	if err == nil {
+		t.Errorf(`asynql.First(ctx, failing) => nil; want error`)
+	}
+}
+
+func TestWait_ChannelClosed(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	received := func() <-chan *asynql.Result {
+		ch := db.ExecContext(ctx, `UPDATE test_table SET name = name`)
+		<-ch
+		return ch
+	}
+
+	results, err := asynql.WaitAll(db.ExecContext(ctx, `UPDATE test_table SET name = name`), received())
+	if !errors.Is(err, asynql.ErrChannelClosed) || results[0].Err() != nil || results[1] != nil {
+		t.Errorf(`asynql.WaitAll(ch, closed) => %#v, %#v; want [ok, nil], %#v`, results, err, asynql.ErrChannelClosed)
+	}
+	if _, _, err := asynql.WaitAny(ctx, received()); !errors.Is(err, asynql.ErrChannelClosed) {
+		t.Errorf(`asynql.WaitAny(ctx, closed) => %#v; want %#v`, err, asynql.ErrChannelClosed)
+	}
+	if _, err := asynql.First(ctx, received(), received()); !errors.Is(err, asynql.ErrChannelClosed) {
+		t.Errorf(`asynql.First(ctx, closed, closed) => %#v; want %#v`, err, asynql.ErrChannelClosed)
+	}
+	if _, err := asynql.SelectAny(ctx, received()); !errors.Is(err, asynql.ErrChannelClosed) {
+		t.Errorf(`asynql.SelectAny(ctx, closed) => %#v; want %#v`, err, asynql.ErrChannelClosed)
+	}
+	o := <-asynql.Then(received(), func(r *asynql.Result) (int64, error) {
+		return r.RowsAffected()
+	})
+	if err := o.Err(); !errors.Is(err, asynql.ErrChannelClosed) {
+		t.Errorf(`asynql.Then(closed, fn) => %#v; want %#v`, err, asynql.ErrChannelClosed)
+	}
+	closed := make(chan *asynql.Outcome[int64])
+	close(closed)
+	o = <-asynql.Catch(closed, func(err error) (int64, error) {
+		return 0, err
+	})
+	if err := o.Err(); !errors.Is(err, asynql.ErrChannelClosed) {
+		t.Errorf(`asynql.Catch(closed, handler) => %#v; want %#v`, err, asynql.ErrChannelClosed)
+	}
+	rows := db.QueryContext(ctx, `SELECT name FROM test_table`)
+	(<-rows).Close()
+	if _, err := asynql.Materialize(<-rows); !errors.Is(err, asynql.ErrChannelClosed) {
+		t.Errorf(`asynql.Materialize(<-closed) => %#v; want %#v`, err, asynql.ErrChannelClosed)
+	}
+}
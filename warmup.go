@@ -0,0 +1,131 @@
+package asynql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// WithStmtCache returns an Option that keeps the statements prepared by Warmup,
+// and uses them for Exec, Query and QueryRow of the DB with the same query.
+// The statements are closed when the DB is closed.
+func WithStmtCache() Option {
+	return func(db *DB) {
+		db.stmtCache = true
+	}
+}
+
+// WithWarmConnections returns an Option that opens and pings n connections concurrently when the DB is opened,
+// so that the first burst of operations doesn't wait for the handshakes of the connections.
+// SetMaxIdleConns is raised to n if needed to keep them in the pool.
+// Open fails if a connection cannot be opened, but OpenDB, which cannot fail, leaves the connections to be opened on demand.
+// WithWarmConnections has no effect on New because the pool of the given *sql.DB already exists.
+func WithWarmConnections(n int) Option {
+	return func(db *DB) {
+		db.warmConns = n
+	}
+}
+
+// defaultMaxIdleConns is the default of SetMaxIdleConns of database/sql.
+const defaultMaxIdleConns = 2
+
+// warmConnections opens and pings db.warmConns connections concurrently, and then returns them to the pool.
+func (db *DB) warmConnections(ctx context.Context) error {
+	n := db.warmConns
+	if n <= 0 {
+		return nil
+	}
+	if n > defaultMaxIdleConns {
+		db.SetMaxIdleConns(n)
+	}
+	conns := make([]*sql.Conn, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := db.DB.Conn(ctx)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			conns[i] = conn
+			errs[i] = conn.PingContext(ctx)
+		}(i)
+	}
+	wg.Wait()
+	for _, conn := range conns {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Warmup prepares queries concurrently, so that the first requests don't pay the latency of preparing them.
+// It also validates the queries, and returns the errors of those that cannot be prepared.
+// As the queries are prepared concurrently, up to len(queries) connections are opened within the limit of SetMaxOpenConns,
+// which stay in the pool within the limit of SetMaxIdleConns.
+//
+// With WithStmtCache, the prepared statements are kept and used by the operations of the DB.
+// Otherwise, they are closed after preparation, which still warms up the connections and the caches of the driver and the database, if any.
+func (db *DB) Warmup(ctx context.Context, queries ...string) error {
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func(i int, query string) {
+			defer wg.Done()
+			errs[i] = db.warmup(ctx, query)
+		}(i, query)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (db *DB) warmup(ctx context.Context, query string) error {
+	if db.stmtCache && db.cachedStmt(query) != nil {
+		return nil
+	}
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	if !db.stmtCache {
+		return stmt.Close()
+	}
+	db.stmtMu.Lock()
+	defer db.stmtMu.Unlock()
+	if _, ok := db.stmts[query]; ok {
+		return stmt.Close()
+	}
+	if db.stmts == nil {
+		db.stmts = make(map[string]*Stmt)
+	}
+	db.stmts[query] = stmt
+	return nil
+}
+
+// cachedStmt returns the cached statement of query, or nil.
+func (db *DB) cachedStmt(query string) *Stmt {
+	if !db.stmtCache {
+		return nil
+	}
+	db.stmtMu.Lock()
+	defer db.stmtMu.Unlock()
+	return db.stmts[query]
+}
+
+// closeStmts closes the cached statements.
+func (db *DB) closeStmts() error {
+	db.stmtMu.Lock()
+	defer db.stmtMu.Unlock()
+	var errs []error
+	for query, stmt := range db.stmts {
+		errs = append(errs, stmt.Close())
+		delete(db.stmts, query)
+	}
+	return errors.Join(errs...)
+}
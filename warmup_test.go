@@ -0,0 +1,75 @@
+package asynql_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_Warmup(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		var opts []asynql.Option
+		if cache {
+			opts = append(opts, asynql.WithStmtCache())
+		}
+		db := newTestDB(t, opts...)
+		queries := []string{
+			`SELECT name FROM test_table WHERE id = ?`,
+			`SELECT name FROM test_table ORDER BY id`,
+			`UPDATE test_table SET name = ? WHERE id = ?`,
+		}
+		if err := db.Warmup(context.Background(), queries...); err != nil {
+			t.Fatal(err)
+		}
+		if err := (<-db.Exec(queries[2], "carol", 1)).Err(); err != nil {
+			t.Fatal(err)
+		}
+		var name string
+		if err := (<-db.QueryRow(queries[0], 1)).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = []interface{}{name, scanNames(t, <-db.Query(queries[1]))}
+		var expected interface{} = []interface{}{"carol", []string{"carol", "bob"}}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf(`with cache %v: results after db.Warmup => %#v; want %#v`, cache, actual, expected)
+		}
+
+		if err := db.Warmup(context.Background(), `SELECT * FROM missing_table`, queries[0]); err == nil {
+			t.Errorf(`with cache %v: db.Warmup(ctx, "SELECT * FROM missing_table", ...) => nil; want error`, cache)
+		}
+		if err := db.Close(); err != nil {
+			t.Errorf(`with cache %v: db.Close() => %#v; want nil`, cache, err)
+		}
+	}
+}
+
+func TestWithWarmConnections(t *testing.T) {
+	var mu sync.Mutex
+	inits := 0
+	db, err := asynql.Open("sqlite3", ":memory:", asynql.WithWarmConnections(3), asynql.WithConnInit(func(ctx context.Context, conn *sql.Conn) error {
+		mu.Lock()
+		defer mu.Unlock()
+		inits++
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	stats := db.Stats()
+	var actual interface{} = []int{inits, stats.OpenConnections, stats.Idle}
+	var expected interface{} = []int{3, 3, 3}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`inits, OpenConnections, Idle after Open with WithWarmConnections(3) => %#v; want %#v`, actual, expected)
+	}
+
+	if db, err := asynql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "missing", "test.db"), asynql.WithWarmConnections(2)); err == nil {
+		db.Close()
+		t.Errorf(`asynql.Open with WithWarmConnections(2) and an unreachable database => nil; want error`)
+	}
+}
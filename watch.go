@@ -0,0 +1,136 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrInvalidWatchInterval is reported by Watch when the interval is not positive.
+var ErrInvalidWatchInterval = errors.New("asynql: watch interval must be positive")
+
+// ChangeSet represents a change of the result of a query watched by Watch.
+// The rows are identified by the value of their first column.
+type ChangeSet struct {
+	// Columns is the names of the columns.
+	Columns []string
+
+	// Added is the rows that appeared, in the order of the new result.
+	Added [][]interface{}
+
+	// Removed is the rows that disappeared, in the order of the previous result.
+	Removed [][]interface{}
+
+	// Changed is the rows that have the same key as before but different values, in the order of the new result.
+	Changed []RowChange
+
+	err error
+}
+
+// Err returns an error.
+func (cs *ChangeSet) Err() error {
+	return cs.err
+}
+
+// RowChange represents a row changed between two results.
+type RowChange struct {
+	Old []interface{}
+	New []interface{}
+}
+
+// Watch executes a query with args every interval, and sends the differences from the previous result on the returned channel.
+// The first ChangeSet reports all the rows of the first result as added, and then a ChangeSet is sent only when the result changes.
+// A failed query is reported by a ChangeSet with an error, and the query is retried at the next interval.
+// The channel is closed when ctx is done.
+// If interval is not positive, a ChangeSet with ErrInvalidWatchInterval is sent and the channel is closed.
+//
+// Watch is intended for the drivers that do not support change notifications.
+// A change that is reverted within an interval goes unnoticed.
+func (db *DB) Watch(ctx context.Context, query string, args []interface{}, interval time.Duration) <-chan *ChangeSet {
+	ch := make(chan *ChangeSet)
+	go func() {
+		defer close(ch)
+		if interval <= 0 {
+			select {
+			case ch <- &ChangeSet{err: ErrInvalidWatchInterval}:
+			case <-ctx.Done():
+			}
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var prev *Snapshot
+		for {
+			snap, err := Materialize(<-db.QueryContext(ctx, query, args...))
+			var cs *ChangeSet
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				cs = &ChangeSet{err: err}
+			default:
+				cs = diffSnapshots(prev, snap)
+				prev = snap
+			}
+			if cs != nil {
+				select {
+				case ch <- cs:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// diffSnapshots returns the changes from prev to next, or nil if there are none.
+// prev may be nil, in which case all the rows of next are added.
+func diffSnapshots(prev, next *Snapshot) *ChangeSet {
+	cs := &ChangeSet{
+		Columns: next.Columns,
+	}
+	old := make(map[string][]interface{})
+	if prev != nil {
+		for _, row := range prev.Values {
+			old[rowKey(row)] = row
+		}
+	}
+	seen := make(map[string]bool, len(next.Values))
+	for _, row := range next.Values {
+		key := rowKey(row)
+		seen[key] = true
+		switch o, ok := old[key]; {
+		case !ok:
+			cs.Added = append(cs.Added, row)
+		case !reflect.DeepEqual(o, row):
+			cs.Changed = append(cs.Changed, RowChange{Old: o, New: row})
+		}
+	}
+	if prev != nil {
+		for _, row := range prev.Values {
+			if !seen[rowKey(row)] {
+				cs.Removed = append(cs.Removed, row)
+			}
+		}
+	}
+	if prev != nil && len(cs.Added) == 0 && len(cs.Removed) == 0 && len(cs.Changed) == 0 {
+		return nil
+	}
+	return cs
+}
+
+// rowKey returns the key that identifies row, which is derived from its first column.
+func rowKey(row []interface{}) string {
+	if len(row) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%#v", row[0])
+}
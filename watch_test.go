@@ -0,0 +1,78 @@
+package asynql_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestDB_Watch(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	query := `SELECT id, name FROM test_table ORDER BY id`
+	ch := db.Watch(ctx, query, nil, 10*time.Millisecond)
+
+	cs := <-ch
+	if err := cs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = cs.Added
+	var expected interface{} = [][]interface{}{{int64(1), "alice"}, {int64(2), "bob"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Watch(ctx, %#v, nil, 10ms) first Added => %#v; want %#v`, query, actual, expected)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`INSERT INTO test_table (id, name) VALUES (3, "carol")`,
+		`UPDATE test_table SET name = "bobby" WHERE id = 2`,
+		`DELETE FROM test_table WHERE id = 1`,
+	} {
+		if err := (<-tx.Exec(q)).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	cs = <-ch
+	if err := cs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	actual = []interface{}{cs.Added, cs.Removed, cs.Changed}
+	expected = []interface{}{
+		[][]interface{}{{int64(3), "carol"}},
+		[][]interface{}{{int64(1), "alice"}},
+		[]asynql.RowChange{{Old: []interface{}{int64(2), "bob"}, New: []interface{}{int64(2), "bobby"}}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Watch(ctx, %#v, nil, 10ms) after commit => %#v; want %#v`, query, actual, expected)
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestDB_Watch_InvalidInterval(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	query := `SELECT id, name FROM test_table ORDER BY id`
+	ch := db.Watch(context.Background(), query, nil, 0)
+	var actual interface{} = (<-ch).Err()
+	var expected interface{} = asynql.ErrInvalidWatchInterval
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.Watch(ctx, %#v, nil, 0).Err() => %#v; want %#v`, query, actual, expected)
+	}
+	if _, ok := <-ch; ok {
+		t.Errorf(`db.Watch(ctx, %#v, nil, 0) is open after the error; want closed`, query)
+	}
+}
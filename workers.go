@@ -0,0 +1,112 @@
+package asynql
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned by TrySubmitExec when all the workers are busy and their queue is full.
+var ErrQueueFull = errors.New("asynql: queue is full")
+
+// WithWorkers returns an Option that runs the asynchronous operations of the DB, its statements, and its Tx and Conn
+// on a fixed pool of n worker goroutines instead of starting a goroutine for each operation.
+// The operations wait for a worker in a queue of up to queueSize operations in the order they were called,
+// and the number of them is reported by PoolStats as Queued.
+// When the queue is full, the calls of the operations block until it has room, which applies backpressure to the callers.
+// If ctx of an operation is done while it waits for room, the operation is run in a goroutine of its own so that it fails with the error of ctx.
+// An operation whose ctx is done by the time a worker takes it fails with the error of ctx without executing the query,
+// and is counted by PoolStats as Expired.
+// The priorities given by WithPriority are applied by WithMaxConcurrency, which can be combined with WithWorkers.
+//
+// TrySubmitExec can be used to shed the load instead of waiting for room.
+//
+// The workers are started by the first operation, and are stopped by DB.Close.
+// WithFIFO takes precedence over WithWorkers.
+// If n <= 0, WithWorkers has no effect.
+func WithWorkers(n, queueSize int) Option {
+	return func(db *DB) {
+		db.workers = nil
+		if n > 0 {
+			db.workers = &workerPool{
+				n:     n,
+				queue: make(chan func(), max(queueSize, 0)),
+			}
+		}
+	}
+}
+
+// workerPool runs the operations on a fixed number of goroutines.
+type workerPool struct {
+	n     int
+	queue chan func()
+	once  sync.Once
+
+	// mu guards closed and the sends to queue, so that queue is not closed while an operation is being submitted.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// submit queues op, waiting for room while ctx is not done.
+// op is run in a goroutine of its own if ctx is done first or the pool is closed.
+func (p *workerPool) submit(ctx context.Context, op func()) {
+	p.once.Do(p.start)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.closed {
+		select {
+		case p.queue <- op:
+			return
+		case <-ctx.Done():
+		}
+	}
+	go op()
+}
+
+// trySubmit queues op if the queue has room, and reports whether it has.
+// op is run in a goroutine of its own if the pool is closed.
+func (p *workerPool) trySubmit(op func()) bool {
+	p.once.Do(p.start)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		go op()
+		return true
+	}
+	select {
+	case p.queue <- op:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *workerPool) start() {
+	for i := 0; i < p.n; i++ {
+		go func() {
+			for op := range p.queue {
+				op()
+			}
+		}()
+	}
+}
+
+// close stops the workers after they have run the queued operations.
+func (p *workerPool) close() {
+	p.once.Do(func() {})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+}
+
+// TrySubmitExec is the same as ExecContext, but returns ErrQueueFull instead of blocking
+// if all the workers of WithWorkers are busy and their queue is full, so that the caller can shed the load.
+// An unbuffered queue is full unless a worker is idle.
+// Without WithWorkers, the operations are not queued, so TrySubmitExec never returns ErrQueueFull.
+func (db *DB) TrySubmitExec(ctx context.Context, query string, args ...interface{}) (<-chan *Result, error) {
+	ctx = killable(ctx, db)
+	return submit(ctx, db, db, nil, db.exec(ctx, query, args), true)
+}
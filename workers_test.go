@@ -0,0 +1,138 @@
+package asynql_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/naoina/asynql"
+)
+
+func TestWithWorkers(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithWorkers(1, 2))
+	defer db.Close()
+	results := []<-chan *asynql.Result{db.Exec("block")}
+	<-conn.started
+	results = append(results, db.Exec("a"), db.Exec("b"))
+
+	// The queue is full, so the call blocks until a worker takes an operation.
+	queued := make(chan (<-chan *asynql.Result))
+	go func() {
+		queued <- db.Exec("c")
+	}()
+	select {
+	case <-queued:
+		t.Fatalf(`db.Exec("c") returned with a full queue; want blocked`)
+	case <-time.After(20 * time.Millisecond):
+	}
+	var actual interface{} = db.PoolStats().Queued
+	var expected interface{} = int64(3)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.PoolStats().Queued => %#v; want %#v`, actual, expected)
+	}
+
+	// An operation whose context is done doesn't wait for the queue.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := (<-db.ExecContext(ctx, "canceled")).Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf(`db.ExecContext(canceled, "canceled"); Result.Err() => %#v; want %#v`, err, context.Canceled)
+	}
+
+	close(conn.release)
+	results = append(results, <-queued)
+	if _, err := asynql.WaitAll(results...); err != nil {
+		t.Fatal(err)
+	}
+	actual = conn.queries
+	expected = []string{"block", "a", "b", "c"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`executed queries => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestWithWorkers_Close(t *testing.T) {
+	db := newTestDB(t, asynql.WithWorkers(2, 0))
+	if err := (<-db.Exec(`DELETE FROM test_table WHERE id = 1`)).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The operations after Close fail instead of waiting for the stopped workers.
+	if err := (<-db.Exec(`DELETE FROM test_table`)).Err(); err == nil {
+		t.Errorf(`db.Exec(...) after db.Close(); Result.Err() => nil; want error`)
+	}
+}
+
+func TestDB_TrySubmitExec(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithWorkers(1, 1))
+	defer db.Close()
+	ctx := context.Background()
+	blocked, err := db.TrySubmitExec(ctx, "block")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-conn.started
+	queued, err := db.TrySubmitExec(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := db.TrySubmitExec(ctx, "b")
+	var actual interface{} = []interface{}{ch, err}
+	var expected interface{} = []interface{}{(<-chan *asynql.Result)(nil), asynql.ErrQueueFull}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.TrySubmitExec(ctx, "b") with a full queue => %#v; want %#v`, actual, expected)
+	}
+	actual = db.PoolStats().Queued
+	expected = int64(1)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`db.PoolStats().Queued => %#v; want %#v`, actual, expected)
+	}
+	close(conn.release)
+	if _, err := asynql.WaitAll(blocked, queued); err != nil {
+		t.Fatal(err)
+	}
+	actual = conn.queries
+	expected = []string{"block", "a"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`executed queries => %#v; want %#v`, actual, expected)
+	}
+}
+
+func TestWithWorkers_Expired(t *testing.T) {
+	conn := &orderConn{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	db := asynql.OpenDB(&notifyConnector{conn: conn}, asynql.WithWorkers(1, 1))
+	defer db.Close()
+	blocked := db.Exec("block")
+	<-conn.started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	result := db.ExecContext(ctx, "expired")
+	<-ctx.Done()
+	close(conn.release)
+	if err := (<-blocked).Err(); err != nil {
+		t.Fatal(err)
+	}
+	var qe *asynql.QueryError
+	if err := (<-result).Err(); !errors.Is(err, context.DeadlineExceeded) || !errors.As(err, &qe) {
+		t.Errorf(`db.ExecContext(expired ctx, "expired"); Result.Err() => %#v; want *asynql.QueryError of %#v`, err, context.DeadlineExceeded)
+	}
+	var actual interface{} = []interface{}{conn.queries, db.PoolStats().Expired}
+	var expected interface{} = []interface{}{[]string{"block"}, int64(1)}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf(`executed queries, db.PoolStats().Expired => %#v; want %#v`, actual, expected)
+	}
+}